@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"api-gateway/internal/auth"
+	"api-gateway/internal/cache"
+	"api-gateway/internal/upstream"
+	"api-gateway/pkg/filelogger"
+	requestuser "api-gateway/pkg/hidepost-requestuser"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// RegisterUpstreamsRoute reports health and circuit state of service instances to admins
+func RegisterUpstreamsRoute(
+	route *mux.Route,
+	registry *upstream.Registry,
+	authorizer *auth.Authorizer,
+	fileLogger *filelogger.FileLogger) {
+
+	route.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !authorizeAdmin(w, req, authorizer, fileLogger) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(registry.Status())
+	})
+}
+
+// RegisterCachePurgeRoute removes cached responses of urls with the path prefix from the query,
+// e.g. ?prefix=/api/v1/posts, the whole cache is purged without the prefix
+func RegisterCachePurgeRoute(
+	route *mux.Route,
+	responseCache *cache.Cache,
+	authorizer *auth.Authorizer,
+	fileLogger *filelogger.FileLogger) {
+
+	route.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !authorizeAdmin(w, req, authorizer, fileLogger) {
+			return
+		}
+
+		prefix := req.URL.Query().Get("prefix")
+		purged := responseCache.Purge(prefix)
+		fileLogger.Info("gateway cache is purged", map[string]interface{}{
+			"prefix":  prefix,
+			"purged":  purged,
+			"user_id": req.Header.Get(requestuser.UserIdHeaderKey),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]int{"purged": purged})
+	})
+}
+
+// authorizeAdmin sets user headers of the request and writes the error response if the user isn't admin
+func authorizeAdmin(w http.ResponseWriter, req *http.Request,
+	authorizer *auth.Authorizer, fileLogger *filelogger.FileLogger) bool {
+
+	requestuser.StripIdentityHeaders(req.Header)
+	err := authorizer.SetAuthorizationHeaders(req)
+	if err != nil {
+		fileLogger.Error("failed to authorize gateway admin request", map[string]interface{}{
+			"error":       err.Error(),
+			"request_url": req.URL.String(),
+		})
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if req.Header.Get(requestuser.UserIdHeaderKey) == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	if req.Header.Get(requestuser.UserRoleHeaderKey) != requestuser.UserRoleAdmin {
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"api-gateway/internal/upstream"
+	"api-gateway/pkg/filelogger"
+	requestuser "api-gateway/pkg/hidepost-requestuser"
+	"fmt"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	ServiceHostConfigKey  = "AUTHENTICATION_SERVICE"
+	CacheTtlConfigKey     = "AUTH_CACHE_TTL"
+	AuthorizationEndpoint = "/api/v1/auth/service/authorize"
+	HealthCheckEndpoint   = "/api/v1/auth/admin/healthcheck"
+
+	// requestTimeout limits requests the gateway makes to the auth service itself
+	requestTimeout = 10 * time.Second
+)
+
+// Authorizer sets user headers of requests by their access tokens
+type Authorizer struct {
+	pool     *upstream.Pool
+	client   *http.Client
+	verifier *tokenVerifier
+	cache    *authorizationCache
+}
+
+func NewAuthorizer(
+	serviceHost string,
+	cacheTtl int,
+	registry *upstream.Registry,
+	fileLogger *filelogger.FileLogger,
+	cfgService *configService.ConfigServiceManager) *Authorizer {
+
+	pool := registry.Pool(ServiceHostConfigKey, serviceHost, HealthCheckEndpoint)
+	client := &http.Client{Transport: pool, Timeout: requestTimeout}
+	s := &Authorizer{
+		pool:     pool,
+		client:   client,
+		verifier: newTokenVerifier(client, pool.Name()),
+		cache:    newAuthorizationCache(time.Duration(cacheTtl) * time.Second),
+	}
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err != nil {
+			fileLogger.Error("failed to parse value for auth cache ttl from config", map[string]interface{}{
+				"error": err.Error(),
+				"key":   ss.Key,
+				"value": ss.Value,
+			})
+			return
+		}
+		s.cache.setTtl(time.Duration(value) * time.Second)
+	}, CacheTtlConfigKey)
+
+	return s
+}
+
+// SetAuthorizationHeaders sets user headers of the request by the access token. Signature and expiry
+// are verified locally, ban, role and revocation checks of the session are asked from the auth service
+// and cached for the short time.
+func (s *Authorizer) SetAuthorizationHeaders(req *http.Request) error {
+
+	reqAuthHeader := req.Header.Get(requestuser.AuthorizationHeaderKey)
+	if reqAuthHeader == "" {
+		return nil
+	}
+
+	claims, err := s.verifier.verify(reqAuthHeader)
+	if err != nil {
+		return fmt.Errorf("failed to verify token: %v", err)
+	}
+	if claims == nil {
+		setUserHeaders(req, authorization{role: requestuser.UserRoleUnknown})
+		return nil
+	}
+
+	item, found := s.cache.get(claims.sessionId)
+	if !found || (item.userId != "" && item.userId != claims.userId) {
+		item, err = s.authorize(req, reqAuthHeader)
+		if err != nil {
+			return err
+		}
+		s.cache.set(claims.sessionId, item)
+	}
+	setUserHeaders(req, item)
+	return nil
+}
+
+func setUserHeaders(req *http.Request, item authorization) {
+	req.Header.Set(requestuser.UserIdHeaderKey, item.userId)
+	req.Header.Set(requestuser.UserRoleHeaderKey, item.role)
+	req.Header.Set(requestuser.UserIsBannedHeaderKey, item.banned)
+}
+
+func (s *Authorizer) authorize(req *http.Request, reqAuthHeader string) (authorization, error) {
+	authorizationUrl := url.URL{
+		Scheme: "http",
+		Host:   s.pool.Name(),
+		Path:   AuthorizationEndpoint,
+	}
+
+	serviceRequest, err := http.NewRequest("HEAD", authorizationUrl.String(), nil)
+	if err != nil {
+		return authorization{}, fmt.Errorf("failed to create auth service authorization request: %v", err)
+	}
+	serviceRequest.Header.Set(requestuser.AuthorizationHeaderKey, reqAuthHeader)
+	serviceRequest.Header.Set("USER-REQUEST-URL", req.URL.String())
+
+	serviceResponse, err := s.client.Do(serviceRequest)
+	if err != nil {
+		return authorization{}, fmt.Errorf("failed to send auth service authorization request: %v", err)
+	}
+	defer serviceResponse.Body.Close()
+
+	if serviceResponse.StatusCode != 200 && serviceResponse.StatusCode != 401 {
+		return authorization{}, fmt.Errorf("unexpected auth service authorization response status code: %d", serviceResponse.StatusCode)
+	}
+
+	_, _ = io.Copy(io.Discard, serviceResponse.Body)
+	return authorization{
+		userId: serviceResponse.Header.Get(requestuser.UserIdHeaderKey),
+		role:   serviceResponse.Header.Get(requestuser.UserRoleHeaderKey),
+		banned: serviceResponse.Header.Get(requestuser.UserIsBannedHeaderKey),
+	}, nil
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// authorization is the answer of the auth service about the user of the session,
+// the empty user id means the session is revoked or the user is disabled
+type authorization struct {
+	userId    string
+	role      string
+	banned    string
+	expiresAt time.Time
+}
+
+// authorizationCache keeps ban, role and revocation checks of sessions for a short time,
+// so they are taken into account with the delay of at most the ttl
+type authorizationCache struct {
+	items   map[string]authorization
+	ttl     time.Duration
+	sweptAt time.Time
+	mu      *sync.Mutex
+}
+
+func newAuthorizationCache(ttl time.Duration) *authorizationCache {
+	return &authorizationCache{
+		items: make(map[string]authorization),
+		ttl:   ttl,
+		mu:    new(sync.Mutex),
+	}
+}
+
+func (c *authorizationCache) setTtl(ttl time.Duration) {
+	c.mu.Lock()
+	c.ttl = ttl
+	c.mu.Unlock()
+}
+
+func (c *authorizationCache) get(sessionId string) (authorization, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, found := c.items[sessionId]
+	if !found || time.Now().After(item.expiresAt) {
+		return authorization{}, false
+	}
+	return item, true
+}
+
+// set caches the authorization, nothing is cached with zero ttl
+func (c *authorizationCache) set(sessionId string, item authorization) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return
+	}
+	timeNow := time.Now()
+	item.expiresAt = timeNow.Add(c.ttl)
+	c.items[sessionId] = item
+
+	if timeNow.Sub(c.sweptAt) > c.ttl {
+		for key, value := range c.items {
+			if timeNow.After(value.expiresAt) {
+				delete(c.items, key)
+			}
+		}
+		c.sweptAt = timeNow
+	}
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	JwksEndpoint = "/api/v1/auth/.well-known/jwks.json"
+
+	// jwksMaxAge is how long the fetched keys are used before they are fetched again
+	jwksMaxAge = 10 * time.Minute
+	// jwksMinRefetchInterval limits fetches on unknown key ids, so forged tokens can't flood the auth service
+	jwksMinRefetchInterval = 10 * time.Second
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+}
+
+type jwksResponse struct {
+	Keys []jwk `json:"keys"`
+}
+
+type tokenClaims struct {
+	userId    string
+	sessionId string
+}
+
+// tokenVerifier checks signatures and expiry of access tokens with the keys published by the auth service
+type tokenVerifier struct {
+	client      *http.Client
+	serviceHost string
+	keys        map[string]ed25519.PublicKey
+	fetchedAt   time.Time
+	// fetching is closed when the running fetch is done, fetchErr is the error of the last fetch
+	fetching chan struct{}
+	fetchErr error
+	mu       *sync.Mutex
+}
+
+func newTokenVerifier(client *http.Client, serviceHost string) *tokenVerifier {
+	return &tokenVerifier{
+		client:      client,
+		serviceHost: serviceHost,
+		keys:        make(map[string]ed25519.PublicKey),
+		mu:          new(sync.Mutex),
+	}
+}
+
+// verify returns the claims of the valid access token, nil if the token is invalid or expired.
+// Error is returned only if the keys can't be fetched from the auth service.
+func (v *tokenVerifier) verify(authorizationHeader string) (*tokenClaims, error) {
+	rawToken, found := strings.CutPrefix(authorizationHeader, "Bearer ")
+	if !found {
+		return nil, nil
+	}
+
+	var fetchErr error
+	token, err := jwt.Parse(rawToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.key(kid)
+		if err != nil {
+			fetchErr = err
+			return nil, err
+		}
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key id: %s", kid)
+		}
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithExpirationRequired())
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	if err != nil || !token.Valid {
+		return nil, nil
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, nil
+	}
+	userId, _ := claims["user_id"].(string)
+	sessionId, _ := claims["session_id"].(string)
+	// tokens without session aren't access tokens
+	if userId == "" || sessionId == "" {
+		return nil, nil
+	}
+	return &tokenClaims{userId: userId, sessionId: sessionId}, nil
+}
+
+// key returns the cached key by id, the keys are fetched again when they are stale or the id is unknown.
+// Stale keys are still used if the auth service is unavailable. The keys are fetched without the lock held,
+// concurrent requests wait for the running fetch instead of starting their own.
+func (v *tokenVerifier) key(kid string) (ed25519.PublicKey, error) {
+	v.mu.Lock()
+	key, found := v.keys[kid]
+	sinceFetch := time.Since(v.fetchedAt)
+	if (found && sinceFetch < jwksMaxAge) || (!found && sinceFetch < jwksMinRefetchInterval) {
+		v.mu.Unlock()
+		return key, nil
+	}
+
+	if v.fetching != nil {
+		fetching := v.fetching
+		v.mu.Unlock()
+		<-fetching
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		return v.fetchedKey(kid, key, found)
+	}
+
+	fetching := make(chan struct{})
+	v.fetching = fetching
+	v.mu.Unlock()
+
+	keys, err := v.fetchJwks()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err == nil {
+		v.keys = keys
+		v.fetchedAt = time.Now()
+	}
+	v.fetchErr = err
+	v.fetching = nil
+	close(fetching)
+	return v.fetchedKey(kid, key, found)
+}
+
+// fetchedKey returns the key by id after the fetch, or the stale key if the fetch failed.
+// It must be called with the lock held.
+func (v *tokenVerifier) fetchedKey(kid string, staleKey ed25519.PublicKey, staleFound bool) (ed25519.PublicKey, error) {
+	if v.fetchErr != nil {
+		if staleFound {
+			return staleKey, nil
+		}
+		return nil, v.fetchErr
+	}
+	return v.keys[kid], nil
+}
+
+func (v *tokenVerifier) fetchJwks() (map[string]ed25519.PublicKey, error) {
+	jwksUrl := url.URL{
+		Scheme: "http",
+		Host:   v.serviceHost,
+		Path:   JwksEndpoint,
+	}
+	response, err := v.client.Get(jwksUrl.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to send auth service jwks request: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected auth service jwks response status code: %d", response.StatusCode)
+	}
+
+	var jwks jwksResponse
+	if err = json.NewDecoder(response.Body).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("failed to decode auth service jwks response: %v", err)
+	}
+	keys := make(map[string]ed25519.PublicKey, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		if key.Kty != "OKP" || key.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			continue
+		}
+		keys[key.Kid] = x
+	}
+	return keys, nil
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type jwksServer struct {
+	*httptest.Server
+	fetches atomic.Int32
+	failing atomic.Bool
+}
+
+func newJwksServer(t *testing.T, kid string, key ed25519.PublicKey) *jwksServer {
+	s := &jwksServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		if s.failing.Load() || r.URL.Path != JwksEndpoint {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(jwksResponse{Keys: []jwk{{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+			Kid: kid,
+		}}})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) host() string {
+	serverUrl, _ := url.Parse(s.URL)
+	return serverUrl.Host
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return "Bearer " + signed
+}
+
+func TestTokenVerifierVerify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	server := newJwksServer(t, "key-1", publicKey)
+
+	expires := time.Now().Add(time.Hour).Unix()
+	validClaims := jwt.MapClaims{"user_id": "user", "session_id": "session", "exp": expires}
+
+	tests := []struct {
+		name   string
+		header string
+		claims *tokenClaims
+	}{
+		{
+			name:   "valid token",
+			header: signToken(t, jwt.SigningMethodEdDSA, "key-1", privateKey, validClaims),
+			claims: &tokenClaims{userId: "user", sessionId: "session"},
+		},
+		{
+			name: "expired token",
+			header: signToken(t, jwt.SigningMethodEdDSA, "key-1", privateKey,
+				jwt.MapClaims{"user_id": "user", "session_id": "session", "exp": time.Now().Add(-time.Minute).Unix()}),
+		},
+		{
+			name: "token without expiry",
+			header: signToken(t, jwt.SigningMethodEdDSA, "key-1", privateKey,
+				jwt.MapClaims{"user_id": "user", "session_id": "session"}),
+		},
+		{
+			name: "token without session",
+			header: signToken(t, jwt.SigningMethodEdDSA, "key-1", privateKey,
+				jwt.MapClaims{"user_id": "user", "exp": expires}),
+		},
+		{
+			name:   "token signed by other key",
+			header: signToken(t, jwt.SigningMethodEdDSA, "key-1", otherKey, validClaims),
+		},
+		{
+			name:   "unknown key id",
+			header: signToken(t, jwt.SigningMethodEdDSA, "key-2", privateKey, validClaims),
+		},
+		{
+			name:   "hmac token",
+			header: signToken(t, jwt.SigningMethodHS256, "key-1", []byte(publicKey), validClaims),
+		},
+		{
+			name:   "header without bearer",
+			header: "Basic dXNlcjpwYXNzd29yZA==",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := newTokenVerifier(http.DefaultClient, server.host())
+			claims, err := verifier.verify(tt.header)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.claims, claims)
+		})
+	}
+}
+
+func TestTokenVerifierKeys(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	header := signToken(t, jwt.SigningMethodEdDSA, "key-1", privateKey,
+		jwt.MapClaims{"user_id": "user", "session_id": "session", "exp": time.Now().Add(time.Hour).Unix()})
+	unknownHeader := signToken(t, jwt.SigningMethodEdDSA, "key-2", privateKey,
+		jwt.MapClaims{"user_id": "user", "session_id": "session", "exp": time.Now().Add(time.Hour).Unix()})
+
+	tests := []struct {
+		name string
+		// fetchedAgo is the age of the cached keys, zero means nothing is cached
+		fetchedAgo time.Duration
+		failing    bool
+		header     string
+		valid      bool
+		wantErr    bool
+		fetches    int32
+	}{
+		{name: "keys are fetched", header: header, valid: true, fetches: 1},
+		{name: "fresh keys are cached", fetchedAgo: time.Minute, header: header, valid: true, fetches: 0},
+		{name: "stale keys are refetched", fetchedAgo: jwksMaxAge, header: header, valid: true, fetches: 1},
+		{
+			name: "stale keys are used if the auth service fails", fetchedAgo: jwksMaxAge, failing: true,
+			header: header, valid: true, fetches: 1,
+		},
+		{name: "missing keys fail if the auth service fails", failing: true, header: header, wantErr: true, fetches: 1},
+		{
+			name: "unknown key id is refetched", fetchedAgo: jwksMinRefetchInterval, header: unknownHeader,
+			fetches: 1,
+		},
+		{
+			name: "unknown key id isn't refetched too often", fetchedAgo: time.Second, header: unknownHeader,
+			fetches: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newJwksServer(t, "key-1", publicKey)
+			server.failing.Store(tt.failing)
+			verifier := newTokenVerifier(http.DefaultClient, server.host())
+			if tt.fetchedAgo > 0 {
+				verifier.keys["key-1"] = publicKey
+				verifier.fetchedAt = time.Now().Add(-tt.fetchedAgo)
+			}
+
+			claims, err := verifier.verify(tt.header)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.valid, claims != nil)
+			assert.Equal(t, tt.fetches, server.fetches.Load())
+		})
+	}
+}
+
+func TestTokenVerifierConcurrentFetch(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	server := newJwksServer(t, "key-1", publicKey)
+	header := signToken(t, jwt.SigningMethodEdDSA, "key-1", privateKey,
+		jwt.MapClaims{"user_id": "user", "session_id": "session", "exp": time.Now().Add(time.Hour).Unix()})
+	verifier := newTokenVerifier(http.DefaultClient, server.host())
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claims, err := verifier.verify(header)
+			assert.Nil(t, err)
+			assert.NotNil(t, claims)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), server.fetches.Load())
+}
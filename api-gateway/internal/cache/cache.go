@@ -0,0 +1,221 @@
+package cache
+
+import (
+	proxypass "api-gateway/internal/proxy-pass"
+	"api-gateway/pkg/filelogger"
+	requestuser "api-gateway/pkg/hidepost-requestuser"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	MaxSizeConfigKey = "CACHE_MAX_SIZE"
+
+	CacheStatusHeaderKey = "X-Cache"
+
+	cacheStatusHit         = "HIT"
+	cacheStatusMiss        = "MISS"
+	cacheStatusRevalidated = "REVALIDATED"
+)
+
+// Cache is the shared HTTP cache of anonymous GET requests. It honours Cache-Control, ETag and Vary
+// of upstream responses, stale entries with the ETag are revalidated by the conditional request.
+// Concurrent misses of the same url are coalesced, so only one request goes to the upstream.
+type Cache struct {
+	store   *Store
+	flights map[string]*flight
+	mu      *sync.Mutex
+}
+
+// flight is the upstream request of the miss, other requests of the key wait for it
+type flight struct {
+	done chan struct{}
+}
+
+// NewCache creates the cache bounded by the size in bytes, the size is updated from config
+func NewCache(maxSize int64,
+	cfgService *configService.ConfigServiceManager,
+	fileLogger *filelogger.FileLogger) *Cache {
+
+	c := &Cache{
+		store:   NewStore(maxSize),
+		flights: make(map[string]*flight),
+		mu:      new(sync.Mutex),
+	}
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.ParseInt(ss.Value, 10, 64)
+		if err != nil {
+			fileLogger.Error("failed to parse value for cache max size from config", map[string]interface{}{
+				"error": err.Error(),
+				"key":   ss.Key,
+				"value": ss.Value,
+			})
+			return
+		}
+		c.store.SetMaxSize(value)
+	}, MaxSizeConfigKey)
+
+	return c
+}
+
+// Purge removes cached responses of urls with the path prefix and returns their count
+func (c *Cache) Purge(prefix string) int {
+	return c.store.Purge(prefix)
+}
+
+// Serve answers the request from the cache or by the forward func, which proxies the request to the upstream.
+// Requests of authenticated users and requests which can't be cached are forwarded as is.
+func (c *Cache) Serve(w http.ResponseWriter, req *http.Request, forward http.HandlerFunc) {
+	if !c.applies(req) {
+		forward(w, req)
+		return
+	}
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") {
+		forward(w, req)
+		return
+	}
+	revalidate := reqCC.has("no-cache") || reqCC["max-age"] == "0"
+
+	key := c.store.key(req)
+	e := c.store.get(key)
+	if e != nil && !revalidate && e.fresh(time.Now()) {
+		serveEntry(w, req, e, cacheStatusHit)
+		return
+	}
+
+	f, leader := c.join(key)
+	if !leader {
+		<-f.done
+		e = c.store.get(c.store.key(req))
+		if e != nil && e.fresh(time.Now()) {
+			serveEntry(w, req, e, cacheStatusHit)
+			return
+		}
+		forward(w, req)
+		return
+	}
+	defer c.leave(key, f)
+
+	c.fetch(w, req, e, forward)
+}
+
+// applies reports whether the request may be served from the cache, only anonymous GET requests are cached
+func (c *Cache) applies(req *http.Request) bool {
+	return req.Method == http.MethodGet &&
+		req.Header.Get(requestuser.AuthorizationHeaderKey) == "" &&
+		req.Header.Get(requestuser.UserRoleHeaderKey) == "" &&
+		req.Header.Get("Upgrade") == "" &&
+		c.store.enabled()
+}
+
+func (c *Cache) join(key string) (*flight, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, found := c.flights[key]; found {
+		return f, false
+	}
+	f := &flight{done: make(chan struct{})}
+	c.flights[key] = f
+	return f, true
+}
+
+func (c *Cache) leave(key string, f *flight) {
+	c.mu.Lock()
+	delete(c.flights, key)
+	c.mu.Unlock()
+	close(f.done)
+}
+
+// fetch forwards the request to the upstream and stores the response, the stale entry is revalidated by its ETag.
+// Conditional headers of the client are answered by the cache, so the upstream sends the full response.
+func (c *Cache) fetch(w http.ResponseWriter, req *http.Request, stale *entry, forward http.HandlerFunc) {
+	out := req.Clone(req.Context())
+	out.Header.Del("If-None-Match")
+	out.Header.Del("If-Modified-Since")
+	revalidating := stale != nil && stale.etag() != ""
+	if revalidating {
+		out.Header.Set("If-None-Match", stale.etag())
+	}
+
+	recorder := newRecorder(w, c.store.maxEntrySize(), revalidating)
+	forward(recorder, out)
+	if !recorder.buffered() {
+		if stale != nil {
+			c.store.delete(stale.key)
+		}
+		return
+	}
+
+	timeNow := time.Now()
+	if recorder.status == http.StatusNotModified {
+		// the stale entry is still valid, its headers are updated by the ones of the revalidation response
+		header := stale.header.Clone()
+		for name, values := range storedHeader(recorder.header) {
+			header[name] = values
+		}
+		e := &entry{status: stale.status, header: header, body: stale.body, storedAt: timeNow}
+		c.store.delete(stale.key)
+		if lifetime, ok := freshness(e.status, e.header, timeNow); ok {
+			names, _ := varyNames(e.header)
+			e.expiresAt = timeNow.Add(lifetime)
+			c.store.set(req, e, names)
+		}
+		w.Header().Set(proxypass.RequestIdHeaderKey, recorder.header.Get(proxypass.RequestIdHeaderKey))
+		serveEntry(w, req, e, cacheStatusRevalidated)
+		return
+	}
+
+	e := &entry{
+		status:   recorder.status,
+		header:   storedHeader(recorder.header),
+		body:     recorder.body.Bytes(),
+		storedAt: timeNow,
+	}
+	if lifetime, ok := freshness(e.status, e.header, timeNow); ok {
+		names, _ := varyNames(e.header)
+		e.expiresAt = timeNow.Add(lifetime)
+		c.store.set(req, e, names)
+	} else if stale != nil {
+		c.store.delete(stale.key)
+	}
+	copyHeader(w.Header(), recorder.header)
+	writeEntry(w, req, e, cacheStatusMiss)
+}
+
+// serveEntry writes the cached response with its age, the not modified status is answered
+// if the client has the same version
+func serveEntry(w http.ResponseWriter, req *http.Request, e *entry, cacheStatus string) {
+	copyHeader(w.Header(), e.header)
+	age := int(time.Since(e.storedAt) / time.Second)
+	w.Header().Set("Age", strconv.Itoa(age))
+	writeEntry(w, req, e, cacheStatus)
+}
+
+func writeEntry(w http.ResponseWriter, req *http.Request, e *entry, cacheStatus string) {
+	w.Header().Set(CacheStatusHeaderKey, cacheStatus)
+	if e.status == http.StatusOK && etagMatches(req.Header.Get("If-None-Match"), e.etag()) {
+		w.Header().Del("Content-Length")
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(e.body)))
+	w.WriteHeader(e.status)
+	_, _ = w.Write(e.body)
+}
+
+// copyHeader sets the response header by the stored one, Vary values of the gateway (e.g. Origin) are kept
+func copyHeader(dst http.Header, src http.Header) {
+	for name, values := range src {
+		if name == "Vary" {
+			dst[name] = append(dst[name], values...)
+			continue
+		}
+		dst[name] = append([]string(nil), values...)
+	}
+}
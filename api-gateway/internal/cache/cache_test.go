@@ -0,0 +1,173 @@
+package cache
+
+import (
+	requestuser "api-gateway/pkg/hidepost-requestuser"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFreshness(t *testing.T) {
+	timeNow := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		status   int
+		header   map[string]string
+		lifetime time.Duration
+		stored   bool
+	}{
+		{name: "max age", status: http.StatusOK, header: map[string]string{"Cache-Control": "max-age=60"},
+			lifetime: time.Minute, stored: true},
+		{name: "shared max age wins", status: http.StatusOK,
+			header: map[string]string{"Cache-Control": "max-age=60, s-maxage=120"}, lifetime: 2 * time.Minute, stored: true},
+		{name: "expires", status: http.StatusOK, header: map[string]string{
+			"Expires": "Mon, 01 Jan 2024 00:10:00 GMT", "Date": "Mon, 01 Jan 2024 00:00:00 GMT"},
+			lifetime: 10 * time.Minute, stored: true},
+		{name: "age is subtracted", status: http.StatusOK,
+			header: map[string]string{"Cache-Control": "max-age=60", "Age": "20"}, lifetime: 40 * time.Second, stored: true},
+		{name: "no store", status: http.StatusOK, header: map[string]string{"Cache-Control": "no-store, max-age=60"}},
+		{name: "private", status: http.StatusOK, header: map[string]string{"Cache-Control": "private, max-age=60"}},
+		{name: "set cookie", status: http.StatusOK,
+			header: map[string]string{"Cache-Control": "max-age=60", "Set-Cookie": "session=1"}},
+		{name: "vary on everything", status: http.StatusOK,
+			header: map[string]string{"Cache-Control": "max-age=60", "Vary": "*"}},
+		{name: "not cacheable status", status: http.StatusInternalServerError,
+			header: map[string]string{"Cache-Control": "max-age=60"}},
+		{name: "without freshness", status: http.StatusOK, header: map[string]string{"ETag": `"1"`}},
+		{name: "no cache with etag is revalidated", status: http.StatusOK,
+			header: map[string]string{"Cache-Control": "no-cache", "ETag": `"1"`}, stored: true},
+		{name: "no cache without etag", status: http.StatusOK, header: map[string]string{"Cache-Control": "no-cache"}},
+		{name: "expired with etag", status: http.StatusOK,
+			header: map[string]string{"Cache-Control": "max-age=0", "ETag": `"1"`}, stored: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := make(http.Header)
+			for name, value := range tt.header {
+				header.Set(name, value)
+			}
+			lifetime, stored := freshness(tt.status, header, timeNow)
+			assert.Equal(t, tt.stored, stored)
+			assert.Equal(t, tt.lifetime, lifetime)
+		})
+	}
+}
+
+func TestVaryNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		vary  []string
+		names []string
+		ok    bool
+	}{
+		{name: "no vary", names: []string{}, ok: true},
+		{name: "canonical sorted unique names", vary: []string{"accept-language, Accept-Encoding", "Accept-Language"},
+			names: []string{"Accept-Encoding", "Accept-Language"}, ok: true},
+		{name: "everything", vary: []string{"Accept-Encoding, *"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Vary": tt.vary}
+			names, ok := varyNames(header)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.names, names)
+		})
+	}
+}
+
+type cacheRequest struct {
+	header map[string]string
+	// status is the cache status of the response, empty if the request isn't served by the cache
+	status string
+}
+
+func TestCacheServe(t *testing.T) {
+	tests := []struct {
+		name     string
+		response map[string]string
+		requests []cacheRequest
+		forwards int
+	}{
+		{
+			name:     "fresh response is served from cache",
+			response: map[string]string{"Cache-Control": "max-age=60"},
+			requests: []cacheRequest{{status: cacheStatusMiss}, {status: cacheStatusHit}},
+			forwards: 1,
+		},
+		{
+			name:     "no store response isn't stored",
+			response: map[string]string{"Cache-Control": "no-store"},
+			requests: []cacheRequest{{status: cacheStatusMiss}, {status: cacheStatusMiss}},
+			forwards: 2,
+		},
+		{
+			name:     "no store request bypasses cache",
+			response: map[string]string{"Cache-Control": "max-age=60"},
+			requests: []cacheRequest{
+				{status: cacheStatusMiss},
+				{header: map[string]string{"Cache-Control": "no-store"}},
+			},
+			forwards: 2,
+		},
+		{
+			name:     "variants are stored by vary headers",
+			response: map[string]string{"Cache-Control": "max-age=60", "Vary": "Accept-Language"},
+			requests: []cacheRequest{
+				{header: map[string]string{"Accept-Language": "en"}, status: cacheStatusMiss},
+				{header: map[string]string{"Accept-Language": "ru"}, status: cacheStatusMiss},
+				{header: map[string]string{"Accept-Language": "en"}, status: cacheStatusHit},
+				{header: map[string]string{"Accept-Language": "ru"}, status: cacheStatusHit},
+			},
+			forwards: 2,
+		},
+		{
+			name:     "stale response is revalidated by etag",
+			response: map[string]string{"Cache-Control": "no-cache", "ETag": `"1"`},
+			requests: []cacheRequest{{status: cacheStatusMiss}, {status: cacheStatusRevalidated}},
+			forwards: 2,
+		},
+		{
+			name:     "authorized requests aren't cached",
+			response: map[string]string{"Cache-Control": "max-age=60"},
+			requests: []cacheRequest{
+				{header: map[string]string{requestuser.AuthorizationHeaderKey: "Bearer token"}},
+				{header: map[string]string{requestuser.AuthorizationHeaderKey: "Bearer token"}},
+			},
+			forwards: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cache{store: NewStore(1 << 20), flights: make(map[string]*flight), mu: new(sync.Mutex)}
+			forwards := 0
+			forward := func(w http.ResponseWriter, req *http.Request) {
+				forwards++
+				for name, value := range tt.response {
+					w.Header().Set(name, value)
+				}
+				if etag := tt.response["ETag"]; etag != "" && req.Header.Get("If-None-Match") == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("body " + req.Header.Get("Accept-Language")))
+			}
+
+			for i, request := range tt.requests {
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/posts", nil)
+				for name, value := range request.header {
+					req.Header.Set(name, value)
+				}
+				w := httptest.NewRecorder()
+				c.Serve(w, req, forward)
+				assert.Equal(t, http.StatusOK, w.Code, "request %d", i)
+				assert.Equal(t, "body "+req.Header.Get("Accept-Language"), w.Body.String(), "request %d", i)
+				assert.Equal(t, request.status, w.Header().Get(CacheStatusHeaderKey), "request %d", i)
+			}
+			assert.Equal(t, tt.forwards, forwards)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheableStatuses are statuses stored when the response has explicit freshness
+var cacheableStatuses = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMovedPermanently:     true,
+	http.StatusSeeOther:             true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusGone:                 true,
+}
+
+// storedHeaderExclusions are headers of the single response, they are never served from the cache
+var storedHeaderExclusions = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Connection", "Trailer", "Transfer-Encoding",
+	"Upgrade", "Age", "X-Request-Id", "X-Cache",
+}
+
+// cacheControl is the parsed Cache-Control header, directive names are lower cased
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, found := cc[name]
+	return found
+}
+
+// seconds returns the value of the delta seconds directive, false if it is absent or invalid
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	arg, found := cc[name]
+	if !found {
+		return 0, false
+	}
+	value, err := strconv.Atoi(arg)
+	if err != nil || value < 0 {
+		return 0, false
+	}
+	return time.Duration(value) * time.Second, true
+}
+
+// freshness returns how long the response may be served by the shared cache without revalidation.
+// False is returned if the response mustn't be stored, responses which must be revalidated on every
+// request are stored with zero freshness if they have the ETag.
+func freshness(status int, header http.Header, timeNow time.Time) (time.Duration, bool) {
+	if !cacheableStatuses[status] || header.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	if _, ok := varyNames(header); !ok {
+		return 0, false
+	}
+
+	cc := parseCacheControl(header)
+	if cc.has("no-store") || cc.has("private") {
+		return 0, false
+	}
+
+	var lifetime time.Duration
+	explicit := true
+	if value, ok := cc.seconds("s-maxage"); ok {
+		lifetime = value
+	} else if value, ok := cc.seconds("max-age"); ok {
+		lifetime = value
+	} else if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = timeNow
+		}
+		lifetime = expires.Sub(date)
+	} else {
+		explicit = false
+	}
+	if cc.has("no-cache") {
+		lifetime = 0
+	}
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+		lifetime -= time.Duration(age) * time.Second
+	}
+	if lifetime < 0 {
+		lifetime = 0
+	}
+
+	if lifetime == 0 && (header.Get("ETag") == "" || (!explicit && !cc.has("no-cache"))) {
+		return 0, false
+	}
+	return lifetime, true
+}
+
+// varyNames returns canonical sorted names of the Vary header, false if the response varies on everything
+func varyNames(header http.Header) ([]string, bool) {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			name = textproto.CanonicalMIMEHeaderKey(name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// storedHeader copies the response header without headers of the single response
+func storedHeader(header http.Header) http.Header {
+	stored := header.Clone()
+	for _, name := range storedHeaderExclusions {
+		stored.Del(name)
+	}
+	return stored
+}
+
+// etagMatches reports whether the If-None-Match header matches the etag by the weak comparison
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+)
+
+// recorder buffers the storable upstream response. Responses which can't be stored or outgrow
+// the entry size are passed through to the client writer, the buffered part is written first.
+type recorder struct {
+	w            http.ResponseWriter
+	header       http.Header
+	status       int
+	body         *bytes.Buffer
+	maxSize      int64
+	revalidating bool
+	passing      bool
+}
+
+func newRecorder(w http.ResponseWriter, maxSize int64, revalidating bool) *recorder {
+	return &recorder{
+		w:            w,
+		header:       make(http.Header),
+		body:         new(bytes.Buffer),
+		maxSize:      maxSize,
+		revalidating: revalidating,
+	}
+}
+
+func (r *recorder) Header() http.Header {
+	if r.passing {
+		return r.w.Header()
+	}
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status != 0 || r.passing {
+		return
+	}
+	if status >= 100 && status < 200 {
+		return
+	}
+	r.status = status
+	if status == http.StatusNotModified && r.revalidating {
+		return
+	}
+	if _, ok := freshness(status, r.header, time.Now()); !ok {
+		r.pass()
+	}
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.passing {
+		return r.w.Write(data)
+	}
+	if int64(r.body.Len()+len(data)) > r.maxSize {
+		r.pass()
+		return r.w.Write(data)
+	}
+	return r.body.Write(data)
+}
+
+// Flush is called by the proxy for streamed responses, only passed through ones are flushed
+func (r *recorder) Flush() {
+	if r.passing {
+		_ = http.NewResponseController(r.w).Flush()
+	}
+}
+
+// buffered reports whether the whole response is kept by the recorder
+func (r *recorder) buffered() bool {
+	return !r.passing && r.status != 0
+}
+
+func (r *recorder) pass() {
+	r.passing = true
+	copyHeader(r.w.Header(), r.header)
+	r.w.Header().Set(CacheStatusHeaderKey, cacheStatusMiss)
+	r.w.WriteHeader(r.status)
+	if r.body.Len() > 0 {
+		_, _ = r.w.Write(r.body.Bytes())
+		r.body.Reset()
+	}
+}
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxEntryShare limits the size of one response to the share of the store, so a few large
+// responses can't wipe out the rest of the cache
+const maxEntryShare = 8
+
+// entry is the stored upstream response
+type entry struct {
+	key       string
+	path      string
+	status    int
+	header    http.Header
+	body      []byte
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+func (e *entry) size() int64 {
+	size := len(e.key) + len(e.path) + len(e.body)
+	for name, values := range e.header {
+		size += len(name)
+		for _, value := range values {
+			size += len(value)
+		}
+	}
+	return int64(size)
+}
+
+func (e *entry) fresh(timeNow time.Time) bool {
+	return timeNow.Before(e.expiresAt)
+}
+
+func (e *entry) etag() string {
+	return e.header.Get("ETag")
+}
+
+// variants are header names of the Vary response header of the url, they are counted
+// by stored entries so the names are dropped with the last entry of the url
+type variants struct {
+	names []string
+	count int
+}
+
+// Store is the in-memory LRU of responses bounded by the total size in bytes. Entries are keyed by
+// the url and values of request headers listed in Vary of the response.
+type Store struct {
+	maxSize  int64
+	size     int64
+	items    map[string]*list.Element
+	order    *list.List
+	variants map[string]*variants
+	mu       *sync.Mutex
+}
+
+func NewStore(maxSize int64) *Store {
+	return &Store{
+		maxSize:  maxSize,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		variants: make(map[string]*variants),
+		mu:       new(sync.Mutex),
+	}
+}
+
+// SetMaxSize changes the bound of the store, the least recently used entries are evicted to fit it.
+// Zero size disables caching.
+func (s *Store) SetMaxSize(maxSize int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxSize = maxSize
+	s.evict()
+}
+
+func (s *Store) enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxSize > 0
+}
+
+func (s *Store) maxEntrySize() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxSize / maxEntryShare
+}
+
+// key returns the entry key of the request by the known Vary names of the url
+func (s *Store) key(req *http.Request) string {
+	base := baseKey(req)
+	s.mu.Lock()
+	v, found := s.variants[base]
+	s.mu.Unlock()
+	if !found {
+		return base
+	}
+	return variantKey(base, v.names, req)
+}
+
+func (s *Store) get(key string) *entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, found := s.items[key]
+	if !found {
+		return nil
+	}
+	s.order.MoveToFront(element)
+	return element.Value.(*entry)
+}
+
+// set stores the response of the request, the key is built by the Vary names of the response.
+// False is returned if the response is too large for the store.
+func (s *Store) set(req *http.Request, e *entry, varyNames []string) bool {
+	base := baseKey(req)
+	e.key = variantKey(base, varyNames, req)
+	e.path = req.URL.Path
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.size() > s.maxSize/maxEntryShare {
+		return false
+	}
+
+	// responses of the url with other Vary names can't be matched anymore
+	if v, found := s.variants[base]; found && !equalNames(v.names, varyNames) {
+		s.removeVariants(base)
+	}
+	if element, found := s.items[e.key]; found {
+		s.remove(element)
+	}
+	v, found := s.variants[base]
+	if !found {
+		v = &variants{names: varyNames}
+		s.variants[base] = v
+	}
+	v.count++
+
+	s.items[e.key] = s.order.PushFront(e)
+	s.size += e.size()
+	s.evict()
+	return true
+}
+
+func (s *Store) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, found := s.items[key]; found {
+		s.remove(element)
+	}
+}
+
+// Purge removes entries of urls with the path prefix and returns their count
+func (s *Store) Purge(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for element := s.order.Front(); element != nil; {
+		next := element.Next()
+		if strings.HasPrefix(element.Value.(*entry).path, prefix) {
+			s.remove(element)
+			count++
+		}
+		element = next
+	}
+	return count
+}
+
+func (s *Store) evict() {
+	for s.size > s.maxSize && s.order.Len() > 0 {
+		s.remove(s.order.Back())
+	}
+}
+
+func (s *Store) removeVariants(base string) {
+	for element := s.order.Front(); element != nil; {
+		next := element.Next()
+		if e := element.Value.(*entry); e.key == base || strings.HasPrefix(e.key, base+"\n") {
+			s.remove(element)
+		}
+		element = next
+	}
+	delete(s.variants, base)
+}
+
+func (s *Store) remove(element *list.Element) {
+	e := element.Value.(*entry)
+	s.order.Remove(element)
+	delete(s.items, e.key)
+	s.size -= e.size()
+
+	base, _, _ := strings.Cut(e.key, "\n")
+	if v, found := s.variants[base]; found {
+		v.count--
+		if v.count <= 0 {
+			delete(s.variants, base)
+		}
+	}
+}
+
+func baseKey(req *http.Request) string {
+	return req.URL.RequestURI()
+}
+
+func variantKey(base string, names []string, req *http.Request) string {
+	if len(names) == 0 {
+		return base
+	}
+	var key strings.Builder
+	key.WriteString(base)
+	for _, name := range names {
+		key.WriteString("\n")
+		key.WriteString(name)
+		key.WriteString(":")
+		key.WriteString(strings.Join(req.Header.Values(name), ","))
+	}
+	return key.String()
+}
+
+func equalNames(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
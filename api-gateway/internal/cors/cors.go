@@ -0,0 +1,111 @@
+package cors
+
+import (
+	"api-gateway/pkg/filelogger"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const AllowedOriginsConfigKey = "ALLOWED_HOSTS"
+
+// Cors answers preflight requests and sets CORS headers of responses by the default policy,
+// routes may override it. Allowed origins of the default policy are updated from config.
+type Cors struct {
+	policy atomic.Pointer[Policy]
+}
+
+func NewCors(allowedOrigins string,
+	cfgService *configService.ConfigServiceManager,
+	fileLogger *filelogger.FileLogger) (*Cors, error) {
+
+	policy, err := newPolicy(ParseOrigins(allowedOrigins))
+	if err != nil {
+		return nil, err
+	}
+	c := &Cors{}
+	c.policy.Store(policy)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		policy, err := newPolicy(ParseOrigins(ss.Value))
+		if err != nil {
+			fileLogger.Error("failed to parse allowed origins from config, the current ones are kept", map[string]interface{}{
+				"error": err.Error(),
+				"key":   ss.Key,
+				"value": ss.Value,
+			})
+			return
+		}
+		c.policy.Store(policy)
+	}, AllowedOriginsConfigKey)
+
+	return c, nil
+}
+
+// Handle sets CORS headers of the response by the policy with the route override. Preflight requests are
+// answered here and false is returned for them, the request must not be handled further then.
+func (c *Cors) Handle(w http.ResponseWriter, req *http.Request, override *Override) bool {
+	policy := c.policy.Load().Apply(override)
+
+	requestOrigin := req.Header.Get("Origin")
+	preflight := req.Method == http.MethodOptions && requestOrigin != "" &&
+		req.Header.Get("Access-Control-Request-Method") != ""
+
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if requestOrigin == "" {
+		return true
+	}
+
+	if !policy.allowsOrigin(requestOrigin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+			return false
+		}
+		return true
+	}
+
+	if preflight {
+		requestMethod := strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+		requestHeaders := strings.Join(req.Header.Values("Access-Control-Request-Headers"), ",")
+		if !policy.allowsMethod(requestMethod) || !policy.allowsHeaders(requestHeaders) {
+			w.WriteHeader(http.StatusForbidden)
+			return false
+		}
+		header.Set("Access-Control-Allow-Origin", policy.allowOriginValue(requestOrigin))
+		if policy.allowsCredentials() {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		header.Set("Access-Control-Allow-Methods", strings.Join(policy.methods, ", "))
+		if len(policy.headers) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(policy.headers, ", "))
+		}
+		header.Set("Access-Control-Max-Age", strconv.Itoa(policy.maxAge))
+		w.WriteHeader(http.StatusNoContent)
+		return false
+	}
+
+	header.Set("Access-Control-Allow-Origin", policy.allowOriginValue(requestOrigin))
+	if policy.allowsCredentials() {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(policy.exposeHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(policy.exposeHeaders, ", "))
+	}
+	return true
+}
+
+// Middleware applies the default policy to gateway own routes
+func (c *Cors) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if c.Handle(w, req, nil) {
+			next.ServeHTTP(w, req)
+		}
+	})
+}
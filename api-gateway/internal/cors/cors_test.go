@@ -0,0 +1,150 @@
+package cors
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginMatches(t *testing.T) {
+	policy, err := newPolicy([]string{"https://hidepost.ru", "https://*.hidepost.ru", "http://localhost:4200/"})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://hidepost.ru", allowed: true},
+		{origin: "https://app.hidepost.ru", allowed: true},
+		{origin: "https://a.b.hidepost.ru", allowed: true},
+		{origin: "http://localhost:4200", allowed: true},
+		{origin: "http://hidepost.ru"},
+		{origin: "https://hidepost.ru.evil.com"},
+		{origin: "https://evilhidepost.ru"},
+		{origin: "https://.hidepost.ru"},
+		{origin: "https://evil.com/.hidepost.ru"},
+		{origin: "https://user@evil.com:.hidepost.ru"},
+		{origin: "null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, policy.allowsOrigin(tt.origin))
+		})
+	}
+}
+
+func TestNewPolicyInvalidOrigins(t *testing.T) {
+	tests := []string{"https://*.*.hidepost.ru", "https://hidepost*.ru", "*.hidepost.ru"}
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			_, err := newPolicy([]string{value})
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestCorsHandle(t *testing.T) {
+	noCredentials := false
+	tests := []struct {
+		name     string
+		origins  []string
+		override *Override
+		method   string
+		header   map[string]string
+		// next reports whether the request is handled further
+		next        bool
+		status      int
+		allowOrigin string
+		credentials string
+	}{
+		{
+			name: "request without origin", origins: []string{"https://hidepost.ru"}, method: http.MethodGet,
+			next: true,
+		},
+		{
+			name: "allowed origin", origins: []string{"https://hidepost.ru"}, method: http.MethodGet,
+			header: map[string]string{"Origin": "https://hidepost.ru"},
+			next:   true, allowOrigin: "https://hidepost.ru", credentials: "true",
+		},
+		{
+			name: "not allowed origin", origins: []string{"https://hidepost.ru"}, method: http.MethodGet,
+			header: map[string]string{"Origin": "https://evil.com"},
+			next:   true,
+		},
+		{
+			name: "any origin never allows credentials", origins: []string{"*"}, method: http.MethodGet,
+			header: map[string]string{"Origin": "https://evil.com"},
+			next:   true, allowOrigin: "*",
+		},
+		{
+			name: "route disables credentials", origins: []string{"https://hidepost.ru"},
+			override: &Override{Credentials: &noCredentials}, method: http.MethodGet,
+			header: map[string]string{"Origin": "https://hidepost.ru"},
+			next:   true, allowOrigin: "https://hidepost.ru",
+		},
+		{
+			name: "route replaces origins", origins: []string{"https://hidepost.ru"},
+			override: &Override{Origins: []string{"https://admin.hidepost.ru"}}, method: http.MethodGet,
+			header: map[string]string{"Origin": "https://hidepost.ru"},
+			next:   true,
+		},
+		{
+			name: "allowed preflight", origins: []string{"https://hidepost.ru"}, method: http.MethodOptions,
+			header: map[string]string{
+				"Origin":                         "https://hidepost.ru",
+				"Access-Control-Request-Method":  "delete",
+				"Access-Control-Request-Headers": "authorization, content-type",
+			},
+			status: http.StatusNoContent, allowOrigin: "https://hidepost.ru", credentials: "true",
+		},
+		{
+			name: "preflight of not allowed origin", origins: []string{"https://hidepost.ru"}, method: http.MethodOptions,
+			header: map[string]string{"Origin": "https://evil.com", "Access-Control-Request-Method": "DELETE"},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "preflight of not allowed method", origins: []string{"https://hidepost.ru"},
+			override: &Override{Methods: []string{"get"}}, method: http.MethodOptions,
+			header: map[string]string{"Origin": "https://hidepost.ru", "Access-Control-Request-Method": "DELETE"},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "preflight of not allowed header", origins: []string{"https://hidepost.ru"}, method: http.MethodOptions,
+			header: map[string]string{
+				"Origin":                         "https://hidepost.ru",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "X-Custom",
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "options without request method isn't preflight", origins: []string{"https://hidepost.ru"},
+			method: http.MethodOptions, header: map[string]string{"Origin": "https://hidepost.ru"},
+			next: true, allowOrigin: "https://hidepost.ru", credentials: "true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newPolicy(tt.origins)
+			assert.Nil(t, err)
+			c := &Cors{}
+			c.policy.Store(policy)
+
+			req := httptest.NewRequest(tt.method, "/api/v1/posts", nil)
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			next := c.Handle(w, req, tt.override)
+
+			assert.Equal(t, tt.next, next)
+			if !tt.next {
+				assert.Equal(t, tt.status, w.Code)
+			}
+			assert.Equal(t, tt.allowOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.credentials, w.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Contains(t, w.Header().Values("Vary"), "Origin")
+		})
+	}
+}
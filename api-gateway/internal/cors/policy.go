@@ -0,0 +1,222 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	defaultMethods = []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	}
+	defaultHeaders = []string{
+		"Authorization", "Content-Type", "If-None-Match", "X-Request-Id",
+	}
+	defaultExposeHeaders = []string{
+		"ETag", "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset",
+		"X-Cache", "X-Request-Id",
+	}
+)
+
+const defaultMaxAge = 600
+
+// Policy is the CORS policy of the route. Origins are either exact (https://hidepost.ru), with the wildcard
+// subdomain (https://*.hidepost.ru) or "*" for any origin, which is answered without credentials.
+type Policy struct {
+	origins       []origin
+	anyOrigin     bool
+	methods       []string
+	headers       []string
+	exposeHeaders []string
+	credentials   bool
+	maxAge        int
+}
+
+// Override replaces fields of the default policy for the route, absent fields are kept.
+// Max age is in seconds.
+type Override struct {
+	Origins       []string `json:"origins"`
+	Methods       []string `json:"methods"`
+	Headers       []string `json:"headers"`
+	ExposeHeaders []string `json:"expose_headers"`
+	Credentials   *bool    `json:"credentials"`
+	MaxAge        *int     `json:"max_age"`
+}
+
+// origin is the allowed origin, the wildcard one matches subdomains between the prefix and the suffix
+type origin struct {
+	prefix   string
+	suffix   string
+	wildcard bool
+}
+
+func (o origin) matches(value string) bool {
+	if !o.wildcard {
+		return value == o.prefix
+	}
+	if len(value) <= len(o.prefix)+len(o.suffix) ||
+		!strings.HasPrefix(value, o.prefix) || !strings.HasSuffix(value, o.suffix) {
+		return false
+	}
+	subdomain := value[len(o.prefix) : len(value)-len(o.suffix)]
+	return !strings.ContainsAny(subdomain, "/:@") && !strings.HasPrefix(subdomain, ".")
+}
+
+func newPolicy(origins []string) (*Policy, error) {
+	p := &Policy{
+		methods:       defaultMethods,
+		headers:       defaultHeaders,
+		exposeHeaders: defaultExposeHeaders,
+		credentials:   true,
+		maxAge:        defaultMaxAge,
+	}
+	if err := p.setOrigins(origins); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Policy) setOrigins(values []string) error {
+	p.origins = make([]origin, 0, len(values))
+	p.anyOrigin = false
+	for _, value := range values {
+		value = strings.TrimSuffix(strings.TrimSpace(value), "/")
+		switch {
+		case value == "":
+			continue
+		case value == "*":
+			p.anyOrigin = true
+		case strings.Count(value, "*") > 1:
+			return fmt.Errorf("invalid cors origin %q: only one wildcard is allowed", value)
+		case strings.Contains(value, "*"):
+			prefix, suffix, _ := strings.Cut(value, "*")
+			if !strings.HasSuffix(prefix, "://") || !strings.HasPrefix(suffix, ".") {
+				return fmt.Errorf("invalid cors origin %q: wildcard must be the subdomain", value)
+			}
+			p.origins = append(p.origins, origin{prefix: prefix, suffix: suffix, wildcard: true})
+		default:
+			p.origins = append(p.origins, origin{prefix: value})
+		}
+	}
+	return nil
+}
+
+// Validate checks origins and the max age of the override
+func (o *Override) Validate() error {
+	if o.Origins != nil {
+		if err := new(Policy).setOrigins(o.Origins); err != nil {
+			return err
+		}
+	}
+	if o.MaxAge != nil && *o.MaxAge < 0 {
+		return fmt.Errorf("invalid cors max age %d", *o.MaxAge)
+	}
+	return nil
+}
+
+// Apply returns the copy of the policy with fields of the validated override
+func (p *Policy) Apply(override *Override) *Policy {
+	if override == nil {
+		return p
+	}
+	result := *p
+	if override.Origins != nil {
+		_ = result.setOrigins(override.Origins)
+	}
+	if override.Methods != nil {
+		result.methods = canonicalMethods(override.Methods)
+	}
+	if override.Headers != nil {
+		result.headers = override.Headers
+	}
+	if override.ExposeHeaders != nil {
+		result.exposeHeaders = override.ExposeHeaders
+	}
+	if override.Credentials != nil {
+		result.credentials = *override.Credentials
+	}
+	if override.MaxAge != nil {
+		result.maxAge = *override.MaxAge
+	}
+	return &result
+}
+
+func (p *Policy) allowsOrigin(value string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	for _, o := range p.origins {
+		if o.matches(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) allowsMethod(method string) bool {
+	// simple methods are allowed by browsers without the preflight anyway
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodPost {
+		return true
+	}
+	for _, allowed := range p.methods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders reports whether every header of the comma separated Access-Control-Request-Headers is allowed
+func (p *Policy) allowsHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		allowed := false
+		for _, value := range p.headers {
+			if strings.EqualFold(value, header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// allowOriginValue returns the value of Access-Control-Allow-Origin
+func (p *Policy) allowOriginValue(value string) string {
+	if p.anyOrigin {
+		return "*"
+	}
+	return value
+}
+
+// allowsCredentials reports whether credentials are allowed, they never are for any origin
+func (p *Policy) allowsCredentials() bool {
+	return p.credentials && !p.anyOrigin
+}
+
+// ParseOrigins splits the comma separated origins, e.g. the value of ALLOWED_HOSTS
+func ParseOrigins(value string) []string {
+	origins := make([]string, 0)
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+func canonicalMethods(methods []string) []string {
+	result := make([]string, 0, len(methods))
+	for _, method := range methods {
+		result = append(result, strings.ToUpper(strings.TrimSpace(method)))
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package proxypass
+
+import (
+	"api-gateway/pkg/filelogger"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	TimeoutConfigKey     = "PROXY_TIMEOUT"
+	MaxBodySizeConfigKey = "PROXY_MAX_BODY_SIZE"
+)
+
+// Limits are applied to every proxied request of the route, zero values mean no limit
+type Limits struct {
+	Timeout     time.Duration
+	MaxBodySize int64
+}
+
+// DefaultLimits are used for routes without own limits.
+// The timeout is in seconds and the body size is in bytes.
+type DefaultLimits struct {
+	timeout     int
+	maxBodySize int
+	mu          *sync.RWMutex
+}
+
+func NewDefaultLimits(timeout int, maxBodySize int,
+	cfgService *configService.ConfigServiceManager,
+	fileLogger *filelogger.FileLogger) *DefaultLimits {
+
+	limits := &DefaultLimits{
+		timeout:     timeout,
+		maxBodySize: maxBodySize,
+		mu:          new(sync.RWMutex),
+	}
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err != nil {
+			fileLogger.Error("failed to parse value for proxy timeout from config", map[string]interface{}{
+				"error": err.Error(),
+				"key":   ss.Key,
+				"value": ss.Value,
+			})
+			return
+		}
+		limits.mu.Lock()
+		limits.timeout = value
+		limits.mu.Unlock()
+	}, TimeoutConfigKey)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err != nil {
+			fileLogger.Error("failed to parse value for proxy max body size from config", map[string]interface{}{
+				"error": err.Error(),
+				"key":   ss.Key,
+				"value": ss.Value,
+			})
+			return
+		}
+		limits.mu.Lock()
+		limits.maxBodySize = value
+		limits.mu.Unlock()
+	}, MaxBodySizeConfigKey)
+
+	return limits
+}
+
+// Apply returns the route limits with unset ones replaced by the defaults
+func (l *DefaultLimits) Apply(route Limits) Limits {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if route.Timeout <= 0 {
+		route.Timeout = time.Duration(l.timeout) * time.Second
+	}
+	if route.MaxBodySize <= 0 {
+		route.MaxBodySize = int64(l.maxBodySize)
+	}
+	return route
+}
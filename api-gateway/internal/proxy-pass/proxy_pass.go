@@ -1,46 +1,133 @@
 package proxypass
 
 import (
+	"api-gateway/internal/upstream"
 	requestuser "api-gateway/pkg/hidepost-requestuser"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"mime"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
-func ToServiceWithHost(w http.ResponseWriter, req *http.Request, serviceHost string) error {
+const RequestIdHeaderKey = "X-Request-Id"
 
-	req.URL.Scheme = "http"
-	req.URL.Host = serviceHost
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
 
-	serviceRequest, err := http.NewRequest(req.Method, req.URL.String(), req.Body)
-	if err != nil {
-		return fmt.Errorf("failed to create service request: %v", err)
+// ToService proxies the request to an upstream of the service pool, bodies are streamed both ways and upgraded connections
+// (WebSocket) are tunneled. Identity of the request user is passed as the signed assertion made of user headers
+// set by the gateway. The timeout covers the whole exchange except upgraded and event stream responses, for them
+// it ends when the response headers are received.
+// Error is returned if the request has failed, the error response is already written then.
+func ToService(w http.ResponseWriter, req *http.Request, pool *upstream.Pool,
+	limits Limits, signer *requestuser.Signer) error {
+
+	requestId := req.Header.Get(RequestIdHeaderKey)
+	if !requestIdPattern.MatchString(requestId) {
+		requestId = newRequestId()
 	}
+	w.Header().Set(RequestIdHeaderKey, requestId)
 
-	serviceRequest.Header.Set(requestuser.UserIdHeaderKey, req.Header.Get(requestuser.UserIdHeaderKey))
-	serviceRequest.Header.Set(requestuser.UserRoleHeaderKey, req.Header.Get(requestuser.UserRoleHeaderKey))
-	serviceRequest.Header.Set(requestuser.UserIsBannedHeaderKey, req.Header.Get(requestuser.UserIsBannedHeaderKey))
-	serviceRequest.Header.Set(requestuser.AuthorizationHeaderKey, req.Header.Get(requestuser.AuthorizationHeaderKey))
+	if limits.MaxBodySize > 0 {
+		if req.ContentLength > limits.MaxBodySize {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return nil
+		}
+		if req.Body != nil {
+			req.Body = http.MaxBytesReader(w, req.Body, limits.MaxBodySize)
+		}
+	}
 
-	serviceResponse, err := http.DefaultClient.Do(serviceRequest)
-	if err != nil {
-		return fmt.Errorf("failed to do service request: %v", err)
+	var proxyErr error
+	var timedOut atomic.Bool
+	var timer *time.Timer
+	if limits.Timeout > 0 {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		timer = time.AfterFunc(limits.Timeout, func() {
+			timedOut.Store(true)
+			cancel()
+		})
+		defer timer.Stop()
+		req = req.WithContext(ctx)
 	}
 
-	defer serviceResponse.Body.Close()
+	proxy := &httputil.ReverseProxy{
+		Transport: pool,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// the host is replaced with the one of the picked upstream by the pool
+			pr.SetURL(&url.URL{Scheme: "http", Host: pool.Name()})
+			pr.SetXForwarded()
+			pr.Out.Header.Set(RequestIdHeaderKey, requestId)
 
-	for key, values := range serviceResponse.Header {
-		for _, value := range values {
-			w.Header().Set(key, value)
-		}
+			requestuser.StripIdentityHeaders(pr.Out.Header)
+			if role := pr.In.Header.Get(requestuser.UserRoleHeaderKey); role != "" {
+				pr.Out.Header.Set(requestuser.IdentityAssertionHeaderKey, signer.Sign(requestuser.Assertion{
+					UserId: pr.In.Header.Get(requestuser.UserIdHeaderKey),
+					Role:   role,
+					Banned: pr.In.Header.Get(requestuser.UserIsBannedHeaderKey) == "true",
+				}))
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if timer != nil && isStreaming(resp) {
+				timer.Stop()
+			}
+			resp.Header.Set(RequestIdHeaderKey, requestId)
+			// CORS headers are set by the gateway only, duplicated ones are rejected by browsers
+			for name := range resp.Header {
+				if strings.HasPrefix(name, "Access-Control-") {
+					resp.Header.Del(name)
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesErr):
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			case errors.Is(err, upstream.ErrNoUpstream):
+				proxyErr = err
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case timedOut.Load():
+				proxyErr = fmt.Errorf("service request timed out after %s: %v", limits.Timeout, err)
+				w.WriteHeader(http.StatusGatewayTimeout)
+			case errors.Is(err, context.Canceled):
+				// the client has gone away, there is nobody to answer
+				return
+			default:
+				proxyErr = fmt.Errorf("failed to do service request: %v", err)
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		},
 	}
+	proxy.ServeHTTP(w, req)
 
-	w.WriteHeader(serviceResponse.StatusCode)
+	return proxyErr
+}
 
-	_, err = io.Copy(w, serviceResponse.Body)
-	if err != nil {
-		return fmt.Errorf("failed to copy service response body to client request: %v", err)
+// isStreaming reports whether the response stays open for a long time,
+// the event stream responses are flushed to the client as they come by the proxy
+func isStreaming(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return true
 	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
 
-	return nil
+func newRequestId() string {
+	value := make([]byte, 16)
+	_, _ = rand.Read(value)
+	return hex.EncodeToString(value)
 }
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"api-gateway/pkg/filelogger"
+	requestuser "api-gateway/pkg/hidepost-requestuser"
+	"fmt"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const PoliciesConfigKey = "RATE_LIMIT_POLICIES"
+
+// Limiter applies the policy of the longest matching prefix to requests, they are counted per user
+// if the request is authorized and per client ip otherwise
+type Limiter struct {
+	policies   []Policy
+	store      Store
+	fileLogger *filelogger.FileLogger
+	mu         *sync.RWMutex
+}
+
+// NewLimiter parses policies from the json array, e.g.
+// [{"prefix":"/api/v1/comments","methods":["POST"],"limit":20,"period":60,"burst":5}]
+func NewLimiter(policies string, store Store,
+	cfgService *configService.ConfigServiceManager,
+	fileLogger *filelogger.FileLogger) (*Limiter, error) {
+
+	parsed, err := parsePolicies(policies)
+	if err != nil {
+		return nil, err
+	}
+	limiter := &Limiter{
+		policies:   parsed,
+		store:      store,
+		fileLogger: fileLogger,
+		mu:         new(sync.RWMutex),
+	}
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		parsed, err := parsePolicies(ss.Value)
+		if err != nil {
+			fileLogger.Error("failed to parse value for rate limit policies from config", map[string]interface{}{
+				"error": err.Error(),
+				"key":   ss.Key,
+				"value": ss.Value,
+			})
+			return
+		}
+		limiter.mu.Lock()
+		limiter.policies = parsed
+		limiter.mu.Unlock()
+	}, PoliciesConfigKey)
+
+	return limiter, nil
+}
+
+func (l *Limiter) policy(req *http.Request) (Policy, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, p := range l.policies {
+		if p.matches(req.Method, req.URL.Path) {
+			return p, true
+		}
+	}
+	return Policy{}, false
+}
+
+// Allow counts the request and sets RateLimit headers of the response, 429 is written if the limit is exceeded.
+// The route policy is used if none of the configured policies matches the request, it may be nil.
+// User headers of the request must be set by the gateway before. The request is allowed if the store fails.
+func (l *Limiter) Allow(w http.ResponseWriter, req *http.Request, routePolicy *Policy) bool {
+	policy, found := l.policy(req)
+	if !found {
+		if routePolicy == nil || !routePolicy.matches(req.Method, req.URL.Path) {
+			return true
+		}
+		policy = *routePolicy
+	}
+
+	key := requestKey(policy, req)
+	result, err := l.store.Take(key, policy, time.Now())
+	if err != nil {
+		l.fileLogger.Error("failed to take rate limit token", map[string]interface{}{
+			"error":       err.Error(),
+			"key":         key,
+			"request_url": req.URL.String(),
+		})
+		return true
+	}
+
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, policy.Period))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(int(policy.capacity())))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+	if result.Allowed {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	w.WriteHeader(http.StatusTooManyRequests)
+	return false
+}
+
+// requestKey returns the bucket key of the request user within the policy
+func requestKey(policy Policy, req *http.Request) string {
+	identity := "user:" + req.Header.Get(requestuser.UserIdHeaderKey)
+	if req.Header.Get(requestuser.UserIdHeaderKey) == "" {
+		clientIp, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			clientIp = req.RemoteAddr
+		}
+		identity = "ip:" + clientIp
+	}
+	return policy.Prefix + "|" + strings.ToUpper(strings.Join(policy.Methods, ",")) + "|" + identity
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
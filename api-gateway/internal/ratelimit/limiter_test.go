@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	requestuser "api-gateway/pkg/hidepost-requestuser"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	policy := Policy{Prefix: "/api", Limit: 2, Period: 10}
+	burstPolicy := Policy{Prefix: "/api", Limit: 2, Period: 10, Burst: 4}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		// offsets of requests from the start of the window
+		requests []time.Duration
+		allowed  []bool
+		last     Result
+	}{
+		{
+			name:     "requests within the limit",
+			policy:   policy,
+			requests: []time.Duration{0, 0},
+			allowed:  []bool{true, true},
+			last:     Result{Allowed: true, Remaining: 0, ResetAfter: 10 * time.Second},
+		},
+		{
+			name:     "request over the limit",
+			policy:   policy,
+			requests: []time.Duration{0, 0, 0},
+			allowed:  []bool{true, true, false},
+			last:     Result{Allowed: false, Remaining: 0, RetryAfter: 5 * time.Second, ResetAfter: 10 * time.Second},
+		},
+		{
+			name:     "token is refilled after the part of the window",
+			policy:   policy,
+			requests: []time.Duration{0, 0, 5 * time.Second},
+			allowed:  []bool{true, true, true},
+			last:     Result{Allowed: true, Remaining: 0, ResetAfter: 10 * time.Second},
+		},
+		{
+			name:     "refill doesn't exceed the capacity",
+			policy:   policy,
+			requests: []time.Duration{0, time.Hour, time.Hour, time.Hour},
+			allowed:  []bool{true, true, true, false},
+			last:     Result{Allowed: false, Remaining: 0, RetryAfter: 5 * time.Second, ResetAfter: 10 * time.Second},
+		},
+		{
+			name:     "burst allows more requests at once",
+			policy:   burstPolicy,
+			requests: []time.Duration{0, 0, 0, 0, 0},
+			allowed:  []bool{true, true, true, true, false},
+			last:     Result{Allowed: false, Remaining: 0, RetryAfter: 5 * time.Second, ResetAfter: 20 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			var result Result
+			for i, offset := range tt.requests {
+				var err error
+				result, err = store.Take("key", tt.policy, start.Add(offset))
+				assert.Nil(t, err)
+				assert.Equal(t, tt.allowed[i], result.Allowed, "request %d", i)
+			}
+			assert.Equal(t, tt.last, result)
+		})
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		prefixes []string
+		wantErr  bool
+	}{
+		{name: "empty value", value: " ", prefixes: []string{}},
+		{
+			name:     "longer prefixes go first",
+			value:    `[{"prefix":"/api","limit":1,"period":1},{"prefix":"/api/v1/comments","limit":1,"period":1}]`,
+			prefixes: []string{"/api/v1/comments", "/api"},
+		},
+		{name: "zero limit", value: `[{"prefix":"/api","limit":0,"period":1}]`, wantErr: true},
+		{name: "zero period", value: `[{"prefix":"/api","limit":1,"period":0}]`, wantErr: true},
+		{name: "negative burst", value: `[{"prefix":"/api","limit":1,"period":1,"burst":-1}]`, wantErr: true},
+		{name: "empty prefix", value: `[{"limit":1,"period":1}]`, wantErr: true},
+		{name: "malformed json", value: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, err := parsePolicies(tt.value)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			prefixes := make([]string, 0)
+			for _, p := range policies {
+				prefixes = append(prefixes, p.Prefix)
+			}
+			assert.Equal(t, tt.prefixes, prefixes)
+		})
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	commentsPolicy := Policy{Prefix: "/api/v1/comments", Methods: []string{"post"}, Limit: 1, Period: 60}
+	routePolicy := Policy{Prefix: "/api/v1/posts", Limit: 1, Period: 60}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		userId      string
+		routePolicy *Policy
+		// the first request is allowed in every case, the second one is checked
+		allowed bool
+		limited bool
+	}{
+		{name: "limited method", method: http.MethodPost, path: "/api/v1/comments/1", allowed: false, limited: true},
+		{name: "other method", method: http.MethodGet, path: "/api/v1/comments/1", allowed: true},
+		{name: "not matching path", method: http.MethodPost, path: "/api/v1/users", allowed: true},
+		{
+			name: "route policy", method: http.MethodGet, path: "/api/v1/posts/1", routePolicy: &routePolicy,
+			allowed: false, limited: true,
+		},
+		{
+			name: "limited user", method: http.MethodPost, path: "/api/v1/comments/1", userId: "user",
+			allowed: false, limited: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &Limiter{
+				policies: []Policy{commentsPolicy},
+				store:    NewMemoryStore(),
+				mu:       new(sync.RWMutex),
+			}
+			var w *httptest.ResponseRecorder
+			var allowed bool
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(tt.method, tt.path, nil)
+				req.Header.Set(requestuser.UserIdHeaderKey, tt.userId)
+				w = httptest.NewRecorder()
+				allowed = limiter.Allow(w, req, tt.routePolicy)
+			}
+			assert.Equal(t, tt.allowed, allowed)
+			if !tt.limited {
+				assert.Empty(t, w.Header().Get("RateLimit-Policy"))
+				return
+			}
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "1;w=60", w.Header().Get("RateLimit-Policy"))
+			assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, "60", w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	policy := Policy{Prefix: "/api", Methods: []string{"get", "post"}}
+	tests := []struct {
+		name       string
+		userId     string
+		remoteAddr string
+		key        string
+	}{
+		{name: "authorized user", userId: "user", remoteAddr: "10.0.0.1:1234", key: "/api|GET,POST|user:user"},
+		{name: "client ip", remoteAddr: "10.0.0.1:1234", key: "/api|GET,POST|ip:10.0.0.1"},
+		{name: "client ip without port", remoteAddr: "10.0.0.1", key: "/api|GET,POST|ip:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(requestuser.UserIdHeaderKey, tt.userId)
+			assert.Equal(t, tt.key, requestKey(policy, req))
+		})
+	}
+}
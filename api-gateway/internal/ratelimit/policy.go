@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Policy limits requests to the paths starting with the prefix, Limit requests are allowed per Period seconds
+// with bursts up to Burst requests. Empty Methods means every method is limited.
+type Policy struct {
+	Prefix  string   `json:"prefix"`
+	Methods []string `json:"methods"`
+	Limit   int      `json:"limit"`
+	Period  int      `json:"period"`
+	Burst   int      `json:"burst"`
+}
+
+// rate returns tokens added to the bucket per second
+func (p *Policy) rate() float64 {
+	return float64(p.Limit) / float64(p.Period)
+}
+
+func (p *Policy) capacity() float64 {
+	if p.Burst > 0 {
+		return float64(p.Burst)
+	}
+	return float64(p.Limit)
+}
+
+func (p *Policy) Validate() error {
+	if p.Prefix == "" || p.Limit <= 0 || p.Period <= 0 || p.Burst < 0 {
+		return fmt.Errorf("invalid rate limit policy for prefix %q", p.Prefix)
+	}
+	return nil
+}
+
+func (p *Policy) matches(method string, path string) bool {
+	if !strings.HasPrefix(path, p.Prefix) {
+		return false
+	}
+	if len(p.Methods) == 0 {
+		return true
+	}
+	for _, m := range p.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePolicies parses the json array of policies, the longer prefixes go first so they win on match
+func parsePolicies(value string) ([]Policy, error) {
+	policies := make([]Policy, 0)
+	if strings.TrimSpace(value) == "" {
+		return policies, nil
+	}
+	if err := json.Unmarshal([]byte(value), &policies); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rate limit policies: %v", err)
+	}
+	for _, p := range policies {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(policies, func(i, j int) bool {
+		return len(policies[i].Prefix) > len(policies[j].Prefix)
+	})
+	return policies, nil
+}
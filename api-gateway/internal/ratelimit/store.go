@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Result is the state of the bucket after the request is taken into account
+type Result struct {
+	Allowed bool
+	// Remaining is the number of requests allowed right now
+	Remaining int
+	// RetryAfter is the time until the next request is allowed, zero if it is allowed now
+	RetryAfter time.Duration
+	// ResetAfter is the time until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// Store keeps token buckets, the memory store is used by default and a shared one
+// (e.g. redis) can be plugged in when the gateway runs in several instances
+type Store interface {
+	Take(key string, policy Policy, timeNow time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	fullAt  time.Time
+}
+
+// take refills the bucket for the time passed and takes a token from it if there is one
+func (b *bucket) take(policy Policy, timeNow time.Time) Result {
+	rate, capacity := policy.rate(), policy.capacity()
+	b.tokens = math.Min(capacity, b.tokens+timeNow.Sub(b.updated).Seconds()*rate)
+	b.updated = timeNow
+
+	result := Result{Allowed: b.tokens >= 1}
+	if result.Allowed {
+		b.tokens--
+	} else {
+		result.RetryAfter = secondsDuration((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = secondsDuration((capacity - b.tokens) / rate)
+	b.fullAt = timeNow.Add(result.ResetAfter)
+	return result
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+type MemoryStore struct {
+	buckets map[string]*bucket
+	sweptAt time.Time
+	mu      *sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		mu:      new(sync.Mutex),
+	}
+}
+
+func (s *MemoryStore) Take(key string, policy Policy, timeNow time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, found := s.buckets[key]
+	if !found {
+		b = &bucket{tokens: policy.capacity(), updated: timeNow}
+		s.buckets[key] = b
+	}
+	result := b.take(policy, timeNow)
+
+	// full buckets are the same as missing ones, so they are dropped from time to time
+	if timeNow.Sub(s.sweptAt) > time.Minute {
+		for bucketKey, item := range s.buckets {
+			if timeNow.After(item.fullAt) {
+				delete(s.buckets, bucketKey)
+			}
+		}
+		s.sweptAt = timeNow
+	}
+	return result, nil
+}
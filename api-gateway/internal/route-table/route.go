@@ -0,0 +1,127 @@
+package routetable
+
+import (
+	"api-gateway/internal/cors"
+	proxypass "api-gateway/internal/proxy-pass"
+	"api-gateway/internal/ratelimit"
+	requestuser "api-gateway/pkg/hidepost-requestuser"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Route is the entry of the route table, requests of the path prefix are proxied to the service.
+// Routes with the same service share upstreams, so they may be listed by one of the routes only.
+// The upstreams are required unless the service is bound to the config key (e.g. AUTHENTICATION_SERVICE).
+// Timeout is in seconds, the max body size is in bytes, zero values fall back to the gateway defaults.
+// Anonymous GET requests of routes with cache are served by the gateway cache.
+// Cors overrides fields of the default CORS policy for the route.
+type Route struct {
+	Prefix      string            `json:"prefix"`
+	Service     string            `json:"service"`
+	Upstreams   []string          `json:"upstreams"`
+	Balancer    string            `json:"balancer"`
+	HealthPath  string            `json:"health_path"`
+	Auth        bool              `json:"auth"`
+	Roles       []string          `json:"roles"`
+	Timeout     int               `json:"timeout"`
+	MaxBodySize int               `json:"max_body_size"`
+	RateLimit   *ratelimit.Policy `json:"rate_limit"`
+	Cache       bool              `json:"cache"`
+	Cors        *cors.Override    `json:"cors"`
+}
+
+func (r *Route) limits() proxypass.Limits {
+	return proxypass.Limits{
+		Timeout:     time.Duration(r.Timeout) * time.Second,
+		MaxBodySize: int64(r.MaxBodySize),
+	}
+}
+
+func (r *Route) healthPath() string {
+	if r.HealthPath != "" {
+		return r.HealthPath
+	}
+	return r.Prefix + "/admin/healthcheck"
+}
+
+// allowsRole reports whether the request user role passes the route guard
+func (r *Route) allowsRole(role string) bool {
+	if len(r.Roles) == 0 {
+		return true
+	}
+	for _, allowed := range r.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRoutes parses the json array of routes, the longer prefixes go first so they win on match
+func parseRoutes(value string) ([]Route, error) {
+	routes := make([]Route, 0)
+	if err := json.Unmarshal([]byte(value), &routes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal route table: %v", err)
+	}
+
+	prefixes := make(map[string]bool, len(routes))
+	upstreams := make(map[string][]string)
+	for i := range routes {
+		route := &routes[i]
+		route.Prefix = strings.TrimSuffix(route.Prefix, "/")
+		if !strings.HasPrefix(route.Prefix, "/") || route.Service == "" {
+			return nil, fmt.Errorf("invalid route for prefix %q: prefix and service are required", route.Prefix)
+		}
+		if prefixes[route.Prefix] {
+			return nil, fmt.Errorf("duplicated route for prefix %q", route.Prefix)
+		}
+		prefixes[route.Prefix] = true
+		if len(route.Upstreams) > 0 {
+			if listed, found := upstreams[route.Service]; found && strings.Join(listed, ",") != strings.Join(route.Upstreams, ",") {
+				return nil, fmt.Errorf("invalid route for prefix %q: upstreams differ from other routes of service %q",
+					route.Prefix, route.Service)
+			}
+			upstreams[route.Service] = route.Upstreams
+		}
+
+		for _, role := range route.Roles {
+			switch role {
+			case requestuser.UserRoleUnknown, requestuser.UserRoleUser, requestuser.UserRoleModerator,
+				requestuser.UserRoleAdmin:
+			default:
+				return nil, fmt.Errorf("invalid route for prefix %q: unknown role %q", route.Prefix, role)
+			}
+		}
+		if len(route.Roles) > 0 && !route.Auth {
+			return nil, fmt.Errorf("invalid route for prefix %q: roles require auth", route.Prefix)
+		}
+		if route.Timeout < 0 || route.MaxBodySize < 0 {
+			return nil, fmt.Errorf("invalid route for prefix %q: negative limits", route.Prefix)
+		}
+		if route.RateLimit != nil {
+			route.RateLimit.Prefix = route.Prefix
+			if err := route.RateLimit.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		if route.Cors != nil {
+			if err := route.Cors.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid route for prefix %q: %v", route.Prefix, err)
+			}
+		}
+	}
+
+	for i := range routes {
+		if len(routes[i].Upstreams) == 0 {
+			routes[i].Upstreams = upstreams[routes[i].Service]
+		}
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].Prefix) > len(routes[j].Prefix)
+	})
+	return routes, nil
+}
@@ -0,0 +1,65 @@
+package routetable
+
+import (
+	"api-gateway/internal/upstream"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		upstreams map[string][]string
+		wantErr   bool
+	}{
+		{
+			name: "upstreams are shared by routes of the service",
+			value: `[{"prefix":"/api/v1/posts","service":"posts","upstreams":["posts:8000"]},
+				{"prefix":"/api/v1/posts/admin","service":"posts","auth":true,"roles":["admin"]}]`,
+			upstreams: map[string][]string{"/api/v1/posts": {"posts:8000"}, "/api/v1/posts/admin": {"posts:8000"}},
+		},
+		{
+			name:      "route without upstreams",
+			value:     `[{"prefix":"/api/v1/auth","service":"AUTHENTICATION_SERVICE"}]`,
+			upstreams: map[string][]string{"/api/v1/auth": nil},
+		},
+		{
+			name: "different upstreams of the service",
+			value: `[{"prefix":"/api/v1/posts","service":"posts","upstreams":["posts:8000"]},
+				{"prefix":"/api/v1/blogs","service":"posts","upstreams":["blogs:8000"]}]`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicated prefix",
+			value:   `[{"prefix":"/api","service":"a","upstreams":["a:8000"]},{"prefix":"/api/","service":"b","upstreams":["b:8000"]}]`,
+			wantErr: true,
+		},
+		{name: "missing service", value: `[{"prefix":"/api","upstreams":["a:8000"]}]`, wantErr: true},
+		{name: "roles without auth", value: `[{"prefix":"/api","service":"a","roles":["admin"]}]`, wantErr: true},
+		{name: "unknown role", value: `[{"prefix":"/api","service":"a","auth":true,"roles":["owner"]}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := parseRoutes(tt.value)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			upstreams := make(map[string][]string)
+			for _, route := range routes {
+				upstreams[route.Prefix] = route.Upstreams
+			}
+			assert.Equal(t, tt.upstreams, upstreams)
+		})
+	}
+}
+
+func TestTableLoadRejectsRoutesWithoutUpstreams(t *testing.T) {
+	table := &Table{registry: upstream.NewRegistry(nil, nil), mu: new(sync.Mutex)}
+	err := table.Load(`[{"prefix":"/api/v1/posts","service":"posts"}]`)
+	assert.NotNil(t, err)
+	assert.Nil(t, table.router.Load())
+}
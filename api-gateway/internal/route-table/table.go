@@ -0,0 +1,167 @@
+package routetable
+
+import (
+	"api-gateway/internal/auth"
+	"api-gateway/internal/cache"
+	"api-gateway/internal/cors"
+	proxypass "api-gateway/internal/proxy-pass"
+	"api-gateway/internal/ratelimit"
+	"api-gateway/internal/upstream"
+	"api-gateway/pkg/filelogger"
+	requestuser "api-gateway/pkg/hidepost-requestuser"
+	"fmt"
+	"github.com/gorilla/mux"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+const ConfigKey = "ROUTES"
+
+// Table routes requests by the route table from config, the router is rebuilt and replaced
+// as a whole when the table changes, so requests never see a partly applied table
+type Table struct {
+	router     atomic.Pointer[mux.Router]
+	cors       *cors.Cors
+	authorizer *auth.Authorizer
+	signer     *requestuser.Signer
+	limits     *proxypass.DefaultLimits
+	limiter    *ratelimit.Limiter
+	cache      *cache.Cache
+	registry   *upstream.Registry
+	fileLogger *filelogger.FileLogger
+	mu         *sync.Mutex
+}
+
+// NewTable builds the router by the json array of routes, e.g.
+// [{"prefix":"/api/v1/users","service":"users","upstreams":["users-service:8080"],"auth":true}]
+func NewTable(routes string,
+	corsPolicy *cors.Cors,
+	authorizer *auth.Authorizer,
+	signer *requestuser.Signer,
+	limits *proxypass.DefaultLimits,
+	limiter *ratelimit.Limiter,
+	responseCache *cache.Cache,
+	registry *upstream.Registry,
+	cfgService *configService.ConfigServiceManager,
+	fileLogger *filelogger.FileLogger) (*Table, error) {
+
+	t := &Table{
+		cors:       corsPolicy,
+		authorizer: authorizer,
+		signer:     signer,
+		limits:     limits,
+		limiter:    limiter,
+		cache:      responseCache,
+		registry:   registry,
+		fileLogger: fileLogger,
+		mu:         new(sync.Mutex),
+	}
+	if err := t.Load(routes); err != nil {
+		return nil, err
+	}
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := t.Load(ss.Value); err != nil {
+			fileLogger.Error("failed to load route table from config, the current one is kept", map[string]interface{}{
+				"error": err.Error(),
+				"key":   ss.Key,
+			})
+			return
+		}
+		fileLogger.Info("route table is reloaded from config", nil)
+	}, ConfigKey)
+
+	return t, nil
+}
+
+// Load parses the route table and replaces the router, the current router is kept if the table is invalid.
+// Routes without upstreams are rejected unless their service is bound to the config key.
+func (t *Table) Load(value string) error {
+	routes, err := parseRoutes(value)
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		if len(route.Upstreams) == 0 && !t.registry.ConfigBound(route.Service) {
+			return fmt.Errorf("invalid route for prefix %q: no upstreams of service %q", route.Prefix, route.Service)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	router := mux.NewRouter()
+	services := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		pool := t.registry.RoutePool(route.Service, route.Upstreams, route.Balancer, route.healthPath())
+		services[route.Service] = true
+		router.PathPrefix(route.Prefix).Handler(t.handler(route, pool))
+	}
+	t.router.Store(router)
+	t.registry.PruneRoutePools(services)
+	return nil
+}
+
+func (t *Table) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	t.router.Load().ServeHTTP(w, req)
+}
+
+func (t *Table) handler(route Route, pool *upstream.Pool) http.HandlerFunc {
+	forward := func(w http.ResponseWriter, req *http.Request) {
+		err := proxypass.ToService(w, req, pool, t.limits.Apply(route.limits()), t.signer)
+		if err != nil {
+			t.fileLogger.Error("failed to proxy request to service", map[string]interface{}{
+				"error":        err.Error(),
+				"request_url":  req.URL.String(),
+				"request_id":   w.Header().Get(proxypass.RequestIdHeaderKey),
+				"service_name": route.Service,
+			})
+		}
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+
+		if !t.cors.Handle(w, req, route.Cors) {
+			return
+		}
+
+		requestuser.StripIdentityHeaders(req.Header)
+		if route.Auth {
+			err := t.authorizer.SetAuthorizationHeaders(req)
+			if err != nil {
+				t.fileLogger.Error("failed to proxy request to service, authorization request failed", map[string]interface{}{
+					"error":        err.Error(),
+					"request_url":  req.URL.String(),
+					"service_name": route.Service,
+				})
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		role := req.Header.Get(requestuser.UserRoleHeaderKey)
+		if role == "" {
+			role = requestuser.UserRoleUnknown
+		}
+		if !route.allowsRole(role) {
+			if role == requestuser.UserRoleUnknown {
+				w.WriteHeader(http.StatusUnauthorized)
+			} else {
+				w.WriteHeader(http.StatusForbidden)
+			}
+			return
+		}
+
+		if !t.limiter.Allow(w, req, route.RateLimit) {
+			return
+		}
+
+		if route.Cache {
+			t.cache.Serve(w, req, forward)
+			return
+		}
+		forward(w, req)
+	}
+}
@@ -1,9 +1,16 @@
 package server
 
 import (
+	"api-gateway/internal/admin"
 	"api-gateway/internal/auth"
-	basicroute "api-gateway/internal/basic-route"
+	"api-gateway/internal/cache"
+	"api-gateway/internal/cors"
+	proxypass "api-gateway/internal/proxy-pass"
+	"api-gateway/internal/ratelimit"
+	routetable "api-gateway/internal/route-table"
+	"api-gateway/internal/upstream"
 	"api-gateway/pkg/filelogger"
+	requestuser "api-gateway/pkg/hidepost-requestuser"
 	"context"
 	"errors"
 	"fmt"
@@ -43,68 +50,61 @@ func Run() {
 	fileLogger := filelogger.NewFileLogger("app.log")
 	fileLogger.EnableConsoleLog()
 
-	// create router
+	// CORS policy of the gateway, allowed origins are updated from config
+	corsPolicy, err := cors.NewCors(cfg.CorsAllowOrigins, cfgService, fileLogger)
+	if err != nil {
+		log.Fatalln("failed to init cors policy:", err)
+	}
+
+	// identity assertions are signed for every request proxied to services
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := identitySigner.SetSecret(ss.Value); err != nil {
+			fileLogger.Error("failed to update identity assertion secret from config",
+				map[string]any{"error": err.Error(), "key": ss.Key})
+		}
+	}, requestuser.IdentityAssertionSecretConfigKey)
+
+	// limits of proxied requests, routes can override them in the route table
+	proxyLimits := proxypass.NewDefaultLimits(cfg.ProxyTimeout, cfg.ProxyMaxBodySize, cfgService, fileLogger)
+
+	// rate limits are kept in memory of the instance
+	limiter, err := ratelimit.NewLimiter(cfg.RateLimitPolicies, ratelimit.NewMemoryStore(), cfgService, fileLogger)
+	if err != nil {
+		log.Fatalln("failed to init rate limiter:", err)
+	}
+
+	// responses of anonymous requests to routes with cache, kept in memory of the instance
+	responseCache := cache.NewCache(int64(cfg.CacheMaxSize), cfgService, fileLogger)
+
+	// instances of services, health checked in background
+	upstreams := upstream.NewRegistry(cfgService, fileLogger)
+
+	// authorization of requests by access tokens
+	authorizer := auth.NewAuthorizer(cfg.AuthServiceHost, cfg.AuthCacheTtl, upstreams, fileLogger, cfgService)
+
+	// routes to services are loaded from config
+	routes, err := routetable.NewTable(cfg.Routes, corsPolicy, authorizer, identitySigner, proxyLimits, limiter,
+		responseCache, upstreams, cfgService, fileLogger)
+	if err != nil {
+		log.Fatalln("failed to load route table:", err)
+	}
+
+	// create router, requests not matching gateway routes go to the route table
 	app := mux.NewRouter()
+	app.NotFoundHandler = routes
+	app.Use(corsPolicy.Middleware)
 
-	// register routes
-	authRoute := auth.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/auth"),
-		cfg.AuthServiceHost, fileLogger, cfgService)
-
-	// config service route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/config"), cfg.ConfServiceHost, "CONFIG_SERVICE",
-		fileLogger, cfgService, authRoute)
-
-	// logs consumer route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/logs-consumer"), cfg.HistoryLogsConsumerHost, "HISTORY_LOGS_CONSUMER",
-		fileLogger, cfgService, authRoute)
-
-	// logs service route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/logs"), cfg.HistoryLogsServiceHost, "HISTORY_LOGS_SERVICE",
-		fileLogger, cfgService, authRoute)
-
-	// users service route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/users"), cfg.UsersServiceHost, "USERS_SERVICE",
-		fileLogger, cfgService, authRoute)
-
-	// registration service route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/registration"), cfg.RegistrationServiceHost, "REGISTRATION_SERVICE",
-		fileLogger, cfgService, nil)
-
-	// email service route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/email"), cfg.EmailServiceHost, "EMAIL_SERVICE",
-		fileLogger, cfgService, authRoute)
-
-	// blogs service route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/blogs"), cfg.PostsServiceUrl, "POSTS_SERVICE",
-		fileLogger, cfgService, authRoute)
-
-	// posts service route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/posts"), cfg.PostsServiceUrl, "POSTS_SERVICE",
-		fileLogger, cfgService, authRoute)
-
-	// comments service route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/comments"), cfg.CommentsServiceHost, "COMMENTS_SERVICE",
-		fileLogger, cfgService, authRoute)
-
-	// billing service income route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/billing"), cfg.BillingServiceHost, "BILLING_SERVICE",
-		fileLogger, cfgService, authRoute)
-
-	// notification service route
-	basicroute.RegisterServiceRoute(
-		app.PathPrefix("/api/v1/notifications"), cfg.NotificationsServiceHost, "NOTIFICATIONS_SERVICE",
-		fileLogger, cfgService, authRoute)
+	// upstreams health
+	admin.RegisterUpstreamsRoute(
+		app.Path("/api/v1/api-gateway/admin/upstreams").Methods(http.MethodGet, http.MethodOptions), upstreams, authorizer, fileLogger)
+
+	// gateway cache purge
+	admin.RegisterCachePurgeRoute(
+		app.Path("/api/v1/api-gateway/admin/cache").Methods(http.MethodDelete, http.MethodOptions), responseCache, authorizer, fileLogger)
 
 	// gateway healthcheck
 	app.PathPrefix("/api/v1/api-gateway/admin/healthcheck").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -116,6 +116,10 @@ func Run() {
 	// start config updater
 	go cfgService.Updater()
 
+	// start upstreams health checks
+	upstreams.StartHealthChecks()
+	defer upstreams.StopHealthChecks()
+
 	// setting up server
 	srv := http.Server{
 		Addr:    fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
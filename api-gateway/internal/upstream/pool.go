@@ -0,0 +1,235 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	BalancerRoundRobin       = "round_robin"
+	BalancerLeastConnections = "least_connections"
+
+	// maxRetries is the number of other upstreams a failed safe request is retried on
+	maxRetries = 2
+)
+
+// ErrNoUpstream is returned when every upstream of the pool is unhealthy or its circuit is open
+var ErrNoUpstream = errors.New("no available upstream")
+
+// transport is shared by all pools, so connections to services are reused
+var transport = &http.Transport{
+	DialContext: (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	MaxIdleConns:          200,
+	MaxIdleConnsPerHost:   50,
+	IdleConnTimeout:       90 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// Pool balances requests between instances of the service. It is the round tripper of the proxy,
+// the host of the request url is replaced with the one of the picked upstream.
+type Pool struct {
+	name       string
+	healthPath string
+	upstreams  []*Upstream
+	balancer   string
+	next       atomic.Uint64
+	mu         *sync.RWMutex
+}
+
+type PoolStatus struct {
+	Name      string           `json:"name"`
+	Balancer  string           `json:"balancer"`
+	Upstreams []UpstreamStatus `json:"upstreams"`
+}
+
+func newPool(name string, hosts string, balancer string, healthPath string) *Pool {
+	p := &Pool{
+		name:       name,
+		healthPath: healthPath,
+		mu:         new(sync.RWMutex),
+	}
+	p.SetHosts(hosts)
+	p.SetBalancer(balancer)
+	return p
+}
+
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// SetHosts replaces upstreams with the comma separated hosts, state of the kept ones is preserved
+func (p *Pool) SetHosts(hosts string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := make(map[string]*Upstream, len(p.upstreams))
+	for _, u := range p.upstreams {
+		current[u.host] = u
+	}
+	upstreams := make([]*Upstream, 0)
+	for _, host := range strings.Split(hosts, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		if u, found := current[host]; found {
+			upstreams = append(upstreams, u)
+			delete(current, host)
+			continue
+		}
+		upstreams = append(upstreams, newUpstream(host))
+	}
+	p.upstreams = upstreams
+}
+
+func (p *Pool) setHealthPath(healthPath string) {
+	p.mu.Lock()
+	p.healthPath = healthPath
+	p.mu.Unlock()
+}
+
+func (p *Pool) SetBalancer(balancer string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if balancer == BalancerLeastConnections {
+		p.balancer = BalancerLeastConnections
+	} else {
+		p.balancer = BalancerRoundRobin
+	}
+}
+
+// pick returns the acquired upstream which isn't tried yet, nil if there is none
+func (p *Pool) pick(tried map[*Upstream]bool) *Upstream {
+	p.mu.RLock()
+	upstreams := make([]*Upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		if !tried[u] {
+			upstreams = append(upstreams, u)
+		}
+	}
+	balancer := p.balancer
+	p.mu.RUnlock()
+	if len(upstreams) == 0 {
+		return nil
+	}
+
+	if balancer == BalancerLeastConnections {
+		sort.SliceStable(upstreams, func(i, j int) bool {
+			return upstreams[i].activeRequests() < upstreams[j].activeRequests()
+		})
+	} else {
+		start := int(p.next.Add(1) % uint64(len(upstreams)))
+		upstreams = append(upstreams[start:], upstreams[:start]...)
+	}
+
+	timeNow := time.Now()
+	for _, u := range upstreams {
+		if u.acquire(timeNow) {
+			return u
+		}
+	}
+	return nil
+}
+
+// RoundTrip sends the request to the picked upstream. Safe requests without body are retried
+// on other upstreams if the upstream is unreachable or answers with the gateway error.
+func (p *Pool) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := isSafeMethod(req.Method) && (req.Body == nil || req.Body == http.NoBody)
+	tried := make(map[*Upstream]bool)
+
+	u := p.pick(tried)
+	if u == nil {
+		return nil, fmt.Errorf("%w of %s", ErrNoUpstream, p.name)
+	}
+	for attempt := 0; ; attempt++ {
+		tried[u] = true
+		out := req.Clone(req.Context())
+		out.URL.Host = u.host
+		resp, err := transport.RoundTrip(out)
+
+		// requests cancelled by the client don't tell anything about the upstream
+		failed := req.Context().Err() == nil && (err != nil || isGatewayError(resp.StatusCode))
+		if failed && retryable && attempt < maxRetries {
+			if next := p.pick(tried); next != nil {
+				u.release(true, time.Now())
+				if resp != nil {
+					_, _ = io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+				u = next
+				continue
+			}
+		}
+
+		if err != nil {
+			u.release(failed, time.Now())
+			return nil, err
+		}
+		release := func() { u.release(failed, time.Now()) }
+		if body, ok := resp.Body.(io.ReadWriteCloser); ok && resp.StatusCode == http.StatusSwitchingProtocols {
+			resp.Body = &upgradedBody{ReadWriteCloser: body, release: release}
+		} else {
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+		}
+		return resp, nil
+	}
+}
+
+func (p *Pool) Status() PoolStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	timeNow := time.Now()
+	status := PoolStatus{
+		Name:      p.name,
+		Balancer:  p.balancer,
+		Upstreams: make([]UpstreamStatus, 0, len(p.upstreams)),
+	}
+	for _, u := range p.upstreams {
+		status.Upstreams = append(status.Upstreams, u.status(timeNow))
+	}
+	return status
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func isGatewayError(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
+
+// releasingBody releases the upstream when the response is read, so streamed responses are counted until the end
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	b.once.Do(b.release)
+	return b.ReadCloser.Close()
+}
+
+// upgradedBody is the connection of the upgraded response, the proxy writes to it as well
+type upgradedBody struct {
+	io.ReadWriteCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *upgradedBody) Close() error {
+	b.once.Do(b.release)
+	return b.ReadWriteCloser.Close()
+}
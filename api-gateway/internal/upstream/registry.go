@@ -0,0 +1,202 @@
+package upstream
+
+import (
+	"api-gateway/pkg/filelogger"
+	"context"
+	"fmt"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BalancerConfigKeySuffix = "_BALANCER"
+
+	healthCheckInterval = 10 * time.Second
+	healthCheckTimeout  = 2 * time.Second
+)
+
+// Registry keeps pools of services by name, so routes to the same service share upstreams.
+// Pools are either bound to config keys or defined by the route table.
+type Registry struct {
+	pools      map[string]*Pool
+	routed     map[string]bool
+	order      []string
+	cfgService *configService.ConfigServiceManager
+	fileLogger *filelogger.FileLogger
+	mu         *sync.Mutex
+	ticker     *time.Ticker
+}
+
+func NewRegistry(cfgService *configService.ConfigServiceManager, fileLogger *filelogger.FileLogger) *Registry {
+	return &Registry{
+		pools:      make(map[string]*Pool),
+		routed:     make(map[string]bool),
+		order:      make([]string, 0),
+		cfgService: cfgService,
+		fileLogger: fileLogger,
+		mu:         new(sync.Mutex),
+	}
+}
+
+// Pool returns the pool of the service, it is created on the first call with the comma separated hosts
+// and the health check path. Hosts are updated by the config key and the balancer by the key with
+// the _BALANCER suffix, round robin is used by default.
+func (r *Registry) Pool(configKey string, hosts string, healthPath string) *Pool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pool, found := r.pools[configKey]; found {
+		return pool
+	}
+
+	balancer, _ := r.cfgService.GetParam(configKey + BalancerConfigKeySuffix)
+	pool := newPool(configKey, hosts, balancer, healthPath)
+	r.pools[configKey] = pool
+	r.order = append(r.order, configKey)
+
+	r.cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		pool.SetHosts(ss.Value)
+	}, configKey)
+	r.cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		pool.SetBalancer(ss.Value)
+	}, configKey+BalancerConfigKeySuffix)
+
+	return pool
+}
+
+// RoutePool returns the pool of the route table service with the hosts, balancer and health check path
+// of the table, the existing pool is updated keeping state of its upstreams. Pools bound to config keys
+// are returned as is, so routes can use them by the key without listing hosts.
+func (r *Registry) RoutePool(name string, hosts []string, balancer string, healthPath string) *Pool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pool, found := r.pools[name]; found {
+		if !r.routed[name] {
+			return pool
+		}
+		pool.SetHosts(strings.Join(hosts, ","))
+		pool.SetBalancer(balancer)
+		pool.setHealthPath(healthPath)
+		return pool
+	}
+
+	pool := newPool(name, strings.Join(hosts, ","), balancer, healthPath)
+	r.pools[name] = pool
+	r.routed[name] = true
+	r.order = append(r.order, name)
+	return pool
+}
+
+// ConfigBound reports whether the pool of the service is bound to the config key
+func (r *Registry) ConfigBound(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, found := r.pools[name]
+	return found && !r.routed[name]
+}
+
+// PruneRoutePools removes pools of services which aren't in the route table anymore
+func (r *Registry) PruneRoutePools(names map[string]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order := make([]string, 0, len(r.order))
+	for _, name := range r.order {
+		if r.routed[name] && !names[name] {
+			delete(r.pools, name)
+			delete(r.routed, name)
+			continue
+		}
+		order = append(order, name)
+	}
+	r.order = order
+}
+
+func (r *Registry) Status() []PoolStatus {
+	r.mu.Lock()
+	pools := make([]*Pool, 0, len(r.order))
+	for _, key := range r.order {
+		pools = append(pools, r.pools[key])
+	}
+	r.mu.Unlock()
+
+	result := make([]PoolStatus, 0, len(pools))
+	for _, pool := range pools {
+		result = append(result, pool.Status())
+	}
+	return result
+}
+
+func (r *Registry) StartHealthChecks() {
+	r.ticker = time.NewTicker(healthCheckInterval)
+	go func() {
+		for range r.ticker.C {
+			r.checkHealth()
+		}
+	}()
+}
+
+func (r *Registry) StopHealthChecks() {
+	r.ticker.Stop()
+}
+
+func (r *Registry) checkHealth() {
+	r.mu.Lock()
+	pools := make([]*Pool, 0, len(r.pools))
+	for _, pool := range r.pools {
+		pools = append(pools, pool)
+	}
+	r.mu.Unlock()
+
+	wg := new(sync.WaitGroup)
+	for _, pool := range pools {
+		pool.mu.RLock()
+		upstreams := append([]*Upstream(nil), pool.upstreams...)
+		healthPath := pool.healthPath
+		pool.mu.RUnlock()
+
+		for _, u := range upstreams {
+			wg.Add(1)
+			go func(pool *Pool, u *Upstream) {
+				defer wg.Done()
+				err := checkUpstream(u.host, healthPath)
+				if u.setHealthy(err == nil) {
+					data := map[string]interface{}{"service_name": pool.name, "service_host": u.host}
+					if err != nil {
+						data["error"] = err.Error()
+						r.fileLogger.Warn("upstream is unhealthy", data)
+					} else {
+						r.fileLogger.Info("upstream is healthy again", data)
+					}
+				}
+			}(pool, u)
+		}
+	}
+	wg.Wait()
+}
+
+// checkUpstream asks the healthcheck of the service, every answer but the server error means the instance
+// is up since healthchecks of services are behind the admin middleware
+func checkUpstream(host string, healthPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	checkUrl := url.URL{Scheme: "http", Host: host, Path: healthPath}
+	req, err := http.NewRequestWithContext(ctx, "GET", checkUrl.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create healthcheck request: %v", err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("failed to send healthcheck request: %v", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected healthcheck response status code: %d", resp.StatusCode)
+	}
+	return nil
+}
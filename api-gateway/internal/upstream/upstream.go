@@ -0,0 +1,102 @@
+package upstream
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// breakerThreshold is the number of consecutive failures opening the circuit of the upstream
+	breakerThreshold = 5
+	// breakerCooldown is how long the open circuit fails fast before a trial request is let through
+	breakerCooldown = 30 * time.Second
+)
+
+// Upstream is one instance of the service, it takes requests while it passes health checks
+// and its circuit breaker is closed
+type Upstream struct {
+	host      string
+	healthy   bool
+	active    int
+	failures  int
+	openUntil time.Time
+	trial     bool
+	mu        *sync.Mutex
+}
+
+type UpstreamStatus struct {
+	Host                string `json:"host"`
+	Healthy             bool   `json:"healthy"`
+	CircuitOpen         bool   `json:"circuit_open"`
+	ActiveRequests      int    `json:"active_requests"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+}
+
+func newUpstream(host string) *Upstream {
+	return &Upstream{
+		host:    host,
+		healthy: true,
+		mu:      new(sync.Mutex),
+	}
+}
+
+// acquire counts the request to the upstream, false is returned if the upstream can't take it.
+// After the cooldown the open circuit lets through a single trial request.
+func (u *Upstream) acquire(timeNow time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.healthy || timeNow.Before(u.openUntil) {
+		return false
+	}
+	if u.failures >= breakerThreshold {
+		if u.trial {
+			return false
+		}
+		u.trial = true
+	}
+	u.active++
+	return true
+}
+
+// release ends the request counted by acquire, the failure is taken into account by the circuit breaker
+func (u *Upstream) release(failed bool, timeNow time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.active--
+	u.trial = false
+	if !failed {
+		u.failures = 0
+		return
+	}
+	u.failures++
+	if u.failures >= breakerThreshold {
+		u.openUntil = timeNow.Add(breakerCooldown)
+	}
+}
+
+func (u *Upstream) activeRequests() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.active
+}
+
+// setHealthy saves the result of the health check, returns true if the health has changed
+func (u *Upstream) setHealthy(healthy bool) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	changed := u.healthy != healthy
+	u.healthy = healthy
+	return changed
+}
+
+func (u *Upstream) status(timeNow time.Time) UpstreamStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return UpstreamStatus{
+		Host:                u.host,
+		Healthy:             u.healthy,
+		CircuitOpen:         timeNow.Before(u.openUntil),
+		ActiveRequests:      u.active,
+		ConsecutiveFailures: u.failures,
+	}
+}
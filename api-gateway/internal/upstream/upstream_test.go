@@ -0,0 +1,176 @@
+package upstream
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type breakerStep struct {
+	// acquire is checked against allowed, otherwise the request is released with failed
+	acquire bool
+	allowed bool
+	failed  bool
+	offset  time.Duration
+}
+
+// requests returns steps of the count of requests taken and released at the offset
+func requests(count int, failed bool, offset time.Duration) []breakerStep {
+	steps := make([]breakerStep, 0, count*2)
+	for i := 0; i < count; i++ {
+		steps = append(steps,
+			breakerStep{acquire: true, allowed: true, offset: offset},
+			breakerStep{failed: failed, offset: offset})
+	}
+	return steps
+}
+
+func steps(groups ...[]breakerStep) []breakerStep {
+	result := make([]breakerStep, 0)
+	for _, group := range groups {
+		result = append(result, group...)
+	}
+	return result
+}
+
+func TestUpstreamCircuitBreaker(t *testing.T) {
+	cooldown := breakerCooldown
+	tests := []struct {
+		name  string
+		steps []breakerStep
+		open  bool
+		// failures is the number of consecutive failures at the end
+		failures int
+		offset   time.Duration
+	}{
+		{
+			name:     "failures under the threshold keep the circuit closed",
+			steps:    requests(breakerThreshold-1, true, 0),
+			failures: breakerThreshold - 1,
+		},
+		{
+			name:     "success resets failures",
+			steps:    steps(requests(breakerThreshold-1, true, 0), requests(1, false, 0), requests(breakerThreshold-1, true, 0)),
+			failures: breakerThreshold - 1,
+		},
+		{
+			name: "failures at the threshold open the circuit",
+			steps: steps(requests(breakerThreshold, true, 0),
+				[]breakerStep{{acquire: true, allowed: false, offset: cooldown - time.Second}}),
+			open:     true,
+			failures: breakerThreshold,
+			offset:   cooldown - time.Second,
+		},
+		{
+			name: "only one trial request is let through after the cooldown",
+			steps: steps(requests(breakerThreshold, true, 0), []breakerStep{
+				{acquire: true, allowed: true, offset: cooldown},
+				{acquire: true, allowed: false, offset: cooldown},
+			}),
+			failures: breakerThreshold,
+			offset:   cooldown,
+		},
+		{
+			name: "successful trial closes the circuit",
+			steps: steps(requests(breakerThreshold, true, 0), requests(1, false, cooldown), []breakerStep{
+				{acquire: true, allowed: true, offset: cooldown},
+				{acquire: true, allowed: true, offset: cooldown},
+			}),
+			offset: cooldown,
+		},
+		{
+			name: "failed trial opens the circuit again",
+			steps: steps(requests(breakerThreshold, true, 0), requests(1, true, cooldown), []breakerStep{
+				{acquire: true, allowed: false, offset: 2*cooldown - time.Second},
+			}),
+			open:     true,
+			failures: breakerThreshold + 1,
+			offset:   2*cooldown - time.Second,
+		},
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUpstream("service:8000")
+			for i, step := range tt.steps {
+				if step.acquire {
+					assert.Equal(t, step.allowed, u.acquire(start.Add(step.offset)), "step %d", i)
+					continue
+				}
+				u.release(step.failed, start.Add(step.offset))
+			}
+			status := u.status(start.Add(tt.offset))
+			assert.Equal(t, tt.open, status.CircuitOpen)
+			assert.Equal(t, tt.failures, status.ConsecutiveFailures)
+		})
+	}
+}
+
+func TestUpstreamUnhealthy(t *testing.T) {
+	u := newUpstream("service:8000")
+	assert.True(t, u.setHealthy(false))
+	assert.False(t, u.setHealthy(false))
+	assert.False(t, u.acquire(time.Now()))
+	assert.True(t, u.setHealthy(true))
+	assert.True(t, u.acquire(time.Now()))
+}
+
+func TestPoolRoundTrip(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer working.Close()
+	failingHost, workingHost := serverHost(failing), serverHost(working)
+
+	tests := []struct {
+		name   string
+		method string
+		hosts  string
+		status int
+		// failures of the failing upstream after the request
+		failures int
+	}{
+		{name: "safe request is retried", method: http.MethodGet, hosts: failingHost + "," + workingHost,
+			status: http.StatusOK, failures: 1},
+		{name: "unsafe request isn't retried", method: http.MethodPost, hosts: failingHost,
+			status: http.StatusBadGateway, failures: 1},
+		{name: "working upstream", method: http.MethodGet, hosts: workingHost, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newPool("service", tt.hosts, BalancerLeastConnections, "/health")
+			req := httptest.NewRequest(tt.method, "http://service/api", nil)
+			req.RequestURI = ""
+			resp, err := pool.RoundTrip(req)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			resp.Body.Close()
+
+			for _, status := range pool.Status().Upstreams {
+				if status.Host == failingHost {
+					assert.Equal(t, tt.failures, status.ConsecutiveFailures)
+				}
+				assert.Equal(t, 0, status.ActiveRequests)
+			}
+		})
+	}
+}
+
+func TestPoolNoUpstream(t *testing.T) {
+	pool := newPool("service", "", BalancerRoundRobin, "/health")
+	req := httptest.NewRequest(http.MethodGet, "http://service/api", nil)
+	_, err := pool.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrNoUpstream)
+}
+
+func serverHost(server *httptest.Server) string {
+	serverUrl, _ := url.Parse(server.URL)
+	return serverUrl.Host
+}
@@ -0,0 +1,92 @@
+package requestuser
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	IdentityAssertionHeaderKey       = "IDENTITY-ASSERTION"
+	IdentityAssertionSecretConfigKey = "IDENTITY_ASSERTION_SECRET"
+
+	// MinSecretLength is the shortest secret accepted, shorter ones could be brute forced
+	MinSecretLength = 32
+
+	// assertionLifetime is short since the assertion is made for every request
+	assertionLifetime = 1 * time.Minute
+)
+
+// Assertion is the identity of the request user the gateway signs for services
+type Assertion struct {
+	UserId    string `json:"user_id"`
+	Role      string `json:"role"`
+	Banned    bool   `json:"banned"`
+	Issuer    string `json:"iss"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Signer signs identity assertions with the secret shared by the gateway and services
+type Signer struct {
+	secret []byte
+	issuer string
+	mu     *sync.RWMutex
+}
+
+func NewSigner(secret string, issuer string) (*Signer, error) {
+	if err := validateSecret(secret); err != nil {
+		return nil, err
+	}
+	return &Signer{
+		secret: []byte(secret),
+		issuer: issuer,
+		mu:     &sync.RWMutex{},
+	}, nil
+}
+
+func validateSecret(secret string) error {
+	if len(secret) < MinSecretLength {
+		return fmt.Errorf("identity assertion secret must be at least %d characters long", MinSecretLength)
+	}
+	return nil
+}
+
+// SetSecret changes the signing secret, services accept the previous one for a grace window.
+// The short secret is rejected and the current one is kept.
+func (s *Signer) SetSecret(secret string) error {
+	if err := validateSecret(secret); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.secret = []byte(secret)
+	s.mu.Unlock()
+	return nil
+}
+
+// Sign returns the assertion value of the header, the issuer and the expiry are set by the signer
+func (s *Signer) Sign(assertion Assertion) string {
+	assertion.Issuer = s.issuer
+	assertion.ExpiresAt = time.Now().Add(assertionLifetime).Unix()
+	// marshaling of the plain struct can't fail
+	body, _ := json.Marshal(assertion)
+	payload := base64.RawURLEncoding.EncodeToString(body)
+
+	s.mu.RLock()
+	mac := hmac.New(sha256.New, s.secret)
+	s.mu.RUnlock()
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// StripIdentityHeaders removes identity headers sent by the client, they are never trusted
+func StripIdentityHeaders(header http.Header) {
+	header.Del(UserIdHeaderKey)
+	header.Del(UserRoleHeaderKey)
+	header.Del(UserIsBannedHeaderKey)
+	header.Del(IdentityAssertionHeaderKey)
+}
@@ -0,0 +1,92 @@
+package email
+
+import (
+	"auth-service/pkg/filelogger"
+	"auth-service/pkg/queuelogger"
+	"bytes"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"html/template"
+	"time"
+)
+
+const (
+	EmailQueueConfigKey = "EMAIL_QUEUE"
+)
+
+type Service struct {
+	sender      *Sender
+	fileLogger  *filelogger.FileLogger
+	queueLogger *queuelogger.RemoteLogger
+}
+
+func NewService(sender *Sender, cfgService *configService.ConfigServiceManager,
+	fileLogger *filelogger.FileLogger,
+	queueLogger *queuelogger.RemoteLogger) *Service {
+
+	service := &Service{
+		sender:      sender,
+		fileLogger:  fileLogger,
+		queueLogger: queueLogger,
+	}
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		sender.queue = ss.Value
+	}, EmailQueueConfigKey)
+
+	return service
+}
+
+func (s *Service) SendPasswordResetEmail(login, email, token string) {
+	data := PasswordResetEmailData{
+		Token: token,
+	}
+
+	t, _ := template.ParseFiles("./templates/password_reset.html")
+	var tpl bytes.Buffer
+	_ = t.Execute(&tpl, data)
+	html := tpl.String()
+	ToName := login
+
+	err := s.sender.handleMessage(QueueMessage{
+		From:    nil,
+		Name:    nil,
+		Subject: "Восстановление пароля",
+		To:      email,
+		ToName:  &ToName,
+		Html:    html,
+	})
+	if err != nil {
+		data := map[string]any{"error": err.Error()}
+		s.fileLogger.Error("failed to send password reset email to queue", data)
+		data["message"] = "failed to send password reset email to queue"
+		_ = s.queueLogger.Error(nil, data)
+	}
+}
+
+func (s *Service) SendAccountLockedEmail(login, email string, lockedUntil time.Time, ip string) {
+	data := AccountLockedEmailData{
+		LockedUntil: lockedUntil.Format("02.01.2006 15:04"),
+		IP:          ip,
+	}
+
+	t, _ := template.ParseFiles("./templates/account_locked.html")
+	var tpl bytes.Buffer
+	_ = t.Execute(&tpl, data)
+	html := tpl.String()
+	ToName := login
+
+	err := s.sender.handleMessage(QueueMessage{
+		From:    nil,
+		Name:    nil,
+		Subject: "Вход в аккаунт заблокирован",
+		To:      email,
+		ToName:  &ToName,
+		Html:    html,
+	})
+	if err != nil {
+		data := map[string]any{"error": err.Error()}
+		s.fileLogger.Error("failed to send account locked email to queue", data)
+		data["message"] = "failed to send account locked email to queue"
+		_ = s.queueLogger.Error(nil, data)
+	}
+}
@@ -0,0 +1,15 @@
+package keys
+
+// JWK is the public Ed25519 key in the JSON Web Key format of RFC 8037
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+type JWKSResponse struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,21 @@
+package keys
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type jwksHandler struct {
+	service *Service
+}
+
+func RegisterJwksHandler(api *gin.RouterGroup, service *Service) {
+	h := &jwksHandler{service: service}
+
+	api.GET("/jwks.json", h.getJwks)
+}
+
+func (h *jwksHandler) getJwks(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, JWKSResponse{Keys: h.service.JWKS()})
+}
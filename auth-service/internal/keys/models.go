@@ -0,0 +1,14 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"time"
+)
+
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+	RotatedAt  *time.Time
+	Created    time.Time
+}
@@ -0,0 +1,84 @@
+package keys
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// PublishedKeys returns the active key and the rotated ones that still verify issued tokens, newest first
+func (r *Repository) PublishedKeys(ctx context.Context, rotatedAfter time.Time) ([]SigningKey, error) {
+	query := `select kid, private_key, public_key, rotated_at, created
+			from jwt_signing_keys
+			where rotated_at is null or rotated_at > $1
+			order by created desc`
+
+	rows, err := r.db.Query(ctx, query, rotatedAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]SigningKey, 0)
+	var key SigningKey
+	for rows.Next() {
+		var privateKey, publicKey []byte
+		err = rows.Scan(&key.ID, &privateKey, &publicKey, &key.RotatedAt, &key.Created)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey = privateKey
+		key.PublicKey = publicKey
+		resultArray = append(resultArray, key)
+	}
+	return resultArray, nil
+}
+
+// RotateKey saves the new active key unless another instance has rotated the key after activeBefore.
+// The advisory lock keeps instances from rotating at the same time.
+func (r *Repository) RotateKey(ctx context.Context, key *SigningKey, activeBefore time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext('jwt_signing_keys'))`)
+	if err != nil {
+		return false, err
+	}
+	var fresh bool
+	err = tx.QueryRow(ctx, `select exists(select 1 from jwt_signing_keys where rotated_at is null and created > $1)`,
+		activeBefore).Scan(&fresh)
+	if err != nil {
+		return false, err
+	}
+	if fresh {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, `update jwt_signing_keys set rotated_at = $1 where rotated_at is null`, key.Created)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(ctx, `insert into jwt_signing_keys (kid, private_key, public_key, rotated_at, created)
+			values ($1, $2, $3, null, $4)`,
+		key.ID, []byte(key.PrivateKey), []byte(key.PublicKey), key.Created)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *Repository) DeleteRotatedKeys(ctx context.Context, rotatedBefore time.Time) error {
+	_, err := r.db.Exec(ctx, `delete from jwt_signing_keys where rotated_at < $1`, rotatedBefore)
+	return err
+}
@@ -0,0 +1,213 @@
+package keys
+
+import (
+	"auth-service/pkg/filelogger"
+	"auth-service/pkg/queuelogger"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/google/uuid"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	RotationIntervalConfigKey = "JWT_KEY_ROTATION_INTERVAL"
+
+	// keyRetentionTime is how long the rotated key stays published, every token signed by it
+	// expires earlier since access tokens live for minutes
+	keyRetentionTime = 24 * time.Hour
+	// reloadInterval limits reloads of the keys on unknown key id, which appears when another
+	// instance has just rotated the key
+	reloadInterval = 5 * time.Second
+)
+
+type Service struct {
+	repository       *Repository
+	fileLogger       *filelogger.FileLogger
+	queueLogger      *queuelogger.RemoteLogger
+	rotationInterval int
+	keys             []SigningKey
+	loadedAt         time.Time
+	mu               *sync.RWMutex
+	rotationTicker   *time.Ticker
+}
+
+// NewService loads the signing keys, creating the first one if there is none, and starts the rotation worker.
+// The rotation interval is in hours.
+func NewService(ctx context.Context, repository *Repository, rotationInterval int,
+	cfgService *configService.ConfigServiceManager,
+	fileLogger *filelogger.FileLogger,
+	queueLogger *queuelogger.RemoteLogger) (*Service, error) {
+
+	service := &Service{
+		repository:       repository,
+		fileLogger:       fileLogger,
+		queueLogger:      queueLogger,
+		rotationInterval: rotationInterval,
+		mu:               &sync.RWMutex{},
+	}
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err != nil {
+			data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+			fileLogger.Error("failed to parse value for jwt key rotation interval from config", data)
+			data["message"] = "failed to parse value for jwt key rotation interval from config"
+			err1 := queueLogger.Error(nil, data)
+			if err1 != nil {
+				fileLogger.Error("failed to send log about jwt key rotation interval parsing error to queue",
+					map[string]any{"error": err1.Error()})
+			}
+			return
+		}
+		service.mu.Lock()
+		service.rotationInterval = value
+		service.mu.Unlock()
+	}, RotationIntervalConfigKey)
+
+	if err := service.rotate(ctx); err != nil {
+		return nil, fmt.Errorf("failed to init signing keys: %v", err)
+	}
+
+	service.rotationTicker = time.NewTicker(1 * time.Minute)
+	go service.StartRotationWorker(ctx, service.rotationTicker)
+
+	return service, nil
+}
+
+func (s *Service) StopWorkers() {
+	s.rotationTicker.Stop()
+}
+
+// StartRotationWorker replaces the active key when it is older than the rotation interval
+// and picks up keys rotated by other instances
+func (s *Service) StartRotationWorker(ctx context.Context, ticker *time.Ticker) {
+	for range ticker.C {
+		if err := s.rotate(ctx); err != nil {
+			data := map[string]any{"error": err.Error()}
+			s.fileLogger.Error("failed to rotate jwt signing keys", data)
+			data["message"] = "failed to rotate jwt signing keys"
+			_ = s.queueLogger.Error(nil, data)
+		}
+	}
+}
+
+func (s *Service) rotate(ctx context.Context) error {
+	timeNow := time.Now().UTC()
+	if err := s.reload(ctx, timeNow); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	interval := time.Hour * time.Duration(s.rotationInterval)
+	due := len(s.keys) == 0 || s.keys[0].RotatedAt != nil || (interval > 0 && s.keys[0].Created.Before(timeNow.Add(-interval)))
+	s.mu.RUnlock()
+	if !due {
+		return nil
+	}
+
+	key, err := newSigningKey(timeNow)
+	if err != nil {
+		return err
+	}
+	activeBefore := timeNow
+	if interval > 0 {
+		activeBefore = timeNow.Add(-interval)
+	}
+	if _, err = s.repository.RotateKey(ctx, key, activeBefore); err != nil {
+		return fmt.Errorf("failed to save signing key: %v", err)
+	}
+	if err = s.repository.DeleteRotatedKeys(ctx, timeNow.Add(-keyRetentionTime)); err != nil {
+		return fmt.Errorf("failed to delete rotated signing keys: %v", err)
+	}
+	return s.reload(ctx, timeNow)
+}
+
+func (s *Service) reload(ctx context.Context, timeNow time.Time) error {
+	keys, err := s.repository.PublishedKeys(ctx, timeNow.Add(-keyRetentionTime))
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %v", err)
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.loadedAt = timeNow
+	s.mu.Unlock()
+	return nil
+}
+
+func newSigningKey(timeNow time.Time) (*SigningKey, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %v", err)
+	}
+	return &SigningKey{
+		ID:         uuid.New().String(),
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+		RotatedAt:  nil,
+		Created:    timeNow,
+	}, nil
+}
+
+// SigningKey returns the active key tokens are signed with
+func (s *Service) SigningKey() (*SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.keys) == 0 {
+		return nil, fmt.Errorf("no signing key loaded")
+	}
+	key := s.keys[0]
+	return &key, nil
+}
+
+// PublicKey returns the published key by id, reloading the keys if the id is unknown
+func (s *Service) PublicKey(kid string) (ed25519.PublicKey, error) {
+	if key := s.publicKey(kid); key != nil {
+		return key, nil
+	}
+	s.mu.RLock()
+	reloadable := time.Since(s.loadedAt) > reloadInterval
+	s.mu.RUnlock()
+	if reloadable {
+		if err := s.reload(context.Background(), time.Now().UTC()); err != nil {
+			return nil, err
+		}
+		if key := s.publicKey(kid); key != nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key id: %s", kid)
+}
+
+func (s *Service) publicKey(kid string) ed25519.PublicKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key.PublicKey
+		}
+	}
+	return nil
+}
+
+func (s *Service) JWKS() []JWK {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]JWK, 0, len(s.keys))
+	for _, key := range s.keys {
+		result = append(result, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key.PublicKey),
+			Kid: key.ID,
+			Alg: "EdDSA",
+			Use: "sig",
+		})
+	}
+	return result
+}
@@ -5,3 +5,9 @@ import "time"
 type AuthenticationEventData struct {
 	At time.Time `json:"at" validate:"required"`
 }
+
+type AccountLockedEventData struct {
+	At          time.Time `json:"at" validate:"required"`
+	LockedUntil time.Time `json:"locked_until" validate:"required"`
+	IP          string    `json:"ip"`
+}
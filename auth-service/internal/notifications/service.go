@@ -11,6 +11,7 @@ import (
 const (
 	QueueConfigKey          = "NOTIFICATIONS_QUEUE"
 	EventCodeAuthentication = "AUTHENTICATION"
+	EventCodeAccountLocked  = "ACCOUNT_LOCKED"
 )
 
 type Service struct {
@@ -56,3 +57,26 @@ func (s *Service) Authentication(userId string) {
 		_ = s.queueLogger.Error(nil, loggingMap)
 	}
 }
+
+func (s *Service) AccountLocked(userId string, lockedUntil time.Time, ip string) {
+	loggingMap := map[string]any{}
+	obj := AccountLockedEventData{
+		At:          time.Now().UTC(),
+		LockedUntil: lockedUntil,
+		IP:          ip,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal account locked event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeAccountLocked, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send account locked event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
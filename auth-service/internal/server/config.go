@@ -12,11 +12,23 @@ type Config struct {
 	ConfigServicePort    string `env:"CONFIG_SERVICE_PORT"`
 	ConfigUpdateInterval int    `env:"CONFIG_UPDATE_INTERVAL" env-default:"60"`
 
-	JwtSecret             string `config-service:"JWT_SECRET"`
-	JwtDefaultLifetime    int    `config-service:"JWT_DEFAULT_LIFETIME"`
-	JwtRememberMeLifetime int    `config-service:"JWT_REMEMBER_ME_LIFETIME"`
+	IdentityAssertionSecret string `config-service:"IDENTITY_ASSERTION_SECRET"`
+
+	JwtDefaultLifetime    int `config-service:"JWT_DEFAULT_LIFETIME"`
+	JwtRememberMeLifetime int `config-service:"JWT_REMEMBER_ME_LIFETIME"`
+	JwtAccessLifetime     int `config-service:"JWT_ACCESS_LIFETIME"`
+	JwtKeyRotation        int `config-service:"JWT_KEY_ROTATION_INTERVAL"`
+
+	PasswordResetLifetime   int `config-service:"PASSWORD_RESET_LIFETIME"`
+	PasswordResetEmailLimit int `config-service:"PASSWORD_RESET_EMAIL_LIMIT"`
+	PasswordResetIpLimit    int `config-service:"PASSWORD_RESET_IP_LIMIT"`
+
+	LoginMaxFailures     int `config-service:"LOGIN_MAX_FAILURES"`
+	LoginIpMaxFailures   int `config-service:"LOGIN_IP_MAX_FAILURES"`
+	LoginLockoutDuration int `config-service:"LOGIN_LOCKOUT_DURATION"`
 
 	NotificationQueue string `config-service:"NOTIFICATION_QUEUE"`
+	EmailQueue        string `config-service:"EMAIL_QUEUE"`
 
 	DbHost     string `config-service:"DB_HOST"`
 	DbPort     string `config-service:"DB_PORT"`
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"auth-service/internal/email"
+	"auth-service/internal/keys"
 	"auth-service/internal/notifications"
 	"auth-service/internal/users"
 	"auth-service/pkg/filelogger"
+	requestuser "auth-service/pkg/hidepost-requestuser"
 	"auth-service/pkg/pgutils"
 	"auth-service/pkg/queuelogger"
 	serverlogging "auth-service/pkg/serverlogging/gin"
@@ -65,20 +68,56 @@ func Run() {
 		log.Fatalln("failed to init files sender:", err)
 	}
 
+	emailSender, err := email.NewSender(ctx, cfg.MqUrl(), cfg.EmailQueue)
+	if err != nil {
+		log.Fatalln("failed to init email sender:", err)
+	}
+	defer emailSender.Close()
+
 	// init repositories
 	usersRepository := users.NewRepository(dbConn)
+	keysRepository := keys.NewRepository(dbConn)
 
 	// init services
 	notificationsService := notifications.NewService(notificationsSender, cfgService, fileLogger, mqLogger)
-	usersService := users.NewService(usersRepository, notificationsService,
-		cfg.JwtSecret, cfg.JwtDefaultLifetime, cfg.JwtRememberMeLifetime,
+	emailService := email.NewService(emailSender, cfgService, fileLogger, mqLogger)
+	keysService, err := keys.NewService(ctx, keysRepository, cfg.JwtKeyRotation, cfgService, fileLogger, mqLogger)
+	if err != nil {
+		log.Fatalln("failed to init keys service:", err)
+	}
+	defer keysService.StopWorkers()
+	usersService := users.NewService(usersRepository, notificationsService, emailService,
+		keysService, cfg.JwtDefaultLifetime, cfg.JwtRememberMeLifetime, cfg.JwtAccessLifetime,
+		cfg.PasswordResetLifetime,
+		users.ResetLimits{
+			EmailLimit: cfg.PasswordResetEmailLimit,
+			IpLimit:    cfg.PasswordResetIpLimit,
+		},
+		users.LoginLimits{
+			MaxFailures:     cfg.LoginMaxFailures,
+			IpMaxFailures:   cfg.LoginIpMaxFailures,
+			LockoutDuration: cfg.LoginLockoutDuration,
+		},
 		cfgService, fileLogger, mqLogger)
 
+	// identity assertions are verified for every request and signed for requests to other services
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := identitySigner.SetSecret(ss.Value); err != nil {
+			fileLogger.Error("failed to update identity assertion secret from config",
+				map[string]any{"error": err.Error(), "key": ss.Key})
+		}
+	}, requestuser.IdentityAssertionSecretConfigKey)
+
 	// setting up gin app
 	gin.SetMode(gin.ReleaseMode)
 	app := gin.New()
 	app.Use(gin.CustomRecovery(serverlogging.NewPanicLogger(fileLogger, mqLogger)))
 	app.Use(serverlogging.NewRequestLogger(fileLogger, mqLogger))
+	app.Use(identitySigner.Middleware())
 
 	// init handlers
 	apiV1 := app.Group("/api/v1/auth")
@@ -89,9 +128,21 @@ func Run() {
 	// admin handler
 	users.RegisterAdminHandler(apiV1.Group("/admin"), usersService)
 
+	// password reset handler
+	users.RegisterPasswordResetHandler(apiV1.Group("/password-reset"), usersService)
+
+	// two factor handler
+	users.RegisterTwoFactorHandler(apiV1.Group("/2fa"), usersService)
+
+	// sessions handler
+	users.RegisterSessionsHandler(apiV1.Group("/sessions"), usersService)
+
 	// user info handler
 	users.RegisterUserInfoHandler(apiV1.Group("/user-info"), usersService)
 
+	// jwks handler
+	keys.RegisterJwksHandler(apiV1.Group("/.well-known"), keysService)
+
 	// service handler
 	users.RegisterServiceHandler(apiV1.Group("/service"), usersService)
 
@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type loginHandler struct {
@@ -21,6 +24,7 @@ func RegisterLoginHandler(api *gin.RouterGroup, service *Service) {
 	}
 
 	api.POST("", h.login)
+	api.POST("/2fa", h.loginTwoFactor)
 }
 
 func (h *loginHandler) login(ctx *gin.Context) {
@@ -51,23 +55,167 @@ func (h *loginHandler) login(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
-	if user == nil {
-		loggingMap.SetMessage("wrong login or password")
+	if !h.loginAllowed(ctx, user, req.Login) {
+		return
+	}
+
+	if user == nil || !cryptservice.ValueHashMatched(req.Password, user.HashedPassword) {
+		h.loginFailed(ctx, user, req.Login)
+		return
+	}
+
+	loggingMap.SetUserId(&user.ID)
+
+	twoFactor, err := h.service.repository.TwoFactorByUserId(ctx, user.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get two factor of user")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if twoFactor.IsEnabled() {
+		challengeToken, err := h.service.GenerateChallengeToken(user.ID, req.RememberMe)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("challenge token generation failed for user")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		loggingMap.SetMessage("password checked, waiting for the second factor")
+		loggingMap.Info()
+		ctx.JSON(http.StatusOK, LoginResponse{TwoFactorRequired: true, ChallengeToken: challengeToken})
+		return
+	}
+
+	if err = h.service.ResetLoginFailures(ctx, user); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reset login failures")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	h.startSession(ctx, user.ID, req.RememberMe)
+}
+
+// loginTwoFactor is the second login step of the user with two-factor authentication
+func (h *loginHandler) loginTwoFactor(ctx *gin.Context) {
+
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req LoginTwoFactorRequest
+
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad login request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad login request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	userId, remember, err := h.service.ParseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("invalid challenge token")
 		loggingMap.Debug()
 		ctx.JSON(http.StatusUnauthorized, nil)
 		return
 	}
+	loggingMap.SetUserId(userId)
 
-	if !cryptservice.ValueHashMatched(req.Password, user.HashedPassword) {
-		loggingMap.SetMessage("wrong login or password")
+	user, err := h.service.ByID(ctx, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get user by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if user == nil {
+		loggingMap.SetMessage("user of the challenge token not found")
 		loggingMap.Debug()
 		ctx.JSON(http.StatusUnauthorized, nil)
 		return
 	}
 
-	loggingMap.SetUserId(&user.ID)
+	if !h.loginAllowed(ctx, user, user.Login) {
+		return
+	}
+
+	verified, err := h.service.VerifySecondFactor(ctx, user.ID, req.Code)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to verify second factor")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !verified {
+		h.loginFailed(ctx, user, user.Login)
+		return
+	}
+
+	if err = h.service.ResetLoginFailures(ctx, user); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reset login failures")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	h.startSession(ctx, user.ID, remember)
+}
+
+// loginAllowed writes too many requests with the time to retry after
+// if the account or the ip is blocked because of failed logins
+func (h *loginHandler) loginAllowed(ctx *gin.Context, user *User, login string) bool {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	blockedUntil, err := h.service.LoginBlockedUntil(ctx, user, login, ctx.ClientIP())
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to check login block")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return false
+	}
+	if blockedUntil != nil {
+		retryAfter := int(time.Until(*blockedUntil).Seconds()) + 1
+		loggingMap["blocked_until"] = blockedUntil
+		loggingMap.SetMessage("login is blocked after failed attempts")
+		loggingMap.Info()
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		ctx.JSON(http.StatusTooManyRequests, nil)
+		return false
+	}
+	return true
+}
+
+func (h *loginHandler) loginFailed(ctx *gin.Context, user *User, login string) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	if err := h.service.RegisterLoginFailure(ctx, user, login, ctx.ClientIP()); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to register login failure")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	loggingMap.SetMessage("wrong login, password or second factor code")
+	loggingMap.Info()
+	ctx.JSON(http.StatusUnauthorized, nil)
+}
+
+func (h *loginHandler) startSession(ctx *gin.Context, userId uuid.UUID, remember bool) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	session, refreshToken, err := h.service.CreateSession(ctx, userId, remember, ctx.Request.UserAgent(), ctx.ClientIP())
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to create session for user")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	loggingMap["session_id"] = session.ID
 
-	token, err := h.service.GenerateToken(user.ID, req.RememberMe)
+	token, err := h.service.GenerateToken(userId, session.ID)
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("jwt token generation failed for user")
@@ -75,9 +223,9 @@ func (h *loginHandler) login(ctx *gin.Context) {
 		return
 	}
 
-	go h.service.notifService.Authentication(user.ID.String())
+	go h.service.notifService.Authentication(userId.String())
 
 	loggingMap.SetMessage("user successfully logged in")
 	loggingMap.Info()
-	ctx.JSON(http.StatusOK, LoginResponse{Token: token})
+	ctx.JSON(http.StatusOK, LoginResponse{Token: token, RefreshToken: refreshToken})
 }
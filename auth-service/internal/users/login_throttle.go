@@ -0,0 +1,138 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LoginLimits are the failed login thresholds, the lockout duration is in minutes.
+// Failures are counted within the lockout duration since the last one.
+type LoginLimits struct {
+	MaxFailures     int
+	IpMaxFailures   int
+	LockoutDuration int
+}
+
+// loginFailureKeys returns the keys failures are counted by. Unknown logins are counted too,
+// so probing of nonexistent accounts is slowed down as well.
+func loginFailureKeys(user *User, login, ip string) (string, string) {
+	accountKey := "login:" + strings.ToLower(login)
+	if user != nil {
+		accountKey = "user:" + user.ID.String()
+	}
+	return accountKey, "ip:" + ip
+}
+
+// loginBackoff doubles the wait after every failure and stops at the lockout duration
+func loginBackoff(failures int, lockout time.Duration) time.Duration {
+	if failures > 30 {
+		return lockout
+	}
+	backoff := time.Second << (failures - 1)
+	if backoff > lockout {
+		return lockout
+	}
+	return backoff
+}
+
+// LoginBlockedUntil returns the time the next login attempt of the account or from the ip is allowed at,
+// nil if it is allowed now
+func (s *Service) LoginBlockedUntil(ctx context.Context, user *User, login, ip string) (*time.Time, error) {
+	accountKey, ipKey := loginFailureKeys(user, login, ip)
+	blockedUntil, err := s.repository.LoginBlockedUntil(ctx, []string{accountKey, ipKey}, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login block: %v", err)
+	}
+	return blockedUntil, nil
+}
+
+// RegisterLoginFailure counts the failed attempt and blocks the next attempts for the backoff time.
+// The account is locked for the lockout duration when the failures reach the limit, its owner is notified then.
+func (s *Service) RegisterLoginFailure(ctx context.Context, user *User, login, ip string) error {
+	limits := s.GetLoginLimits()
+	lockout := time.Minute * time.Duration(limits.LockoutDuration)
+	timeNow := time.Now().UTC()
+	accountKey, ipKey := loginFailureKeys(user, login, ip)
+
+	if err := s.repository.DeleteStaleLoginFailures(ctx, timeNow.Add(-lockout)); err != nil {
+		return fmt.Errorf("failed to delete stale login failures: %v", err)
+	}
+
+	failures, err := s.repository.RegisterLoginFailure(ctx, accountKey, timeNow.Add(-lockout), timeNow)
+	if err != nil {
+		return fmt.Errorf("failed to register account login failure: %v", err)
+	}
+	blockedUntil := timeNow.Add(loginBackoff(failures, lockout))
+	locked := limits.MaxFailures > 0 && failures >= limits.MaxFailures
+	if locked {
+		blockedUntil = timeNow.Add(lockout)
+	}
+	if err = s.repository.SetLoginBlockedUntil(ctx, accountKey, blockedUntil); err != nil {
+		return fmt.Errorf("failed to block account login: %v", err)
+	}
+	// the owner is notified once, the next attempts are rejected until the lockout is over
+	if locked && failures == limits.MaxFailures && user != nil {
+		s.historyLog(user.ID, "account locked after failed logins", map[string]any{"ip": ip, "locked_until": blockedUntil})
+		go s.notifService.AccountLocked(user.ID.String(), blockedUntil, ip)
+		go s.emailService.SendAccountLockedEmail(user.Login, user.Email, blockedUntil, ip)
+	}
+
+	failures, err = s.repository.RegisterLoginFailure(ctx, ipKey, timeNow.Add(-lockout), timeNow)
+	if err != nil {
+		return fmt.Errorf("failed to register ip login failure: %v", err)
+	}
+	blockedUntil = timeNow.Add(loginBackoff(failures, lockout))
+	if limits.IpMaxFailures > 0 && failures >= limits.IpMaxFailures {
+		blockedUntil = timeNow.Add(lockout)
+	}
+	if err = s.repository.SetLoginBlockedUntil(ctx, ipKey, blockedUntil); err != nil {
+		return fmt.Errorf("failed to block ip login: %v", err)
+	}
+	return nil
+}
+
+// ResetLoginFailures forgets the failures of the account after the successful login,
+// the failures of the ip are kept so one known password doesn't unblock stuffing from it
+func (s *Service) ResetLoginFailures(ctx context.Context, user *User) error {
+	accountKey, _ := loginFailureKeys(user, user.Login, "")
+	return s.repository.DeleteLoginFailures(ctx, accountKey)
+}
+
+func (r *Repository) LoginBlockedUntil(ctx context.Context, keys []string, timeNow time.Time) (*time.Time, error) {
+	query := `select max(blocked_until) from login_failures where key = any($1) and blocked_until > $2`
+	var blockedUntil *time.Time
+	err := r.db.QueryRow(ctx, query, keys, timeNow).Scan(&blockedUntil)
+	return blockedUntil, err
+}
+
+// RegisterLoginFailure increments the failures of the key and returns them,
+// the count starts over if the last failure was before windowStart
+func (r *Repository) RegisterLoginFailure(ctx context.Context, key string, windowStart, timeNow time.Time) (int, error) {
+	query := `insert into login_failures (key, failures, blocked_until, last_failure)
+	values ($1, 1, null, $2)
+	on conflict (key) do update set
+		failures = case when login_failures.last_failure < $3 then 1 else login_failures.failures + 1 end,
+		last_failure = excluded.last_failure
+	returning failures`
+	var failures int
+	err := r.db.QueryRow(ctx, query, key, timeNow, windowStart).Scan(&failures)
+	return failures, err
+}
+
+func (r *Repository) SetLoginBlockedUntil(ctx context.Context, key string, blockedUntil time.Time) error {
+	_, err := r.db.Exec(ctx, `update login_failures set blocked_until = $2 where key = $1`, key, blockedUntil)
+	return err
+}
+
+func (r *Repository) DeleteLoginFailures(ctx context.Context, key string) error {
+	_, err := r.db.Exec(ctx, `delete from login_failures where key = $1`, key)
+	return err
+}
+
+func (r *Repository) DeleteStaleLoginFailures(ctx context.Context, before time.Time) error {
+	query := `delete from login_failures where last_failure < $1 and (blocked_until is null or blocked_until < $1)`
+	_, err := r.db.Exec(ctx, query, before)
+	return err
+}
@@ -20,3 +20,30 @@ type User struct {
 	BannedUntil      *time.Time
 	BannedReason     *string
 }
+
+type Session struct {
+	ID               uuid.UUID
+	UserId           uuid.UUID
+	RefreshTokenHash string
+	RememberMe       bool
+	UserAgent        string
+	IP               string
+	LastSeen         time.Time
+	ExpiresAt        time.Time
+	RevokedAt        *time.Time
+	Created          time.Time
+}
+
+type AccessClaims struct {
+	UserId    uuid.UUID
+	SessionId uuid.UUID
+}
+
+type TwoFactor struct {
+	UserId       uuid.UUID
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+	Created      time.Time
+	Updated      time.Time
+}
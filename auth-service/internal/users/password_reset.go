@@ -0,0 +1,178 @@
+package users
+
+import (
+	"auth-service/pkg/cryptservice"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+const passwordResetRateWindow = 1 * time.Hour
+
+// ResetLimits are the numbers of password resets allowed for the email and from the ip within the rate window
+type ResetLimits struct {
+	EmailLimit int
+	IpLimit    int
+}
+
+// RequestPasswordReset sends the one-time reset link to the email if there is an active user with it.
+// Returns false if too many resets were requested for the email or from the ip within the rate window.
+func (s *Service) RequestPasswordReset(ctx context.Context, email, ip string) (bool, error) {
+	timeNow := time.Now().UTC()
+	since := timeNow.Add(-passwordResetRateWindow)
+	if err := s.repository.DeletePasswordResetRequestsBefore(ctx, since); err != nil {
+		return false, fmt.Errorf("failed to delete old password reset requests: %v", err)
+	}
+	byEmail, byIp, err := s.repository.CountPasswordResetRequests(ctx, email, ip, since)
+	if err != nil {
+		return false, fmt.Errorf("failed to count password reset requests: %v", err)
+	}
+	limits := s.GetResetLimits()
+	if byEmail >= limits.EmailLimit || byIp >= limits.IpLimit {
+		return false, nil
+	}
+	if err = s.repository.CreatePasswordResetRequest(ctx, email, ip, timeNow); err != nil {
+		return false, fmt.Errorf("failed to save password reset request: %v", err)
+	}
+
+	user, err := s.repository.ByEmail(ctx, email)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user by email: %v", err)
+	}
+	// the response doesn't tell whether the email is registered
+	if user == nil {
+		return true, nil
+	}
+
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return false, fmt.Errorf("failed to generate password reset token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	expiresAt := timeNow.Add(time.Minute * time.Duration(s.GetResetLifetime()))
+	if err = s.repository.CreatePasswordResetToken(ctx, hashToken(token), user.ID, expiresAt, timeNow); err != nil {
+		return false, fmt.Errorf("failed to save password reset token: %v", err)
+	}
+
+	go s.emailService.SendPasswordResetEmail(user.Login, user.Email, token)
+	return true, nil
+}
+
+// ResetPassword sets the new password of the user by the reset token and revokes all user sessions.
+// Returns nil if the token is unknown, expired or already used.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) (*uuid.UUID, error) {
+	hashedPassword, err := cryptservice.CryptValue(password)
+	if err != nil {
+		return nil, err
+	}
+	userId, err := s.repository.ResetPassword(ctx, hashToken(token), hashedPassword, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset password: %v", err)
+	}
+	return userId, nil
+}
+
+func (r *Repository) ByEmail(ctx context.Context, email string) (*User, error) {
+	query := `select
+			id, login, email, role, deleted, enabled, banned_until, banned_reason, hashed_password
+	from users
+	where lower(email) = lower($1) and deleted is false and enabled is true`
+	var user User
+	err := r.db.QueryRow(ctx, query, email).Scan(
+		&user.ID,
+		&user.Login,
+		&user.Email,
+		&user.Role,
+		&user.Deleted,
+		&user.Enabled,
+		&user.BannedUntil,
+		&user.BannedReason,
+		&user.HashedPassword,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *Repository) CountPasswordResetRequests(ctx context.Context, email, ip string, since time.Time) (int, int, error) {
+	query := `select count(*) filter (where lower(email) = lower($1)), count(*) filter (where ip = $2)
+			from password_reset_requests
+			where created > $3`
+	var byEmail, byIp int
+	err := r.db.QueryRow(ctx, query, email, ip, since).Scan(&byEmail, &byIp)
+	return byEmail, byIp, err
+}
+
+func (r *Repository) CreatePasswordResetRequest(ctx context.Context, email, ip string, timeNow time.Time) error {
+	query := `insert into password_reset_requests (id, email, ip, created) values ($1, $2, $3, $4)`
+	_, err := r.db.Exec(ctx, query, uuid.New(), email, ip, timeNow)
+	return err
+}
+
+func (r *Repository) DeletePasswordResetRequestsBefore(ctx context.Context, before time.Time) error {
+	_, err := r.db.Exec(ctx, `delete from password_reset_requests where created < $1`, before)
+	return err
+}
+
+// CreatePasswordResetToken saves the new token of the user and drops the unused ones,
+// so only the link from the latest email works
+func (r *Repository) CreatePasswordResetToken(ctx context.Context, tokenHash string, userId uuid.UUID, expiresAt, timeNow time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `delete from password_reset_tokens where user_id = $1 and used_at is null`, userId)
+	if err != nil {
+		return err
+	}
+	query := `insert into password_reset_tokens (token_hash, user_id, expires_at, used_at, created)
+			values ($1, $2, $3, null, $4)`
+	_, err = tx.Exec(ctx, query, tokenHash, userId, expiresAt, timeNow)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ResetPassword uses up the token, updates the password of its user and revokes the user sessions at once
+func (r *Repository) ResetPassword(ctx context.Context, tokenHash, hashedPassword string, timeNow time.Time) (*uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userId uuid.UUID
+	err = tx.QueryRow(ctx, `update password_reset_tokens set used_at = $2
+			where token_hash = $1 and used_at is null and expires_at > $2
+			returning user_id`, tokenHash, timeNow).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `update users set hashed_password = $2, updated = $3 where id = $1`,
+		userId, hashedPassword, timeNow)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `update auth_sessions set revoked_at = $2 where user_id = $1 and revoked_at is null`,
+		userId, timeNow)
+	if err != nil {
+		return nil, err
+	}
+	return &userId, tx.Commit(ctx)
+}
@@ -0,0 +1,98 @@
+package users
+
+import (
+	serverlogging "auth-service/pkg/serverlogging/gin"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+type passwordResetHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterPasswordResetHandler(api *gin.RouterGroup, service *Service) {
+	h := &passwordResetHandler{
+		service:  service,
+		validate: NewUsersValidator(),
+	}
+
+	api.POST("", h.requestReset)
+	api.POST("/confirm", h.confirmReset)
+}
+
+func (h *passwordResetHandler) requestReset(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req PasswordResetRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad password reset request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad password reset request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["request_email"] = req.Email
+
+	allowed, err := h.service.RequestPasswordReset(ctx, req.Email, ctx.ClientIP())
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to request password reset")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !allowed {
+		loggingMap.SetMessage("too many password reset requests")
+		loggingMap.Info()
+		ctx.JSON(http.StatusTooManyRequests, nil)
+		return
+	}
+
+	loggingMap.SetMessage("password reset requested")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, nil)
+}
+
+func (h *passwordResetHandler) confirmReset(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req PasswordResetConfirmRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad password reset confirm request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad password reset confirm request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	userId, err := h.service.ResetPassword(ctx, req.Token, req.Password)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reset password")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if userId == nil {
+		loggingMap.SetMessage("password reset token is invalid, expired or already used")
+		loggingMap.Info()
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	loggingMap.SetUserId(userId)
+
+	loggingMap.SetMessage("password reset, all user sessions revoked")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, nil)
+}
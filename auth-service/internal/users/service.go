@@ -1,6 +1,8 @@
 package users
 
 import (
+	"auth-service/internal/email"
+	"auth-service/internal/keys"
 	"auth-service/internal/notifications"
 	"auth-service/pkg/filelogger"
 	"auth-service/pkg/queuelogger"
@@ -16,22 +18,36 @@ import (
 )
 
 const (
-	JwtSecretConfigKey             = "JWT_SECRET"
-	JwtDefaultLifetimeConfigKey    = "JWT_DEFAULT_LIFETIME"
-	JwtRememberMeLifetimeConfigKey = "JWT_REMEMBER_ME_LIFETIME"
+	JwtDefaultLifetimeConfigKey      = "JWT_DEFAULT_LIFETIME"
+	JwtRememberMeLifetimeConfigKey   = "JWT_REMEMBER_ME_LIFETIME"
+	JwtAccessLifetimeConfigKey       = "JWT_ACCESS_LIFETIME"
+	PasswordResetLifetimeConfigKey   = "PASSWORD_RESET_LIFETIME"
+	PasswordResetEmailLimitConfigKey = "PASSWORD_RESET_EMAIL_LIMIT"
+	PasswordResetIpLimitConfigKey    = "PASSWORD_RESET_IP_LIMIT"
+	LoginMaxFailuresConfigKey        = "LOGIN_MAX_FAILURES"
+	LoginIpMaxFailuresConfigKey      = "LOGIN_IP_MAX_FAILURES"
+	LoginLockoutDurationConfigKey    = "LOGIN_LOCKOUT_DURATION"
 )
 
 type Service struct {
 	repository       *Repository
 	notifService     *notifications.Service
-	jwtSecret        []byte
+	emailService     *email.Service
+	queueLogger      *queuelogger.RemoteLogger
+	keysService      *keys.Service
 	defaultLifetime  int
 	rememberLifetime int
+	accessLifetime   int
+	resetLifetime    int
+	resetLimits      ResetLimits
+	loginLimits      LoginLimits
 	mu               *sync.RWMutex
 }
 
-func NewService(repository *Repository, notifService *notifications.Service,
-	jwtSecret string, defaultLifetime int, rememberLifetime int,
+func NewService(repository *Repository, notifService *notifications.Service, emailService *email.Service,
+	keysService *keys.Service, defaultLifetime int, rememberLifetime int, accessLifetime int, resetLifetime int,
+	resetLimits ResetLimits,
+	loginLimits LoginLimits,
 	cfgService *configService.ConfigServiceManager,
 	fileLogger *filelogger.FileLogger,
 	queueLogger *queuelogger.RemoteLogger) *Service {
@@ -39,18 +55,18 @@ func NewService(repository *Repository, notifService *notifications.Service,
 	service := Service{
 		repository:       repository,
 		notifService:     notifService,
-		jwtSecret:        []byte(jwtSecret),
+		emailService:     emailService,
+		queueLogger:      queueLogger,
+		keysService:      keysService,
 		defaultLifetime:  defaultLifetime,
 		rememberLifetime: rememberLifetime,
+		accessLifetime:   accessLifetime,
+		resetLifetime:    resetLifetime,
+		resetLimits:      resetLimits,
+		loginLimits:      loginLimits,
 		mu:               &sync.RWMutex{},
 	}
 
-	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
-		service.mu.Lock()
-		service.jwtSecret = []byte(ss.Value)
-		service.mu.Unlock()
-	}, JwtSecretConfigKey)
-
 	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
 		value, err := strconv.Atoi(ss.Value)
 		if err != nil {
@@ -85,13 +101,154 @@ func NewService(repository *Repository, notifService *notifications.Service,
 		service.mu.Unlock()
 	}, JwtRememberMeLifetimeConfigKey)
 
-	return &service
-}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil && value <= 0 {
+			err = fmt.Errorf("value must be positive")
+		}
+		if err != nil {
+			data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+			fileLogger.Error("failed to parse value for jwt access lifetime from config", data)
+			data["message"] = "failed to parse value for jwt access lifetime from config"
+			err1 := queueLogger.Error(nil, data)
+			if err1 != nil {
+				fileLogger.Error("failed to send log about jwt access lifetime parsing error to queue",
+					map[string]any{"error": err1.Error()})
+			}
+			return
+		}
+		service.mu.Lock()
+		service.accessLifetime = value
+		service.mu.Unlock()
+	}, JwtAccessLifetimeConfigKey)
 
-func (s *Service) GetJwtSecret() []byte {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.jwtSecret
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil && value <= 0 {
+			err = fmt.Errorf("value must be positive")
+		}
+		if err != nil {
+			data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+			fileLogger.Error("failed to parse value for password reset lifetime from config", data)
+			data["message"] = "failed to parse value for password reset lifetime from config"
+			err1 := queueLogger.Error(nil, data)
+			if err1 != nil {
+				fileLogger.Error("failed to send log about password reset lifetime parsing error to queue",
+					map[string]any{"error": err1.Error()})
+			}
+			return
+		}
+		service.mu.Lock()
+		service.resetLifetime = value
+		service.mu.Unlock()
+	}, PasswordResetLifetimeConfigKey)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil && value <= 0 {
+			err = fmt.Errorf("value must be positive")
+		}
+		if err != nil {
+			data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+			fileLogger.Error("failed to parse value for password reset email limit from config", data)
+			data["message"] = "failed to parse value for password reset email limit from config"
+			err1 := queueLogger.Error(nil, data)
+			if err1 != nil {
+				fileLogger.Error("failed to send log about password reset email limit parsing error to queue",
+					map[string]any{"error": err1.Error()})
+			}
+			return
+		}
+		service.mu.Lock()
+		service.resetLimits.EmailLimit = value
+		service.mu.Unlock()
+	}, PasswordResetEmailLimitConfigKey)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil && value <= 0 {
+			err = fmt.Errorf("value must be positive")
+		}
+		if err != nil {
+			data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+			fileLogger.Error("failed to parse value for password reset ip limit from config", data)
+			data["message"] = "failed to parse value for password reset ip limit from config"
+			err1 := queueLogger.Error(nil, data)
+			if err1 != nil {
+				fileLogger.Error("failed to send log about password reset ip limit parsing error to queue",
+					map[string]any{"error": err1.Error()})
+			}
+			return
+		}
+		service.mu.Lock()
+		service.resetLimits.IpLimit = value
+		service.mu.Unlock()
+	}, PasswordResetIpLimitConfigKey)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil && value <= 0 {
+			err = fmt.Errorf("value must be positive")
+		}
+		if err != nil {
+			data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+			fileLogger.Error("failed to parse value for login max failures from config", data)
+			data["message"] = "failed to parse value for login max failures from config"
+			err1 := queueLogger.Error(nil, data)
+			if err1 != nil {
+				fileLogger.Error("failed to send log about login max failures parsing error to queue",
+					map[string]any{"error": err1.Error()})
+			}
+			return
+		}
+		service.mu.Lock()
+		service.loginLimits.MaxFailures = value
+		service.mu.Unlock()
+	}, LoginMaxFailuresConfigKey)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil && value <= 0 {
+			err = fmt.Errorf("value must be positive")
+		}
+		if err != nil {
+			data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+			fileLogger.Error("failed to parse value for login ip max failures from config", data)
+			data["message"] = "failed to parse value for login ip max failures from config"
+			err1 := queueLogger.Error(nil, data)
+			if err1 != nil {
+				fileLogger.Error("failed to send log about login ip max failures parsing error to queue",
+					map[string]any{"error": err1.Error()})
+			}
+			return
+		}
+		service.mu.Lock()
+		service.loginLimits.IpMaxFailures = value
+		service.mu.Unlock()
+	}, LoginIpMaxFailuresConfigKey)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil && value <= 0 {
+			err = fmt.Errorf("value must be positive")
+		}
+		if err != nil {
+			data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+			fileLogger.Error("failed to parse value for login lockout duration from config", data)
+			data["message"] = "failed to parse value for login lockout duration from config"
+			err1 := queueLogger.Error(nil, data)
+			if err1 != nil {
+				fileLogger.Error("failed to send log about login lockout duration parsing error to queue",
+					map[string]any{"error": err1.Error()})
+			}
+			return
+		}
+		service.mu.Lock()
+		service.loginLimits.LockoutDuration = value
+		service.mu.Unlock()
+	}, LoginLockoutDurationConfigKey)
+
+	return &service
 }
 
 func (s *Service) GetLifetime(remember bool) int {
@@ -103,6 +260,32 @@ func (s *Service) GetLifetime(remember bool) int {
 	return s.defaultLifetime
 }
 
+// GetAccessLifetime returns the lifetime of the access token in minutes
+func (s *Service) GetAccessLifetime() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.accessLifetime
+}
+
+// GetResetLifetime returns the lifetime of the password reset link in minutes
+func (s *Service) GetResetLifetime() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.resetLifetime
+}
+
+func (s *Service) GetResetLimits() ResetLimits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.resetLimits
+}
+
+func (s *Service) GetLoginLimits() LoginLimits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loginLimits
+}
+
 func (s *Service) ByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	return s.repository.ByID(ctx, id)
 }
@@ -111,23 +294,78 @@ func (s *Service) ByValue(ctx context.Context, value string) (*User, error) {
 	return s.repository.ByValue(ctx, value)
 }
 
-func (s *Service) GenerateToken(userId uuid.UUID, remember bool) (string, error) {
-	exp := time.Now().Add(time.Hour * time.Duration(s.GetLifetime(remember))).Unix()
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userId.String(),
-		"exp":     exp,
+// historyLog records the change of the user account security to history logs
+func (s *Service) historyLog(userId uuid.UUID, message string, data map[string]any) {
+	if data == nil {
+		data = map[string]any{}
+	}
+	data["message"] = message
+	data["user_id"] = userId.String()
+	_ = s.queueLogger.Info(&userId, data)
+}
+
+// GenerateToken issues the short-lived access token of the session
+func (s *Service) GenerateToken(userId uuid.UUID, sessionId uuid.UUID) (string, error) {
+	exp := time.Now().Add(time.Minute * time.Duration(s.GetAccessLifetime())).Unix()
+	return s.signToken(jwt.MapClaims{
+		"user_id":    userId.String(),
+		"session_id": sessionId.String(),
+		"exp":        exp,
 	})
-	return t.SignedString(s.GetJwtSecret())
+}
+
+// GenerateChallengeToken issues the token of the passed password check which is exchanged
+// for the session on the second login step. It has no session so it isn't accepted as access token.
+func (s *Service) GenerateChallengeToken(userId uuid.UUID, remember bool) (string, error) {
+	exp := time.Now().Add(twoFactorChallengeLifetime).Unix()
+	return s.signToken(jwt.MapClaims{
+		"user_id":     userId.String(),
+		"remember_me": remember,
+		"purpose":     twoFactorChallengePurpose,
+		"exp":         exp,
+	})
+}
+
+// ParseChallengeToken returns the user id and the remember me flag of the login challenge
+func (s *Service) ParseChallengeToken(challengeToken string) (*uuid.UUID, bool, error) {
+	token, err := jwt.Parse(challengeToken, s.KeyFunction)
+	if err != nil {
+		return nil, false, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != twoFactorChallengePurpose {
+		return nil, false, fmt.Errorf("invalid challenge token")
+	}
+	userIdClaim, _ := claims["user_id"].(string)
+	userId, err := uuid.Parse(userIdClaim)
+	if err != nil {
+		return nil, false, err
+	}
+	remember, _ := claims["remember_me"].(bool)
+	return &userId, remember, nil
+}
+
+// signToken signs the claims with the active key, its id is set to the header so the key
+// is found in the published JWKS on verification
+func (s *Service) signToken(claims jwt.MapClaims) (string, error) {
+	key, err := s.keysService.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	t.Header["kid"] = key.ID
+	return t.SignedString(key.PrivateKey)
 }
 
 func (s *Service) KeyFunction(token *jwt.Token) (interface{}, error) {
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+	if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
 		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 	}
-	return s.GetJwtSecret(), nil
+	kid, _ := token.Header["kid"].(string)
+	return s.keysService.PublicKey(kid)
 }
 
-func (s *Service) ParseToken(authorizationHeader string) (*uuid.UUID, error) {
+func (s *Service) ParseToken(authorizationHeader string) (*AccessClaims, error) {
 	if authorizationHeader == "" {
 		return nil, fmt.Errorf("authorization header is empty")
 	}
@@ -145,11 +383,18 @@ func (s *Service) ParseToken(authorizationHeader string) (*uuid.UUID, error) {
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userId, err := uuid.Parse(claims["user_id"].(string))
+		userIdClaim, _ := claims["user_id"].(string)
+		userId, err := uuid.Parse(userIdClaim)
 		if err != nil {
 			return nil, err
 		}
-		return &userId, nil
+		// tokens issued before sessions were introduced can't be revoked, so they aren't accepted
+		sessionIdClaim, _ := claims["session_id"].(string)
+		sessionId, err := uuid.Parse(sessionIdClaim)
+		if err != nil {
+			return nil, fmt.Errorf("token has no valid session: %v", err)
+		}
+		return &AccessClaims{UserId: userId, SessionId: sessionId}, nil
 	} else {
 		return nil, err
 	}
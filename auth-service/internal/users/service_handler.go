@@ -23,7 +23,7 @@ func (h *serviceHandler) authorize(ctx *gin.Context) {
 
 	loggingMap["original_request_url"] = ctx.GetHeader("USER-REQUEST-URL")
 
-	userId, err := h.service.ParseToken(ctx.GetHeader(requestuser.AuthorizationHeaderKey))
+	claims, err := h.service.Authenticate(ctx, ctx.GetHeader(requestuser.AuthorizationHeaderKey))
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("invalid jwt token")
@@ -32,9 +32,9 @@ func (h *serviceHandler) authorize(ctx *gin.Context) {
 		ctx.Status(http.StatusUnauthorized)
 		return
 	}
-	loggingMap.SetUserId(userId)
+	loggingMap.SetUserId(&claims.UserId)
 
-	user, err := h.service.ByID(ctx, *userId)
+	user, err := h.service.ByID(ctx, claims.UserId)
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("fail to get user by id")
@@ -0,0 +1,288 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"strings"
+	"time"
+)
+
+// sessionTouchInterval limits how often the last seen time of the session is written on authorization
+const sessionTouchInterval = 1 * time.Minute
+
+// newRefreshToken returns the refresh token of the session and its hash, the token is prefixed with
+// the session id so the session is found without scanning hashes
+func newRefreshToken(sessionId uuid.UUID) (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+	token := sessionId.String() + "." + base64.RawURLEncoding.EncodeToString(secret)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateSession starts the session of the logged in user and returns it with its refresh token.
+// The session lives as long as the token used to live before and is prolonged on every refresh.
+func (s *Service) CreateSession(ctx context.Context, userId uuid.UUID, remember bool, userAgent, ip string) (*Session, string, error) {
+	timeNow := time.Now().UTC()
+	session := Session{
+		ID:         uuid.New(),
+		UserId:     userId,
+		RememberMe: remember,
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastSeen:   timeNow,
+		ExpiresAt:  timeNow.Add(time.Hour * time.Duration(s.GetLifetime(remember))),
+		Created:    timeNow,
+	}
+	refreshToken, hash, err := newRefreshToken(session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	session.RefreshTokenHash = hash
+
+	if err = s.repository.DeleteExpiredSessions(ctx, userId, timeNow); err != nil {
+		return nil, "", fmt.Errorf("failed to delete expired sessions: %v", err)
+	}
+	if err = s.repository.CreateSession(ctx, &session); err != nil {
+		return nil, "", fmt.Errorf("failed to create session: %v", err)
+	}
+	return &session, refreshToken, nil
+}
+
+// RefreshSession rotates the refresh token of the session. Returns nil if the token is unknown or expired.
+// The token that was already rotated means it is stolen or leaked, the whole session is revoked then.
+// A token with the session id and an unknown secret is only rejected, the session id isn't a secret.
+func (s *Service) RefreshSession(ctx context.Context, refreshToken, userAgent, ip string) (*Session, string, error) {
+	sessionIdPart, _, found := strings.Cut(refreshToken, ".")
+	if !found {
+		return nil, "", nil
+	}
+	sessionId, err := uuid.Parse(sessionIdPart)
+	if err != nil {
+		return nil, "", nil
+	}
+	session, err := s.repository.SessionById(ctx, sessionId)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get session by id: %v", err)
+	}
+	timeNow := time.Now().UTC()
+	if session == nil || session.RevokedAt != nil || !session.ExpiresAt.After(timeNow) {
+		return nil, "", nil
+	}
+
+	newToken, newHash, err := newRefreshToken(session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	session.UserAgent = userAgent
+	session.IP = ip
+	session.LastSeen = timeNow
+	session.ExpiresAt = timeNow.Add(time.Hour * time.Duration(s.GetLifetime(session.RememberMe)))
+
+	tokenHash := hashToken(refreshToken)
+	rotated, err := s.repository.RotateSessionRefreshToken(ctx, session, tokenHash, newHash, timeNow)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to rotate refresh token: %v", err)
+	}
+	if !rotated {
+		reused, err := s.repository.IsRotatedRefreshToken(ctx, session.ID, tokenHash)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to check rotated refresh token: %v", err)
+		}
+		if !reused {
+			return nil, "", nil
+		}
+		if _, err = s.repository.RevokeSession(ctx, session.ID, session.UserId, timeNow); err != nil {
+			return nil, "", fmt.Errorf("failed to revoke session on refresh token reuse: %v", err)
+		}
+		return nil, "", nil
+	}
+	session.RefreshTokenHash = newHash
+	return session, newToken, nil
+}
+
+// Authenticate parses the access token and checks that its session is neither revoked nor expired
+func (s *Service) Authenticate(ctx context.Context, authorizationHeader string) (*AccessClaims, error) {
+	claims, err := s.ParseToken(authorizationHeader)
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.repository.SessionById(ctx, claims.SessionId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session by id: %v", err)
+	}
+	timeNow := time.Now().UTC()
+	if session == nil || session.UserId != claims.UserId || session.RevokedAt != nil || !session.ExpiresAt.After(timeNow) {
+		return nil, fmt.Errorf("session of the token is revoked or expired")
+	}
+	if timeNow.Sub(session.LastSeen) > sessionTouchInterval {
+		if err = s.repository.TouchSession(ctx, session.ID, timeNow); err != nil {
+			return nil, fmt.Errorf("failed to update session last seen: %v", err)
+		}
+	}
+	return claims, nil
+}
+
+func (r *Repository) SessionById(ctx context.Context, id uuid.UUID) (*Session, error) {
+	query := `select id, user_id, refresh_token_hash, remember_me, user_agent, ip, last_seen, expires_at, revoked_at, created
+			from auth_sessions
+			where id = $1`
+
+	var session Session
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&session.ID,
+		&session.UserId,
+		&session.RefreshTokenHash,
+		&session.RememberMe,
+		&session.UserAgent,
+		&session.IP,
+		&session.LastSeen,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.Created,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *Repository) ActiveSessionsByUserId(ctx context.Context, userId uuid.UUID, timeNow time.Time) ([]Session, error) {
+	query := `select id, user_id, refresh_token_hash, remember_me, user_agent, ip, last_seen, expires_at, revoked_at, created
+			from auth_sessions
+			where user_id = $1 and revoked_at is null and expires_at > $2
+			order by last_seen desc`
+
+	rows, err := r.db.Query(ctx, query, userId, timeNow)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]Session, 0)
+	var session Session
+	for rows.Next() {
+		err = rows.Scan(
+			&session.ID,
+			&session.UserId,
+			&session.RefreshTokenHash,
+			&session.RememberMe,
+			&session.UserAgent,
+			&session.IP,
+			&session.LastSeen,
+			&session.ExpiresAt,
+			&session.RevokedAt,
+			&session.Created,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, session)
+	}
+	return resultArray, nil
+}
+
+func (r *Repository) CreateSession(ctx context.Context, session *Session) error {
+	query := `insert into auth_sessions
+	(id, user_id, refresh_token_hash, remember_me, user_agent, ip, last_seen, expires_at, revoked_at, created)
+	values
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := r.db.Exec(ctx, query,
+		session.ID,
+		session.UserId,
+		session.RefreshTokenHash,
+		session.RememberMe,
+		session.UserAgent,
+		session.IP,
+		session.LastSeen,
+		session.ExpiresAt,
+		session.RevokedAt,
+		session.Created,
+	)
+	return err
+}
+
+// RotateSessionRefreshToken replaces the refresh token hash only if the session still has the presented one,
+// so the same refresh token can't be used twice. The replaced hash is kept to detect its reuse.
+func (r *Repository) RotateSessionRefreshToken(ctx context.Context, session *Session, oldHash, newHash string, timeNow time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `update auth_sessions
+			set refresh_token_hash = $3, user_agent = $4, ip = $5, last_seen = $6, expires_at = $7
+			where id = $1 and refresh_token_hash = $2 and revoked_at is null`
+	tag, err := tx.Exec(ctx, query,
+		session.ID,
+		oldHash,
+		newHash,
+		session.UserAgent,
+		session.IP,
+		session.LastSeen,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	_, err = tx.Exec(ctx, `insert into auth_session_rotated_tokens (token_hash, session_id, rotated_at)
+			values ($1, $2, $3)`, oldHash, session.ID, timeNow)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// IsRotatedRefreshToken reports whether the refresh token was issued for the session and rotated already
+func (r *Repository) IsRotatedRefreshToken(ctx context.Context, sessionId uuid.UUID, tokenHash string) (bool, error) {
+	query := `select exists(select 1 from auth_session_rotated_tokens where token_hash = $1 and session_id = $2)`
+	var rotated bool
+	err := r.db.QueryRow(ctx, query, tokenHash, sessionId).Scan(&rotated)
+	return rotated, err
+}
+
+func (r *Repository) TouchSession(ctx context.Context, id uuid.UUID, timeNow time.Time) error {
+	_, err := r.db.Exec(ctx, `update auth_sessions set last_seen = $2 where id = $1`, id, timeNow)
+	return err
+}
+
+// RevokeSession revokes the active session of the user, returns false if there is no such session
+func (r *Repository) RevokeSession(ctx context.Context, id, userId uuid.UUID, timeNow time.Time) (bool, error) {
+	query := `update auth_sessions set revoked_at = $3 where id = $1 and user_id = $2 and revoked_at is null`
+	tag, err := r.db.Exec(ctx, query, id, userId, timeNow)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repository) RevokeUserSessions(ctx context.Context, userId uuid.UUID, timeNow time.Time) error {
+	query := `update auth_sessions set revoked_at = $2 where user_id = $1 and revoked_at is null`
+	_, err := r.db.Exec(ctx, query, userId, timeNow)
+	return err
+}
+
+func (r *Repository) DeleteExpiredSessions(ctx context.Context, userId uuid.UUID, timeNow time.Time) error {
+	_, err := r.db.Exec(ctx, `delete from auth_sessions where user_id = $1 and expires_at < $2`, userId, timeNow)
+	return err
+}
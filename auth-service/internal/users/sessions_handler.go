@@ -0,0 +1,191 @@
+package users
+
+import (
+	requestuser "auth-service/pkg/hidepost-requestuser"
+	serverlogging "auth-service/pkg/serverlogging/gin"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+type sessionsHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterSessionsHandler(api *gin.RouterGroup, service *Service) {
+	h := &sessionsHandler{
+		service:  service,
+		validate: NewUsersValidator(),
+	}
+
+	api.POST("/refresh", h.refresh)
+	api.GET("", h.getSessions)
+	api.DELETE("", h.revokeAllSessions)
+	api.DELETE("/id/:id", h.revokeSession)
+}
+
+func (h *sessionsHandler) refresh(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req RefreshRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad refresh request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad refresh request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	session, refreshToken, err := h.service.RefreshSession(ctx, req.RefreshToken, ctx.Request.UserAgent(), ctx.ClientIP())
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to refresh session")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if session == nil {
+		loggingMap.SetMessage("refresh token is invalid, expired or reused")
+		loggingMap.Info()
+		ctx.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+	loggingMap.SetUserId(&session.UserId)
+	loggingMap["session_id"] = session.ID
+
+	user, err := h.service.ByID(ctx, session.UserId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get user by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if user == nil {
+		loggingMap.SetMessage("user of the session not found")
+		loggingMap.Info()
+		ctx.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+
+	token, err := h.service.GenerateToken(session.UserId, session.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("jwt token generation failed for user")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("session refreshed")
+	loggingMap.Debug()
+	ctx.JSON(http.StatusOK, LoginResponse{Token: token, RefreshToken: refreshToken})
+}
+
+func (h *sessionsHandler) getSessions(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	claims, err := h.service.Authenticate(ctx, ctx.GetHeader(requestuser.AuthorizationHeaderKey))
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("invalid jwt token")
+		loggingMap.Debug()
+		ctx.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+	loggingMap.SetUserId(&claims.UserId)
+
+	sessions, err := h.service.repository.ActiveSessionsByUserId(ctx, claims.UserId, time.Now().UTC())
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get user sessions")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:        session.ID,
+			Device:    session.UserAgent,
+			IP:        session.IP,
+			LastSeen:  session.LastSeen,
+			Created:   session.Created,
+			IsCurrent: session.ID == claims.SessionId,
+		})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h *sessionsHandler) revokeSession(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	claims, err := h.service.Authenticate(ctx, ctx.GetHeader(requestuser.AuthorizationHeaderKey))
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("invalid jwt token")
+		loggingMap.Debug()
+		ctx.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+	loggingMap.SetUserId(&claims.UserId)
+
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["session_id"] = id
+
+	revoked, err := h.service.repository.RevokeSession(ctx, id, claims.UserId, time.Now().UTC())
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to revoke session")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !revoked {
+		loggingMap.SetMessage("active session by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	loggingMap.SetMessage("session revoked")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, nil)
+}
+
+func (h *sessionsHandler) revokeAllSessions(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	claims, err := h.service.Authenticate(ctx, ctx.GetHeader(requestuser.AuthorizationHeaderKey))
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("invalid jwt token")
+		loggingMap.Debug()
+		ctx.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+	loggingMap.SetUserId(&claims.UserId)
+
+	err = h.service.repository.RevokeUserSessions(ctx, claims.UserId, time.Now().UTC())
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to revoke user sessions")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("all user sessions revoked")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, nil)
+}
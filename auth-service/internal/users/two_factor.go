@@ -0,0 +1,300 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"strings"
+	"time"
+)
+
+const (
+	twoFactorChallengePurpose  = "two_factor_challenge"
+	twoFactorChallengeLifetime = 5 * time.Minute
+	recoveryCodesCount         = 10
+)
+
+// IsEnabled reports whether the enrollment of the two-factor authentication is confirmed
+func (t *TwoFactor) IsEnabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// newRecoveryCodes returns the codes shown to the user once and their hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		value := make([]byte, 5)
+		if _, err := rand.Read(value); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %v", err)
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(value))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// EnrollTwoFactor generates the new secret of the user, it is enabled after the first code is confirmed.
+// Returns nil if the two-factor authentication is already enabled.
+func (s *Service) EnrollTwoFactor(ctx context.Context, userId uuid.UUID) (*TwoFactor, error) {
+	current, err := s.repository.TwoFactorByUserId(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two factor by user id: %v", err)
+	}
+	if current.IsEnabled() {
+		return nil, nil
+	}
+	secret, err := newTotpSecret()
+	if err != nil {
+		return nil, err
+	}
+	timeNow := time.Now().UTC()
+	twoFactor := TwoFactor{
+		UserId:       userId,
+		Secret:       secret,
+		EnabledAt:    nil,
+		LastUsedStep: 0,
+		Created:      timeNow,
+		Updated:      timeNow,
+	}
+	if err = s.repository.UpsertPendingTwoFactor(ctx, &twoFactor); err != nil {
+		return nil, fmt.Errorf("failed to save two factor: %v", err)
+	}
+	s.historyLog(userId, "two factor enrollment started", nil)
+	return &twoFactor, nil
+}
+
+// ConfirmTwoFactor enables the pending two-factor authentication by the code from the authenticator app
+// and returns the recovery codes. Returns nil if there is no pending enrollment or the code is wrong.
+func (s *Service) ConfirmTwoFactor(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	twoFactor, err := s.repository.TwoFactorByUserId(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two factor by user id: %v", err)
+	}
+	if twoFactor == nil || twoFactor.IsEnabled() {
+		return nil, nil
+	}
+	timeNow := time.Now().UTC()
+	step := totpMatchedStep(twoFactor.Secret, code, twoFactor.LastUsedStep, timeNow)
+	if step == 0 {
+		return nil, nil
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.repository.EnableTwoFactor(ctx, userId, step, hashes, timeNow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two factor: %v", err)
+	}
+	if !enabled {
+		return nil, nil
+	}
+	s.historyLog(userId, "two factor enabled", nil)
+	return codes, nil
+}
+
+// VerifySecondFactor checks the code from the authenticator app or the unused recovery code of the user.
+// Every code is accepted only once.
+func (s *Service) VerifySecondFactor(ctx context.Context, userId uuid.UUID, code string) (bool, error) {
+	twoFactor, err := s.repository.TwoFactorByUserId(ctx, userId)
+	if err != nil {
+		return false, fmt.Errorf("failed to get two factor by user id: %v", err)
+	}
+	if !twoFactor.IsEnabled() {
+		return false, nil
+	}
+	timeNow := time.Now().UTC()
+	if step := totpMatchedStep(twoFactor.Secret, code, twoFactor.LastUsedStep, timeNow); step != 0 {
+		used, err := s.repository.UseTotpStep(ctx, userId, step, timeNow)
+		if err != nil {
+			return false, fmt.Errorf("failed to save used totp step: %v", err)
+		}
+		return used, nil
+	}
+
+	used, err := s.repository.UseRecoveryCode(ctx, userId, hashToken(normalizeRecoveryCode(code)), timeNow)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %v", err)
+	}
+	if used {
+		s.historyLog(userId, "two factor recovery code used", nil)
+	}
+	return used, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user with the new ones
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userId uuid.UUID) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repository.ReplaceRecoveryCodes(ctx, userId, hashes, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to replace recovery codes: %v", err)
+	}
+	s.historyLog(userId, "two factor recovery codes regenerated", nil)
+	return codes, nil
+}
+
+// DisableTwoFactor removes the secret and the recovery codes of the user,
+// adminId is set when the two-factor authentication is reset by admin
+func (s *Service) DisableTwoFactor(ctx context.Context, userId uuid.UUID, adminId *uuid.UUID) error {
+	if err := s.repository.DeleteTwoFactor(ctx, userId); err != nil {
+		return fmt.Errorf("failed to delete two factor: %v", err)
+	}
+	if adminId != nil {
+		s.historyLog(userId, "two factor reset by admin", map[string]any{"admin_id": adminId.String()})
+	} else {
+		s.historyLog(userId, "two factor disabled", nil)
+	}
+	return nil
+}
+
+func (r *Repository) TwoFactorByUserId(ctx context.Context, userId uuid.UUID) (*TwoFactor, error) {
+	query := `select user_id, secret, enabled_at, last_used_step, created, updated
+			from user_two_factor
+			where user_id = $1`
+
+	var twoFactor TwoFactor
+	err := r.db.QueryRow(ctx, query, userId).Scan(
+		&twoFactor.UserId,
+		&twoFactor.Secret,
+		&twoFactor.EnabledAt,
+		&twoFactor.LastUsedStep,
+		&twoFactor.Created,
+		&twoFactor.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func (r *Repository) CountRecoveryCodesLeft(ctx context.Context, userId uuid.UUID) (int, error) {
+	query := `select count(*) from two_factor_recovery_codes where user_id = $1 and used_at is null`
+	var count int
+	err := r.db.QueryRow(ctx, query, userId).Scan(&count)
+	return count, err
+}
+
+// UpsertPendingTwoFactor saves the secret of the enrollment, the enabled two-factor authentication is kept as is
+func (r *Repository) UpsertPendingTwoFactor(ctx context.Context, twoFactor *TwoFactor) error {
+	query := `insert into user_two_factor
+	(user_id, secret, enabled_at, last_used_step, created, updated)
+	values
+	($1, $2, $3, $4, $5, $6)
+	on conflict (user_id) do update set secret = excluded.secret, last_used_step = excluded.last_used_step,
+		updated = excluded.updated
+	where user_two_factor.enabled_at is null`
+	_, err := r.db.Exec(ctx, query,
+		twoFactor.UserId,
+		twoFactor.Secret,
+		twoFactor.EnabledAt,
+		twoFactor.LastUsedStep,
+		twoFactor.Created,
+		twoFactor.Updated,
+	)
+	return err
+}
+
+// EnableTwoFactor enables the pending two-factor authentication and saves its recovery codes,
+// returns false if it was enabled already
+func (r *Repository) EnableTwoFactor(ctx context.Context, userId uuid.UUID, step int64, codeHashes []string, timeNow time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `update user_two_factor set enabled_at = $2, last_used_step = $3, updated = $2
+			where user_id = $1 and enabled_at is null`, userId, timeNow, step)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if err = replaceRecoveryCodes(ctx, tx, userId, codeHashes, timeNow); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, codeHashes []string, timeNow time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = replaceRecoveryCodes(ctx, tx, userId, codeHashes, timeNow); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userId uuid.UUID, codeHashes []string, timeNow time.Time) error {
+	_, err := tx.Exec(ctx, `delete from two_factor_recovery_codes where user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		_, err = tx.Exec(ctx, `insert into two_factor_recovery_codes (id, user_id, code_hash, used_at, created)
+				values ($1, $2, $3, null, $4)`, uuid.New(), userId, codeHash, timeNow)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseTotpStep saves the step of the accepted code, returns false if the same or a later step was used already
+func (r *Repository) UseTotpStep(ctx context.Context, userId uuid.UUID, step int64, timeNow time.Time) (bool, error) {
+	query := `update user_two_factor set last_used_step = $2, updated = $3
+			where user_id = $1 and enabled_at is not null and last_used_step < $2`
+	tag, err := r.db.Exec(ctx, query, userId, step, timeNow)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repository) UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string, timeNow time.Time) (bool, error) {
+	query := `update two_factor_recovery_codes set used_at = $3
+			where user_id = $1 and code_hash = $2 and used_at is null`
+	tag, err := r.db.Exec(ctx, query, userId, codeHash, timeNow)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repository) DeleteTwoFactor(ctx context.Context, userId uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `delete from two_factor_recovery_codes where user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `delete from user_two_factor where user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,290 @@
+package users
+
+import (
+	requestuser "auth-service/pkg/hidepost-requestuser"
+	serverlogging "auth-service/pkg/serverlogging/gin"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+type twoFactorHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterTwoFactorHandler(api *gin.RouterGroup, service *Service) {
+	h := &twoFactorHandler{
+		service:  service,
+		validate: NewUsersValidator(),
+	}
+
+	api.GET("", h.getStatus)
+	api.POST("/enroll", h.enroll)
+	api.POST("/enroll/confirm", h.confirmEnroll)
+	api.POST("/recovery-codes", h.regenerateRecoveryCodes)
+	api.DELETE("", h.disable)
+	api.DELETE("/admin/users/id/:id", h.adminReset)
+}
+
+// authenticatedUser returns the active user of the access token, the response is written if there is none
+func (h *twoFactorHandler) authenticatedUser(ctx *gin.Context) *User {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	claims, err := h.service.Authenticate(ctx, ctx.GetHeader(requestuser.AuthorizationHeaderKey))
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("invalid jwt token")
+		loggingMap.Debug()
+		ctx.JSON(http.StatusUnauthorized, nil)
+		return nil
+	}
+	loggingMap.SetUserId(&claims.UserId)
+
+	user, err := h.service.ByID(ctx, claims.UserId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get user by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return nil
+	}
+	if user == nil {
+		loggingMap.SetMessage("user with id of successfully parsed jwt token not found")
+		loggingMap.Error()
+		ctx.JSON(http.StatusUnauthorized, nil)
+		return nil
+	}
+	return user
+}
+
+// verifiedCode decodes the code request and checks it as the second factor of the user,
+// the response is written if the code isn't accepted
+func (h *twoFactorHandler) verifiedCode(ctx *gin.Context, user *User) bool {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return false
+	}
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return false
+	}
+
+	verified, err := h.service.VerifySecondFactor(ctx, user.ID, req.Code)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to verify second factor")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return false
+	}
+	if !verified {
+		loggingMap.SetMessage("wrong second factor code")
+		loggingMap.Info()
+		ctx.JSON(http.StatusForbidden, nil)
+		return false
+	}
+	return true
+}
+
+func (h *twoFactorHandler) getStatus(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	user := h.authenticatedUser(ctx)
+	if user == nil {
+		return
+	}
+
+	twoFactor, err := h.service.repository.TwoFactorByUserId(ctx, user.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get two factor of user")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !twoFactor.IsEnabled() {
+		ctx.JSON(http.StatusOK, TwoFactorStatusResponse{Enabled: false})
+		return
+	}
+	codesLeft, err := h.service.repository.CountRecoveryCodesLeft(ctx, user.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to count recovery codes of user")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, TwoFactorStatusResponse{
+		Enabled:           true,
+		EnabledAt:         twoFactor.EnabledAt,
+		RecoveryCodesLeft: codesLeft,
+	})
+}
+
+func (h *twoFactorHandler) enroll(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	user := h.authenticatedUser(ctx)
+	if user == nil {
+		return
+	}
+
+	twoFactor, err := h.service.EnrollTwoFactor(ctx, user.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to enroll two factor")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if twoFactor == nil {
+		loggingMap.SetMessage("two factor is already enabled")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	loggingMap.SetMessage("two factor enrollment started")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, TwoFactorEnrollResponse{
+		Secret:          twoFactor.Secret,
+		ProvisioningUri: totpProvisioningUri(twoFactor.Secret, user.Login),
+	})
+}
+
+func (h *twoFactorHandler) confirmEnroll(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	user := h.authenticatedUser(ctx)
+	if user == nil {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	codes, err := h.service.ConfirmTwoFactor(ctx, user.ID, req.Code)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to confirm two factor")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if codes == nil {
+		loggingMap.SetMessage("no pending two factor enrollment or wrong code")
+		loggingMap.Info()
+		ctx.JSON(http.StatusForbidden, nil)
+		return
+	}
+
+	loggingMap.SetMessage("two factor enabled")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, TwoFactorRecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *twoFactorHandler) regenerateRecoveryCodes(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	user := h.authenticatedUser(ctx)
+	if user == nil {
+		return
+	}
+	if !h.verifiedCode(ctx, user) {
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to regenerate recovery codes")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("recovery codes regenerated")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, TwoFactorRecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *twoFactorHandler) disable(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	user := h.authenticatedUser(ctx)
+	if user == nil {
+		return
+	}
+	if !h.verifiedCode(ctx, user) {
+		return
+	}
+
+	if err := h.service.DisableTwoFactor(ctx, user.ID, nil); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to disable two factor")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("two factor disabled")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, nil)
+}
+
+// adminReset disables the two-factor authentication of the user who lost the authenticator and recovery codes.
+// Requests to the auth service aren't authorized by the gateway, so the admin role is checked by the token.
+func (h *twoFactorHandler) adminReset(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	admin := h.authenticatedUser(ctx)
+	if admin == nil {
+		return
+	}
+	if admin.Role != requestuser.UserRoleAdmin {
+		loggingMap.SetMessage("request user is not admin")
+		loggingMap["user_role"] = admin.Role
+		ctx.JSON(http.StatusForbidden, nil)
+		return
+	}
+
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["target_user_id"] = id
+
+	twoFactor, err := h.service.repository.TwoFactorByUserId(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get two factor of user")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if twoFactor == nil {
+		loggingMap.SetMessage("user has no two factor")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	if err = h.service.DisableTwoFactor(ctx, id, &admin.ID); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reset two factor")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("two factor of user reset by admin")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, nil)
+}
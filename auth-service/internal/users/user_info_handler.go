@@ -22,7 +22,7 @@ func (h *userInfoHandler) getUserInfo(ctx *gin.Context) {
 
 	loggingMap := serverlogging.GetLoggingMap(ctx)
 
-	userId, err := h.service.ParseToken(ctx.GetHeader(requestuser.AuthorizationHeaderKey))
+	claims, err := h.service.Authenticate(ctx, ctx.GetHeader(requestuser.AuthorizationHeaderKey))
 	if err != nil {
 		loggingMap["error"] = err.Error()
 		loggingMap.SetMessage("invalid jwt token")
@@ -30,9 +30,9 @@ func (h *userInfoHandler) getUserInfo(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, nil)
 		return
 	}
-	loggingMap.SetUserId(userId)
+	loggingMap.SetUserId(&claims.UserId)
 
-	user, err := h.service.ByID(ctx, *userId)
+	user, err := h.service.ByID(ctx, claims.UserId)
 	if err != nil {
 		loggingMap["error"] = err.Error()
 		loggingMap.SetMessage("fail to get user by id")
@@ -4,7 +4,12 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+func PasswordValidator(fl validator.FieldLevel) bool {
+	return len([]byte(fl.Field().String())) <= 72
+}
+
 func NewUsersValidator() *validator.Validate {
 	validate := validator.New(validator.WithRequiredStructEnabled())
+	_ = validate.RegisterValidation("password", PasswordValidator)
 	return validate
 }
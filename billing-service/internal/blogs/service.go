@@ -15,13 +15,18 @@ type Service struct {
 	BaseUrl              string
 	GrantSubscriptionUrl string
 	ConfirmDonationUrl   string
+	ConfirmGiftUrl       string
+	ConfirmTierChangeUrl string
+	signer               *requestuser.Signer
 }
 
 var GrantSubscriptionPath = "/subscriptions/grant"
 var ConfirmDonationPath = "/donations/confirm"
+var ConfirmGiftPath = "/gifts/confirm"
+var ConfirmTierChangePath = "/subscriptions/tier-changes/confirm"
 
-func NewService(serviceUrl string, cfgService *configService.ConfigServiceManager) *Service {
-	service := &Service{BaseUrl: serviceUrl}
+func NewService(serviceUrl string, signer *requestuser.Signer, cfgService *configService.ConfigServiceManager) *Service {
+	service := &Service{BaseUrl: serviceUrl, signer: signer}
 	tempStr, err := url.JoinPath(serviceUrl, GrantSubscriptionPath)
 	if err != nil {
 		panic(err)
@@ -32,26 +37,77 @@ func NewService(serviceUrl string, cfgService *configService.ConfigServiceManage
 		panic(err)
 	}
 	service.ConfirmDonationUrl = tempStr
+	tempStr, err = url.JoinPath(serviceUrl, ConfirmGiftPath)
+	if err != nil {
+		panic(err)
+	}
+	service.ConfirmGiftUrl = tempStr
+	tempStr, err = url.JoinPath(serviceUrl, ConfirmTierChangePath)
+	if err != nil {
+		panic(err)
+	}
+	service.ConfirmTierChangeUrl = tempStr
 
 	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
 		service.BaseUrl = ss.Value
 		service.GrantSubscriptionUrl, _ = url.JoinPath(ss.Value, GrantSubscriptionPath)
 		service.ConfirmDonationUrl, _ = url.JoinPath(ss.Value, ConfirmDonationPath)
+		service.ConfirmGiftUrl, _ = url.JoinPath(ss.Value, ConfirmGiftPath)
+		service.ConfirmTierChangeUrl, _ = url.JoinPath(ss.Value, ConfirmTierChangePath)
 	}, "BLOGS_SERVICE_URL")
 	return service
 }
 
 type GrantItemServiceRequest struct {
-	ItemId   uuid.UUID `json:"item_id" validate:"required"`
-	UserId   uuid.UUID `json:"user_id" validate:"required"`
-	Value    float64   `json:"value" validate:"required"`
-	Currency string    `json:"currency" validate:"required"`
+	ItemId    uuid.UUID `json:"item_id" validate:"required"`
+	UserId    uuid.UUID `json:"user_id" validate:"required"`
+	Value     float64   `json:"value" validate:"required"`
+	Currency  string    `json:"currency" validate:"required"`
+	InvoiceId int       `json:"invoice_id"`
 }
 
-func (s *Service) GrantSubscription(subscriptionId, userId uuid.UUID, value float64, currency string) error {
+// GrantSubscription returns false if the subscription is full and can't be granted
+func (s *Service) GrantSubscription(subscriptionId, userId uuid.UUID, value float64, currency string, invoiceId int) (bool, error) {
 
 	requestBody := GrantItemServiceRequest{
-		ItemId:   subscriptionId,
+		ItemId:    subscriptionId,
+		UserId:    userId,
+		Value:     value,
+		Currency:  currency,
+		InvoiceId: invoiceId,
+	}
+
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		return false, fmt.Errorf("fail to marshal request body cause %v", err)
+	}
+
+	req, err := http.NewRequest("POST", s.GrantSubscriptionUrl, strings.NewReader(string(body)))
+	if err != nil {
+		return false, fmt.Errorf("fail to create request cause %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	return true, nil
+}
+
+func (s *Service) ConfirmDonation(donationId, userId uuid.UUID, value float64, currency string) error {
+	requestBody := GrantItemServiceRequest{
+		ItemId:   donationId,
 		UserId:   userId,
 		Value:    value,
 		Currency: currency,
@@ -62,12 +118,12 @@ func (s *Service) GrantSubscription(subscriptionId, userId uuid.UUID, value floa
 		return fmt.Errorf("fail to marshal request body cause %v", err)
 	}
 
-	req, err := http.NewRequest("POST", s.GrantSubscriptionUrl, strings.NewReader(string(body)))
+	req, err := http.NewRequest("POST", s.ConfirmDonationUrl, strings.NewReader(string(body)))
 	if err != nil {
 		return fmt.Errorf("fail to create request cause %v", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set(requestuser.UserRoleHeaderKey, requestuser.UserRoleService)
+	s.signer.SignServiceRequest(req)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -82,9 +138,9 @@ func (s *Service) GrantSubscription(subscriptionId, userId uuid.UUID, value floa
 	return nil
 }
 
-func (s *Service) ConfirmDonation(donationId, userId uuid.UUID, value float64, currency string) error {
+func (s *Service) ConfirmGift(giftId, userId uuid.UUID, value float64, currency string) error {
 	requestBody := GrantItemServiceRequest{
-		ItemId:   donationId,
+		ItemId:   giftId,
 		UserId:   userId,
 		Value:    value,
 		Currency: currency,
@@ -95,12 +151,161 @@ func (s *Service) ConfirmDonation(donationId, userId uuid.UUID, value float64, c
 		return fmt.Errorf("fail to marshal request body cause %v", err)
 	}
 
-	req, err := http.NewRequest("POST", s.ConfirmDonationUrl, strings.NewReader(string(body)))
+	req, err := http.NewRequest("POST", s.ConfirmGiftUrl, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("fail to create request cause %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *Service) ConfirmTierChange(changeId, userId uuid.UUID, value float64, currency string) error {
+	requestBody := GrantItemServiceRequest{
+		ItemId:   changeId,
+		UserId:   userId,
+		Value:    value,
+		Currency: currency,
+	}
+
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("fail to marshal request body cause %v", err)
+	}
+
+	req, err := http.NewRequest("POST", s.ConfirmTierChangeUrl, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("fail to create request cause %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+var PaymentItemsInfoPath = "/payment-items/info"
+
+type PaymentItemRef struct {
+	ItemId   uuid.UUID `json:"item_id"`
+	ItemType string    `json:"item_type"`
+}
+
+type PaymentItemsInfoServiceRequest struct {
+	Items []PaymentItemRef `json:"items"`
+}
+
+type PaymentItemInfo struct {
+	ItemId    uuid.UUID  `json:"item_id"`
+	ItemType  string     `json:"item_type"`
+	Title     string     `json:"title"`
+	BlogId    *uuid.UUID `json:"blog_id"`
+	BlogTitle string     `json:"blog_title"`
+}
+
+// PaymentItemsInfo returns titles of paid items in the same order as requested
+func (s *Service) PaymentItemsInfo(items []PaymentItemRef) ([]PaymentItemInfo, error) {
+	if len(items) == 0 {
+		return make([]PaymentItemInfo, 0), nil
+	}
+
+	body, err := json.Marshal(PaymentItemsInfoServiceRequest{Items: items})
+	if err != nil {
+		return nil, fmt.Errorf("fail to marshal request body cause %v", err)
+	}
+
+	reqUrl, err := url.JoinPath(s.BaseUrl, PaymentItemsInfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to join url cause %v", err)
+	}
+
+	req, err := http.NewRequest("POST", reqUrl, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request cause %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	var response []PaymentItemInfo
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("fail to unmarshal response body cause %v", err)
+	}
+	if len(response) != len(items) {
+		return nil, fmt.Errorf("unexpected payment items info count: %d", len(response))
+	}
+	return response, nil
+}
+
+var RefundItemPath = "/refunds"
+
+type RefundItemServiceRequest struct {
+	RefundId string    `json:"refund_id"`
+	ItemId   uuid.UUID `json:"item_id"`
+	ItemType string    `json:"item_type"`
+	UserId   uuid.UUID `json:"user_id"`
+	Value    float64   `json:"value"`
+	Currency string    `json:"currency"`
+	Full     bool      `json:"full"`
+}
+
+// RefundItem reverses the blog income of the refunded item, full refund also revokes access to the item
+func (s *Service) RefundItem(refundId string, itemId uuid.UUID, itemType string, userId uuid.UUID, value float64, currency string, full bool) error {
+	requestBody := RefundItemServiceRequest{
+		RefundId: refundId,
+		ItemId:   itemId,
+		ItemType: itemType,
+		UserId:   userId,
+		Value:    value,
+		Currency: currency,
+		Full:     full,
+	}
+
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("fail to marshal request body cause %v", err)
+	}
+
+	reqUrl, err := url.JoinPath(s.BaseUrl, RefundItemPath)
+	if err != nil {
+		return fmt.Errorf("fail to join url cause %v", err)
+	}
+	req, err := http.NewRequest("POST", reqUrl, strings.NewReader(string(body)))
 	if err != nil {
 		return fmt.Errorf("fail to create request cause %v", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set(requestuser.UserRoleHeaderKey, requestuser.UserRoleService)
+	s.signer.SignServiceRequest(req)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
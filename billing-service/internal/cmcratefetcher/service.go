@@ -6,38 +6,38 @@ import (
 	configService "github.com/llc-ldbit/go-cloud-config-client"
 	"net/http"
 	"net/url"
-	"time"
+	"sync"
 )
 
 type Service struct {
-	ServiceUrl            string
-	LastTonPriceQueryTime time.Time
-	LastTonPrice          float64
+	mu         *sync.RWMutex
+	serviceUrl string
 }
 
 func NewService(serviceUrl string, cfgService *configService.ConfigServiceManager) *Service {
 	service := Service{
-		ServiceUrl:            serviceUrl,
-		LastTonPriceQueryTime: time.Now().UTC().Add(-2 * time.Hour),
-		LastTonPrice:          0,
+		mu:         &sync.RWMutex{},
+		serviceUrl: serviceUrl,
 	}
 	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
-		service.ServiceUrl = ss.Value
+		service.mu.Lock()
+		service.serviceUrl = ss.Value
+		service.mu.Unlock()
 	}, "CMCRATE_SERVICE_URL")
 	return &service
 }
 
-type TonPriceResponse struct {
+type PriceResponse struct {
 	Price float64 `json:"price"`
 }
 
-func (s *Service) TonPrice() (float64, error) {
+// Price returns current price of one base currency unit in quote currency
+func (s *Service) Price(base, quote string) (float64, error) {
+	s.mu.RLock()
+	serviceUrl := s.serviceUrl
+	s.mu.RUnlock()
 
-	if time.Since(s.LastTonPriceQueryTime) < 1*time.Hour {
-		return s.LastTonPrice, nil
-	}
-
-	reqUrl, _ := url.JoinPath(s.ServiceUrl, "toncoin/rub")
+	reqUrl, _ := url.JoinPath(serviceUrl, base, quote)
 
 	req, err := http.NewRequest("GET", reqUrl, nil)
 	if err != nil {
@@ -55,15 +55,12 @@ func (s *Service) TonPrice() (float64, error) {
 		return 0, fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
 	}
 
-	var response TonPriceResponse
+	var response PriceResponse
 
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
 		return 0, fmt.Errorf("fail to unmarshal response body cause %v", err)
 	}
 
-	s.LastTonPrice = response.Price
-	s.LastTonPriceQueryTime = time.Now().UTC()
-
 	return response.Price, nil
 }
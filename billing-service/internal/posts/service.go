@@ -14,12 +14,13 @@ import (
 type Service struct {
 	BaseUrl            string
 	GrantPaidAccessUrl string
+	signer             *requestuser.Signer
 }
 
 var GrantSubscriptionPath = "/paid-access/grant"
 
-func NewService(serviceUrl string, cfgService *configService.ConfigServiceManager) *Service {
-	service := Service{BaseUrl: serviceUrl}
+func NewService(serviceUrl string, signer *requestuser.Signer, cfgService *configService.ConfigServiceManager) *Service {
+	service := Service{BaseUrl: serviceUrl, signer: signer}
 	tempStr, err := url.JoinPath(serviceUrl, GrantSubscriptionPath)
 	if err != nil {
 		panic(err)
@@ -33,19 +34,21 @@ func NewService(serviceUrl string, cfgService *configService.ConfigServiceManage
 }
 
 type GrantItemServiceRequest struct {
-	ItemId   uuid.UUID `json:"item_id" validate:"required"`
-	UserId   uuid.UUID `json:"user_id" validate:"required"`
-	Value    float64   `json:"value" validate:"required"`
-	Currency string    `json:"currency" validate:"required"`
+	ItemId    uuid.UUID `json:"item_id" validate:"required"`
+	UserId    uuid.UUID `json:"user_id" validate:"required"`
+	Value     float64   `json:"value" validate:"required"`
+	Currency  string    `json:"currency" validate:"required"`
+	InvoiceId int       `json:"invoice_id"`
 }
 
-func (s *Service) GrantPostPaidAccess(postId, userId uuid.UUID, value float64, currency string) error {
+func (s *Service) GrantPostPaidAccess(postId, userId uuid.UUID, value float64, currency string, invoiceId int) error {
 
 	requestBody := GrantItemServiceRequest{
-		ItemId:   postId,
-		UserId:   userId,
-		Value:    value,
-		Currency: currency,
+		ItemId:    postId,
+		UserId:    userId,
+		Value:     value,
+		Currency:  currency,
+		InvoiceId: invoiceId,
 	}
 
 	body, err := json.Marshal(requestBody)
@@ -58,7 +61,7 @@ func (s *Service) GrantPostPaidAccess(postId, userId uuid.UUID, value float64, c
 		return fmt.Errorf("fail to create request cause %v", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set(requestuser.UserRoleHeaderKey, requestuser.UserRoleService)
+	s.signer.SignServiceRequest(req)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -0,0 +1,67 @@
+package rates
+
+import (
+	serverlogging "billing-service/pkg/serverlogging/gin"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"time"
+)
+
+type adminHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterAdminHandler(api *gin.RouterGroup, service *Service) {
+	h := &adminHandler{
+		service:  service,
+		validate: validator.New(validator.WithRequiredStructEnabled()),
+	}
+	adminM := AdminMiddleware()
+	api.POST("/:base/:quote", adminM, h.create)
+}
+
+func (h *adminHandler) create(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	base, quote := pairParams(ctx)
+
+	var req CreateRateRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if base == quote {
+		loggingMap.SetMessage("base and quote currencies are the same")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	fetchedAt := time.Now().UTC()
+	if req.FetchedAt != nil {
+		fetchedAt = req.FetchedAt.UTC()
+	}
+
+	rate, err := h.service.CreateRate(ctx, base, quote, req.Value, SourceManual, fetchedAt)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to create rate")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("rate created")
+	loggingMap.Info()
+	ctx.JSON(http.StatusCreated, rate)
+}
@@ -0,0 +1,23 @@
+package rates
+
+import "time"
+
+type ConvertItem struct {
+	Value    float64   `json:"value"`
+	Currency string    `json:"currency" validate:"required"`
+	At       time.Time `json:"at" validate:"required"`
+}
+
+type ConvertRequest struct {
+	To    string        `json:"to" validate:"required"`
+	Items []ConvertItem `json:"items" validate:"dive"`
+}
+
+type ConvertResponse struct {
+	Values []float64 `json:"values"`
+}
+
+type CreateRateRequest struct {
+	Value     float64    `json:"value" validate:"required,gt=0"`
+	FetchedAt *time.Time `json:"fetched_at"`
+}
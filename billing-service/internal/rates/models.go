@@ -0,0 +1,38 @@
+package rates
+
+import "time"
+
+const (
+	CurrencyRub = "rub"
+	CurrencyTon = "toncoin"
+	CurrencyUsd = "usd"
+)
+
+const (
+	PairsConfigKey          = "CURRENCY_RATE_PAIRS"
+	UpdateIntervalConfigKey = "CURRENCY_RATE_UPDATE_INTERVAL"
+)
+
+const (
+	SourceCmcrate = "cmcrate"
+	SourceManual  = "manual"
+)
+
+type Rate struct {
+	ID        int       `json:"id"`
+	Base      string    `json:"base"`
+	Quote     string    `json:"quote"`
+	Value     float64   `json:"value"`
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Created   time.Time `json:"created"`
+}
+
+type Pair struct {
+	Base  string
+	Quote string
+}
+
+func (p Pair) String() string {
+	return p.Base + "/" + p.Quote
+}
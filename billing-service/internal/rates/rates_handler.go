@@ -0,0 +1,142 @@
+package rates
+
+import (
+	serverlogging "billing-service/pkg/serverlogging/gin"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type ratesHandler struct {
+	service *Service
+}
+
+func RegisterRatesHandler(api *gin.RouterGroup, service *Service) {
+	h := &ratesHandler{
+		service: service,
+	}
+
+	api.GET("/toncoin", h.tonPrice)
+	api.GET("/:base/:quote", h.latest)
+	api.GET("/:base/:quote/at", h.at)
+	api.GET("/:base/:quote/history", h.history)
+}
+
+func pairParams(ctx *gin.Context) (string, string) {
+	return strings.ToLower(ctx.Param("base")), strings.ToLower(ctx.Param("quote"))
+}
+
+// allowedPairParams returns the pair params, responding with not found if the pair isn't configured
+func (h *ratesHandler) allowedPairParams(ctx *gin.Context) (string, string, bool) {
+	base, quote := pairParams(ctx)
+	if !h.service.PairAllowed(base, quote) {
+		loggingMap := serverlogging.GetLoggingMap(ctx)
+		loggingMap.SetMessage("currency pair is not configured")
+		ctx.JSON(http.StatusNotFound, nil)
+		return "", "", false
+	}
+	return base, quote, true
+}
+
+func (h *ratesHandler) tonPrice(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	rate, err := h.service.Latest(ctx, CurrencyTon, CurrencyRub)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get ton price")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"rub": rate.Value,
+	})
+}
+
+func (h *ratesHandler) latest(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	base, quote, ok := h.allowedPairParams(ctx)
+	if !ok {
+		return
+	}
+
+	rate, err := h.service.Latest(ctx, base, quote)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get latest rate")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, rate)
+}
+
+func (h *ratesHandler) at(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	base, quote, ok := h.allowedPairParams(ctx)
+	if !ok {
+		return
+	}
+
+	timeQuery := ctx.Query("time")
+	loggingMap["time_query"] = timeQuery
+	at, err := time.Parse(time.RFC3339, timeQuery)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect time query")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	rate, err := h.service.At(ctx, base, quote, at.UTC())
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get rate at time")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, rate)
+}
+
+func (h *ratesHandler) history(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	base, quote, ok := h.allowedPairParams(ctx)
+	if !ok {
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-7 * 24 * time.Hour)
+	if fromQuery := ctx.Query("from"); fromQuery != "" {
+		loggingMap["from_query"] = fromQuery
+		parsed, err := time.Parse(time.RFC3339, fromQuery)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("incorrect from query")
+			ctx.JSON(http.StatusBadRequest, nil)
+			return
+		}
+		from = parsed.UTC()
+	}
+	if toQuery := ctx.Query("to"); toQuery != "" {
+		loggingMap["to_query"] = toQuery
+		parsed, err := time.Parse(time.RFC3339, toQuery)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("incorrect to query")
+			ctx.JSON(http.StatusBadRequest, nil)
+			return
+		}
+		to = parsed.UTC()
+	}
+
+	rates, err := h.service.History(ctx, base, quote, from, to)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get rates history")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, rates)
+}
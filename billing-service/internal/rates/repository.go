@@ -0,0 +1,103 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) scanRate(row pgx.Row) (*Rate, error) {
+	var item Rate
+	err := row.Scan(
+		&item.ID,
+		&item.Base,
+		&item.Quote,
+		&item.Value,
+		&item.Source,
+		&item.FetchedAt,
+		&item.Created,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// RateAt returns the last rate fetched before the given time
+func (r *Repository) RateAt(ctx context.Context, base, quote string, at time.Time) (*Rate, error) {
+	query := `select id, base, quote, value, source, fetched_at, created
+			from currency_rates
+			where base = $1 and quote = $2 and fetched_at <= $3
+			order by fetched_at desc
+			limit 1`
+	return r.scanRate(r.db.QueryRow(ctx, query, base, quote, at))
+}
+
+// FirstRateAfter returns the first rate fetched after the given time
+func (r *Repository) FirstRateAfter(ctx context.Context, base, quote string, at time.Time) (*Rate, error) {
+	query := `select id, base, quote, value, source, fetched_at, created
+			from currency_rates
+			where base = $1 and quote = $2 and fetched_at > $3
+			order by fetched_at
+			limit 1`
+	return r.scanRate(r.db.QueryRow(ctx, query, base, quote, at))
+}
+
+func (r *Repository) RatesHistory(ctx context.Context, base, quote string, from, to time.Time) ([]Rate, error) {
+	query := `select id, base, quote, value, source, fetched_at, created
+			from currency_rates
+			where base = $1 and quote = $2 and fetched_at >= $3 and fetched_at <= $4
+			order by fetched_at`
+
+	rows, err := r.db.Query(ctx, query, base, quote, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates = make([]Rate, 0)
+	var item Rate
+	for rows.Next() {
+		err := rows.Scan(
+			&item.ID,
+			&item.Base,
+			&item.Quote,
+			&item.Value,
+			&item.Source,
+			&item.FetchedAt,
+			&item.Created,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, item)
+	}
+	return rates, nil
+}
+
+func (r *Repository) CreateRate(ctx context.Context, rate *Rate) error {
+	query := `insert into currency_rates (base, quote, value, source, fetched_at, created)
+			values ($1, $2, $3, $4, $5, $6)
+			returning id`
+	return r.db.QueryRow(ctx, query,
+		rate.Base,
+		rate.Quote,
+		rate.Value,
+		rate.Source,
+		rate.FetchedAt,
+		rate.Created,
+	).Scan(&rate.ID)
+}
@@ -0,0 +1,236 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fetcher returns current price of one base currency unit in quote currency
+type Fetcher interface {
+	Price(base, quote string) (float64, error)
+}
+
+type Service struct {
+	repository *Repository
+	fetcher    Fetcher
+
+	mu             *sync.RWMutex
+	pairs          []Pair
+	latest         map[Pair]Rate
+	updateInterval time.Duration
+
+	updateTicker *time.Ticker
+}
+
+func NewService(repository *Repository, fetcher Fetcher, pairs string, updateIntervalMinutes int,
+	cfgService *configService.ConfigServiceManager) *Service {
+
+	if updateIntervalMinutes <= 0 {
+		updateIntervalMinutes = 60
+	}
+
+	service := &Service{
+		repository:     repository,
+		fetcher:        fetcher,
+		mu:             &sync.RWMutex{},
+		pairs:          ParsePairs(pairs),
+		latest:         make(map[Pair]Rate),
+		updateInterval: time.Duration(updateIntervalMinutes) * time.Minute,
+	}
+
+	service.updateTicker = time.NewTicker(service.updateInterval)
+	go service.StartUpdateWorker(context.Background(), service.updateTicker)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		pairs := ParsePairs(ss.Value)
+		service.mu.Lock()
+		service.pairs = pairs
+		service.mu.Unlock()
+	}, PairsConfigKey)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err != nil || value <= 0 {
+			return
+		}
+		service.mu.Lock()
+		service.updateInterval = time.Duration(value) * time.Minute
+		service.updateTicker.Reset(service.updateInterval)
+		service.mu.Unlock()
+	}, UpdateIntervalConfigKey)
+
+	return service
+}
+
+// ParsePairs parses comma separated pairs like "toncoin/rub,toncoin/usd".
+// Returns toncoin/rub pair if nothing is parsed.
+func ParsePairs(value string) []Pair {
+	pairs := make([]Pair, 0)
+	for _, pairStr := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(pairStr), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+		pairs = append(pairs, Pair{Base: strings.ToLower(parts[0]), Quote: strings.ToLower(parts[1])})
+	}
+	if len(pairs) == 0 {
+		pairs = append(pairs, Pair{Base: CurrencyTon, Quote: CurrencyRub})
+	}
+	return pairs
+}
+
+func (s *Service) StopWorkers() {
+	s.updateTicker.Stop()
+}
+
+func (s *Service) StartUpdateWorker(ctx context.Context, ticker *time.Ticker) {
+	s.UpdateRates(ctx)
+	for range ticker.C {
+		s.UpdateRates(ctx)
+	}
+}
+
+// UpdateRates fetches and stores rates of all configured pairs
+func (s *Service) UpdateRates(ctx context.Context) {
+	s.mu.RLock()
+	pairs := make([]Pair, len(s.pairs))
+	copy(pairs, s.pairs)
+	s.mu.RUnlock()
+
+	for _, pair := range pairs {
+		_, _ = s.fetchRate(ctx, pair)
+	}
+}
+
+func (s *Service) fetchRate(ctx context.Context, pair Pair) (*Rate, error) {
+	value, err := s.fetcher.Price(pair.Base, pair.Quote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s rate: %v", pair, err)
+	}
+	if value <= 0 {
+		return nil, fmt.Errorf("fetched %s rate is not positive: %v", pair, value)
+	}
+	timeNow := time.Now().UTC()
+	return s.CreateRate(ctx, pair.Base, pair.Quote, value, SourceCmcrate, timeNow)
+}
+
+func (s *Service) CreateRate(ctx context.Context, base, quote string, value float64, source string, fetchedAt time.Time) (*Rate, error) {
+	rate := Rate{
+		Base:      base,
+		Quote:     quote,
+		Value:     value,
+		Source:    source,
+		FetchedAt: fetchedAt,
+		Created:   time.Now().UTC(),
+	}
+	err := s.repository.CreateRate(ctx, &rate)
+	if err != nil {
+		return nil, err
+	}
+
+	pair := Pair{Base: base, Quote: quote}
+	s.mu.Lock()
+	if cached, ok := s.latest[pair]; !ok || !cached.FetchedAt.After(rate.FetchedAt) {
+		s.latest[pair] = rate
+	}
+	s.mu.Unlock()
+	return &rate, nil
+}
+
+// PairAllowed reports whether the pair or its inverse is configured to be fetched
+func (s *Service) PairAllowed(base, quote string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, pair := range s.pairs {
+		if (pair.Base == base && pair.Quote == quote) || (pair.Base == quote && pair.Quote == base) {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the last known rate of the pair, fetching it if the pair was never fetched
+func (s *Service) Latest(ctx context.Context, base, quote string) (*Rate, error) {
+	if base == quote {
+		return identityRate(base, time.Now().UTC()), nil
+	}
+
+	s.mu.RLock()
+	rate, ok := s.latest[Pair{Base: base, Quote: quote}]
+	if !ok {
+		if inverse, ok := s.latest[Pair{Base: quote, Quote: base}]; ok {
+			s.mu.RUnlock()
+			return inverseRate(&inverse), nil
+		}
+	}
+	s.mu.RUnlock()
+	if ok {
+		return &rate, nil
+	}
+
+	return s.At(ctx, base, quote, time.Now().UTC())
+}
+
+// At returns the rate that was in effect at the given time.
+// If there is no history before the time, the first known rate after it is used.
+func (s *Service) At(ctx context.Context, base, quote string, at time.Time) (*Rate, error) {
+	if base == quote {
+		return identityRate(base, at), nil
+	}
+
+	rate, err := s.storedRateAt(ctx, base, quote, at)
+	if err != nil || rate != nil {
+		return rate, err
+	}
+	inverse, err := s.storedRateAt(ctx, quote, base, at)
+	if err != nil {
+		return nil, err
+	}
+	if inverse != nil {
+		return inverseRate(inverse), nil
+	}
+
+	return s.fetchRate(ctx, Pair{Base: base, Quote: quote})
+}
+
+func (s *Service) storedRateAt(ctx context.Context, base, quote string, at time.Time) (*Rate, error) {
+	rate, err := s.repository.RateAt(ctx, base, quote, at)
+	if err != nil || rate != nil {
+		return rate, err
+	}
+	return s.repository.FirstRateAfter(ctx, base, quote, at)
+}
+
+func (s *Service) History(ctx context.Context, base, quote string, from, to time.Time) ([]Rate, error) {
+	return s.repository.RatesHistory(ctx, base, quote, from, to)
+}
+
+// Convert converts value from one currency to another at the rate in effect at the given time
+func (s *Service) Convert(ctx context.Context, value float64, from, to string, at time.Time) (float64, error) {
+	rate, err := s.At(ctx, from, to, at)
+	if err != nil {
+		return 0, err
+	}
+	return value * rate.Value, nil
+}
+
+func identityRate(currency string, at time.Time) *Rate {
+	return &Rate{Base: currency, Quote: currency, Value: 1, FetchedAt: at, Created: at}
+}
+
+func inverseRate(rate *Rate) *Rate {
+	return &Rate{
+		ID:        rate.ID,
+		Base:      rate.Quote,
+		Quote:     rate.Base,
+		Value:     1 / rate.Value,
+		Source:    rate.Source,
+		FetchedAt: rate.FetchedAt,
+		Created:   rate.Created,
+	}
+}
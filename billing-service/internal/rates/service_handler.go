@@ -0,0 +1,58 @@
+package rates
+
+import (
+	serverlogging "billing-service/pkg/serverlogging/gin"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+type serviceHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterServiceHandler(api *gin.RouterGroup, service *Service) {
+	h := &serviceHandler{
+		service:  service,
+		validate: validator.New(validator.WithRequiredStructEnabled()),
+	}
+	serviceM := ServiceMiddleware()
+	api.POST("/convert", serviceM, h.convert)
+}
+
+// convert converts every item to the requested currency at the rate in effect at the item time
+func (h *serviceHandler) convert(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req ConvertRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	values := make([]float64, len(req.Items))
+	for i, item := range req.Items {
+		value, err := h.service.Convert(ctx, item.Value, item.Currency, req.To, item.At)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("fail to convert value")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		values[i] = value
+	}
+
+	ctx.JSON(http.StatusOK, ConvertResponse{Values: values})
+}
@@ -0,0 +1,80 @@
+package receipts
+
+import (
+	"time"
+)
+
+const (
+	FiscalSnoUsnIncome         = "usn_income"
+	FiscalTaxNone              = "none"
+	FiscalPaymentMethodFull    = "full_payment"
+	FiscalPaymentObjectService = "service"
+	fiscalItemNameMaxLength    = 128
+	receiptDateTimeLayout      = "02.01.2006 15:04:05 UTC"
+	receiptDefaultSellerTitle  = "HidePost"
+)
+
+type Receipt struct {
+	Number   string        `json:"number"`
+	Seller   string        `json:"seller"`
+	BuyerId  string        `json:"buyer_id"`
+	PaidAt   time.Time     `json:"paid_at"`
+	Currency string        `json:"currency"`
+	Items    []Item        `json:"items"`
+	Total    float64       `json:"total"`
+	Fiscal   FiscalReceipt `json:"fiscal"`
+}
+
+type Item struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"`
+	Sum      float64 `json:"sum"`
+}
+
+// FiscalReceipt is the receipt in the format of Robokassa "Receipt" parameter
+type FiscalReceipt struct {
+	Sno   string       `json:"sno"`
+	Items []FiscalItem `json:"items"`
+}
+
+type FiscalItem struct {
+	Name          string  `json:"name"`
+	Quantity      float64 `json:"quantity"`
+	Sum           float64 `json:"sum"`
+	PaymentMethod string  `json:"payment_method"`
+	PaymentObject string  `json:"payment_object"`
+	Tax           string  `json:"tax"`
+}
+
+// NewReceipt creates a receipt with fiscal items built from the receipt items
+func NewReceipt(number, buyerId string, paidAt time.Time, currency string, items []Item) *Receipt {
+	receipt := Receipt{
+		Number:   number,
+		Seller:   receiptDefaultSellerTitle,
+		BuyerId:  buyerId,
+		PaidAt:   paidAt,
+		Currency: currency,
+		Items:    items,
+		Fiscal: FiscalReceipt{
+			Sno:   FiscalSnoUsnIncome,
+			Items: make([]FiscalItem, 0, len(items)),
+		},
+	}
+	for _, item := range items {
+		receipt.Total += item.Sum
+		name := []rune(item.Name)
+		if len(name) > fiscalItemNameMaxLength {
+			name = name[:fiscalItemNameMaxLength]
+		}
+		receipt.Fiscal.Items = append(receipt.Fiscal.Items, FiscalItem{
+			Name:          string(name),
+			Quantity:      item.Quantity,
+			Sum:           item.Sum,
+			PaymentMethod: FiscalPaymentMethodFull,
+			PaymentObject: FiscalPaymentObjectService,
+			Tax:           FiscalTaxNone,
+		})
+	}
+	return &receipt
+}
@@ -0,0 +1,104 @@
+package receipts
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"html/template"
+	"strings"
+)
+
+//go:embed fonts/DejaVuSans.ttf
+var dejaVuSansFont []byte
+
+var htmlTemplate = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"money": formatMoney,
+	"date":  func(r *Receipt) string { return r.PaidAt.UTC().Format(receiptDateTimeLayout) },
+}).Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Чек № {{.Number}}</title>
+<style>
+body { font-family: sans-serif; max-width: 640px; margin: 24px auto; color: #222; }
+table { width: 100%; border-collapse: collapse; }
+th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+td.num, th.num { text-align: right; }
+.total { font-weight: bold; }
+</style>
+</head>
+<body>
+<h2>Чек № {{.Number}}</h2>
+<p>Продавец: {{.Seller}}<br>Покупатель: {{.BuyerId}}<br>Дата оплаты: {{date .}}</p>
+<table>
+<tr><th>Наименование</th><th class="num">Кол-во</th><th class="num">Цена</th><th class="num">Сумма</th></tr>
+{{range .Items}}<tr><td>{{.Name}}</td><td class="num">{{.Quantity}}</td><td class="num">{{money .Price}}</td><td class="num">{{money .Sum}}</td></tr>
+{{end}}<tr class="total"><td colspan="3">Итого, {{.Currency}}</td><td class="num">{{money .Total}}</td></tr>
+</table>
+<p>Система налогообложения: {{.Fiscal.Sno}}. НДС не облагается.</p>
+</body>
+</html>
+`))
+
+func formatMoney(value float64) string {
+	return fmt.Sprintf("%.2f", value)
+}
+
+func RenderHTML(receipt *Receipt) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, receipt); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func RenderPDF(receipt *Receipt) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes("DejaVuSans", "", dejaVuSansFont)
+	pdf.SetTitle(fmt.Sprintf("Чек № %s", receipt.Number), true)
+	pdf.AddPage()
+
+	pdf.SetFont("DejaVuSans", "", 16)
+	pdf.CellFormat(0, 10, fmt.Sprintf("Чек № %s", receipt.Number), "", 1, "L", false, 0, "")
+
+	pdf.SetFont("DejaVuSans", "", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Продавец: %s", receipt.Seller), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Покупатель: %s", receipt.BuyerId), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Дата оплаты: %s", receipt.PaidAt.UTC().Format(receiptDateTimeLayout)), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	widths := []float64{100, 20, 30, 30}
+	headers := []string{"Наименование", "Кол-во", "Цена", "Сумма"}
+	for i, header := range headers {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 8, header, "B", 0, align, false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	for _, item := range receipt.Items {
+		name := item.Name
+		for pdf.GetStringWidth(name) > widths[0]-2 && len([]rune(name)) > 1 {
+			name = strings.TrimSpace(string([]rune(name)[:len([]rune(name))-2])) + "…"
+		}
+		pdf.CellFormat(widths[0], 8, name, "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 8, fmt.Sprintf("%g", item.Quantity), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 8, formatMoney(item.Price), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 8, formatMoney(item.Sum), "B", 0, "R", false, 0, "")
+		pdf.Ln(-1)
+	}
+
+	pdf.CellFormat(widths[0]+widths[1]+widths[2], 8, fmt.Sprintf("Итого, %s", receipt.Currency), "", 0, "L", false, 0, "")
+	pdf.CellFormat(widths[3], 8, formatMoney(receipt.Total), "", 1, "R", false, 0, "")
+	pdf.Ln(4)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Система налогообложения: %s. НДС не облагается.", receipt.Fiscal.Sno), "", 1, "L", false, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -1,9 +1,13 @@
 package robokassa
 
 import (
+	requestuser "billing-service/pkg/hidepost-requestuser"
 	serverlogging "billing-service/pkg/serverlogging/gin"
+	"encoding/json"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
 )
 
 type adminHandler struct {
@@ -16,6 +20,9 @@ func RegisterAdminHandler(api *gin.RouterGroup, service *Service) {
 	}
 	adminM := AdminMiddleware()
 	api.GET("/invoices", adminM, h.invoicesList)
+	api.GET("/invoices/:id/refunds", adminM, h.invoiceRefunds)
+	api.POST("/invoices/:id/refunds", adminM, h.createRefund)
+	api.PUT("/refunds/:id/reverse", adminM, h.reverseRefund)
 }
 
 func (h *adminHandler) invoicesList(ctx *gin.Context) {
@@ -32,3 +39,161 @@ func (h *adminHandler) invoicesList(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, invoices)
 }
+
+func (h *adminHandler) invoiceByParam(ctx *gin.Context) *Invoice {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	invoiceIdStr := ctx.Param("id")
+	loggingMap["invoice_id_param"] = invoiceIdStr
+	invoiceId, err := strconv.Atoi(invoiceIdStr)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get invoice id from param")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return nil
+	}
+
+	invoice, err := h.service.repository.InvoiceById(ctx, invoiceId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get invoice by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return nil
+	}
+	if invoice == nil {
+		loggingMap.SetMessage("invoice not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return nil
+	}
+	return invoice
+}
+
+func (h *adminHandler) invoiceRefunds(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	invoice := h.invoiceByParam(ctx)
+	if invoice == nil {
+		return
+	}
+
+	refunds, err := h.service.repository.RefundsByInvoiceId(ctx, invoice.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get invoice refunds")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, refunds)
+}
+
+// createRefund refunds the whole rest of the invoice sum if the sum isn't given.
+// If the refund is done but the blog income reversal failed, it responds with accepted status
+// and the reversal can be retried with reverseRefund.
+func (h *adminHandler) createRefund(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	adminId := requestuser.GetUserID(ctx)
+
+	invoice := h.invoiceByParam(ctx)
+	if invoice == nil {
+		return
+	}
+
+	var req CreateRefundRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if req.Sum < 0 {
+		loggingMap.SetMessage("bad request, refund sum is negative")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if invoice.Status != InvoiceStatusConfirmed {
+		loggingMap.SetMessage("invoice is not confirmed")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+	refund, err := h.service.Refund(ctx, invoice, req.Sum, req.Reason, *adminId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to refund invoice")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if refund == nil {
+		loggingMap.SetMessage("refund sum exceeds refundable sum of the invoice")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	err = h.service.ReverseRefund(ctx, invoice, refund)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("invoice refunded, but fail to reverse refunded item")
+		loggingMap.Info()
+		ctx.JSON(http.StatusAccepted, refund)
+		return
+	}
+
+	loggingMap.SetMessage("invoice refunded")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, refund)
+}
+
+func (h *adminHandler) reverseRefund(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	refundIdStr := ctx.Param("id")
+	loggingMap["refund_id_param"] = refundIdStr
+	refundId, err := strconv.Atoi(refundIdStr)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get refund id from param")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	refund, err := h.service.repository.RefundById(ctx, refundId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get refund by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if refund == nil {
+		loggingMap.SetMessage("refund not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if refund.Status != RefundStatusRefunded {
+		loggingMap.SetMessage("refund is not waiting for reversal")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	invoice, err := h.service.repository.InvoiceById(ctx, refund.InvoiceId)
+	if err != nil || invoice == nil {
+		if err != nil {
+			loggingMap.SetError(err.Error())
+		}
+		loggingMap.SetMessage("fail to get invoice of refund")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = h.service.ReverseRefund(ctx, invoice, refund)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to reverse refunded item")
+		ctx.JSON(http.StatusBadGateway, nil)
+		return
+	}
+
+	loggingMap.SetMessage("refunded item reversed")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, refund)
+}
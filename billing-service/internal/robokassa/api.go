@@ -1,9 +1,13 @@
 package robokassa
 
 import (
+	"billing-service/internal/receipts"
+	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"github.com/google/uuid"
 	"io"
@@ -23,7 +27,17 @@ type CreateInvoiceResponse struct {
 	Error        any    `json:"error"`
 }
 
-func ApiCreateInvoice(MerchantLogin, Description, Password1 string, OutSum float64, InvId int, ExpirationDate time.Time, IsTest bool) (*CreateInvoiceResponse, error) {
+// ApiCreateInvoice creates the invoice with the fiscal receipt. The receipt is url encoded in the signature
+// and encoded once more in the request, as Robokassa requires.
+func ApiCreateInvoice(MerchantLogin, Description, Password1 string, OutSum float64, InvId int, ExpirationDate time.Time, IsTest bool,
+	Receipt *receipts.FiscalReceipt) (*CreateInvoiceResponse, error) {
+
+	receiptBytes, err := json.Marshal(Receipt)
+	if err != nil {
+		return nil, fmt.Errorf("fail to marshal receipt cause %v", err)
+	}
+	encodedReceipt := url.QueryEscape(string(receiptBytes))
+
 	form := url.Values{}
 	form.Add("InvId", fmt.Sprint(InvId))
 	form.Add("MerchantLogin", MerchantLogin)
@@ -32,11 +46,12 @@ func ApiCreateInvoice(MerchantLogin, Description, Password1 string, OutSum float
 	form.Add("Description", Description)
 	form.Add("Culture", "ru")
 	form.Add("ExpirationDate", ExpirationDate.Format(FormatISO8601))
+	form.Add("Receipt", encodedReceipt)
 	if IsTest {
 		form.Add("IsTest", "1")
 	}
 	//amountString := strconv.FormatFloat(OutSum, 'f', -1, 64)
-	sha := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s:%s", MerchantLogin, formattedSum, fmt.Sprint(InvId), Password1)))
+	sha := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s:%s:%s", MerchantLogin, formattedSum, fmt.Sprint(InvId), encodedReceipt, Password1)))
 	form.Add("SignatureValue", hex.EncodeToString(sha[:]))
 
 	req, err := http.NewRequest("POST", CreateInvoiceUrl, strings.NewReader(form.Encode()))
@@ -74,3 +89,93 @@ func ApiCreateInvoice(MerchantLogin, Description, Password1 string, OutSum float
 
 	return &result, nil
 }
+
+const OpStateExtUrl = "https://auth.robokassa.ru/Merchant/WebService/Service.asmx/OpStateExt?"
+
+const CreateRefundUrl = "https://services.robokassa.ru/RefundService/Refund/Create"
+
+type OpStateExtResponse struct {
+	Result struct {
+		Code        int    `xml:"Code"`
+		Description string `xml:"Description"`
+	} `xml:"Result"`
+	Info struct {
+		OpKey string `xml:"OpKey"`
+	} `xml:"Info"`
+}
+
+// ApiOpKey returns the operation key of the paid invoice, it is required to refund the payment
+func ApiOpKey(MerchantLogin, Password2 string, InvId int) (string, error) {
+	query := url.Values{}
+	query.Add("MerchantLogin", MerchantLogin)
+	query.Add("InvoiceID", fmt.Sprint(InvId))
+	sha := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s", MerchantLogin, fmt.Sprint(InvId), Password2)))
+	query.Add("Signature", hex.EncodeToString(sha[:]))
+
+	resp, err := http.Get(OpStateExtUrl + query.Encode())
+	if err != nil {
+		return "", fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	var result OpStateExtResponse
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return "", fmt.Errorf("fail to decode response cause %v", err)
+	}
+	if result.Result.Code != 0 || result.Info.OpKey == "" {
+		return "", fmt.Errorf("failed to get operation key, code: %d, description: %s",
+			result.Result.Code, result.Result.Description)
+	}
+	return result.Info.OpKey, nil
+}
+
+type CreateRefundResponse struct {
+	Success   bool    `json:"success"`
+	Message   *string `json:"message"`
+	RequestId string  `json:"requestId"`
+}
+
+// ApiCreateRefund sends the refund request signed as HS256 JWT with the third password
+func ApiCreateRefund(OpKey, Password3 string, RefundSum float64) (*CreateRefundResponse, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, err := json.Marshal(map[string]any{
+		"OpKey":     OpKey,
+		"RefundSum": json.Number(fmt.Sprintf("%.2f", RefundSum)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to marshal request payload cause %v", err)
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(Password3))
+	mac.Write([]byte(unsigned))
+	token := unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequest("POST", CreateRefundUrl, strings.NewReader(token))
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request cause %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	var result CreateRefundResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("fail to decode response cause %v", err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("failed to create a refund, response: %+v", result)
+	}
+	return &result, nil
+}
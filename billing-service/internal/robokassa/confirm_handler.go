@@ -54,7 +54,7 @@ func (h *confirmHandler) confirm(ctx *gin.Context) {
 		return
 	}
 
-	err = h.service.GrantItemByType(ctx, invoice)
+	granted, err := h.service.GrantItemByType(ctx, invoice)
 	if err != nil {
 		loggingMap["invoice"] = fmt.Sprintf("%+v", *invoice)
 		loggingMap.SetMessage("fail to grant item")
@@ -73,6 +73,26 @@ func (h *confirmHandler) confirm(ctx *gin.Context) {
 		return
 	}
 
+	if !granted {
+		// the payment is received, so the invoice is confirmed first and then refunded
+		_, err = h.service.RefundUngranted(ctx, invoice)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("paid item isn't granted and fail to refund invoice")
+		} else {
+			loggingMap.SetMessage("paid item isn't granted, invoice refunded")
+		}
+		loggingMap.Info()
+		ctx.String(http.StatusOK, "OK%s", InvId)
+		return
+	}
+
+	// the referral reward failure doesn't fail the payment, it's granted on the next payment of the user
+	err = h.service.usersService.RefereeFirstPayment(invoice.UserId, invoice.ID)
+	if err != nil {
+		loggingMap["referral_error"] = err.Error()
+	}
+
 	loggingMap.SetMessage("invoice confirmed")
 	loggingMap.Info()
 	ctx.String(http.StatusOK, "OK%s", InvId)
@@ -1,6 +1,8 @@
 package robokassa
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+)
 
 type PaymentLinkRequest struct {
 	ItemId      uuid.UUID `json:"item_id"`
@@ -11,5 +13,30 @@ type PaymentLinkRequest struct {
 }
 
 type PaymentLinkResponse struct {
-	Url string `json:"url"`
+	Url       string `json:"url"`
+	InvoiceId int    `json:"invoice_id"`
+}
+
+type ItemRefundRequest struct {
+	ItemId   uuid.UUID `json:"item_id"`
+	ItemType string    `json:"item_type"`
+	Reason   string    `json:"reason"`
+}
+
+type PaymentHistoryItem struct {
+	Invoice
+	Title     string     `json:"title"`
+	BlogId    *uuid.UUID `json:"blog_id"`
+	BlogTitle string     `json:"blog_title"`
+}
+
+type MyPaymentsResponse struct {
+	Items  []PaymentHistoryItem `json:"items"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+type CreateRefundRequest struct {
+	Sum    float64 `json:"sum"`
+	Reason string  `json:"reason"`
 }
@@ -1,11 +1,19 @@
 package robokassa
 
 import (
+	"billing-service/internal/receipts"
 	requestuser "billing-service/pkg/hidepost-requestuser"
 	serverlogging "billing-service/pkg/serverlogging/gin"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"strings"
+)
+
+const (
+	myPaymentsDefaultLimit = 20
+	myPaymentsMaxLimit     = 100
 )
 
 type invoicesHandler struct {
@@ -17,7 +25,9 @@ func RegisterInvoicesHandler(api *gin.RouterGroup, service *Service) {
 		service: service,
 	}
 	userM := UserMiddleware()
+	api.GET("/invoices/my", userM, h.myPayments)
 	api.GET("/invoices/:id", userM, h.invoiceById)
+	api.GET("/invoices/:id/receipt", userM, h.invoiceReceipt)
 }
 
 func (h *invoicesHandler) invoiceById(ctx *gin.Context) {
@@ -61,3 +71,137 @@ func (h *invoicesHandler) invoiceById(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, invoices[0])
 }
+
+func (h *invoicesHandler) myPayments(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	limit, offset := myPaymentsDefaultLimit, 0
+	if limitQuery := ctx.Query("limit"); limitQuery != "" {
+		value, err := strconv.Atoi(limitQuery)
+		if err != nil || value <= 0 {
+			loggingMap["limit_query"] = limitQuery
+			loggingMap.SetMessage("incorrect limit query")
+			ctx.JSON(http.StatusBadRequest, nil)
+			return
+		}
+		limit = min(value, myPaymentsMaxLimit)
+	}
+	if offsetQuery := ctx.Query("offset"); offsetQuery != "" {
+		value, err := strconv.Atoi(offsetQuery)
+		if err != nil || value < 0 {
+			loggingMap["offset_query"] = offsetQuery
+			loggingMap.SetMessage("incorrect offset query")
+			ctx.JSON(http.StatusBadRequest, nil)
+			return
+		}
+		offset = value
+	}
+
+	var itemTypes, statuses []string
+	if itemTypeQuery := ctx.Query("item_type"); itemTypeQuery != "" {
+		itemTypes = strings.Split(itemTypeQuery, ",")
+	}
+	if statusQuery := ctx.Query("status"); statusQuery != "" {
+		statuses = strings.Split(statusQuery, ",")
+	}
+
+	invoices, err := h.service.repository.InvoicesByParams(
+		ctx,
+		nil, nil, itemTypes,
+		[]string{userId.String()},
+		statuses, nil, nil, &limit, &offset)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get invoices")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	items, err := h.service.PaymentsHistory(invoices)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get payment items info")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, MyPaymentsResponse{
+		Items:  items,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (h *invoicesHandler) invoiceReceipt(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	invoiceIdStr := ctx.Param("id")
+	loggingMap["invoice_id_param"] = invoiceIdStr
+	invoiceId, err := strconv.Atoi(invoiceIdStr)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get invoice id from param")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	format := ctx.DefaultQuery("format", "html")
+	if format != "html" && format != "pdf" && format != "json" {
+		loggingMap["format_query"] = format
+		loggingMap.SetMessage("unknown receipt format")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	invoices, err := h.service.repository.InvoicesByParams(
+		ctx,
+		[]int{invoiceId}, nil, nil,
+		[]string{userId.String()},
+		[]string{InvoiceStatusConfirmed}, nil, nil, nil, nil)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get invoice")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if len(invoices) == 0 {
+		loggingMap.SetMessage("confirmed invoice not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	items, err := h.service.PaymentsHistory(invoices)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to get payment item info")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	receipt := h.service.Receipt(&items[0])
+
+	switch format {
+	case "json":
+		ctx.JSON(http.StatusOK, receipt)
+	case "pdf":
+		data, err := receipts.RenderPDF(receipt)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("fail to render pdf receipt")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"receipt-%d.pdf\"", invoiceId))
+		ctx.Data(http.StatusOK, "application/pdf", data)
+	default:
+		data, err := receipts.RenderHTML(receipt)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("fail to render html receipt")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", data)
+	}
+}
@@ -23,18 +23,43 @@ const (
 	InvoiceStatusConfirmed = "confirmed"
 	InvoiceStatusExpired   = "expired"
 	InvoiceStatusFailed    = "failed"
+	InvoiceStatusRefunded  = "refunded"
+)
+
+type Refund struct {
+	ID          int       `json:"id"`
+	InvoiceId   int       `json:"invoice_id"`
+	Value       float64   `json:"value"`
+	IsFull      bool      `json:"is_full"`
+	Reason      string    `json:"reason"`
+	Status      string    `json:"status"`
+	ProviderRef string    `json:"provider_ref"`
+	CreatedBy   uuid.UUID `json:"created_by"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+// Refund is refunded when the provider accepted it and completed when the blog income is reversed
+const (
+	RefundStatusNew       = "new"
+	RefundStatusRefunded  = "refunded"
+	RefundStatusCompleted = "completed"
+	RefundStatusFailed    = "failed"
 )
 
 const (
 	InvoiceItemTypeSubscription = "subscription"
 	InvoiceItemTypePost         = "post"
 	InvoiceItemTypeDonation     = "donation"
+	InvoiceItemTypeGift         = "gift"
+	InvoiceItemTypeTierChange   = "tier_change"
 )
 
 const (
 	MerchantLoginConfigKey = "ROBOKASSA_MERCHANT_LOGIN"
 	Password1ConfigKey     = "ROBOKASSA_PASSWORD1"
 	Password2ConfigKey     = "ROBOKASSA_PASSWORD2"
+	Password3ConfigKey     = "ROBOKASSA_PASSWORD3"
 	IsTestConfigKey        = "ROBOKASSA_IS_TEST"
 	TestPassword1ConfigKey = "ROBOKASSA_TEST_PASSWORD1"
 	TestPassword2ConfigKey = "ROBOKASSA_TEST_PASSWORD2"
@@ -195,6 +195,36 @@ func (r *Repository) InvoicesByUserIdAndItemId(ctx context.Context, userId, subI
 	return resultArray, nil
 }
 
+// LatestInvoiceByItemAndStatus returns the latest invoice of the item with the status
+func (r *Repository) LatestInvoiceByItemAndStatus(ctx context.Context, itemId uuid.UUID, itemType, status string) (*Invoice, error) {
+	query := `select id, out_sum, item_id, item_type, user_id, expires_at, status, payment_link, created, updated
+		from robokassa_invoices
+		where item_id = $1 and item_type = $2 and status = $3
+		order by created desc
+		limit 1`
+
+	var item Invoice
+	err := r.db.QueryRow(ctx, query, itemId, itemType, status).Scan(
+		&item.ID,
+		&item.OutSum,
+		&item.ItemId,
+		&item.ItemType,
+		&item.UserId,
+		&item.ExpiresAt,
+		&item.Status,
+		&item.PaymentLink,
+		&item.Created,
+		&item.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
 func (r *Repository) CreateInvoice(ctx context.Context, item *Invoice) error {
 	query := `insert into robokassa_invoices
     (out_sum, item_id, item_type, user_id, expires_at, status, payment_link, created, updated) 
@@ -246,3 +276,164 @@ func (r *Repository) UpdateInvoice(ctx context.Context, item *Invoice) error {
 	)
 	return err
 }
+
+func (r *Repository) RefundById(ctx context.Context, id int) (*Refund, error) {
+	query := `select id, invoice_id, value, is_full, reason, status, provider_ref, created_by, created, updated
+		from robokassa_refunds
+		where id = $1`
+
+	var item Refund
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&item.ID,
+		&item.InvoiceId,
+		&item.Value,
+		&item.IsFull,
+		&item.Reason,
+		&item.Status,
+		&item.ProviderRef,
+		&item.CreatedBy,
+		&item.Created,
+		&item.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) RefundsByInvoiceId(ctx context.Context, invoiceId int) ([]Refund, error) {
+	query := `select id, invoice_id, value, is_full, reason, status, provider_ref, created_by, created, updated
+		from robokassa_refunds
+		where invoice_id = $1
+		order by created desc`
+
+	rows, err := r.db.Query(ctx, query, invoiceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]Refund, 0)
+	var item Refund
+	for rows.Next() {
+		err = rows.Scan(
+			&item.ID,
+			&item.InvoiceId,
+			&item.Value,
+			&item.IsFull,
+			&item.Reason,
+			&item.Status,
+			&item.ProviderRef,
+			&item.CreatedBy,
+			&item.Created,
+			&item.Updated,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, item)
+	}
+	return resultArray, nil
+}
+
+func (r *Repository) CreateRefund(ctx context.Context, item *Refund) error {
+	query := `insert into robokassa_refunds
+    (invoice_id, value, is_full, reason, status, provider_ref, created_by, created, updated)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id`
+
+	var id int
+	err := r.db.QueryRow(ctx, query,
+		item.InvoiceId,
+		item.Value,
+		item.IsFull,
+		item.Reason,
+		item.Status,
+		item.ProviderRef,
+		item.CreatedBy,
+		item.Created,
+		item.Updated,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+	item.ID = id
+	return nil
+}
+
+// CreateRefundWithinSum creates the refund under the lock of the invoice, so concurrent refunds
+// can't exceed the invoice sum. Zero refund value refunds the rest of the invoice sum.
+// Returns false if the invoice isn't confirmed or the refund exceeds its not refunded sum.
+func (r *Repository) CreateRefundWithinSum(ctx context.Context, item *Refund) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var outSum float64
+	var status string
+	query := `select out_sum, status from robokassa_invoices where id = $1 for update`
+	err = tx.QueryRow(ctx, query, item.InvoiceId).Scan(&outSum, &status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if status != InvoiceStatusConfirmed {
+		return false, nil
+	}
+
+	var refunded float64
+	query = `select coalesce(sum(value), 0) from robokassa_refunds where invoice_id = $1 and status != $2`
+	err = tx.QueryRow(ctx, query, item.InvoiceId, RefundStatusFailed).Scan(&refunded)
+	if err != nil {
+		return false, err
+	}
+	refundable := outSum - refunded
+	if item.Value <= 0 {
+		item.Value = refundable
+	}
+	if refundable < refundSumEpsilon || item.Value > refundable+refundSumEpsilon {
+		return false, nil
+	}
+	item.IsFull = item.Value+refundSumEpsilon >= refundable
+
+	query = `insert into robokassa_refunds
+    (invoice_id, value, is_full, reason, status, provider_ref, created_by, created, updated)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id`
+	err = tx.QueryRow(ctx, query,
+		item.InvoiceId,
+		item.Value,
+		item.IsFull,
+		item.Reason,
+		item.Status,
+		item.ProviderRef,
+		item.CreatedBy,
+		item.Created,
+		item.Updated,
+	).Scan(&item.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *Repository) UpdateRefund(ctx context.Context, item *Refund) error {
+	query := `update robokassa_refunds set
+            status = $2,
+            provider_ref = $3,
+            updated = $4
+			where id = $1`
+	_, err := r.db.Exec(ctx, query,
+		item.ID,
+		item.Status,
+		item.ProviderRef,
+		item.Updated,
+	)
+	return err
+}
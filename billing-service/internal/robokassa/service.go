@@ -3,6 +3,8 @@ package robokassa
 import (
 	"billing-service/internal/blogs"
 	"billing-service/internal/posts"
+	"billing-service/internal/receipts"
+	"billing-service/internal/users"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -19,26 +21,30 @@ type Service struct {
 	repository   *Repository
 	blogsService *blogs.Service
 	postsService *posts.Service
+	usersService *users.Service
 
 	MerchantLogin string
 	Password1     string
 	Password2     string
+	Password3     string
 	IsTest        bool
 	TestPassword1 string
 	TestPassword2 string
 }
 
-func NewService(repository *Repository, blogsService *blogs.Service, postsService *posts.Service,
-	MerchantLogin, Password1, Password2, TestPassword1, TestPassword2 string, IsTest bool,
+func NewService(repository *Repository, blogsService *blogs.Service, postsService *posts.Service, usersService *users.Service,
+	MerchantLogin, Password1, Password2, Password3, TestPassword1, TestPassword2 string, IsTest bool,
 	cfgService *configService.ConfigServiceManager) *Service {
 
 	service := Service{
 		repository:    repository,
 		blogsService:  blogsService,
 		postsService:  postsService,
+		usersService:  usersService,
 		MerchantLogin: MerchantLogin,
 		Password1:     Password1,
 		Password2:     Password2,
+		Password3:     Password3,
 		IsTest:        IsTest,
 		TestPassword1: TestPassword1,
 		TestPassword2: TestPassword2,
@@ -65,6 +71,10 @@ func NewService(repository *Repository, blogsService *blogs.Service, postsServic
 		service.Password2 = ss.Value
 	}, Password2ConfigKey)
 
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		service.Password3 = ss.Value
+	}, Password3ConfigKey)
+
 	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
 		service.TestPassword1 = ss.Value
 	}, TestPassword1ConfigKey)
@@ -91,7 +101,8 @@ func (s *Service) ConfirmSignatureValueValid(OutSum, InvId, SignatureValue strin
 	return SignatureValue == CheckSum
 }
 
-func (s *Service) CreateInvoice(ctx context.Context, itemId uuid.UUID, itemType, description string, sum float64, userId uuid.UUID) (string, error) {
+// CreateInvoice creates the invoice with the robokassa payment link
+func (s *Service) CreateInvoice(ctx context.Context, itemId uuid.UUID, itemType, description string, sum float64, userId uuid.UUID) (*Invoice, error) {
 	timeNow := time.Now().UTC()
 	expirationDate := timeNow.Add(1 * time.Hour).UTC()
 	pass := s.Password1
@@ -111,37 +122,233 @@ func (s *Service) CreateInvoice(ctx context.Context, itemId uuid.UUID, itemType,
 
 	err := s.repository.CreateInvoice(ctx, &invoice)
 	if err != nil {
-		return "", fmt.Errorf("fail to create invoice cause %v", err)
+		return nil, fmt.Errorf("fail to create invoice cause %v", err)
 	}
 
-	invoiceResponse, err := ApiCreateInvoice(s.MerchantLogin, description, pass, sum, invoice.ID, expirationDate, s.IsTest)
+	receipt := receipts.NewReceipt(strconv.Itoa(invoice.ID), userId.String(), timeNow, CurrencyRub,
+		[]receipts.Item{{Name: description, Quantity: 1, Price: sum, Sum: sum}})
+	invoiceResponse, err := ApiCreateInvoice(s.MerchantLogin, description, pass, sum, invoice.ID, expirationDate, s.IsTest, &receipt.Fiscal)
 	if err != nil {
 		invoice.Status = InvoiceStatusFailed
 		_ = s.repository.UpdateInvoice(ctx, &invoice)
-		return "", fmt.Errorf("fail to create invoice through robokassa api cause %v", err)
+		return nil, fmt.Errorf("fail to create invoice through robokassa api cause %v", err)
 	}
 
 	fullUrl, err := url.JoinPath(PaymentLinkPrefixUrl, invoiceResponse.InvoiceId)
 	if err != nil {
 		invoice.Status = InvoiceStatusFailed
 		_ = s.repository.UpdateInvoice(ctx, &invoice)
-		return "", fmt.Errorf("fail to join url cause %v", err)
+		return nil, fmt.Errorf("fail to join url cause %v", err)
 	}
 
 	invoice.PaymentLink = fullUrl
-	return fullUrl, s.repository.UpdateInvoice(ctx, &invoice)
+	return &invoice, s.repository.UpdateInvoice(ctx, &invoice)
 }
 
-func (s *Service) GrantItemByType(ctx context.Context, invoice *Invoice) error {
+// GrantItemByType grants the paid item to the user.
+// Returns false if the item can't be granted anymore, like the subscription that became full.
+func (s *Service) GrantItemByType(ctx context.Context, invoice *Invoice) (bool, error) {
 
 	if invoice.ItemType == InvoiceItemTypeSubscription {
-		return s.blogsService.GrantSubscription(invoice.ItemId, invoice.UserId, invoice.OutSum, CurrencyRub)
+		return s.blogsService.GrantSubscription(invoice.ItemId, invoice.UserId, invoice.OutSum, CurrencyRub, invoice.ID)
 	} else if invoice.ItemType == InvoiceItemTypePost {
-		return s.postsService.GrantPostPaidAccess(invoice.ItemId, invoice.UserId, invoice.OutSum, CurrencyRub)
+		return true, s.postsService.GrantPostPaidAccess(invoice.ItemId, invoice.UserId, invoice.OutSum, CurrencyRub, invoice.ID)
 	} else if invoice.ItemType == InvoiceItemTypeDonation {
-		return s.blogsService.ConfirmDonation(invoice.ItemId, invoice.UserId, invoice.OutSum, CurrencyRub)
+		return true, s.blogsService.ConfirmDonation(invoice.ItemId, invoice.UserId, invoice.OutSum, CurrencyRub)
+	} else if invoice.ItemType == InvoiceItemTypeGift {
+		return true, s.blogsService.ConfirmGift(invoice.ItemId, invoice.UserId, invoice.OutSum, CurrencyRub)
+	} else if invoice.ItemType == InvoiceItemTypeTierChange {
+		return true, s.blogsService.ConfirmTierChange(invoice.ItemId, invoice.UserId, invoice.OutSum, CurrencyRub)
 	} else {
-		return fmt.Errorf("unknown item type: %s", invoice.ItemType)
+		return false, fmt.Errorf("unknown item type: %s", invoice.ItemType)
+	}
+
+}
+
+// ungrantedRefundReason is the reason of the refund of the payment for the item that can't be granted
+const ungrantedRefundReason = "paid item is not available anymore"
+
+// RefundUngranted refunds the confirmed invoice of the item that wasn't granted.
+// Nothing was granted, so there is no blog income to reverse and the refund is completed right away.
+func (s *Service) RefundUngranted(ctx context.Context, invoice *Invoice) (*Refund, error) {
+	refund, err := s.Refund(ctx, invoice, 0, ungrantedRefundReason, uuid.Nil)
+	if err != nil || refund == nil {
+		return nil, err
+	}
+
+	refund.Status = RefundStatusCompleted
+	refund.Updated = time.Now().UTC()
+	err = s.repository.UpdateRefund(ctx, refund)
+	if err != nil {
+		return nil, fmt.Errorf("fail to update refund cause %v", err)
+	}
+
+	invoice.Status = InvoiceStatusRefunded
+	invoice.Updated = time.Now().UTC()
+	return refund, s.repository.UpdateInvoice(ctx, invoice)
+}
+
+// refundSumEpsilon is the tolerance used when refunds sum is compared with the invoice sum
+const refundSumEpsilon = 0.001
+
+// Refund refunds the sum of the confirmed invoice through robokassa.
+// The refund is full when it covers the rest of the invoice sum.
+// Returns nil refund if the invoice isn't confirmed or the sum exceeds its not refunded sum.
+// In test mode the provider isn't called, as robokassa doesn't refund test payments.
+func (s *Service) Refund(ctx context.Context, invoice *Invoice, sum float64, reason string, adminId uuid.UUID) (*Refund, error) {
+	timeNow := time.Now().UTC()
+	refund := Refund{
+		InvoiceId: invoice.ID,
+		Value:     sum,
+		Reason:    reason,
+		Status:    RefundStatusNew,
+		CreatedBy: adminId,
+		Created:   timeNow,
+		Updated:   timeNow,
+	}
+	created, err := s.repository.CreateRefundWithinSum(ctx, &refund)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create refund cause %v", err)
+	}
+	if !created {
+		return nil, nil
+	}
+	sum = refund.Value
+
+	if s.IsTest {
+		refund.ProviderRef = fmt.Sprintf("test-%d", refund.ID)
+	} else {
+		var refundResponse *CreateRefundResponse
+		opKey, err := ApiOpKey(s.MerchantLogin, s.Password2, invoice.ID)
+		if err == nil {
+			refundResponse, err = ApiCreateRefund(opKey, s.Password3, sum)
+		}
+		if err != nil {
+			refund.Status = RefundStatusFailed
+			refund.Updated = time.Now().UTC()
+			_ = s.repository.UpdateRefund(ctx, &refund)
+			return nil, fmt.Errorf("fail to create refund through robokassa api cause %v", err)
+		}
+		refund.ProviderRef = refundResponse.RequestId
+	}
+
+	refund.Status = RefundStatusRefunded
+	refund.Updated = time.Now().UTC()
+	return &refund, s.repository.UpdateRefund(ctx, &refund)
+}
+
+// ReverseRefund reverses the blog income of the refunded invoice item. Full refund also revokes the referral
+// reward granted for the invoice and access to the item and marks the invoice as refunded, partial refund
+// only adjusts the income. The reversal is keyed by the refund id and the reward by the invoice,
+// so retries don't reverse them twice.
+func (s *Service) ReverseRefund(ctx context.Context, invoice *Invoice, refund *Refund) error {
+	if refund.IsFull {
+		err := s.usersService.RevokeReferralReward(invoice.ID)
+		if err != nil {
+			return fmt.Errorf("fail to revoke referral reward of refunded invoice cause %v", err)
+		}
+	}
+
+	err := s.blogsService.RefundItem(RefundReversalId(refund.ID), invoice.ItemId, invoice.ItemType, invoice.UserId,
+		refund.Value, CurrencyRub, refund.IsFull)
+	if err != nil {
+		return fmt.Errorf("fail to reverse refunded item cause %v", err)
+	}
+
+	refund.Status = RefundStatusCompleted
+	refund.Updated = time.Now().UTC()
+	err = s.repository.UpdateRefund(ctx, refund)
+	if err != nil {
+		return fmt.Errorf("fail to update refund cause %v", err)
 	}
 
+	if refund.IsFull {
+		invoice.Status = InvoiceStatusRefunded
+		invoice.Updated = time.Now().UTC()
+		return s.repository.UpdateInvoice(ctx, invoice)
+	}
+	return nil
+}
+
+// RefundItemPayment fully refunds the confirmed payment of the item on behalf of a service.
+// The refund that is done but not reversed yet is reversed again instead of a new refund.
+// Returns nil refund if the item has no confirmed payment or nothing is left to refund.
+func (s *Service) RefundItemPayment(ctx context.Context, itemId uuid.UUID, itemType, reason string) (*Refund, error) {
+	invoice, err := s.repository.LatestInvoiceByItemAndStatus(ctx, itemId, itemType, InvoiceStatusConfirmed)
+	if err != nil || invoice == nil {
+		return nil, err
+	}
+
+	refunds, err := s.repository.RefundsByInvoiceId(ctx, invoice.ID)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get refunds of invoice cause %v", err)
+	}
+	for i := range refunds {
+		if refunds[i].Status == RefundStatusRefunded {
+			return &refunds[i], s.ReverseRefund(ctx, invoice, &refunds[i])
+		}
+	}
+
+	refund, err := s.Refund(ctx, invoice, 0, reason, uuid.Nil)
+	if err != nil || refund == nil {
+		return nil, err
+	}
+	return refund, s.ReverseRefund(ctx, invoice, refund)
+}
+
+// RefundReversalId returns the key of the blog income reversal of the refund
+func RefundReversalId(refundId int) string {
+	return fmt.Sprintf("robokassa-%d", refundId)
+}
+
+// PaymentsHistory joins invoices with titles of paid items from posts service
+func (s *Service) PaymentsHistory(invoices []Invoice) ([]PaymentHistoryItem, error) {
+	refs := make([]blogs.PaymentItemRef, len(invoices))
+	for i, invoice := range invoices {
+		refs[i] = blogs.PaymentItemRef{ItemId: invoice.ItemId, ItemType: invoice.ItemType}
+	}
+	infos, err := s.blogsService.PaymentItemsInfo(refs)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]PaymentHistoryItem, len(invoices))
+	for i, invoice := range invoices {
+		result[i] = PaymentHistoryItem{
+			Invoice:   invoice,
+			Title:     infos[i].Title,
+			BlogId:    infos[i].BlogId,
+			BlogTitle: infos[i].BlogTitle,
+		}
+	}
+	return result, nil
+}
+
+func (s *Service) Receipt(item *PaymentHistoryItem) *receipts.Receipt {
+	var name string
+	switch item.ItemType {
+	case InvoiceItemTypeSubscription:
+		name = fmt.Sprintf("Подписка \"%s\" на блог \"%s\"", item.Title, item.BlogTitle)
+	case InvoiceItemTypePost:
+		name = fmt.Sprintf("Доступ к публикации \"%s\" блога \"%s\"", item.Title, item.BlogTitle)
+	case InvoiceItemTypeDonation:
+		name = fmt.Sprintf("Пожертвование для развития блога \"%s\"", item.BlogTitle)
+	case InvoiceItemTypeGift:
+		name = fmt.Sprintf("Подарок \"%s\" блога \"%s\"", item.Title, item.BlogTitle)
+	case InvoiceItemTypeTierChange:
+		name = fmt.Sprintf("Переход на подписку \"%s\" блога \"%s\"", item.Title, item.BlogTitle)
+	default:
+		name = fmt.Sprintf("Оплата (ID: %s)", item.ItemId)
+	}
+	return receipts.NewReceipt(
+		strconv.Itoa(item.ID),
+		item.UserId.String(),
+		item.Updated,
+		CurrencyRub,
+		[]receipts.Item{{
+			Name:     name,
+			Quantity: 1,
+			Price:    item.OutSum,
+			Sum:      item.OutSum,
+		}},
+	)
 }
@@ -3,6 +3,7 @@ package robokassa
 import (
 	serverlogging "billing-service/pkg/serverlogging/gin"
 	"encoding/json"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"time"
@@ -18,6 +19,38 @@ func RegisterServiceHandler(api *gin.RouterGroup, service *Service) {
 	}
 	serviceM := ServiceMiddleware()
 	api.POST("/payment-link", serviceM, h.paymentLink)
+	api.POST("/refunds", serviceM, h.refundItem)
+}
+
+// refundItem refunds the payment of the item that can't be delivered, like the expired gift
+func (h *serviceHandler) refundItem(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req ItemRefundRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+
+	refund, err := h.service.RefundItemPayment(ctx, req.ItemId, req.ItemType, req.Reason)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("fail to refund item payment")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if refund == nil {
+		loggingMap.SetMessage("confirmed payment of item not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	loggingMap.SetMessage("item payment refunded")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, refund)
 }
 
 func (h *serviceHandler) paymentLink(ctx *gin.Context) {
@@ -40,14 +73,14 @@ func (h *serviceHandler) paymentLink(ctx *gin.Context) {
 	}
 
 	if len(invoices) == 0 {
-		link, err := h.service.CreateInvoice(ctx, req.ItemId, req.ItemType, req.Description, req.Sum, req.UserId)
+		invoice, err := h.service.CreateInvoice(ctx, req.ItemId, req.ItemType, req.Description, req.Sum, req.UserId)
 		if err != nil {
 			loggingMap.SetError(err.Error())
 			loggingMap.SetMessage("fail to create invoice")
 			ctx.JSON(http.StatusInternalServerError, nil)
 			return
 		}
-		ctx.JSON(http.StatusOK, PaymentLinkResponse{Url: link})
+		ctx.JSON(http.StatusOK, PaymentLinkResponse{Url: invoice.PaymentLink, InvoiceId: invoice.ID})
 	} else {
 		latestInvoice := invoices[0]
 		timeNow := time.Now().UTC()
@@ -63,26 +96,26 @@ func (h *serviceHandler) paymentLink(ctx *gin.Context) {
 					ctx.JSON(http.StatusInternalServerError, nil)
 					return
 				}
-				link, err := h.service.CreateInvoice(ctx, req.ItemId, req.ItemType, req.Description, req.Sum, req.UserId)
+				invoice, err := h.service.CreateInvoice(ctx, req.ItemId, req.ItemType, req.Description, req.Sum, req.UserId)
 				if err != nil {
 					loggingMap.SetError(err.Error())
 					loggingMap.SetMessage("fail to create invoice")
 					ctx.JSON(http.StatusInternalServerError, nil)
 					return
 				}
-				ctx.JSON(http.StatusOK, PaymentLinkResponse{Url: link})
+				ctx.JSON(http.StatusOK, PaymentLinkResponse{Url: invoice.PaymentLink, InvoiceId: invoice.ID})
 			} else {
-				ctx.JSON(http.StatusOK, PaymentLinkResponse{Url: latestInvoice.PaymentLink})
+				ctx.JSON(http.StatusOK, PaymentLinkResponse{Url: latestInvoice.PaymentLink, InvoiceId: latestInvoice.ID})
 			}
 		} else {
-			link, err := h.service.CreateInvoice(ctx, req.ItemId, req.ItemType, req.Description, req.Sum, req.UserId)
+			invoice, err := h.service.CreateInvoice(ctx, req.ItemId, req.ItemType, req.Description, req.Sum, req.UserId)
 			if err != nil {
 				loggingMap.SetError(err.Error())
 				loggingMap.SetMessage("fail to create invoice")
 				ctx.JSON(http.StatusInternalServerError, nil)
 				return
 			}
-			ctx.JSON(http.StatusOK, PaymentLinkResponse{Url: link})
+			ctx.JSON(http.StatusOK, PaymentLinkResponse{Url: invoice.PaymentLink, InvoiceId: invoice.ID})
 		}
 	}
 }
@@ -12,17 +12,23 @@ type Config struct {
 	ConfigServicePort    string `env:"CONFIG_SERVICE_PORT"`
 	ConfigUpdateInterval int    `env:"CONFIG_UPDATE_INTERVAL" env-default:"60"`
 
+	IdentityAssertionSecret string `config-service:"IDENTITY_ASSERTION_SECRET"`
+
 	BlogsServiceUrl string `config-service:"BLOGS_SERVICE_URL"`
 	PostsServiceUrl string `config-service:"POSTS_SERVICE_URL"`
+	UsersServiceUrl string `config-service:"USERS_SERVICE_URL"`
 
 	RobokassaMerchantLogin string `config-service:"ROBOKASSA_MERCHANT_LOGIN"`
 	RobokassaPassword1     string `config-service:"ROBOKASSA_PASSWORD1"`
 	RobokassaPassword2     string `config-service:"ROBOKASSA_PASSWORD2"`
+	RobokassaPassword3     string `config-service:"ROBOKASSA_PASSWORD3"`
 	RobokassaIsTest        bool   `config-service:"ROBOKASSA_IS_TEST"`
 	RobokassaTestPassword1 string `config-service:"ROBOKASSA_TEST_PASSWORD1"`
 	RobokassaTestPassword2 string `config-service:"ROBOKASSA_TEST_PASSWORD2"`
 
-	CmcrateServiceUrl string `config-service:"CMCRATE_SERVICE_URL"`
+	CmcrateServiceUrl          string `config-service:"CMCRATE_SERVICE_URL"`
+	CurrencyRatePairs          string `config-service:"CURRENCY_RATE_PAIRS"`
+	CurrencyRateUpdateInterval int    `config-service:"CURRENCY_RATE_UPDATE_INTERVAL"`
 
 	DbHost     string `config-service:"DB_HOST"`
 	DbPort     string `config-service:"DB_PORT"`
@@ -5,8 +5,11 @@ import (
 	"billing-service/internal/cmcratefetcher"
 	"billing-service/internal/healthcheck"
 	"billing-service/internal/posts"
+	"billing-service/internal/rates"
 	"billing-service/internal/robokassa"
+	"billing-service/internal/users"
 	"billing-service/pkg/filelogger"
+	requestuser "billing-service/pkg/hidepost-requestuser"
 	"billing-service/pkg/pgutils"
 	"billing-service/pkg/queuelogger"
 	serverlogging "billing-service/pkg/serverlogging/gin"
@@ -70,23 +73,40 @@ func Run() {
 
 	// init repositories
 	robokassaRepo := robokassa.NewRepository(dbConn)
+	ratesRepo := rates.NewRepository(dbConn)
+
+	// identity assertions are verified for every request and signed for requests to other services
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := identitySigner.SetSecret(ss.Value); err != nil {
+			fileLogger.Error("failed to update identity assertion secret from config",
+				map[string]any{"error": err.Error(), "key": ss.Key})
+		}
+	}, requestuser.IdentityAssertionSecretConfigKey)
 
 	// init services
-	blogsService := blogs.NewService(cfg.BlogsServiceUrl, cfgService)
-	postsService := posts.NewService(cfg.PostsServiceUrl, cfgService)
+	blogsService := blogs.NewService(cfg.BlogsServiceUrl, identitySigner, cfgService)
+	postsService := posts.NewService(cfg.PostsServiceUrl, identitySigner, cfgService)
+	usersService := users.NewService(cfg.UsersServiceUrl, identitySigner, cfgService)
 	robokassaService := robokassa.NewService(
 		robokassaRepo,
 		blogsService,
 		postsService,
+		usersService,
 		cfg.RobokassaMerchantLogin,
 		cfg.RobokassaPassword1,
 		cfg.RobokassaPassword2,
+		cfg.RobokassaPassword3,
 		cfg.RobokassaTestPassword1,
 		cfg.RobokassaTestPassword2,
 		cfg.RobokassaIsTest,
 		cfgService,
 	)
 	cmcRateService := cmcratefetcher.NewService(cfg.CmcrateServiceUrl, cfgService)
+	ratesService := rates.NewService(ratesRepo, cmcRateService, cfg.CurrencyRatePairs, cfg.CurrencyRateUpdateInterval, cfgService)
 
 	// setting up gin apps
 	gin.SetMode(gin.ReleaseMode)
@@ -99,6 +119,7 @@ func Run() {
 	app := gin.New()
 	app.Use(recovery)
 	app.Use(requestLogging)
+	app.Use(identitySigner.Middleware())
 
 	apiV1 := app.Group("/api/v1/billing")
 
@@ -108,13 +129,15 @@ func Run() {
 
 	serviceGroup := apiV1.Group("/service")
 	robokassa.RegisterServiceHandler(serviceGroup.Group("/robokassa"), robokassaService)
+	rates.RegisterServiceHandler(serviceGroup.Group("/currency-rates"), ratesService)
 
 	adminGroup := apiV1.Group("/admin")
 	robokassa.RegisterAdminHandler(adminGroup.Group("/robokassa"), robokassaService)
+	rates.RegisterAdminHandler(adminGroup.Group("/currency-rates"), ratesService)
 	healthcheck.RegisterHealthcheckHandler(adminGroup.Group("/healthcheck"))
 
 	rateGroup := apiV1.Group("/currency-rates")
-	cmcratefetcher.RegisterTonPriceHandler(rateGroup.Group("/toncoin"), cmcRateService)
+	rates.RegisterRatesHandler(rateGroup, ratesService)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
@@ -138,6 +161,7 @@ func Run() {
 	log.Println("Graceful shutdown, timeout of 5 seconds ...")
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
+	ratesService.StopWorkers()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server Shutdown error:", err)
 	}
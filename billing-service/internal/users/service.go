@@ -0,0 +1,94 @@
+package users
+
+import (
+	requestuser "billing-service/pkg/hidepost-requestuser"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Service struct {
+	BaseUrl string
+	signer  *requestuser.Signer
+}
+
+var RefereeFirstPaymentPath = "/referrals/referee/id/%s/first-payment"
+var RevokeReferralRewardPath = "/referrals/invoice/id/%d/refund"
+
+func NewService(serviceUrl string, signer *requestuser.Signer, cfgService *configService.ConfigServiceManager) *Service {
+	service := &Service{BaseUrl: serviceUrl, signer: signer}
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		service.BaseUrl = ss.Value
+	}, "USERS_SERVICE_URL")
+	return service
+}
+
+type FirstPaymentServiceRequest struct {
+	InvoiceId int `json:"invoice_id"`
+}
+
+// RefereeFirstPayment reports the confirmed payment of the user, users service grants the reward
+// to the referrer of the user once, so the call is repeated safely for every payment.
+// The invoice is kept with the reward, so the reward is revoked if the invoice is refunded.
+func (s *Service) RefereeFirstPayment(userId uuid.UUID, invoiceId int) error {
+	body, err := json.Marshal(FirstPaymentServiceRequest{InvoiceId: invoiceId})
+	if err != nil {
+		return fmt.Errorf("fail to marshal request body cause %v", err)
+	}
+
+	reqUrl, err := url.JoinPath(s.BaseUrl, fmt.Sprintf(RefereeFirstPaymentPath, userId))
+	if err != nil {
+		return fmt.Errorf("fail to join url cause %v", err)
+	}
+
+	req, err := http.NewRequest("PUT", reqUrl, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("fail to create request cause %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// RevokeReferralReward takes back the referral reward granted for the refunded invoice,
+// it does nothing if the invoice wasn't rewarded, so the call is repeated safely for every refund
+func (s *Service) RevokeReferralReward(invoiceId int) error {
+	reqUrl, err := url.JoinPath(s.BaseUrl, fmt.Sprintf(RevokeReferralRewardPath, invoiceId))
+	if err != nil {
+		return fmt.Errorf("fail to join url cause %v", err)
+	}
+
+	req, err := http.NewRequest("PUT", reqUrl, nil)
+	if err != nil {
+		return fmt.Errorf("fail to create request cause %v", err)
+	}
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
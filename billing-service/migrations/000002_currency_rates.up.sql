@@ -0,0 +1,12 @@
+create table currency_rates
+(
+    id         serial primary key not null,
+    base       text               not null,
+    quote      text               not null,
+    value      double precision   not null,
+    source     text               not null,
+    fetched_at timestamp          not null,
+    created    timestamp          not null default current_timestamp
+);
+
+create index currency_rates_pair_fetched_at_idx on currency_rates (base, quote, fetched_at);
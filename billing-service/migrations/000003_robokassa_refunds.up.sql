@@ -0,0 +1,15 @@
+create table robokassa_refunds
+(
+    id           serial primary key not null,
+    invoice_id   int                not null references robokassa_invoices (id),
+    value        numeric(10, 2)     not null,
+    is_full      boolean            not null default false,
+    reason       text               not null default '',
+    status       text               not null,
+    provider_ref text               not null default '',
+    created_by   uuid               not null,
+    created      timestamp          not null,
+    updated      timestamp          not null
+);
+
+create index robokassa_refunds_invoice_id_idx on robokassa_refunds (invoice_id);
@@ -12,6 +12,8 @@ type Config struct {
 	ConfigServicePort    string `env:"CONFIG_SERVICE_PORT"`
 	ConfigUpdateInterval int    `env:"CONFIG_UPDATE_INTERVAL" env-default:"60"`
 
+	IdentityAssertionSecret string `config-service:"IDENTITY_ASSERTION_SECRET"`
+
 	DbHost     string `config-service:"DB_HOST"`
 	DbPort     string `config-service:"DB_PORT"`
 	DbUser     string `config-service:"DB_USER"`
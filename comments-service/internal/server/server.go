@@ -3,6 +3,7 @@ package server
 import (
 	"comments-service/internal/comments"
 	"comments-service/pkg/filelogger"
+	requestuser "comments-service/pkg/hidepost-requestuser"
 	"comments-service/pkg/pgutils"
 	"comments-service/pkg/queuelogger"
 	serverlogging "comments-service/pkg/serverlogging/gin"
@@ -70,11 +71,24 @@ func Run() {
 	// init services
 	commentsService := comments.NewService(commentRepo)
 
+	// identity assertions are verified for every request and signed for requests to other services
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := identitySigner.SetSecret(ss.Value); err != nil {
+			fileLogger.Error("failed to update identity assertion secret from config",
+				map[string]any{"error": err.Error(), "key": ss.Key})
+		}
+	}, requestuser.IdentityAssertionSecretConfigKey)
+
 	// setting up gin app
 	gin.SetMode(gin.ReleaseMode)
 	app := gin.New()
 	app.Use(gin.CustomRecovery(serverlogging.NewPanicLogger(fileLogger, mqLogger)))
 	app.Use(serverlogging.NewRequestLogger(fileLogger, mqLogger))
+	app.Use(identitySigner.Middleware())
 
 	// init handlers
 	apiV1 := app.Group("/api/v1/comments")
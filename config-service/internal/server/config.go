@@ -2,8 +2,11 @@ package server
 
 import (
 	configservice "config-service/internal/config-service"
+	"config-service/pkg/filelogger"
+	requestuser "config-service/pkg/hidepost-requestuser"
 	"context"
 	"fmt"
+	"time"
 )
 
 type Config struct {
@@ -22,6 +25,8 @@ type Config struct {
 	MQPassword string
 	MQPort     string
 	MQUser     string
+
+	IdentityAssertionSecret string
 }
 
 func (cfg *Config) DbUrl() string {
@@ -51,7 +56,32 @@ func (cfg *Config) UpdateConfigFromService(ctx context.Context, service *configs
 			cfg.MQPassword = s.Value
 		case "LOG_QUEUE":
 			cfg.LogQueue = s.Value
+		case requestuser.IdentityAssertionSecretConfigKey:
+			cfg.IdentityAssertionSecret = s.Value
 		}
 	}
 	return nil
 }
+
+// StartIdentitySecretReloader sets the secret saved in db to the signer on every tick, the config service
+// reads its settings from db directly and doesn't get setting updates like other services
+func (cfg *Config) StartIdentitySecretReloader(ctx context.Context, service *configservice.Service,
+	signer *requestuser.Signer, fileLogger *filelogger.FileLogger, ticker *time.Ticker) {
+	for range ticker.C {
+		settings, err := service.SettingsByService(ctx, cfg.ServiceName)
+		if err != nil {
+			fileLogger.Error("failed to get identity assertion secret from db",
+				map[string]any{"error": err.Error()})
+			continue
+		}
+		for _, s := range settings {
+			if s.Key != requestuser.IdentityAssertionSecretConfigKey {
+				continue
+			}
+			if err = signer.SetSecret(s.Value); err != nil {
+				fileLogger.Error("failed to update identity assertion secret from db",
+					map[string]any{"error": err.Error(), "key": s.Key})
+			}
+		}
+	}
+}
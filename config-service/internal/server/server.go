@@ -1,7 +1,9 @@
 package server
 
 import (
+	configservice "config-service/internal/config-service"
 	"config-service/pkg/filelogger"
+	requestuser "config-service/pkg/hidepost-requestuser"
 	"config-service/pkg/pgutils"
 	"config-service/pkg/queuelogger"
 	serverlogging "config-service/pkg/serverlogging/gin"
@@ -64,11 +66,19 @@ func Run() {
 	}
 	defer mqLogger.Close()
 
+	// identity assertions are verified for every request, the secret is reloaded from db
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	go cfg.StartIdentitySecretReloader(ctx, settingsService, identitySigner, fileLogger, time.NewTicker(time.Minute))
+
 	// setting up gin app
 	gin.SetMode(gin.ReleaseMode)
 	app := gin.New()
 	app.Use(gin.CustomRecovery(serverlogging.NewPanicLogger(fileLogger, mqLogger)))
 	app.Use(serverlogging.NewRequestLogger(fileLogger, mqLogger))
+	app.Use(identitySigner.Middleware())
 
 	// setting up routes
 	apiV1 := app.Group("/api/v1/config")
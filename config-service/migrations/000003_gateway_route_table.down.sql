@@ -0,0 +1 @@
+delete from settings_items where service = 'api_gateway' and key = 'ROUTES';
@@ -0,0 +1,16 @@
+insert into settings_items(service, key, value)
+values ('api_gateway', 'ROUTES', '[
+  {"prefix": "/api/v1/auth", "service": "AUTHENTICATION_SERVICE", "auth": false},
+  {"prefix": "/api/v1/config", "service": "config", "upstreams": ["config_service:8000"], "auth": true},
+  {"prefix": "/api/v1/logs-consumer", "service": "history_logs_consumer", "upstreams": ["history_logs_consumer:8000"], "auth": true},
+  {"prefix": "/api/v1/logs", "service": "history_logs", "upstreams": ["history_logs_service:8000"], "auth": true},
+  {"prefix": "/api/v1/users", "service": "users", "upstreams": ["users_service:8000"], "auth": true},
+  {"prefix": "/api/v1/registration", "service": "registration", "upstreams": ["registration_service:8000"], "auth": false},
+  {"prefix": "/api/v1/email", "service": "email", "upstreams": ["email_service:8000"], "auth": true},
+  {"prefix": "/api/v1/blogs", "service": "posts", "upstreams": ["posts_service:8000"], "auth": true},
+  {"prefix": "/api/v1/posts", "service": "posts", "upstreams": ["posts_service:8000"], "auth": true},
+  {"prefix": "/api/v1/comments", "service": "comments", "upstreams": ["comments_service:8000"], "auth": true},
+  {"prefix": "/api/v1/billing", "service": "billing", "upstreams": ["billing_service:8000"], "auth": true},
+  {"prefix": "/api/v1/notifications", "service": "notifications", "upstreams": ["notifications_service:8000"], "auth": true}
+]')
+on conflict (service, key) do nothing;
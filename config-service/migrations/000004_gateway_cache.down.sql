@@ -0,0 +1,7 @@
+update settings_items
+set value = (select jsonb_pretty(jsonb_agg(route - 'cache' order by position))
+             from jsonb_array_elements(value::jsonb) with ordinality as routes(route, position))
+where service = 'api_gateway'
+  and key = 'ROUTES';
+
+delete from settings_items where service = 'api_gateway' and key = 'CACHE_MAX_SIZE';
@@ -0,0 +1,12 @@
+insert into settings_items(service, key, value)
+values ('api_gateway', 'CACHE_MAX_SIZE', '67108864')
+on conflict (service, key) do nothing;
+
+update settings_items
+set value = (select jsonb_pretty(jsonb_agg(case
+                                              when route ->> 'prefix' in ('/api/v1/blogs', '/api/v1/posts')
+                                                  then route || '{"cache": true}'
+                                              else route end order by position))
+             from jsonb_array_elements(value::jsonb) with ordinality as routes(route, position))
+where service = 'api_gateway'
+  and key = 'ROUTES';
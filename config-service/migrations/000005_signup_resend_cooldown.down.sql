@@ -0,0 +1 @@
+delete from settings_items where service = 'registration_service' and key = 'SIGNUP_RESEND_COOLDOWN';
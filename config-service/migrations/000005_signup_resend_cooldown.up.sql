@@ -0,0 +1,3 @@
+insert into settings_items(service, key, value)
+values ('registration_service', 'SIGNUP_RESEND_COOLDOWN', '60')
+on conflict (service, key) do nothing;
@@ -0,0 +1,2 @@
+delete from settings_items where service = 'users_service' and key = 'REFERRAL_REWARD_RUB';
+delete from settings_items where service = 'registration_service' and key = 'INVITE_ONLY';
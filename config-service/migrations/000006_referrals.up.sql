@@ -0,0 +1,4 @@
+insert into settings_items(service, key, value)
+values ('users_service', 'REFERRAL_REWARD_RUB', '100'),
+       ('registration_service', 'INVITE_ONLY', 'false')
+on conflict (service, key) do nothing;
@@ -0,0 +1,12 @@
+update settings_items
+set value = (select jsonb_pretty(jsonb_agg(route order by position))
+             from jsonb_array_elements(value::jsonb) with ordinality as routes(route, position)
+             where route ->> 'prefix' <> '/api/v1/registration/admin')
+where service = 'api_gateway'
+  and key = 'ROUTES';
+
+delete from settings_items
+where service = 'registration_service'
+  and key in ('SIGNUP_BLOCK_DISPOSABLE_EMAILS', 'SIGNUP_DISPOSABLE_DOMAINS', 'SIGNUP_RESERVED_LOGINS',
+              'SIGNUP_OFFENSIVE_LOGIN_WORDS', 'SIGNUP_CONFUSABLE_LOGIN_CHECK', 'SIGNUP_IP_LIMIT', 'SIGNUP_IP_WINDOW',
+              'SIGNUP_POW_DIFFICULTY', 'SIGNUP_POW_LIFETIME');
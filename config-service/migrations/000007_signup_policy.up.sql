@@ -0,0 +1,18 @@
+insert into settings_items(service, key, value)
+values ('registration_service', 'SIGNUP_BLOCK_DISPOSABLE_EMAILS', 'true'),
+       ('registration_service', 'SIGNUP_DISPOSABLE_DOMAINS', ''),
+       ('registration_service', 'SIGNUP_RESERVED_LOGINS', ''),
+       ('registration_service', 'SIGNUP_OFFENSIVE_LOGIN_WORDS', ''),
+       ('registration_service', 'SIGNUP_CONFUSABLE_LOGIN_CHECK', 'true'),
+       ('registration_service', 'SIGNUP_IP_LIMIT', '5'),
+       ('registration_service', 'SIGNUP_IP_WINDOW', '60'),
+       ('registration_service', 'SIGNUP_POW_DIFFICULTY', '0'),
+       ('registration_service', 'SIGNUP_POW_LIFETIME', '300')
+on conflict (service, key) do nothing;
+
+-- admin endpoints of registration service need the identity of the request user
+update settings_items
+set value = jsonb_pretty(value::jsonb || '[{"prefix": "/api/v1/registration/admin", "service": "registration", "upstreams": ["registration_service:8000"], "auth": true, "roles": ["admin"]}]'::jsonb)
+where service = 'api_gateway'
+  and key = 'ROUTES'
+  and not value::jsonb @> '[{"prefix": "/api/v1/registration/admin"}]'::jsonb;
@@ -0,0 +1,2 @@
+delete from settings_items
+where key = 'IDENTITY_ASSERTION_SECRET';
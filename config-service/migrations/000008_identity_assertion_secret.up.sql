@@ -0,0 +1,21 @@
+insert into settings_services(service)
+values ('comments_service'),
+       ('billing_service'),
+       ('notifications_service')
+on conflict (service) do nothing;
+
+-- the secret shared by the gateway and services is random for every deployment
+insert into settings_items(service, key, value)
+select services.service, 'IDENTITY_ASSERTION_SECRET', secret.value
+from (values ('api_gateway'),
+             ('authentication_service'),
+             ('config_service'),
+             ('users_service'),
+             ('registration_service'),
+             ('posts_service'),
+             ('comments_service'),
+             ('billing_service'),
+             ('notifications_service'),
+             ('history_logs_service')) as services(service),
+     (select md5(random()::text) || md5(random()::text) as value) as secret
+on conflict (service, key) do nothing;
@@ -0,0 +1,3 @@
+delete from settings_items
+where service = 'authentication_service'
+  and key in ('PASSWORD_RESET_EMAIL_LIMIT', 'PASSWORD_RESET_IP_LIMIT');
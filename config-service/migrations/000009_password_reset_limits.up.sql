@@ -0,0 +1,4 @@
+insert into settings_items(service, key, value)
+values ('authentication_service', 'PASSWORD_RESET_EMAIL_LIMIT', '3'),
+       ('authentication_service', 'PASSWORD_RESET_IP_LIMIT', '10')
+on conflict (service, key) do nothing;
@@ -10,6 +10,7 @@ import (
 	"log"
 	"logs-service/internal/historylogs"
 	"logs-service/pkg/filelogger"
+	requestuser "logs-service/pkg/hidepost-requestuser"
 	"logs-service/pkg/pgutils"
 	"logs-service/pkg/queuelogger"
 	serverlogging "logs-service/pkg/serverlogging/gin"
@@ -63,11 +64,24 @@ func Run() {
 	// init services
 	historyLogsService := historylogs.NewService(historyLogsRepository)
 
+	// identity assertions are verified for every request and signed for requests to other services
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := identitySigner.SetSecret(ss.Value); err != nil {
+			fileLogger.Error("failed to update identity assertion secret from config",
+				map[string]any{"error": err.Error(), "key": ss.Key})
+		}
+	}, requestuser.IdentityAssertionSecretConfigKey)
+
 	// setting up gin app
 	gin.SetMode(gin.ReleaseMode)
 	app := gin.New()
 	app.Use(gin.CustomRecovery(serverlogging.NewPanicLogger(fileLogger, queuelogger.Mock{})))
 	app.Use(serverlogging.NewRequestLogger(fileLogger, queuelogger.Mock{}))
+	app.Use(identitySigner.Middleware())
 
 	// init handlers
 	historylogs.RegisterAdminHandler(app.Group("/api/v1/logs/admin"), historyLogsService)
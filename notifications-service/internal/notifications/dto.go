@@ -61,3 +61,129 @@ type DonationUserEventData struct {
 	PaymentValue    float64   `json:"payment_value" validate:"required"`
 	PaymentCurrency string    `json:"payment_currency" validate:"required"`
 }
+
+type PayoutRequestedEventData struct {
+	At       time.Time `json:"at" validate:"required"`
+	PayoutId string    `json:"payout_id" validate:"required"`
+	Value    float64   `json:"value" validate:"required"`
+	Currency string    `json:"currency" validate:"required"`
+}
+
+type PayoutCompletedEventData struct {
+	At       time.Time `json:"at" validate:"required"`
+	PayoutId string    `json:"payout_id" validate:"required"`
+	Value    float64   `json:"value" validate:"required"`
+	Currency string    `json:"currency" validate:"required"`
+}
+
+type PayoutRejectedEventData struct {
+	At       time.Time `json:"at" validate:"required"`
+	PayoutId string    `json:"payout_id" validate:"required"`
+	Value    float64   `json:"value" validate:"required"`
+	Currency string    `json:"currency" validate:"required"`
+	Reason   string    `json:"reason"`
+}
+
+type PayoutFailedEventData struct {
+	At       time.Time `json:"at" validate:"required"`
+	PayoutId string    `json:"payout_id" validate:"required"`
+	Value    float64   `json:"value" validate:"required"`
+	Currency string    `json:"currency" validate:"required"`
+}
+
+type RefundAuthorEventData struct {
+	At             time.Time `json:"at" validate:"required"`
+	BlogId         string    `json:"blog_id" validate:"required"`
+	ItemId         string    `json:"item_id" validate:"required"`
+	ItemType       string    `json:"item_type" validate:"required"`
+	FromUserId     string    `json:"from_user_id" validate:"required"`
+	RefundValue    float64   `json:"refund_value" validate:"required"`
+	RefundCurrency string    `json:"refund_currency" validate:"required"`
+}
+
+type RefundUserEventData struct {
+	At             time.Time `json:"at" validate:"required"`
+	BlogId         string    `json:"blog_id" validate:"required"`
+	ItemId         string    `json:"item_id" validate:"required"`
+	ItemType       string    `json:"item_type" validate:"required"`
+	RefundValue    float64   `json:"refund_value" validate:"required"`
+	RefundCurrency string    `json:"refund_currency" validate:"required"`
+	AccessRevoked  bool      `json:"access_revoked"`
+}
+
+type SubscriptionTrialEndedEventData struct {
+	At             time.Time `json:"at" validate:"required"`
+	BlogId         string    `json:"blog_id" validate:"required"`
+	SubscriptionId string    `json:"subscription_id" validate:"required"`
+	PriceRub       float64   `json:"price_rub" validate:"required"`
+	PaymentLink    string    `json:"payment_link" validate:"required"`
+}
+
+type GiftPurchasedEventData struct {
+	At              time.Time `json:"at" validate:"required"`
+	BlogId          string    `json:"blog_id" validate:"required"`
+	GiftId          string    `json:"gift_id" validate:"required"`
+	ItemId          string    `json:"item_id" validate:"required"`
+	ItemType        string    `json:"item_type" validate:"required"`
+	Code            string    `json:"code" validate:"required"`
+	PaymentValue    float64   `json:"payment_value" validate:"required"`
+	PaymentCurrency string    `json:"payment_currency" validate:"required"`
+	ExpiresAt       time.Time `json:"expires_at" validate:"required"`
+}
+
+type GiftReceivedEventData struct {
+	At         time.Time `json:"at" validate:"required"`
+	BlogId     string    `json:"blog_id" validate:"required"`
+	GiftId     string    `json:"gift_id" validate:"required"`
+	ItemId     string    `json:"item_id" validate:"required"`
+	ItemType   string    `json:"item_type" validate:"required"`
+	FromUserId string    `json:"from_user_id" validate:"required"`
+	Code       string    `json:"code" validate:"required"`
+	ExpiresAt  time.Time `json:"expires_at" validate:"required"`
+}
+
+type GiftRedeemedEventData struct {
+	At         time.Time `json:"at" validate:"required"`
+	BlogId     string    `json:"blog_id" validate:"required"`
+	GiftId     string    `json:"gift_id" validate:"required"`
+	ItemId     string    `json:"item_id" validate:"required"`
+	ItemType   string    `json:"item_type" validate:"required"`
+	RedeemedBy string    `json:"redeemed_by" validate:"required"`
+}
+
+type GiftExpiredEventData struct {
+	At       time.Time `json:"at" validate:"required"`
+	BlogId   string    `json:"blog_id" validate:"required"`
+	GiftId   string    `json:"gift_id" validate:"required"`
+	ItemId   string    `json:"item_id" validate:"required"`
+	ItemType string    `json:"item_type" validate:"required"`
+}
+
+type SubscriptionTierChangedEventData struct {
+	At                 time.Time `json:"at" validate:"required"`
+	BlogId             string    `json:"blog_id" validate:"required"`
+	FromSubscriptionId string    `json:"from_subscription_id" validate:"required"`
+	ToSubscriptionId   string    `json:"to_subscription_id" validate:"required"`
+	Kind               string    `json:"kind" validate:"required,oneof=upgrade downgrade"`
+	PriceRub           float64   `json:"price_rub"`
+	PaymentLink        string    `json:"payment_link"`
+}
+
+type SubscriptionSlotAvailableEventData struct {
+	At             time.Time `json:"at" validate:"required"`
+	BlogId         string    `json:"blog_id" validate:"required"`
+	SubscriptionId string    `json:"subscription_id" validate:"required"`
+}
+
+type PostOpenedEventData struct {
+	At        time.Time `json:"at" validate:"required"`
+	BlogId    string    `json:"blog_id" validate:"required"`
+	PostId    string    `json:"post_id" validate:"required"`
+	PostTitle string    `json:"post_title"`
+}
+
+type AccountLockedEventData struct {
+	At          time.Time `json:"at" validate:"required"`
+	LockedUntil time.Time `json:"locked_until" validate:"required"`
+	IP          string    `json:"ip"`
+}
@@ -25,4 +25,19 @@ const (
 	EventCodePostPaidAccessUser   = "POST_PAID_ACCESS_USER"
 	EventCodeDonationAuthor       = "DONATION_AUTHOR"
 	EventCodeDonationUser         = "DONATION_USER"
+	EventCodePayoutRequested      = "PAYOUT_REQUESTED"
+	EventCodePayoutCompleted      = "PAYOUT_COMPLETED"
+	EventCodePayoutRejected       = "PAYOUT_REJECTED"
+	EventCodePayoutFailed         = "PAYOUT_FAILED"
+	EventCodeRefundAuthor         = "REFUND_AUTHOR"
+	EventCodeRefundUser           = "REFUND_USER"
+	EventCodeTrialEnded           = "SUBSCRIPTION_TRIAL_ENDED"
+	EventCodeGiftPurchased        = "GIFT_PURCHASED"
+	EventCodeGiftReceived         = "GIFT_RECEIVED"
+	EventCodeGiftRedeemed         = "GIFT_REDEEMED"
+	EventCodeGiftExpired          = "GIFT_EXPIRED"
+	EventCodeTierChanged          = "SUBSCRIPTION_TIER_CHANGED"
+	EventCodeSlotAvailable        = "SUBSCRIPTION_SLOT_AVAILABLE"
+	EventCodePostOpened           = "POST_OPENED"
+	EventCodeAccountLocked        = "ACCOUNT_LOCKED"
 )
@@ -44,6 +44,51 @@ func (s *Service) ValidateNotificationData(notification *Notification) error {
 	case EventCodeDonationUser:
 		var data DonationUserEventData
 		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodePayoutRequested:
+		var data PayoutRequestedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodePayoutCompleted:
+		var data PayoutCompletedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodePayoutRejected:
+		var data PayoutRejectedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodePayoutFailed:
+		var data PayoutFailedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeRefundAuthor:
+		var data RefundAuthorEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeRefundUser:
+		var data RefundUserEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeTrialEnded:
+		var data SubscriptionTrialEndedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeGiftPurchased:
+		var data GiftPurchasedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeGiftReceived:
+		var data GiftReceivedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeGiftRedeemed:
+		var data GiftRedeemedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeGiftExpired:
+		var data GiftExpiredEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeTierChanged:
+		var data SubscriptionTierChangedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeSlotAvailable:
+		var data SubscriptionSlotAvailableEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodePostOpened:
+		var data PostOpenedEventData
+		return s.ValidateStructAndWrite(&data, notification)
+	case EventCodeAccountLocked:
+		var data AccountLockedEventData
+		return s.ValidateStructAndWrite(&data, notification)
 	default:
 		return fmt.Errorf("unknown event code: %s", notification.EventCode)
 	}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"notifications-service/internal/notifications"
 	"notifications-service/pkg/filelogger"
+	requestuser "notifications-service/pkg/hidepost-requestuser"
 	"notifications-service/pkg/pgutils"
 	"notifications-service/pkg/queuelogger"
 	serverlogging "notifications-service/pkg/serverlogging/gin"
@@ -90,11 +91,24 @@ func Run() {
 		log.Fatalln("failed to start consumer:", err)
 	}
 
+	// identity assertions are verified for every request and signed for requests to other services
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := identitySigner.SetSecret(ss.Value); err != nil {
+			fileLogger.Error("failed to update identity assertion secret from config",
+				map[string]any{"error": err.Error(), "key": ss.Key})
+		}
+	}, requestuser.IdentityAssertionSecretConfigKey)
+
 	// setting up gin app
 	gin.SetMode(gin.ReleaseMode)
 	app := gin.New()
 	app.Use(gin.CustomRecovery(serverlogging.NewPanicLogger(fileLogger, mqLogger)))
 	app.Use(serverlogging.NewRequestLogger(fileLogger, mqLogger))
+	app.Use(identitySigner.Middleware())
 
 	// init handlers
 	apiV1 := app.Group("/api/v1/notifications")
@@ -9,14 +9,16 @@ import (
 	configService "posts-service/pkg/config-client"
 	requestuser "posts-service/pkg/hidepost-requestuser"
 	"strings"
+	"time"
 )
 
 type Service struct {
 	ServiceUrl string
+	signer     *requestuser.Signer
 }
 
-func NewService(serviceUrl string, cfgService *configService.ConfigServiceManager) *Service {
-	service := Service{ServiceUrl: serviceUrl}
+func NewService(serviceUrl string, signer *requestuser.Signer, cfgService *configService.ConfigServiceManager) *Service {
+	service := Service{ServiceUrl: serviceUrl, signer: signer}
 	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
 		service.ServiceUrl = ss.Value
 	}, "BILLING_SERVICE_URL")
@@ -32,10 +34,20 @@ type RobokassaPaymentLinkRequest struct {
 }
 
 type RobokassaPaymentLinkResponse struct {
-	Url string `json:"url"`
+	Url       string `json:"url"`
+	InvoiceId int    `json:"invoice_id"`
 }
 
 func (s *Service) RobokassaPaymentLink(itemId, userId uuid.UUID, sum float64, itemType, description string) (string, error) {
+	response, err := s.RobokassaPaymentInvoice(itemId, userId, sum, itemType, description)
+	if err != nil {
+		return "", err
+	}
+	return response.Url, nil
+}
+
+// RobokassaPaymentInvoice returns the payment link with the id of its invoice
+func (s *Service) RobokassaPaymentInvoice(itemId, userId uuid.UUID, sum float64, itemType, description string) (*RobokassaPaymentLinkResponse, error) {
 
 	roboUrl, _ := url.JoinPath(s.ServiceUrl, "robokassa/payment-link")
 
@@ -49,32 +61,125 @@ func (s *Service) RobokassaPaymentLink(itemId, userId uuid.UUID, sum float64, it
 
 	body, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("fail to marshal request body cause %v", err)
+		return nil, fmt.Errorf("fail to marshal request body cause %v", err)
 	}
 
 	req, err := http.NewRequest("POST", roboUrl, strings.NewReader(string(body)))
 	if err != nil {
-		return "", fmt.Errorf("fail to create request cause %v", err)
+		return nil, fmt.Errorf("fail to create request cause %v", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set(requestuser.UserRoleHeaderKey, requestuser.UserRoleService)
+	s.signer.SignServiceRequest(req)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("fail to send request cause %v", err)
+		return nil, fmt.Errorf("fail to send request cause %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
 	}
 
 	var response RobokassaPaymentLinkResponse
 
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
-		return "", fmt.Errorf("fail to unmarshal response body cause %v", err)
+		return nil, fmt.Errorf("fail to unmarshal response body cause %v", err)
 	}
 
-	return response.Url, nil
+	return &response, nil
+}
+
+type RefundItemRequest struct {
+	ItemId   uuid.UUID `json:"item_id"`
+	ItemType string    `json:"item_type"`
+	Reason   string    `json:"reason"`
+}
+
+// RefundItem fully refunds the robokassa payment of the item.
+// Returns false if the item has no confirmed payment.
+func (s *Service) RefundItem(itemId uuid.UUID, itemType, reason string) (bool, error) {
+
+	refundUrl, _ := url.JoinPath(s.ServiceUrl, "robokassa/refunds")
+
+	body, err := json.Marshal(RefundItemRequest{ItemId: itemId, ItemType: itemType, Reason: reason})
+	if err != nil {
+		return false, fmt.Errorf("fail to marshal request body cause %v", err)
+	}
+
+	req, err := http.NewRequest("POST", refundUrl, strings.NewReader(string(body)))
+	if err != nil {
+		return false, fmt.Errorf("fail to create request cause %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+	return true, nil
+}
+
+type ConvertItem struct {
+	Value    float64   `json:"value"`
+	Currency string    `json:"currency"`
+	At       time.Time `json:"at"`
+}
+
+type ConvertRequest struct {
+	To    string        `json:"to"`
+	Items []ConvertItem `json:"items"`
+}
+
+type ConvertResponse struct {
+	Values []float64 `json:"values"`
+}
+
+// Convert converts every item to the currency at the rate in effect at the item time
+func (s *Service) Convert(items []ConvertItem, to string) ([]float64, error) {
+
+	convertUrl, _ := url.JoinPath(s.ServiceUrl, "currency-rates/convert")
+
+	body, err := json.Marshal(ConvertRequest{To: to, Items: items})
+	if err != nil {
+		return nil, fmt.Errorf("fail to marshal request body cause %v", err)
+	}
+
+	req, err := http.NewRequest("POST", convertUrl, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request cause %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	var response ConvertResponse
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("fail to unmarshal response body cause %v", err)
+	}
+	if len(response.Values) != len(items) {
+		return nil, fmt.Errorf("unexpected converted values count: %d", len(response.Values))
+	}
+
+	return response.Values, nil
 }
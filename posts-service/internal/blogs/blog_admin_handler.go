@@ -0,0 +1,145 @@
+package blogs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	serverlogging "posts-service/pkg/serverlogging/gin"
+	"time"
+)
+
+type blogAdminHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterBlogAdminHandler(api *gin.RouterGroup, service *Service) {
+	h := &blogAdminHandler{
+		service:  service,
+		validate: NewValidator(),
+	}
+	api.Use(AdminMiddleware())
+	api.GET("/toncoin-transfers", h.toncoinTransfers)
+	api.PUT("/toncoin-transfers/:hash/resolve", h.resolveToncoinTransfer)
+}
+
+func (h *blogAdminHandler) toncoinTransfers(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	status := ctx.DefaultQuery("status", ToncoinTransferStatusUnmatched)
+	loggingMap["status_query"] = status
+
+	transfers, err := h.service.repository.ToncoinTransfersByStatus(ctx, status)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get toncoin transfers")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, transfers)
+}
+
+// resolveToncoinTransfer confirms the donation chosen by admin with the unmatched transfer,
+// or marks the transfer as ignored if no donation is given
+func (h *blogAdminHandler) resolveToncoinTransfer(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	hash := ctx.Param("hash")
+	loggingMap["hash_param"] = hash
+
+	var req ResolveToncoinTransferRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+
+	transfer, err := h.service.repository.ToncoinTransferByHash(ctx, hash)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get toncoin transfer")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if transfer == nil {
+		loggingMap.SetMessage("toncoin transfer not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if transfer.Status != ToncoinTransferStatusUnmatched {
+		loggingMap.SetMessage("toncoin transfer is already resolved")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	transfer.Updated = time.Now().UTC()
+	if req.DonationId == nil {
+		transfer.Status = ToncoinTransferStatusIgnored
+		err = h.service.repository.UpdateToncoinTransfer(ctx, transfer)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to update toncoin transfer")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		loggingMap.SetMessage("toncoin transfer ignored")
+		loggingMap.Info()
+		ctx.JSON(http.StatusOK, transfer)
+		return
+	}
+
+	donation, err := h.service.repository.DonationById(ctx, *req.DonationId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get donation by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if donation == nil || donation.Currency != CurrencyTon {
+		loggingMap.SetMessage("toncoin donation not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if donation.Status != DonationStatusNew {
+		loggingMap.SetMessage("donation is already confirmed")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	blog, err := h.service.repository.BlogById(ctx, donation.BlogId)
+	if err != nil || blog == nil {
+		if err != nil {
+			loggingMap.SetError(err.Error())
+		}
+		loggingMap.SetMessage("failed to get blog")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if blog.AuthorId != transfer.AuthorId {
+		loggingMap.SetMessage("toncoin donation of the transfer author not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	transfer.Status = ToncoinTransferStatusResolved
+	confirmed, err := h.service.ConfirmToncoinDonation(ctx, blog, donation, transfer)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to confirm donation")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !confirmed {
+		loggingMap.SetMessage("donation is already confirmed")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	loggingMap.SetMessage("toncoin transfer resolved")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, transfer)
+}
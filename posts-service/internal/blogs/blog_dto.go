@@ -1,6 +1,9 @@
 package blogs
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"time"
+)
 
 type BlogUpdateRequest struct {
 	Title            string   `json:"title" validate:"required,min=2,max=50"`
@@ -34,10 +37,13 @@ type BlogUpdateStatusRequest struct {
 }
 
 type SubscriptionUpdateInfoRequest struct {
-	Title            string  `json:"title"`
-	ShortDescription string  `json:"short_description"`
-	PriceRub         float64 `json:"price_rub"`
-	IsActive         bool    `json:"is_active"`
+	Title            string             `json:"title"`
+	ShortDescription string             `json:"short_description"`
+	PriceRub         float64            `json:"price_rub"`
+	TrialDays        int                `json:"trial_days" validate:"min=0,max=90"`
+	Perks            []SubscriptionPerk `json:"perks" validate:"max=20,dive"`
+	MaxSubscribers   *int               `json:"max_subscribers" validate:"omitempty,gt=0"`
+	IsActive         bool               `json:"is_active"`
 }
 
 //type GrantSubscriptionServiceRequest struct {
@@ -57,10 +63,12 @@ type UpdateGoalRequest struct {
 }
 
 type BlogStatsResponse struct {
-	CommentsCount        int `json:"comments_count"`
-	PostsCount           int `json:"posts_count"`
-	FollowersCount       int `json:"followers_count"`
-	PaidSubscribersCount int `json:"paid_subscribers_count"`
+	CommentsCount         int `json:"comments_count"`
+	PostsCount            int `json:"posts_count"`
+	FollowersCount        int `json:"followers_count"`
+	PaidSubscribersCount  int `json:"paid_subscribers_count"`
+	TrialSubscribersCount int `json:"trial_subscribers_count"`
+	PromoCodeUsesCount    int `json:"promo_code_uses_count"`
 }
 
 //type ConfirmDonationServiceRequest struct {
@@ -73,9 +81,113 @@ type MakeDonationRequest struct {
 	Comment string  `json:"comment"`
 }
 
-type GrantItemServiceRequest struct {
+type MakeDonationToncoinResponse struct {
+	DonationId uuid.UUID `json:"donation_id"`
+	Address    string    `json:"address"`
+	Value      float64   `json:"value"`
+	Memo       string    `json:"memo"`
+}
+
+type ResolveToncoinTransferRequest struct {
+	DonationId *uuid.UUID `json:"donation_id"`
+}
+
+type BlogIncomeSummaryResponse struct {
+	TotalRub   float64            `json:"total_rub"`
+	ByCurrency map[string]float64 `json:"by_currency"`
+}
+
+type PaymentItemRef struct {
 	ItemId   uuid.UUID `json:"item_id" validate:"required"`
+	ItemType string    `json:"item_type" validate:"required"`
+}
+
+type PaymentItemsInfoServiceRequest struct {
+	Items []PaymentItemRef `json:"items" validate:"max=100,dive"`
+}
+
+type PaymentItemInfo struct {
+	ItemId    uuid.UUID  `json:"item_id"`
+	ItemType  string     `json:"item_type"`
+	Title     string     `json:"title"`
+	BlogId    *uuid.UUID `json:"blog_id"`
+	BlogTitle string     `json:"blog_title"`
+}
+
+type RefundItemServiceRequest struct {
+	RefundId string    `json:"refund_id" validate:"required,max=100"`
+	ItemId   uuid.UUID `json:"item_id" validate:"required"`
+	ItemType string    `json:"item_type" validate:"required,oneof=subscription post donation gift tier_change"`
 	UserId   uuid.UUID `json:"user_id" validate:"required"`
-	Value    float64   `json:"value" validate:"required"`
+	Value    float64   `json:"value" validate:"required,gt=0"`
 	Currency string    `json:"currency" validate:"required"`
+	Full     bool      `json:"full"`
+}
+
+type GrantItemServiceRequest struct {
+	ItemId    uuid.UUID `json:"item_id" validate:"required"`
+	UserId    uuid.UUID `json:"user_id" validate:"required"`
+	Value     float64   `json:"value" validate:"required"`
+	Currency  string    `json:"currency" validate:"required"`
+	InvoiceId int       `json:"invoice_id"`
+}
+
+type CreatePromoCodeRequest struct {
+	Code          string      `json:"code" validate:"required,alphanum,min=3,max=32"`
+	DiscountType  string      `json:"discount_type" validate:"required,oneof=percent fixed"`
+	DiscountValue float64     `json:"discount_value" validate:"required,gt=0"`
+	MaxUses       *int        `json:"max_uses" validate:"omitempty,gt=0"`
+	ItemIds       []uuid.UUID `json:"item_ids"`
+	ExpiresAt     *time.Time  `json:"expires_at"`
+}
+
+type UpdatePromoCodeRequest struct {
+	DiscountType  string      `json:"discount_type" validate:"required,oneof=percent fixed"`
+	DiscountValue float64     `json:"discount_value" validate:"required,gt=0"`
+	MaxUses       *int        `json:"max_uses" validate:"omitempty,gt=0"`
+	ItemIds       []uuid.UUID `json:"item_ids"`
+	ExpiresAt     *time.Time  `json:"expires_at"`
+	IsActive      bool        `json:"is_active"`
+}
+
+type PromoCodeStatsResponse struct {
+	PromoCode
+	UsesCount   int     `json:"uses_count"`
+	DiscountRub float64 `json:"discount_rub"`
+}
+
+type PromoCodeCheckResponse struct {
+	Code        string  `json:"code"`
+	PriceRub    float64 `json:"price_rub"`
+	DiscountRub float64 `json:"discount_rub"`
+	TotalRub    float64 `json:"total_rub"`
+}
+
+type CreateGiftRequest struct {
+	ItemId         uuid.UUID `json:"item_id" validate:"required"`
+	ItemType       string    `json:"item_type" validate:"required,oneof=subscription post"`
+	RecipientLogin string    `json:"recipient_login"`
+}
+
+type CreateGiftResponse struct {
+	GiftId      uuid.UUID `json:"gift_id"`
+	PaymentLink string    `json:"payment_link"`
+}
+
+type RedeemGiftRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type ChangeSubscriptionTierRequest struct {
+	FromSubscriptionId uuid.UUID `json:"from_subscription_id" validate:"required"`
+}
+
+type ChangeSubscriptionTierResponse struct {
+	TierChange  *SubscriptionTierChange `json:"tier_change"`
+	PaymentLink string                  `json:"payment_link"`
+}
+
+type WaitlistEntryResponse struct {
+	SubscriptionWaitlistEntry
+	Position int `json:"position"`
 }
@@ -47,6 +47,7 @@ func RegisterBlogHandler(api *gin.RouterGroup, service *Service) {
 	api.GET("/id/:id/stats", h.stats)
 
 	api.GET("/id/:id/income", h.getIncome)
+	api.GET("/id/:id/income/summary", userM, h.getIncomeSummary)
 
 	api.GET("/id/:id/goals", h.getGoals)
 	api.POST("/id/:id/goals/new", userM, h.createGoal)
@@ -58,9 +59,15 @@ func RegisterBlogHandler(api *gin.RouterGroup, service *Service) {
 
 	api.GET("/id/:id/subscriptions", h.getBlogSubscriptions)
 	api.GET("/id/:id/subscriptions/my", userM, h.getMyBlogUserSubscriptions)
+	api.GET("/id/:id/subscriptions/tier-changes/my", userM, h.getMyTierChanges)
 	api.POST("/id/:id/subscriptions/new/free", userM, h.createFreeSubscription)
 	api.POST("/id/:id/subscriptions/new/paid", userM, h.createPaidSubscription)
 
+	api.GET("/id/:id/promo-codes", userM, h.getPromoCodes)
+	api.POST("/id/:id/promo-codes/new", userM, h.createPromoCode)
+	api.PUT("/id/:id/promo-codes/id/:code_id", userM, h.updatePromoCode)
+	api.GET("/id/:id/promo-codes/check", userM, h.checkPromoCode)
+
 	api.GET("/id/:id/donations", h.getBlogDonations)
 	api.POST("/id/:id/donate/robokassa", userM, h.makeDonationRobokassa)
 	api.POST("/id/:id/donate/toncoin", userM, h.makeDonationToncoin)
@@ -69,12 +76,23 @@ func RegisterBlogHandler(api *gin.RouterGroup, service *Service) {
 
 	api.GET("/follows/my", userM, h.getMyFollowedBlogs)
 
+	api.POST("/gifts/new", userM, h.createGift)
+	api.GET("/gifts/my", userM, h.getMyGifts)
+	api.GET("/gifts/received", userM, h.getReceivedGifts)
+	api.POST("/gifts/redeem", userM, h.redeemGift)
+
 	api.GET("/subscriptions/my", h.getMyUserSubscriptions)
 	api.GET("/subscriptions/id", h.blogSubscriptionsByIdList)
 
 	api.POST("/subscriptions/id/:id/subscribe", userM, h.subscribe)
 	api.POST("/subscriptions/id/:id/subscribe/free", userM, h.subscribeFree)
 	api.POST("/subscriptions/id/:id/subscribe/robokassa", userM, h.subscribeRobokassa)
+	api.POST("/subscriptions/id/:id/subscribe/trial", userM, h.subscribeTrial)
+	api.POST("/subscriptions/id/:id/change-tier", userM, h.changeSubscriptionTier)
+	api.GET("/subscriptions/id/:id/waitlist/my", userM, h.getMyWaitlistEntry)
+	api.POST("/subscriptions/id/:id/waitlist", userM, h.joinWaitlist)
+	api.DELETE("/subscriptions/id/:id/waitlist", userM, h.leaveWaitlist)
+	api.DELETE("/subscriptions/tier-changes/id/:id", userM, h.cancelTierChange)
 
 	api.PUT("/subscriptions/id/:id/info", userM, h.updateSubscriptionInfo)
 	api.GET("/subscriptions/id/:id/cover", h.getSubscriptionCover)
@@ -98,7 +116,7 @@ func (h *blogHandler) getCategories(ctx *gin.Context) {
 		return
 	}
 	loggingMap.None()
-	ctx.JSON(http.StatusOK, categories)
+	publicJSON(ctx, categories)
 }
 
 func (h *blogHandler) getUserCategoriesPreference(ctx *gin.Context) {
@@ -153,7 +171,7 @@ func (h *blogHandler) all(ctx *gin.Context) {
 			ctx.JSON(http.StatusInternalServerError, nil)
 			return
 		}
-		ctx.JSON(http.StatusOK, blogs)
+		publicJSON(ctx, blogs)
 	} else {
 		blogs, err := h.service.repository.BlogsByUserId(ctx, userId)
 		if err != nil {
@@ -162,7 +180,7 @@ func (h *blogHandler) all(ctx *gin.Context) {
 			ctx.JSON(http.StatusInternalServerError, nil)
 			return
 		}
-		ctx.JSON(http.StatusOK, blogs)
+		publicJSON(ctx, blogs)
 	}
 }
 
@@ -180,7 +198,7 @@ func (h *blogHandler) byIdList(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, blogs)
+	publicJSON(ctx, blogs)
 }
 
 func (h *blogHandler) byId(ctx *gin.Context) {
@@ -208,7 +226,7 @@ func (h *blogHandler) byId(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, blog)
+	publicJSON(ctx, blog)
 }
 
 func (h *blogHandler) byUrl(ctx *gin.Context) {
@@ -231,7 +249,7 @@ func (h *blogHandler) byUrl(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, blog)
+	publicJSON(ctx, blog)
 }
 
 func (h *blogHandler) newPersonal(ctx *gin.Context) {
@@ -702,7 +720,7 @@ func (h *blogHandler) getAvatar(ctx *gin.Context) {
 	}
 
 	loggingMap.None()
-	ctx.Redirect(http.StatusSeeOther, redirectUrl)
+	publicRedirect(ctx, redirectUrl)
 }
 
 func (h *blogHandler) updateAvatar(ctx *gin.Context) {
@@ -819,7 +837,7 @@ func (h *blogHandler) getCover(ctx *gin.Context) {
 	}
 
 	loggingMap.None()
-	ctx.Redirect(http.StatusSeeOther, redirectUrl)
+	publicRedirect(ctx, redirectUrl)
 }
 
 func (h *blogHandler) updateCover(ctx *gin.Context) {
@@ -1103,6 +1121,7 @@ func (h *blogHandler) createFreeSubscription(ctx *gin.Context) {
 		ShortDescription: "Бесплатная подписка",
 		IsFree:           true,
 		PriceRub:         0,
+		Perks:            make([]SubscriptionPerk, 0),
 		Cover:            nil,
 		IsActive:         true,
 		Created:          time.Now().UTC(),
@@ -1184,6 +1203,7 @@ func (h *blogHandler) createPaidSubscription(ctx *gin.Context) {
 		ShortDescription: "Платная подписка",
 		IsFree:           false,
 		PriceRub:         price,
+		Perks:            make([]SubscriptionPerk, 0),
 		Cover:            nil,
 		IsActive:         false,
 		Created:          time.Now().UTC(),
@@ -1265,6 +1285,14 @@ func (h *blogHandler) updateSubscriptionInfo(ctx *gin.Context) {
 	subscription.ShortDescription = req.ShortDescription
 	subscription.Title = req.Title
 	subscription.PriceRub = req.PriceRub
+	if !subscription.IsFree {
+		subscription.TrialDays = req.TrialDays
+	}
+	if req.Perks == nil {
+		req.Perks = make([]SubscriptionPerk, 0)
+	}
+	subscription.Perks = req.Perks
+	subscription.MaxSubscribers = req.MaxSubscribers
 
 	err = h.service.repository.UpdateSubscription(ctx, subscription)
 	if err != nil {
@@ -1385,7 +1413,7 @@ func (h *blogHandler) getSubscriptionCover(ctx *gin.Context) {
 	}
 
 	loggingMap.None()
-	ctx.Redirect(http.StatusSeeOther, redirectUrl)
+	publicRedirect(ctx, redirectUrl)
 }
 
 func (h *blogHandler) getMyBlogUserSubscriptions(ctx *gin.Context) {
@@ -1525,6 +1553,9 @@ func (h *blogHandler) subscribe(ctx *gin.Context) {
 	}
 	if subscription.IsFree {
 		if userSubscription == nil {
+			if !reserveSubscriptionSlot(ctx, h.service, subscription, *userId) {
+				return
+			}
 			newUserSubscription := UserSubscription{
 				ID:             uuid.New(),
 				UserId:         *userId,
@@ -1546,28 +1577,48 @@ func (h *blogHandler) subscribe(ctx *gin.Context) {
 			ctx.JSON(http.StatusOK, struct{}{})
 		}
 	} else {
+		promoCode, ok := promoCodeFromQuery(ctx, h.service, blog.ID, subscription.ID, *userId)
+		if !ok {
+			return
+		}
 		if userSubscription != nil {
-			if userSubscription.IsActive {
+			if userSubscription.IsActive && userSubscription.Status != UserSubscriptionStatusTrial {
 				loggingMap.SetMessage("user is already subscribed")
 				ctx.JSON(http.StatusOK, struct{}{})
 			} else {
-				link, err := h.service.GetSubscriptionRobokassaPaymentLink(ctx, subscription, *userId)
+				if !reserveSubscriptionSlot(ctx, h.service, subscription, *userId) {
+					return
+				}
+				link, err := h.service.GetSubscriptionRobokassaPaymentLink(ctx, subscription, *userId, promoCode)
 				if err != nil {
 					loggingMap.SetError(err.Error())
 					loggingMap.SetMessage("failed to get payment link")
 					ctx.JSON(http.StatusInternalServerError, nil)
 					return
 				}
+				if link == "" {
+					loggingMap.SetMessage("promo code is used up")
+					ctx.JSON(http.StatusConflict, nil)
+					return
+				}
 				ctx.JSON(http.StatusOK, gin.H{"payment_link": link})
 			}
 		} else {
-			link, err := h.service.GetSubscriptionRobokassaPaymentLink(ctx, subscription, *userId)
+			if !reserveSubscriptionSlot(ctx, h.service, subscription, *userId) {
+				return
+			}
+			link, err := h.service.GetSubscriptionRobokassaPaymentLink(ctx, subscription, *userId, promoCode)
 			if err != nil {
 				loggingMap.SetError(err.Error())
 				loggingMap.SetMessage("failed to get payment link")
 				ctx.JSON(http.StatusInternalServerError, nil)
 				return
 			}
+			if link == "" {
+				loggingMap.SetMessage("promo code is used up")
+				ctx.JSON(http.StatusConflict, nil)
+				return
+			}
 			ctx.JSON(http.StatusOK, gin.H{"payment_link": link})
 		}
 	}
@@ -1652,6 +1703,9 @@ func (h *blogHandler) subscribeFree(ctx *gin.Context) {
 	}
 
 	if userSubscription == nil {
+		if !reserveSubscriptionSlot(ctx, h.service, subscription, *userId) {
+			return
+		}
 		newUserSubscription := UserSubscription{
 			ID:             uuid.New(),
 			UserId:         *userId,
@@ -1730,19 +1784,31 @@ func (h *blogHandler) subscribeRobokassa(ctx *gin.Context) {
 		return
 	}
 	if userSubscription != nil {
-		if userSubscription.IsActive {
+		if userSubscription.IsActive && userSubscription.Status != UserSubscriptionStatusTrial {
 			loggingMap.SetMessage("user is already subscribed")
 			ctx.JSON(http.StatusConflict, nil)
 			return
 		}
 	}
-	link, err := h.service.GetSubscriptionRobokassaPaymentLink(ctx, subscription, *userId)
+	promoCode, ok := promoCodeFromQuery(ctx, h.service, blog.ID, subscription.ID, *userId)
+	if !ok {
+		return
+	}
+	if !reserveSubscriptionSlot(ctx, h.service, subscription, *userId) {
+		return
+	}
+	link, err := h.service.GetSubscriptionRobokassaPaymentLink(ctx, subscription, *userId, promoCode)
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("failed to get payment link")
 		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
+	if link == "" {
+		loggingMap.SetMessage("promo code is used up")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
 	ctx.JSON(http.StatusOK, gin.H{"payment_link": link})
 
 }
@@ -2129,11 +2195,29 @@ func (h *blogHandler) stats(ctx *gin.Context) {
 		return
 	}
 
+	trialSubsCount, err := h.service.repository.CountBlogTrialUserSubscriptions(ctx, blog.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to count blog trial user subscriptions")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	promoCodeUsesCount, err := h.service.repository.CountBlogPromoCodeUses(ctx, blog.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to count blog promo code uses")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, BlogStatsResponse{
-		CommentsCount:        commentsCount,
-		PostsCount:           postsCount,
-		FollowersCount:       followsCount,
-		PaidSubscribersCount: paidSubsCount,
+		CommentsCount:         commentsCount,
+		PostsCount:            postsCount,
+		FollowersCount:        followsCount,
+		PaidSubscribersCount:  paidSubsCount,
+		TrialSubscribersCount: trialSubsCount,
+		PromoCodeUsesCount:    promoCodeUsesCount,
 	})
 
 }
@@ -2256,9 +2340,10 @@ func (h *blogHandler) makeDonationToncoin(ctx *gin.Context) {
 		return
 	}
 
-	if req.Value < h.service.DonationsToncoinMinValue() {
+	minValue := h.service.ToncoinDonationMinValue()
+	if req.Value < minValue {
 		loggingMap.SetMessage("value is below minimum toncoin required value")
-		loggingMap["min_value"] = h.service.donationsRobokassaMinValue
+		loggingMap["min_value"] = minValue
 		ctx.JSON(http.StatusBadRequest, nil)
 		return
 	}
@@ -2281,6 +2366,14 @@ func (h *blogHandler) makeDonationToncoin(ctx *gin.Context) {
 		return
 	}
 
+	address, err := h.service.usersService.WalletAddressOfUser(blog.AuthorId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get author wallet address")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
 	timeNow := time.Now().UTC()
 	donation := Donation{
 		ID:               uuid.New(),
@@ -2303,14 +2396,19 @@ func (h *blogHandler) makeDonationToncoin(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusAccepted, nil)
+	ctx.JSON(http.StatusAccepted, MakeDonationToncoinResponse{
+		DonationId: donation.ID,
+		Address:    address,
+		Value:      donation.Value,
+		Memo:       donation.Memo(),
+	})
 }
 
 func (h *blogHandler) getMinDonationValues(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"rub":     h.service.donationsRobokassaMinValue,
-		"toncoin": h.service.donationsToncoinMinValue,
+		"rub":     h.service.DonationsRobokassaMinValue(),
+		"toncoin": h.service.ToncoinDonationMinValue(),
 	})
 }
 
@@ -2391,6 +2489,55 @@ func (h *blogHandler) getIncome(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, blogIncomes)
 }
 
+func (h *blogHandler) getIncomeSummary(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	blog, err := h.service.BlogById(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get blog by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if blog == nil {
+		loggingMap.SetMessage("blog by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if blog.AuthorId != *userId {
+		loggingMap.SetMessage("request user is not the author of the blog")
+		ctx.JSON(http.StatusForbidden, nil)
+		return
+	}
+
+	blogIncomes, err := h.service.repository.BlogIncomesByBlogId(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get blog incomes")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	summary, err := h.service.IncomesSummary(blogIncomes)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to summarize blog incomes")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, summary)
+}
+
 func (h *blogHandler) getBlogDonations(ctx *gin.Context) {
 
 	loggingMap := serverlogging.GetLoggingMap(ctx)
@@ -24,6 +24,10 @@ func RegisterBlogServiceHandler(api *gin.RouterGroup, service *Service) {
 	serviceM := ServiceMiddleware()
 	api.POST("/subscriptions/grant", serviceM, h.grantSubscription)
 	api.POST("/donations/confirm", serviceM, h.confirmDonation)
+	api.POST("/gifts/confirm", serviceM, h.confirmGift)
+	api.POST("/subscriptions/tier-changes/confirm", serviceM, h.confirmTierChange)
+	api.POST("/payment-items/info", serviceM, h.paymentItemsInfo)
+	api.POST("/refunds", serviceM, h.refundItem)
 }
 
 func (h *blogServiceHandler) grantSubscription(ctx *gin.Context) {
@@ -73,6 +77,21 @@ func (h *blogServiceHandler) grantSubscription(ctx *gin.Context) {
 		return
 	}
 
+	// the slot reservation could expire while the user was paying and the slot be taken by another user,
+	// then the subscription isn't granted and billing service refunds the payment
+	reserved, err := h.service.ReserveSubscriptionSlot(ctx, subscription, req.UserId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reserve subscription slot")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !reserved {
+		loggingMap.SetMessage("subscription is full")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
 	blogIncome := BlogIncome{
 		ID:               uuid.New(),
 		BlogId:           subscription.BlogId,
@@ -101,7 +120,7 @@ func (h *blogServiceHandler) grantSubscription(ctx *gin.Context) {
 	}
 
 	if userSubscription == nil {
-		expiryDate := time.Now().Add(time.Hour * 720).UTC()
+		expiryDate := time.Now().Add(subscriptionPeriod).UTC()
 		userSubscription := UserSubscription{
 			ID:             uuid.New(),
 			UserId:         req.UserId,
@@ -121,7 +140,7 @@ func (h *blogServiceHandler) grantSubscription(ctx *gin.Context) {
 			return
 		}
 	} else {
-		expiryDate := time.Now().Add(time.Hour * 720).UTC()
+		expiryDate := time.Now().Add(subscriptionPeriod).UTC()
 		userSubscription.ExpiresAt = &expiryDate
 		userSubscription.Status = UserSubscriptionStatusCancelled
 		userSubscription.Updated = timeNow
@@ -160,6 +179,11 @@ func (h *blogServiceHandler) grantSubscription(ctx *gin.Context) {
 		}
 	}
 
+	// the access is already granted, so failed promo code confirmation doesn't fail the request
+	if err := h.service.ConfirmPromoCodeUse(ctx, req.InvoiceId); err != nil {
+		loggingMap["promo_code_error"] = err.Error()
+	}
+
 	go h.service.notifService.SubscriptionAuthor(
 		blog.AuthorId.String(), blog.ID.String(), subscription.ID.String(),
 		req.UserId.String(), req.Value, req.Currency)
@@ -176,7 +200,6 @@ func (h *blogServiceHandler) grantSubscription(ctx *gin.Context) {
 
 func (h *blogServiceHandler) confirmDonation(ctx *gin.Context) {
 	loggingMap := serverlogging.GetLoggingMap(ctx)
-	timeNow := time.Now().UTC()
 
 	var req GrantItemServiceRequest
 	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
@@ -221,48 +244,154 @@ func (h *blogServiceHandler) confirmDonation(ctx *gin.Context) {
 		return
 	}
 
-	blogIncome := BlogIncome{
-		ID:               uuid.New(),
-		BlogId:           donation.BlogId,
-		UserId:           req.UserId,
-		Value:            req.Value,
-		Currency:         req.Currency,
-		ItemId:           donation.ID,
-		ItemType:         PaymentItemTypeDonation,
-		SentToUserWallet: req.Currency == CurrencyTon,
-		Created:          timeNow,
-	}
-	err = h.service.repository.CreateBlogIncome(ctx, &blogIncome)
+	err = h.service.ConfirmDonation(ctx, blog, donation, req.UserId, req.Value, req.Currency)
 	if err != nil {
 		loggingMap.SetError(err.Error())
-		loggingMap.SetMessage("failed to create blog income")
+		loggingMap.SetMessage("failed to confirm donation")
 		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 
-	donation.Status = DonationStatusConfirmed
-	donation.PaymentConfirmed = true
-	donation.Updated = timeNow
+	loggingMap.SetMessage("donation confirmed")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, nil)
+	return
+
+}
+
+func (h *blogServiceHandler) confirmGift(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
 
-	err = h.service.repository.UpdateDonation(ctx, donation)
+	var req GrantItemServiceRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	gift, err := h.service.ConfirmGift(ctx, req.ItemId, req.Value, req.Currency)
 	if err != nil {
 		loggingMap.SetError(err.Error())
-		loggingMap.SetMessage("failed to update donation")
+		loggingMap.SetMessage("failed to confirm gift")
 		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
+	if gift == nil {
+		loggingMap.SetError("gift not found")
+		loggingMap.SetMessage("gift not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
 
-	go h.service.notifService.DonationAuthor(
-		blog.AuthorId.String(), blog.ID.String(), req.UserId.String(),
-		donation.UserComment, req.Value, req.Currency)
+	loggingMap.SetMessage("gift confirmed")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, nil)
+}
 
-	go h.service.notifService.DonationUser(
-		req.UserId.String(), blog.ID.String(),
-		req.Value, req.Currency)
+func (h *blogServiceHandler) confirmTierChange(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
 
-	loggingMap.SetMessage("donation confirmed")
+	var req GrantItemServiceRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	change, err := h.service.ConfirmTierChange(ctx, req.ItemId, req.Value, req.Currency)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to confirm tier change")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if change == nil {
+		loggingMap.SetError("tier change not found")
+		loggingMap.SetMessage("tier change not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	loggingMap.SetMessage("tier change confirmed")
 	loggingMap.Info()
 	ctx.JSON(http.StatusOK, nil)
-	return
+}
+
+func (h *blogServiceHandler) paymentItemsInfo(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req PaymentItemsInfoServiceRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	items, err := h.service.PaymentItemsInfo(ctx, req.Items)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get payment items info")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, items)
+}
+
+func (h *blogServiceHandler) refundItem(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req RefundItemServiceRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	blog, err := h.service.RefundItem(ctx, &req)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to refund item")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if blog == nil {
+		loggingMap.SetMessage("refunded item not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
 
+	loggingMap.SetMessage("item refunded")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, nil)
 }
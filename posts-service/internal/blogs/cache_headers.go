@@ -0,0 +1,63 @@
+package blogs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	serverlogging "posts-service/pkg/serverlogging/gin"
+	"strings"
+)
+
+const (
+	// publicMaxAge is the freshness of public read responses in seconds, the gateway cache
+	// serves them to anonymous users and revalidates by the ETag afterwards
+	publicMaxAge = 30
+	// publicRedirectMaxAge is the freshness of redirects to files, names of files don't change
+	publicRedirectMaxAge = 300
+)
+
+// publicJSON writes the response of the public read endpoint with caching headers, the ETag is the hash
+// of the body and the not modified status is answered if the client has the same version
+func publicJSON(ctx *gin.Context, obj any) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to marshal response")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	hash := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", publicMaxAge))
+	ctx.Header("ETag", etag)
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// publicRedirect redirects to the file with caching headers
+func publicRedirect(ctx *gin.Context, redirectUrl string) {
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", publicRedirectMaxAge))
+	ctx.Redirect(http.StatusSeeOther, redirectUrl)
+}
+
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -43,6 +43,8 @@ const (
 	PaymentItemTypeSubscription = "subscription"
 	PaymentItemTypePost         = "post"
 	PaymentItemTypeDonation     = "donation"
+	PaymentItemTypeGift         = "gift"
+	PaymentItemTypeTierChange   = "tier_change"
 )
 
 const (
@@ -50,14 +52,62 @@ const (
 	UserSubscriptionStatusCancelled = "cancelled"
 	UserSubscriptionStatusExpired   = "expired"
 	UserSubscriptionStatusLifetime  = "lifetime"
+	UserSubscriptionStatusTrial     = "trial"
+)
+
+// Gift is paid when the buyer paid it and can be redeemed until it expires
+const (
+	GiftStatusNew      = "new"
+	GiftStatusPaid     = "paid"
+	GiftStatusRedeemed = "redeemed"
+	GiftStatusExpired  = "expired"
+	GiftStatusRefunded = "refunded"
+)
+
+const (
+	PromoCodeDiscountTypePercent = "percent"
+	PromoCodeDiscountTypeFixed   = "fixed"
+)
+
+// Promo code use is pending since the payment link is created until the payment is confirmed
+const (
+	PromoCodeUseStatusPending = "pending"
+	PromoCodeUseStatusUsed    = "used"
 )
 
 const (
 	DonationStatusNew       = "new"
 	DonationStatusConfirmed = "confirmed"
+	DonationStatusRefunded  = "refunded"
+)
+
+const (
+	ToncoinTransferStatusMatched   = "matched"
+	ToncoinTransferStatusUnmatched = "unmatched"
+	ToncoinTransferStatusResolved  = "resolved"
+	ToncoinTransferStatusIgnored   = "ignored"
 )
 
 const (
 	CurrencyRub = "rub"
 	CurrencyTon = "toncoin"
 )
+
+const (
+	TierChangeKindUpgrade   = "upgrade"
+	TierChangeKindDowngrade = "downgrade"
+)
+
+// Upgrade is new until the price difference is paid, downgrade is scheduled until the end of the paid period
+const (
+	TierChangeStatusNew       = "new"
+	TierChangeStatusScheduled = "scheduled"
+	TierChangeStatusApplied   = "applied"
+	TierChangeStatusCancelled = "cancelled"
+)
+
+// Waiting users are notified once when a slot of the capped subscription frees up
+const (
+	WaitlistStatusWaiting  = "waiting"
+	WaitlistStatusNotified = "notified"
+)
@@ -0,0 +1,103 @@
+package blogs
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"log"
+	"time"
+)
+
+// IsOpenedUp reports whether the early access period of the post is over and the post is free
+func (p *Post) IsOpenedUp(timeNow time.Time) bool {
+	return p.PublicAt != nil && !p.PublicAt.After(timeNow)
+}
+
+// SetEarlyAccess keeps the post gated for the days since it is published and then makes it free.
+// The period of the already published post is counted from the time it was published,
+// the draft gets the period when it is published.
+func (p *Post) SetEarlyAccess(days *int, timeNow time.Time) {
+	if p.AccessMode == "1" || days == nil {
+		p.EarlyAccessDays = nil
+		p.PublicAt = nil
+		return
+	}
+	if p.Status != PostStatusPublic {
+		p.EarlyAccessDays = days
+		p.PublicAt = nil
+		return
+	}
+	publishedAt := timeNow
+	if p.PublicAt != nil && p.EarlyAccessDays != nil {
+		publishedAt = p.PublicAt.Add(-time.Duration(*p.EarlyAccessDays) * 24 * time.Hour)
+	}
+	publicAt := publishedAt.Add(time.Duration(*days) * 24 * time.Hour)
+	p.EarlyAccessDays = days
+	p.PublicAt = &publicAt
+}
+
+// StartEarlyAccessWorker makes free the published posts which early access period is over
+// and notifies followers of their blogs
+func (s *Service) StartEarlyAccessWorker(ctx context.Context, ticker *time.Ticker) {
+	for range ticker.C {
+		posts, err := s.repository.OpenUpEarlyAccessPosts(ctx, time.Now().UTC())
+		if err != nil {
+			log.Println("error worker opening up early access posts:", err)
+			continue
+		}
+
+		for _, post := range posts {
+			followerIds, err := s.repository.FollowerIdsByBlogId(ctx, post.BlogId)
+			if err != nil {
+				log.Println("error worker getting blog followers:", err)
+				continue
+			}
+			for _, followerId := range followerIds {
+				go s.notifService.PostOpened(
+					followerId.String(), post.BlogId.String(), post.ID.String(), post.Title)
+			}
+		}
+	}
+}
+
+// OpenUpEarlyAccessPosts sets free access mode to the published posts which public time has come
+// and returns them
+func (r *Repository) OpenUpEarlyAccessPosts(ctx context.Context, timeNow time.Time) ([]Post, error) {
+	query := `update posts set access_mode = '1', price = null, subscription_id = null, updated = $1
+			where status = $2 and access_mode <> '1' and public_at <= $1
+			returning id, blog_id, title`
+
+	rows, err := r.db.Query(ctx, query, timeNow, PostStatusPublic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]Post, 0)
+	var post Post
+	for rows.Next() {
+		err = rows.Scan(&post.ID, &post.BlogId, &post.Title)
+		if err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, post)
+	}
+	return resultArray, nil
+}
+
+func (r *Repository) FollowerIdsByBlogId(ctx context.Context, blogId uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `select user_id from user_follows where blog_id = $1`, blogId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]uuid.UUID, 0)
+	var id uuid.UUID
+	for rows.Next() {
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, id)
+	}
+	return resultArray, nil
+}
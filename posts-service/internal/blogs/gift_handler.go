@@ -0,0 +1,212 @@
+package blogs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	requestuser "posts-service/pkg/hidepost-requestuser"
+	serverlogging "posts-service/pkg/serverlogging/gin"
+	"strings"
+	"time"
+)
+
+func (h *blogHandler) createGift(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	var req CreateGiftRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	blog, title, price, err := h.service.GiftItemInfo(ctx, req.ItemId, req.ItemType)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get gift item")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if blog == nil {
+		loggingMap.SetMessage("paid item doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if blog.AuthorId == *userId {
+		loggingMap.SetMessage("author can't buy a gift on his own blog")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	var recipientId *uuid.UUID
+	if login := strings.TrimSpace(req.RecipientLogin); login != "" {
+		recipientId, err = h.service.usersService.UserIdByLogin(login)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to get recipient by login")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		if recipientId == nil {
+			loggingMap.SetMessage("recipient by login doesn't exists")
+			ctx.JSON(http.StatusNotFound, nil)
+			return
+		}
+		if *recipientId == *userId || *recipientId == blog.AuthorId {
+			loggingMap.SetMessage("recipient can't be the buyer or the author of the blog")
+			ctx.JSON(http.StatusConflict, nil)
+			return
+		}
+	}
+
+	gift, link, err := h.service.CreateGift(ctx, blog, req.ItemId, req.ItemType, title, price, *userId, recipientId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to create gift")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("gift created")
+	ctx.JSON(http.StatusOK, CreateGiftResponse{
+		GiftId:      gift.ID,
+		PaymentLink: link,
+	})
+}
+
+func (h *blogHandler) getMyGifts(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	gifts, err := h.service.repository.GiftsByBuyerId(ctx, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get gifts by buyer id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, gifts)
+}
+
+// getReceivedGifts returns gifts targeted to the request user, codes of not redeemed gifts are given
+// so the recipient can redeem them
+func (h *blogHandler) getReceivedGifts(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	gifts, err := h.service.repository.GiftsByRecipientId(ctx, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get gifts by recipient id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	for i := range gifts {
+		if gifts[i].Status != GiftStatusPaid {
+			gifts[i].Code = ""
+		}
+	}
+	ctx.JSON(http.StatusOK, gifts)
+}
+
+func (h *blogHandler) redeemGift(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	var req RedeemGiftRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	gift, err := h.service.repository.GiftByCode(ctx, strings.TrimSpace(req.Code))
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get gift by code")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if gift == nil || gift.Status == GiftStatusNew {
+		loggingMap.SetMessage("gift by code doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	loggingMap["gift_id"] = gift.ID
+	if gift.Status != GiftStatusPaid || (gift.ExpiresAt != nil && gift.ExpiresAt.Before(time.Now().UTC())) {
+		loggingMap.SetMessage("gift is already redeemed or expired")
+		ctx.JSON(http.StatusGone, nil)
+		return
+	}
+	if gift.RecipientId != nil && *gift.RecipientId != *userId {
+		loggingMap.SetMessage("gift is for another user")
+		ctx.JSON(http.StatusForbidden, nil)
+		return
+	}
+
+	blog, err := h.service.repository.BlogById(ctx, gift.BlogId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get blog by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if blog == nil {
+		loggingMap.SetMessage("blog by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if blog.AuthorId == *userId {
+		loggingMap.SetMessage("author can't redeem a gift on his own blog")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	if gift.ItemType == PaymentItemTypeSubscription {
+		subscription, err := h.service.repository.SubscriptionById(ctx, gift.ItemId)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to get subscription by id")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		if subscription != nil && !reserveSubscriptionSlot(ctx, h.service, subscription, *userId) {
+			return
+		}
+	}
+
+	redeemed, err := h.service.RedeemGift(ctx, gift, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to redeem gift")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !redeemed {
+		loggingMap.SetMessage("gift is already redeemed or user already has access")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	loggingMap.SetMessage("gift redeemed")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, gift)
+}
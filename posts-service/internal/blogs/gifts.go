@@ -0,0 +1,450 @@
+package blogs
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"log"
+	"math/big"
+	"time"
+)
+
+// GiftExpirationTime is how long the paid gift can be redeemed
+const GiftExpirationTime = 30 * 24 * time.Hour
+
+const giftCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const giftCodeLength = 12
+
+func newGiftCode() (string, error) {
+	code := make([]byte, giftCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(giftCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = giftCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// GiftItemInfo returns the blog, the title and the price of the item that can be gifted,
+// or nil blog if the item doesn't exist or isn't paid
+func (s *Service) GiftItemInfo(ctx context.Context, itemId uuid.UUID, itemType string) (*Blog, string, float64, error) {
+	var blogId uuid.UUID
+	var title string
+	var price float64
+	switch itemType {
+	case PaymentItemTypeSubscription:
+		subscription, err := s.repository.SubscriptionById(ctx, itemId)
+		if err != nil || subscription == nil {
+			return nil, "", 0, err
+		}
+		if subscription.IsFree || !subscription.IsActive {
+			return nil, "", 0, nil
+		}
+		blogId, title, price = subscription.BlogId, subscription.Title, subscription.PriceRub
+	case PaymentItemTypePost:
+		post, err := s.repository.PostById(ctx, itemId)
+		if err != nil || post == nil {
+			return nil, "", 0, err
+		}
+		if post.AccessMode != "4" || post.Price == nil {
+			return nil, "", 0, nil
+		}
+		blogId, title, price = post.BlogId, post.Title, *post.Price
+	default:
+		return nil, "", 0, nil
+	}
+	blog, err := s.repository.BlogById(ctx, blogId)
+	if err != nil || blog == nil {
+		return nil, "", 0, err
+	}
+	return blog, title, price, nil
+}
+
+// CreateGift creates the unpaid gift and returns it with the payment link
+func (s *Service) CreateGift(ctx context.Context, blog *Blog, itemId uuid.UUID, itemType, title string, price float64,
+	buyerId uuid.UUID, recipientId *uuid.UUID) (*Gift, string, error) {
+
+	code, err := newGiftCode()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate gift code: %v", err)
+	}
+	timeNow := time.Now().UTC()
+	gift := Gift{
+		ID:          uuid.New(),
+		Code:        code,
+		BuyerId:     buyerId,
+		BlogId:      blog.ID,
+		ItemId:      itemId,
+		ItemType:    itemType,
+		RecipientId: recipientId,
+		PriceRub:    price,
+		Status:      GiftStatusNew,
+		Created:     timeNow,
+		Updated:     timeNow,
+	}
+	err = s.repository.CreateGift(ctx, &gift)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create gift: %v", err)
+	}
+
+	description := fmt.Sprintf("Оплата подарка \"%s\" блога \"%s\" (ID: %s)", title, blog.Title, gift.ID)
+	link, err := s.billingService.RobokassaPaymentLink(gift.ID, buyerId, price, PaymentItemTypeGift, description)
+	if err != nil {
+		return nil, "", err
+	}
+	return &gift, link, nil
+}
+
+// ConfirmGift marks the gift paid, creates the blog income of the gifted item and notifies the buyer and the recipient.
+// Returns nil gift if the gift doesn't exist.
+func (s *Service) ConfirmGift(ctx context.Context, giftId uuid.UUID, value float64, currency string) (*Gift, error) {
+	gift, err := s.repository.GiftById(ctx, giftId)
+	if err != nil || gift == nil {
+		return nil, err
+	}
+	if gift.Status != GiftStatusNew {
+		return gift, nil
+	}
+	blog, err := s.repository.BlogById(ctx, gift.BlogId)
+	if err != nil || blog == nil {
+		return nil, err
+	}
+
+	timeNow := time.Now().UTC()
+	blogIncome := BlogIncome{
+		ID:               uuid.New(),
+		BlogId:           gift.BlogId,
+		UserId:           gift.BuyerId,
+		Value:            value,
+		Currency:         currency,
+		ItemId:           gift.ItemId,
+		ItemType:         gift.ItemType,
+		SentToUserWallet: currency == CurrencyTon,
+		Created:          timeNow,
+	}
+	err = s.repository.CreateBlogIncome(ctx, &blogIncome)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blog income: %v", err)
+	}
+
+	expiresAt := timeNow.Add(GiftExpirationTime)
+	gift.Status = GiftStatusPaid
+	gift.ExpiresAt = &expiresAt
+	gift.Updated = timeNow
+	err = s.repository.UpdateGift(ctx, gift)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update gift: %v", err)
+	}
+
+	if gift.ItemType == PaymentItemTypeSubscription {
+		go s.notifService.SubscriptionAuthor(
+			blog.AuthorId.String(), blog.ID.String(), gift.ItemId.String(),
+			gift.BuyerId.String(), value, currency)
+	} else {
+		go s.notifService.PostPaidAccessAuthor(
+			blog.AuthorId.String(), blog.ID.String(), gift.ItemId.String(),
+			gift.BuyerId.String(), value, currency)
+	}
+	go s.notifService.GiftPurchased(
+		gift.BuyerId.String(), blog.ID.String(), gift.ID.String(), gift.ItemId.String(), gift.ItemType,
+		gift.Code, value, currency, expiresAt)
+	if gift.RecipientId != nil {
+		go s.notifService.GiftReceived(
+			gift.RecipientId.String(), blog.ID.String(), gift.ID.String(), gift.ItemId.String(), gift.ItemType,
+			gift.BuyerId.String(), gift.Code, expiresAt)
+	}
+	return gift, nil
+}
+
+// RedeemGift marks the gift redeemed and grants the gifted item to the user.
+// Returns false if the gift is already redeemed or the user already has access to the gifted post.
+func (s *Service) RedeemGift(ctx context.Context, gift *Gift, userId uuid.UUID) (bool, error) {
+	timeNow := time.Now().UTC()
+	if gift.ItemType == PaymentItemTypePost {
+		paidAccess, err := s.repository.PostPaidAccessByPostIdAndUserId(ctx, gift.ItemId, userId)
+		if err != nil {
+			return false, fmt.Errorf("failed to get post paid access: %v", err)
+		}
+		if paidAccess != nil {
+			return false, nil
+		}
+	}
+
+	claimed, err := s.repository.ClaimGift(ctx, gift.ID, userId, timeNow)
+	if err != nil {
+		return false, fmt.Errorf("failed to update gift: %v", err)
+	}
+	if !claimed {
+		return false, nil
+	}
+	gift.Status = GiftStatusRedeemed
+	gift.RedeemedBy = &userId
+	gift.RedeemedAt = &timeNow
+	gift.Updated = timeNow
+
+	err = s.grantGiftItem(ctx, gift, userId, timeNow)
+	if err != nil {
+		gift.Status = GiftStatusPaid
+		gift.RedeemedBy = nil
+		gift.RedeemedAt = nil
+		_ = s.repository.UpdateGift(ctx, gift)
+		return false, err
+	}
+
+	go s.notifService.GiftRedeemed(
+		gift.BuyerId.String(), gift.BlogId.String(), gift.ID.String(), gift.ItemId.String(), gift.ItemType,
+		userId.String())
+	return true, nil
+}
+
+func (s *Service) grantGiftItem(ctx context.Context, gift *Gift, userId uuid.UUID, timeNow time.Time) error {
+	switch gift.ItemType {
+	case PaymentItemTypeSubscription:
+		userSubscription, err := s.repository.UserSubscriptionByParams(ctx, userId, gift.BlogId, gift.ItemId)
+		if err != nil {
+			return fmt.Errorf("failed to get user subscription: %v", err)
+		}
+		if userSubscription == nil {
+			expiryDate := timeNow.Add(subscriptionPeriod)
+			userSubscription = &UserSubscription{
+				ID:             uuid.New(),
+				UserId:         userId,
+				SubscriptionId: gift.ItemId,
+				BlogId:         gift.BlogId,
+				Status:         UserSubscriptionStatusCancelled,
+				IsActive:       true,
+				ExpiresAt:      &expiryDate,
+				Created:        timeNow,
+				Updated:        timeNow,
+			}
+			err = s.repository.CreateUserSubscription(ctx, userSubscription)
+		} else {
+			// the gifted period is added to the paid period that is still active
+			from := timeNow
+			if userSubscription.IsActive && userSubscription.Status != UserSubscriptionStatusTrial &&
+				userSubscription.ExpiresAt != nil && userSubscription.ExpiresAt.After(timeNow) {
+				from = *userSubscription.ExpiresAt
+			}
+			expiryDate := from.Add(subscriptionPeriod)
+			userSubscription.ExpiresAt = &expiryDate
+			userSubscription.Status = UserSubscriptionStatusCancelled
+			userSubscription.IsActive = true
+			userSubscription.Updated = timeNow
+			err = s.repository.UpdateUserSubscription(ctx, userSubscription)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save user subscription: %v", err)
+		}
+	case PaymentItemTypePost:
+		paidAccess := &PostPaidAccess{
+			ID:      uuid.New(),
+			PostId:  gift.ItemId,
+			UserId:  userId,
+			Created: timeNow,
+		}
+		if err := s.repository.CreatePostPaidAccess(ctx, paidAccess); err != nil {
+			return fmt.Errorf("failed to create post paid access: %v", err)
+		}
+	default:
+		return fmt.Errorf("unknown gift item type: %s", gift.ItemType)
+	}
+
+	follow, err := s.repository.UserFollowByUserIdAndBlogId(ctx, userId, gift.BlogId)
+	if err != nil {
+		return fmt.Errorf("failed to get follow by user id and blog id: %v", err)
+	}
+	if follow == nil {
+		follow = &UserFollow{
+			ID:      uuid.New(),
+			UserId:  userId,
+			BlogId:  gift.BlogId,
+			Created: timeNow,
+			Updated: timeNow,
+		}
+		if err := s.repository.CreateUserFollow(ctx, follow); err != nil {
+			return fmt.Errorf("failed to create user follow: %v", err)
+		}
+	}
+	return nil
+}
+
+// giftExpiredRefundReason is the reason of the refund of the expired gift
+const giftExpiredRefundReason = "gift expired unredeemed"
+
+// StartGiftsWorker refunds expired unredeemed gifts through billing service. The refund reversal
+// marks the gift as refunded, gifts without confirmed robokassa payment are marked as expired.
+// Gifts that failed to refund are retried on the next tick.
+func (s *Service) StartGiftsWorker(ctx context.Context, ticker *time.Ticker) {
+	for range ticker.C {
+		gifts, err := s.repository.ExpiredPaidGifts(ctx)
+		if err != nil {
+			log.Println("error worker getting expired gifts:", err)
+			continue
+		}
+		for i := range gifts {
+			refunded, err := s.billingService.RefundItem(gifts[i].ID, PaymentItemTypeGift, giftExpiredRefundReason)
+			if err != nil {
+				log.Println("error worker refunding expired gift:", err)
+				continue
+			}
+			if !refunded {
+				gifts[i].Status = GiftStatusExpired
+				gifts[i].Updated = time.Now().UTC()
+				err = s.repository.UpdateGift(ctx, &gifts[i])
+				if err != nil {
+					log.Println("error worker updating gift status:", err)
+					continue
+				}
+			}
+			go s.notifService.GiftExpired(
+				gifts[i].BuyerId.String(), gifts[i].BlogId.String(), gifts[i].ID.String(),
+				gifts[i].ItemId.String(), gifts[i].ItemType)
+		}
+	}
+}
+
+const giftColumns = `id, code, buyer_id, blog_id, item_id, item_type, recipient_id, price_rub, status, expires_at,
+			redeemed_by, redeemed_at, created, updated`
+
+func scanGift(row pgx.Row, item *Gift) error {
+	return row.Scan(
+		&item.ID,
+		&item.Code,
+		&item.BuyerId,
+		&item.BlogId,
+		&item.ItemId,
+		&item.ItemType,
+		&item.RecipientId,
+		&item.PriceRub,
+		&item.Status,
+		&item.ExpiresAt,
+		&item.RedeemedBy,
+		&item.RedeemedAt,
+		&item.Created,
+		&item.Updated,
+	)
+}
+
+func (r *Repository) GiftById(ctx context.Context, id uuid.UUID) (*Gift, error) {
+	query := `select ` + giftColumns + ` from gifts where id = $1`
+
+	var item Gift
+	err := scanGift(r.db.QueryRow(ctx, query, id), &item)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) GiftByCode(ctx context.Context, code string) (*Gift, error) {
+	query := `select ` + giftColumns + ` from gifts where code = upper($1)`
+
+	var item Gift
+	err := scanGift(r.db.QueryRow(ctx, query, code), &item)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) giftsByQuery(ctx context.Context, query string, args ...any) ([]Gift, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]Gift, 0)
+	var item Gift
+	for rows.Next() {
+		err = scanGift(rows, &item)
+		if err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, item)
+	}
+	return resultArray, nil
+}
+
+func (r *Repository) GiftsByBuyerId(ctx context.Context, buyerId uuid.UUID) ([]Gift, error) {
+	query := `select ` + giftColumns + ` from gifts where buyer_id = $1 order by created desc`
+	return r.giftsByQuery(ctx, query, buyerId)
+}
+
+// GiftsByRecipientId returns paid and redeemed gifts targeted to the recipient
+func (r *Repository) GiftsByRecipientId(ctx context.Context, recipientId uuid.UUID) ([]Gift, error) {
+	query := `select ` + giftColumns + ` from gifts
+			where recipient_id = $1 and status in ($2, $3)
+			order by created desc`
+	return r.giftsByQuery(ctx, query, recipientId, GiftStatusPaid, GiftStatusRedeemed)
+}
+
+func (r *Repository) ExpiredPaidGifts(ctx context.Context) ([]Gift, error) {
+	query := `select ` + giftColumns + ` from gifts where status = $1 and expires_at < $2`
+	return r.giftsByQuery(ctx, query, GiftStatusPaid, time.Now().UTC())
+}
+
+func (r *Repository) CreateGift(ctx context.Context, gift *Gift) error {
+	query := `insert into gifts (` + giftColumns + `)
+	values
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	_, err := r.db.Exec(ctx, query,
+		gift.ID,
+		gift.Code,
+		gift.BuyerId,
+		gift.BlogId,
+		gift.ItemId,
+		gift.ItemType,
+		gift.RecipientId,
+		gift.PriceRub,
+		gift.Status,
+		gift.ExpiresAt,
+		gift.RedeemedBy,
+		gift.RedeemedAt,
+		gift.Created,
+		gift.Updated,
+	)
+	return err
+}
+
+// ClaimGift marks the paid gift redeemed by the user, returns false if the gift isn't paid anymore
+func (r *Repository) ClaimGift(ctx context.Context, id, userId uuid.UUID, at time.Time) (bool, error) {
+	query := `update gifts set status = $3, redeemed_by = $4, redeemed_at = $5, updated = $5
+		where id = $1 and status = $2`
+	tag, err := r.db.Exec(ctx, query, id, GiftStatusPaid, GiftStatusRedeemed, userId, at)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *Repository) UpdateGift(ctx context.Context, gift *Gift) error {
+	query := `update gifts set
+		status = $2,
+		expires_at = $3,
+		redeemed_by = $4,
+		redeemed_at = $5,
+		updated = $6
+		where id = $1`
+	_, err := r.db.Exec(ctx, query,
+		gift.ID,
+		gift.Status,
+		gift.ExpiresAt,
+		gift.RedeemedBy,
+		gift.RedeemedAt,
+		gift.Updated,
+	)
+	return err
+}
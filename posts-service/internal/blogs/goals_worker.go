@@ -42,11 +42,9 @@ func (s *Service) HandleGoal(ctx context.Context, goal *Goal) error {
 		if err != nil {
 			return err
 		}
-		var current float64 = 0
-		for _, income := range incomes {
-			if income.Currency == CurrencyRub {
-				current += income.Value
-			}
+		current, err := s.IncomesValueRub(incomes)
+		if err != nil {
+			return err
 		}
 		goal.Current = int(math.Round(current))
 		return s.repository.UpdateGoal(ctx, goal)
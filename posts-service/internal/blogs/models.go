@@ -42,6 +42,8 @@ type Post struct {
 	LikesCount       int        `json:"likes_count"`
 	CommentsCount    int        `json:"comments_count"`
 	SubscriptionId   *uuid.UUID `json:"subscription_id"`
+	EarlyAccessDays  *int       `json:"early_access_days"`
+	PublicAt         *time.Time `json:"public_at"`
 	Created          time.Time  `json:"created"`
 	Updated          time.Time  `json:"updated"`
 }
@@ -64,16 +66,25 @@ type ContentFile struct {
 }
 
 type Subscription struct {
-	ID               uuid.UUID `json:"id"`
-	BlogId           uuid.UUID `json:"blog_id"`
-	Title            string    `json:"title"`
-	ShortDescription string    `json:"short_description"`
-	IsFree           bool      `json:"is_free"`
-	PriceRub         float64   `json:"price_rub"`
-	Cover            *string   `json:"cover"`
-	IsActive         bool      `json:"is_active"`
-	Created          time.Time `json:"created"`
-	Updated          time.Time `json:"updated"`
+	ID               uuid.UUID          `json:"id"`
+	BlogId           uuid.UUID          `json:"blog_id"`
+	Title            string             `json:"title"`
+	ShortDescription string             `json:"short_description"`
+	IsFree           bool               `json:"is_free"`
+	PriceRub         float64            `json:"price_rub"`
+	TrialDays        int                `json:"trial_days"`
+	Perks            []SubscriptionPerk `json:"perks"`
+	MaxSubscribers   *int               `json:"max_subscribers"`
+	Cover            *string            `json:"cover"`
+	IsActive         bool               `json:"is_active"`
+	Created          time.Time          `json:"created"`
+	Updated          time.Time          `json:"updated"`
+}
+
+type SubscriptionPerk struct {
+	Type        string `json:"type" validate:"required,oneof=early_access exclusive_posts discord_role custom"`
+	Title       string `json:"title" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
 }
 
 type UserSubscription struct {
@@ -154,3 +165,88 @@ type BlogIncome struct {
 	SentToUserWallet bool      `json:"sent_to_user_wallet"`
 	Created          time.Time `json:"created"`
 }
+
+type ToncoinTransfer struct {
+	Hash        string     `json:"hash"`
+	BlogId      *uuid.UUID `json:"blog_id"`
+	AuthorId    uuid.UUID  `json:"author_id"`
+	Address     string     `json:"address"`
+	FromAddress string     `json:"from_address"`
+	Value       float64    `json:"value"`
+	Comment     string     `json:"comment"`
+	DonationId  *uuid.UUID `json:"donation_id"`
+	Status      string     `json:"status"`
+	TransferAt  time.Time  `json:"transfer_at"`
+	Created     time.Time  `json:"created"`
+	Updated     time.Time  `json:"updated"`
+}
+
+type PromoCode struct {
+	ID            uuid.UUID   `json:"id"`
+	BlogId        uuid.UUID   `json:"blog_id"`
+	Code          string      `json:"code"`
+	DiscountType  string      `json:"discount_type"`
+	DiscountValue float64     `json:"discount_value"`
+	MaxUses       *int        `json:"max_uses"`
+	ItemIds       []uuid.UUID `json:"item_ids"`
+	ExpiresAt     *time.Time  `json:"expires_at"`
+	IsActive      bool        `json:"is_active"`
+	Created       time.Time   `json:"created"`
+	Updated       time.Time   `json:"updated"`
+}
+
+type PromoCodeUse struct {
+	ID          uuid.UUID `json:"id"`
+	PromoCodeId uuid.UUID `json:"promo_code_id"`
+	UserId      uuid.UUID `json:"user_id"`
+	ItemId      uuid.UUID `json:"item_id"`
+	ItemType    string    `json:"item_type"`
+	PriceRub    float64   `json:"price_rub"`
+	DiscountRub float64   `json:"discount_rub"`
+	InvoiceId   *int      `json:"invoice_id"`
+	Status      string    `json:"status"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+type Gift struct {
+	ID          uuid.UUID  `json:"id"`
+	Code        string     `json:"code"`
+	BuyerId     uuid.UUID  `json:"buyer_id"`
+	BlogId      uuid.UUID  `json:"blog_id"`
+	ItemId      uuid.UUID  `json:"item_id"`
+	ItemType    string     `json:"item_type"`
+	RecipientId *uuid.UUID `json:"recipient_id"`
+	PriceRub    float64    `json:"price_rub"`
+	Status      string     `json:"status"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RedeemedBy  *uuid.UUID `json:"redeemed_by"`
+	RedeemedAt  *time.Time `json:"redeemed_at"`
+	Created     time.Time  `json:"created"`
+	Updated     time.Time  `json:"updated"`
+}
+
+type SubscriptionTierChange struct {
+	ID                 uuid.UUID `json:"id"`
+	UserId             uuid.UUID `json:"user_id"`
+	BlogId             uuid.UUID `json:"blog_id"`
+	UserSubscriptionId uuid.UUID `json:"user_subscription_id"`
+	FromSubscriptionId uuid.UUID `json:"from_subscription_id"`
+	ToSubscriptionId   uuid.UUID `json:"to_subscription_id"`
+	Kind               string    `json:"kind"`
+	PriceRub           float64   `json:"price_rub"`
+	Status             string    `json:"status"`
+	EffectiveAt        time.Time `json:"effective_at"`
+	Created            time.Time `json:"created"`
+	Updated            time.Time `json:"updated"`
+}
+
+type SubscriptionWaitlistEntry struct {
+	ID             uuid.UUID  `json:"id"`
+	SubscriptionId uuid.UUID  `json:"subscription_id"`
+	UserId         uuid.UUID  `json:"user_id"`
+	Status         string     `json:"status"`
+	NotifiedAt     *time.Time `json:"notified_at"`
+	Created        time.Time  `json:"created"`
+	Updated        time.Time  `json:"updated"`
+}
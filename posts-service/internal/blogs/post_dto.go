@@ -1,6 +1,9 @@
 package blogs
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"time"
+)
 
 type PostUpdateRequest struct {
 	Title            string     `json:"title" validate:"required,min=2,max=150"`
@@ -11,6 +14,7 @@ type PostUpdateRequest struct {
 	AccessMode       string     `json:"access_mode" validate:"required,oneof=1 2 3 4"`
 	Price            *float64   `json:"price"`
 	SubscriptionId   *uuid.UUID `json:"subscription_id"`
+	EarlyAccessDays  *int       `json:"early_access_days" validate:"omitempty,min=1,max=365"`
 }
 
 type PostUpdateTitleRequest struct {
@@ -36,9 +40,10 @@ type PostUpdateStatusRequest struct {
 }
 
 type PostUpdateAccessModeRequest struct {
-	AccessMode     string     `json:"access_mode" validate:"required,oneof=1 2 3 4"`
-	Price          *float64   `json:"price"`
-	SubscriptionId *uuid.UUID `json:"subscription_id"`
+	AccessMode      string     `json:"access_mode" validate:"required,oneof=1 2 3 4"`
+	Price           *float64   `json:"price"`
+	SubscriptionId  *uuid.UUID `json:"subscription_id"`
+	EarlyAccessDays *int       `json:"early_access_days" validate:"omitempty,min=1,max=365"`
 }
 
 type PostLikesInfoResponse struct {
@@ -55,6 +60,7 @@ type PostMyContentAccessResponse struct {
 	AccessMode   string        `json:"access_mode"`
 	Price        float64       `json:"price"`
 	Subscription *Subscription `json:"subscription"`
+	PublicAt     *time.Time    `json:"public_at"`
 }
 
 //type GrantPostPaidAccessServiceRequest struct {
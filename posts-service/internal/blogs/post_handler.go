@@ -74,7 +74,7 @@ func (h *postHandler) all(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
-	ctx.JSON(http.StatusOK, posts)
+	publicJSON(ctx, posts)
 }
 
 func (h *postHandler) byId(ctx *gin.Context) {
@@ -102,7 +102,7 @@ func (h *postHandler) byId(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, post)
+	publicJSON(ctx, post)
 }
 
 func (h *postHandler) byBlogID(ctx *gin.Context) {
@@ -124,7 +124,7 @@ func (h *postHandler) byBlogID(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
-	ctx.JSON(http.StatusOK, posts)
+	publicJSON(ctx, posts)
 }
 
 func (h *postHandler) create(ctx *gin.Context) {
@@ -297,6 +297,7 @@ func (h *postHandler) update(ctx *gin.Context) {
 	post.Url = req.Url
 	post.TagsString = req.TagsString
 	post.Updated = time.Now().UTC()
+	post.SetEarlyAccess(req.EarlyAccessDays, post.Updated)
 
 	err = h.service.repository.UpdatePost(ctx, post)
 	if err != nil {
@@ -722,7 +723,7 @@ func (h *postHandler) getContentFile(ctx *gin.Context) {
 	}
 
 	loggingMap.None()
-	ctx.Redirect(http.StatusSeeOther, redirectUrl)
+	publicRedirect(ctx, redirectUrl)
 }
 
 func (h *postHandler) deleteContentFile(ctx *gin.Context) {
@@ -943,6 +944,8 @@ func (h *postHandler) updateStatus(ctx *gin.Context) {
 
 	post.Status = req.Status
 	post.Updated = time.Now().UTC()
+	// the early access period of the draft starts when it is published
+	post.SetEarlyAccess(post.EarlyAccessDays, post.Updated)
 
 	err = h.service.repository.UpdatePost(ctx, post)
 	if err != nil {
@@ -1061,6 +1064,7 @@ func (h *postHandler) updateAccessMode(ctx *gin.Context) {
 		post.SubscriptionId = nil
 	}
 	post.Updated = time.Now().UTC()
+	post.SetEarlyAccess(req.EarlyAccessDays, post.Updated)
 
 	err = h.service.repository.UpdatePost(ctx, post)
 	if err != nil {
@@ -1194,7 +1198,7 @@ func (h *postHandler) byUrl(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, post)
+	publicJSON(ctx, post)
 }
 
 func (h *postHandler) getCover(ctx *gin.Context) {
@@ -1250,7 +1254,7 @@ func (h *postHandler) getCover(ctx *gin.Context) {
 	}
 
 	loggingMap.None()
-	ctx.Redirect(http.StatusSeeOther, redirectUrl)
+	publicRedirect(ctx, redirectUrl)
 }
 
 func (h *postHandler) byFollowedBlogs(ctx *gin.Context) {
@@ -1432,11 +1436,25 @@ func (h *postHandler) checkMyContentAccess(ctx *gin.Context) {
 				Price:        0,
 				AccessMode:   post.AccessMode,
 				Subscription: nil,
+				PublicAt:     post.PublicAt,
 			})
 			return
 		}
 	}
 
+	if post.IsOpenedUp(time.Now().UTC()) {
+		ctx.JSON(http.StatusOK, PostMyContentAccessResponse{
+			HaveAccess:   true,
+			UserId:       *userId,
+			PostId:       post.ID,
+			Price:        0,
+			AccessMode:   "1",
+			Subscription: nil,
+			PublicAt:     post.PublicAt,
+		})
+		return
+	}
+
 	blogSubscriptions, err := h.service.repository.SubscriptionsByBlogId(ctx, post.BlogId)
 	if err != nil {
 		loggingMap.SetError(err.Error())
@@ -1454,6 +1472,7 @@ func (h *postHandler) checkMyContentAccess(ctx *gin.Context) {
 			Price:        0,
 			AccessMode:   "1",
 			Subscription: nil,
+			PublicAt:     post.PublicAt,
 		})
 		return
 
@@ -1474,6 +1493,7 @@ func (h *postHandler) checkMyContentAccess(ctx *gin.Context) {
 				Price:        0,
 				AccessMode:   "2",
 				Subscription: nil,
+				PublicAt:     post.PublicAt,
 			})
 			return
 		}
@@ -1493,6 +1513,7 @@ func (h *postHandler) checkMyContentAccess(ctx *gin.Context) {
 			Price:        0,
 			AccessMode:   "2",
 			Subscription: nil,
+			PublicAt:     post.PublicAt,
 		})
 		return
 
@@ -1514,6 +1535,7 @@ func (h *postHandler) checkMyContentAccess(ctx *gin.Context) {
 				Price:        0,
 				AccessMode:   "3",
 				Subscription: nil,
+				PublicAt:     post.PublicAt,
 			})
 			return
 		}
@@ -1558,6 +1580,7 @@ func (h *postHandler) checkMyContentAccess(ctx *gin.Context) {
 						Price:        0,
 						AccessMode:   "3",
 						Subscription: &blogSubscriptions[reqSubId],
+						PublicAt:     post.PublicAt,
 					})
 					return
 				}
@@ -1571,6 +1594,7 @@ func (h *postHandler) checkMyContentAccess(ctx *gin.Context) {
 			Price:        0,
 			AccessMode:   "3",
 			Subscription: &blogSubscriptions[reqSubId],
+			PublicAt:     post.PublicAt,
 		})
 		return
 
@@ -1589,6 +1613,7 @@ func (h *postHandler) checkMyContentAccess(ctx *gin.Context) {
 			Price:        *post.Price,
 			AccessMode:   "4",
 			Subscription: nil,
+			PublicAt:     post.PublicAt,
 		})
 		return
 
@@ -1661,13 +1686,22 @@ func (h *postHandler) buyPaidAccessRobokassa(ctx *gin.Context) {
 		return
 	}
 
-	link, err := h.service.GetPostRobokassaPaymentLink(ctx, post, *userId)
+	promoCode, ok := promoCodeFromQuery(ctx, h.service, blog.ID, post.ID, *userId)
+	if !ok {
+		return
+	}
+	link, err := h.service.GetPostRobokassaPaymentLink(ctx, post, *userId, promoCode)
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("failed to get payment link")
 		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
+	if link == "" {
+		loggingMap.SetMessage("promo code is used up")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{"payment_link": link})
 }
@@ -1686,7 +1720,7 @@ func (h *postHandler) byCategories(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
-	ctx.JSON(http.StatusOK, posts)
+	publicJSON(ctx, posts)
 }
 
 func (h *postHandler) addAnonView(ctx *gin.Context) {
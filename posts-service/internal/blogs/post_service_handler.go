@@ -114,6 +114,11 @@ func (h *postServiceHandler) grantPaidAccess(ctx *gin.Context) {
 		}
 	}
 
+	// the access is already granted, so failed promo code confirmation doesn't fail the request
+	if err := h.service.ConfirmPromoCodeUse(ctx, req.InvoiceId); err != nil {
+		loggingMap["promo_code_error"] = err.Error()
+	}
+
 	go h.service.notifService.PostPaidAccessAuthor(
 		blog.AuthorId.String(), blog.ID.String(), post.ID.String(),
 		req.UserId.String(), req.Value, req.Currency)
@@ -0,0 +1,452 @@
+package blogs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	requestuser "posts-service/pkg/hidepost-requestuser"
+	serverlogging "posts-service/pkg/serverlogging/gin"
+	"strings"
+	"time"
+)
+
+// promoCodeFromQuery returns the promo code from promo_code query param applicable to the item, or nil if the param is empty.
+// It responds with an error and returns false if the promo code can't be applied.
+func promoCodeFromQuery(ctx *gin.Context, service *Service, blogId, itemId, userId uuid.UUID) (*PromoCode, bool) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	code := ctx.Query("promo_code")
+	if code == "" {
+		return nil, true
+	}
+	loggingMap["promo_code_query_param"] = code
+
+	promoCode, err := service.PromoCodeForItem(ctx, code, blogId, itemId, userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get promo code")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if promoCode == nil {
+		loggingMap.SetMessage("promo code is not applicable")
+		ctx.JSON(http.StatusUnprocessableEntity, nil)
+		return nil, false
+	}
+	return promoCode, true
+}
+
+// authorBlogByParam returns the blog from id param if the request user is its author
+func (h *blogHandler) authorBlogByParam(ctx *gin.Context) *Blog {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return nil
+	}
+
+	blog, err := h.service.BlogById(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get blog by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return nil
+	}
+	if blog == nil {
+		loggingMap.SetMessage("blog by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return nil
+	}
+	if blog.AuthorId != *userId {
+		loggingMap.SetMessage("request user is not the author of the blog")
+		ctx.JSON(http.StatusForbidden, nil)
+		return nil
+	}
+	return blog
+}
+
+func (h *blogHandler) getPromoCodes(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	blog := h.authorBlogByParam(ctx)
+	if blog == nil {
+		return
+	}
+
+	promoCodes, err := h.service.repository.PromoCodesStatsByBlogId(ctx, blog.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get promo codes by blog id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, promoCodes)
+}
+
+func (h *blogHandler) createPromoCode(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	blog := h.authorBlogByParam(ctx)
+	if blog == nil {
+		return
+	}
+
+	var req CreatePromoCodeRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if req.DiscountType == PromoCodeDiscountTypePercent && req.DiscountValue >= 100 {
+		loggingMap.SetMessage("bad request, percent discount must be less than 100")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if ok, status := h.blogItemsExist(ctx, blog.ID, req.ItemIds); !ok {
+		ctx.JSON(status, nil)
+		return
+	}
+
+	exists, err := h.service.repository.PromoCodeByBlogIdAndCode(ctx, blog.ID, req.Code)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get promo code by code")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if exists != nil {
+		loggingMap.SetMessage("promo code already exists")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	if req.ItemIds == nil {
+		req.ItemIds = make([]uuid.UUID, 0)
+	}
+	timeNow := time.Now().UTC()
+	promoCode := PromoCode{
+		ID:            uuid.New(),
+		BlogId:        blog.ID,
+		Code:          strings.ToUpper(req.Code),
+		DiscountType:  req.DiscountType,
+		DiscountValue: req.DiscountValue,
+		MaxUses:       req.MaxUses,
+		ItemIds:       req.ItemIds,
+		ExpiresAt:     req.ExpiresAt,
+		IsActive:      true,
+		Created:       timeNow,
+		Updated:       timeNow,
+	}
+	err = h.service.repository.CreatePromoCode(ctx, &promoCode)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to create promo code")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("promo code created")
+	ctx.JSON(http.StatusCreated, promoCode)
+}
+
+func (h *blogHandler) updatePromoCode(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	blog := h.authorBlogByParam(ctx)
+	if blog == nil {
+		return
+	}
+
+	codeIdParam := ctx.Param("code_id")
+	codeId, err := uuid.Parse(codeIdParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param code id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	var req UpdatePromoCodeRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if req.DiscountType == PromoCodeDiscountTypePercent && req.DiscountValue >= 100 {
+		loggingMap.SetMessage("bad request, percent discount must be less than 100")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if ok, status := h.blogItemsExist(ctx, blog.ID, req.ItemIds); !ok {
+		ctx.JSON(status, nil)
+		return
+	}
+
+	promoCode, err := h.service.repository.PromoCodeById(ctx, codeId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get promo code by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if promoCode == nil || promoCode.BlogId != blog.ID {
+		loggingMap.SetMessage("promo code of the blog doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	if req.ItemIds == nil {
+		req.ItemIds = make([]uuid.UUID, 0)
+	}
+	promoCode.DiscountType = req.DiscountType
+	promoCode.DiscountValue = req.DiscountValue
+	promoCode.MaxUses = req.MaxUses
+	promoCode.ItemIds = req.ItemIds
+	promoCode.ExpiresAt = req.ExpiresAt
+	promoCode.IsActive = req.IsActive
+	promoCode.Updated = time.Now().UTC()
+
+	err = h.service.repository.UpdatePromoCode(ctx, promoCode)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to update promo code")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, promoCode)
+}
+
+// blogItemsExist checks that every id is a paid subscription or a paid post of the blog
+func (h *blogHandler) blogItemsExist(ctx *gin.Context, blogId uuid.UUID, itemIds []uuid.UUID) (bool, int) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	for _, itemId := range itemIds {
+		subscription, err := h.service.repository.SubscriptionById(ctx, itemId)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to get subscription by id")
+			return false, http.StatusInternalServerError
+		}
+		if subscription != nil && subscription.BlogId == blogId && !subscription.IsFree {
+			continue
+		}
+		post, err := h.service.repository.PostById(ctx, itemId)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to get post by id")
+			return false, http.StatusInternalServerError
+		}
+		if post != nil && post.BlogId == blogId && post.Price != nil {
+			continue
+		}
+		loggingMap.SetMessage("paid item of the blog doesn't exists: " + itemId.String())
+		return false, http.StatusBadRequest
+	}
+	return true, 0
+}
+
+// checkPromoCode returns the price of the subscription or the post from item_id query param discounted by the promo code
+func (h *blogHandler) checkPromoCode(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	itemIdParam := ctx.Query("item_id")
+	loggingMap["item_id_query_param"] = itemIdParam
+	itemId, err := uuid.Parse(itemIdParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param item id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	var price float64
+	subscription, err := h.service.repository.SubscriptionById(ctx, itemId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get subscription by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if subscription != nil && subscription.BlogId == id && !subscription.IsFree {
+		price = subscription.PriceRub
+	} else {
+		post, err := h.service.repository.PostById(ctx, itemId)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to get post by id")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		if post == nil || post.BlogId != id || post.Price == nil {
+			loggingMap.SetMessage("paid item of the blog doesn't exists")
+			ctx.JSON(http.StatusNotFound, nil)
+			return
+		}
+		price = *post.Price
+	}
+
+	promoCode, ok := promoCodeFromQuery(ctx, h.service, id, itemId, *userId)
+	if !ok {
+		return
+	}
+	if promoCode == nil {
+		loggingMap.SetMessage("promo code is empty")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	total, discount := promoCode.Apply(price)
+	ctx.JSON(http.StatusOK, PromoCodeCheckResponse{
+		Code:        promoCode.Code,
+		PriceRub:    price,
+		DiscountRub: discount,
+		TotalRub:    total,
+	})
+}
+
+// subscribeTrial starts the free trial of the paid subscription, the trial is given once per user and subscription
+func (h *blogHandler) subscribeTrial(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	subscription, err := h.service.repository.SubscriptionById(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get subscription by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if subscription == nil {
+		loggingMap.SetMessage("subscription by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if subscription.IsFree || !subscription.IsActive || subscription.TrialDays <= 0 {
+		loggingMap.SetMessage("subscription has no trial")
+		ctx.JSON(http.StatusForbidden, nil)
+		return
+	}
+
+	blog, err := h.service.BlogById(ctx, subscription.BlogId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get blog by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if blog == nil {
+		loggingMap.SetMessage("blog by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if blog.AuthorId == *userId {
+		loggingMap.SetMessage("request user is the author of the blog")
+		ctx.JSON(http.StatusForbidden, nil)
+		return
+	}
+
+	userSubscription, err := h.service.repository.UserSubscriptionByParams(ctx, *userId, blog.ID, subscription.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get user subscriptions by user id and blog id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if userSubscription != nil {
+		loggingMap.SetMessage("user already had the subscription")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+	if !reserveSubscriptionSlot(ctx, h.service, subscription, *userId) {
+		return
+	}
+
+	follow, err := h.service.repository.UserFollowByUserIdAndBlogId(ctx, *userId, blog.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get follow by user id and blog id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if follow == nil {
+		follow = &UserFollow{
+			ID:      uuid.New(),
+			UserId:  *userId,
+			BlogId:  blog.ID,
+			Created: time.Now().UTC(),
+			Updated: time.Now().UTC(),
+		}
+		err = h.service.repository.CreateUserFollow(ctx, follow)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to create user follow")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	timeNow := time.Now().UTC()
+	expiryDate := timeNow.Add(time.Duration(subscription.TrialDays) * 24 * time.Hour)
+	newUserSubscription := UserSubscription{
+		ID:             uuid.New(),
+		UserId:         *userId,
+		SubscriptionId: subscription.ID,
+		BlogId:         blog.ID,
+		Status:         UserSubscriptionStatusTrial,
+		IsActive:       true,
+		ExpiresAt:      &expiryDate,
+		Created:        timeNow,
+		Updated:        timeNow,
+	}
+	err = h.service.repository.CreateUserSubscription(ctx, &newUserSubscription)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to create user subscription")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("trial subscription started")
+	ctx.JSON(http.StatusCreated, newUserSubscription)
+}
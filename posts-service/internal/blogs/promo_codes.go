@@ -0,0 +1,407 @@
+package blogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"math"
+	"strings"
+	"time"
+)
+
+// promoCodeReservationTime is how long a pending use holds the promo code, it matches the invoice lifetime
+const promoCodeReservationTime = 1 * time.Hour
+
+// promoCodeMinPriceRub is the lowest price the promo code can discount to, as the payment can't be free
+const promoCodeMinPriceRub = 1.0
+
+// Apply returns the discounted price and the discount
+func (p *PromoCode) Apply(price float64) (float64, float64) {
+	var discounted float64
+	switch p.DiscountType {
+	case PromoCodeDiscountTypePercent:
+		discounted = price * (100 - p.DiscountValue) / 100
+	case PromoCodeDiscountTypeFixed:
+		discounted = price - p.DiscountValue
+	default:
+		discounted = price
+	}
+	discounted = math.Round(discounted*100) / 100
+	if discounted < promoCodeMinPriceRub {
+		discounted = math.Min(price, promoCodeMinPriceRub)
+	}
+	return discounted, math.Round((price-discounted)*100) / 100
+}
+
+// AppliesTo reports whether the promo code isn't restricted or is restricted to the item
+func (p *PromoCode) AppliesTo(itemId uuid.UUID) bool {
+	if len(p.ItemIds) == 0 {
+		return true
+	}
+	for _, id := range p.ItemIds {
+		if id == itemId {
+			return true
+		}
+	}
+	return false
+}
+
+// PromoCodeForItem returns the blog promo code if the user can apply it to the item now, or nil otherwise
+func (s *Service) PromoCodeForItem(ctx context.Context, code string, blogId, itemId, userId uuid.UUID) (*PromoCode, error) {
+	promoCode, err := s.repository.PromoCodeByBlogIdAndCode(ctx, blogId, strings.TrimSpace(code))
+	if err != nil || promoCode == nil {
+		return nil, err
+	}
+	timeNow := time.Now().UTC()
+	if !promoCode.IsActive || (promoCode.ExpiresAt != nil && promoCode.ExpiresAt.Before(timeNow)) {
+		return nil, nil
+	}
+	if !promoCode.AppliesTo(itemId) {
+		return nil, nil
+	}
+
+	used, err := s.repository.PromoCodeUsedByUser(ctx, promoCode.ID, userId)
+	if err != nil || used {
+		return nil, err
+	}
+	if promoCode.MaxUses != nil {
+		usesCount, err := s.repository.CountPromoCodeUses(ctx, promoCode.ID, timeNow.Add(-promoCodeReservationTime))
+		if err != nil {
+			return nil, err
+		}
+		if usesCount >= *promoCode.MaxUses {
+			return nil, nil
+		}
+	}
+	return promoCode, nil
+}
+
+// reservePromoCode creates the pending use of the promo code for the payment link.
+// The discounted price is the use price minus its discount.
+// Returns nil use if the promo code was used up meanwhile.
+func (s *Service) reservePromoCode(ctx context.Context, promoCode *PromoCode, userId, itemId uuid.UUID, itemType string, price float64) (*PromoCodeUse, error) {
+	_, discount := promoCode.Apply(price)
+	timeNow := time.Now().UTC()
+	use := PromoCodeUse{
+		ID:          uuid.New(),
+		PromoCodeId: promoCode.ID,
+		UserId:      userId,
+		ItemId:      itemId,
+		ItemType:    itemType,
+		PriceRub:    price,
+		DiscountRub: discount,
+		Status:      PromoCodeUseStatusPending,
+		Created:     timeNow,
+		Updated:     timeNow,
+	}
+	reserved, err := s.repository.ReservePromoCodeUse(ctx, &use, promoCode.MaxUses, timeNow.Add(-promoCodeReservationTime))
+	if err != nil || !reserved {
+		return nil, err
+	}
+	return &use, nil
+}
+
+// ConfirmPromoCodeUse marks the pending promo code use of the paid invoice as used.
+// The use isn't confirmed if its reservation expired and the promo code was used up meanwhile.
+func (s *Service) ConfirmPromoCodeUse(ctx context.Context, invoiceId int) error {
+	if invoiceId == 0 {
+		return nil
+	}
+	confirmed, err := s.repository.ConfirmPromoCodeUse(ctx, invoiceId, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return fmt.Errorf("promo code of invoice %d is used up", invoiceId)
+	}
+	return nil
+}
+
+func (r *Repository) PromoCodeById(ctx context.Context, id uuid.UUID) (*PromoCode, error) {
+	query := `select id, blog_id, code, discount_type, discount_value, max_uses, item_ids, expires_at, is_active, created, updated
+			from promo_codes
+			where id = $1`
+
+	var item PromoCode
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&item.ID,
+		&item.BlogId,
+		&item.Code,
+		&item.DiscountType,
+		&item.DiscountValue,
+		&item.MaxUses,
+		&item.ItemIds,
+		&item.ExpiresAt,
+		&item.IsActive,
+		&item.Created,
+		&item.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) PromoCodeByBlogIdAndCode(ctx context.Context, blogId uuid.UUID, code string) (*PromoCode, error) {
+	query := `select id, blog_id, code, discount_type, discount_value, max_uses, item_ids, expires_at, is_active, created, updated
+			from promo_codes
+			where blog_id = $1 and upper(code) = upper($2)`
+
+	var item PromoCode
+	err := r.db.QueryRow(ctx, query, blogId, code).Scan(
+		&item.ID,
+		&item.BlogId,
+		&item.Code,
+		&item.DiscountType,
+		&item.DiscountValue,
+		&item.MaxUses,
+		&item.ItemIds,
+		&item.ExpiresAt,
+		&item.IsActive,
+		&item.Created,
+		&item.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// PromoCodesStatsByBlogId returns promo codes of the blog with the count of uses and the sum of discounts
+func (r *Repository) PromoCodesStatsByBlogId(ctx context.Context, blogId uuid.UUID) ([]PromoCodeStatsResponse, error) {
+	query := `select p.id, p.blog_id, p.code, p.discount_type, p.discount_value, p.max_uses, p.item_ids, p.expires_at,
+       			p.is_active, p.created, p.updated, count(u.id), coalesce(sum(u.discount_rub), 0)
+			from promo_codes p
+			left join promo_code_uses u on u.promo_code_id = p.id and u.status = $2
+			where p.blog_id = $1
+			group by p.id
+			order by p.created desc`
+
+	rows, err := r.db.Query(ctx, query, blogId, PromoCodeUseStatusUsed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]PromoCodeStatsResponse, 0)
+	var item PromoCodeStatsResponse
+	for rows.Next() {
+		err = rows.Scan(
+			&item.ID,
+			&item.BlogId,
+			&item.Code,
+			&item.DiscountType,
+			&item.DiscountValue,
+			&item.MaxUses,
+			&item.ItemIds,
+			&item.ExpiresAt,
+			&item.IsActive,
+			&item.Created,
+			&item.Updated,
+			&item.UsesCount,
+			&item.DiscountRub,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, item)
+	}
+	return resultArray, nil
+}
+
+func (r *Repository) CreatePromoCode(ctx context.Context, promoCode *PromoCode) error {
+	query := `insert into promo_codes
+	(id, blog_id, code, discount_type, discount_value, max_uses, item_ids, expires_at, is_active, created, updated)
+	values
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err := r.db.Exec(ctx, query,
+		promoCode.ID,
+		promoCode.BlogId,
+		promoCode.Code,
+		promoCode.DiscountType,
+		promoCode.DiscountValue,
+		promoCode.MaxUses,
+		promoCode.ItemIds,
+		promoCode.ExpiresAt,
+		promoCode.IsActive,
+		promoCode.Created,
+		promoCode.Updated,
+	)
+	return err
+}
+
+func (r *Repository) UpdatePromoCode(ctx context.Context, promoCode *PromoCode) error {
+	query := `update promo_codes set
+		discount_type = $2,
+		discount_value = $3,
+		max_uses = $4,
+		item_ids = $5,
+		expires_at = $6,
+		is_active = $7,
+		updated = $8
+		where id = $1`
+	_, err := r.db.Exec(ctx, query,
+		promoCode.ID,
+		promoCode.DiscountType,
+		promoCode.DiscountValue,
+		promoCode.MaxUses,
+		promoCode.ItemIds,
+		promoCode.ExpiresAt,
+		promoCode.IsActive,
+		promoCode.Updated,
+	)
+	return err
+}
+
+func (r *Repository) PromoCodeUsedByUser(ctx context.Context, promoCodeId, userId uuid.UUID) (bool, error) {
+	query := `select exists(select 1 from promo_code_uses where promo_code_id = $1 and user_id = $2 and status = $3)`
+	var exists bool
+	err := r.db.QueryRow(ctx, query, promoCodeId, userId, PromoCodeUseStatusUsed).Scan(&exists)
+	return exists, err
+}
+
+// CountPromoCodeUses counts users that used the promo code or hold it with a pending use created after the time
+func (r *Repository) CountPromoCodeUses(ctx context.Context, promoCodeId uuid.UUID, pendingAfter time.Time) (int, error) {
+	query := `select count(distinct user_id) from promo_code_uses
+			where promo_code_id = $1 and (status = $2 or (status = $3 and created > $4))`
+	var count int
+	err := r.db.QueryRow(ctx, query, promoCodeId, PromoCodeUseStatusUsed, PromoCodeUseStatusPending, pendingAfter).Scan(&count)
+	return count, err
+}
+
+func (r *Repository) CountBlogPromoCodeUses(ctx context.Context, blogId uuid.UUID) (int, error) {
+	query := `select count(u.id) from promo_code_uses u
+				join promo_codes p on u.promo_code_id = p.id
+				where p.blog_id = $1 and u.status = $2`
+	var count int
+	err := r.db.QueryRow(ctx, query, blogId, PromoCodeUseStatusUsed).Scan(&count)
+	return count, err
+}
+
+// ReservePromoCodeUse creates the promo code use under the lock of the promo code,
+// so concurrent reservations can't exceed the max uses. Returns false if the promo code is used up
+// or the user already used it or holds it with a pending use.
+func (r *Repository) ReservePromoCodeUse(ctx context.Context, use *PromoCodeUse, maxUses *int, pendingAfter time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `select id from promo_codes where id = $1 for update`, use.PromoCodeId)
+	if err != nil {
+		return false, err
+	}
+
+	var held bool
+	err = tx.QueryRow(ctx, `select exists(select 1 from promo_code_uses
+			where promo_code_id = $1 and user_id = $2 and (status = $3 or (status = $4 and created > $5)))`,
+		use.PromoCodeId, use.UserId, PromoCodeUseStatusUsed, PromoCodeUseStatusPending, pendingAfter).Scan(&held)
+	if err != nil {
+		return false, err
+	}
+	if held {
+		return false, nil
+	}
+
+	if maxUses != nil {
+		query := `select count(distinct user_id) from promo_code_uses
+			where promo_code_id = $1 and user_id != $2 and (status = $3 or (status = $4 and created > $5))`
+		var count int
+		err = tx.QueryRow(ctx, query, use.PromoCodeId, use.UserId,
+			PromoCodeUseStatusUsed, PromoCodeUseStatusPending, pendingAfter).Scan(&count)
+		if err != nil {
+			return false, err
+		}
+		if count >= *maxUses {
+			return false, nil
+		}
+	}
+
+	query := `insert into promo_code_uses
+	(id, promo_code_id, user_id, item_id, item_type, price_rub, discount_rub, invoice_id, status, created, updated)
+	values
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err = tx.Exec(ctx, query,
+		use.ID,
+		use.PromoCodeId,
+		use.UserId,
+		use.ItemId,
+		use.ItemType,
+		use.PriceRub,
+		use.DiscountRub,
+		use.InvoiceId,
+		use.Status,
+		use.Created,
+		use.Updated,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *Repository) SetPromoCodeUseInvoice(ctx context.Context, use *PromoCodeUse, invoiceId int) error {
+	use.InvoiceId = &invoiceId
+	use.Updated = time.Now().UTC()
+	query := `update promo_code_uses set invoice_id = $2, updated = $3 where id = $1`
+	_, err := r.db.Exec(ctx, query, use.ID, use.InvoiceId, use.Updated)
+	return err
+}
+
+// ConfirmPromoCodeUse marks the latest pending use of the invoice as used under the lock of the promo code.
+// Returns false and keeps the use pending if the user already used the promo code or the other users
+// used up its max uses, e.g. after the reservation of the use expired.
+func (r *Repository) ConfirmPromoCodeUse(ctx context.Context, invoiceId int, timeNow time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var useId, promoCodeId, userId uuid.UUID
+	err = tx.QueryRow(ctx, `select id, promo_code_id, user_id from promo_code_uses
+			where invoice_id = $1 and status = $2
+			order by created desc limit 1`, invoiceId, PromoCodeUseStatusPending).Scan(&useId, &promoCodeId, &userId)
+	if err != nil {
+		// the invoice was paid without promo code or its use is confirmed already
+		if errors.Is(err, pgx.ErrNoRows) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	var maxUses *int
+	err = tx.QueryRow(ctx, `select max_uses from promo_codes where id = $1 for update`, promoCodeId).Scan(&maxUses)
+	if err != nil {
+		return false, err
+	}
+	var usedByUser bool
+	var usesCount int
+	err = tx.QueryRow(ctx, `select coalesce(bool_or(user_id = $2), false), count(distinct user_id)
+			from promo_code_uses
+			where promo_code_id = $1 and status = $3`,
+		promoCodeId, userId, PromoCodeUseStatusUsed).Scan(&usedByUser, &usesCount)
+	if err != nil {
+		return false, err
+	}
+	if usedByUser || (maxUses != nil && usesCount >= *maxUses) {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, `update promo_code_uses set status = $2, updated = $3 where id = $1`,
+		useId, PromoCodeUseStatusUsed, timeNow)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,199 @@
+package blogs
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+// RefundItem reverses the blog income of the refunded payment. The reversal is saved as a negative
+// income, so rubles already credited to the author wallet are clawed back by the income worker.
+// Full refunds also revoke the access that was granted for the payment.
+// The reversal is saved once per refund id, so retried requests don't reverse the income twice.
+// Returns nil blog if the refunded item doesn't exist.
+func (s *Service) RefundItem(ctx context.Context, req *RefundItemServiceRequest) (*Blog, error) {
+	timeNow := time.Now().UTC()
+
+	var blogId uuid.UUID
+	incomeItemId, incomeItemType := req.ItemId, req.ItemType
+	switch req.ItemType {
+	case PaymentItemTypeSubscription:
+		subscription, err := s.repository.SubscriptionById(ctx, req.ItemId)
+		if err != nil || subscription == nil {
+			return nil, err
+		}
+		blogId = subscription.BlogId
+		if req.Full {
+			if err := s.revokeItemAccess(ctx, subscription.ID, req.ItemType, blogId, req.UserId); err != nil {
+				return nil, err
+			}
+		}
+	case PaymentItemTypePost:
+		post, err := s.repository.PostById(ctx, req.ItemId)
+		if err != nil || post == nil {
+			return nil, err
+		}
+		blogId = post.BlogId
+		if req.Full {
+			if err := s.revokeItemAccess(ctx, post.ID, req.ItemType, blogId, req.UserId); err != nil {
+				return nil, err
+			}
+		}
+	case PaymentItemTypeDonation:
+		donation, err := s.repository.DonationById(ctx, req.ItemId)
+		if err != nil || donation == nil {
+			return nil, err
+		}
+		blogId = donation.BlogId
+		if req.Full {
+			donation.Status = DonationStatusRefunded
+			donation.Updated = timeNow
+			if err := s.repository.UpdateDonation(ctx, donation); err != nil {
+				return nil, fmt.Errorf("failed to update donation: %v", err)
+			}
+		}
+	case PaymentItemTypeGift:
+		// the income of the gift was saved for the gifted item, and the access was granted to the redeemer
+		gift, err := s.repository.GiftById(ctx, req.ItemId)
+		if err != nil || gift == nil {
+			return nil, err
+		}
+		blogId = gift.BlogId
+		incomeItemId, incomeItemType = gift.ItemId, gift.ItemType
+		if req.Full {
+			if gift.Status == GiftStatusRedeemed && gift.RedeemedBy != nil {
+				if err := s.revokeItemAccess(ctx, gift.ItemId, gift.ItemType, blogId, *gift.RedeemedBy); err != nil {
+					return nil, err
+				}
+			}
+			gift.Status = GiftStatusRefunded
+			gift.Updated = timeNow
+			if err := s.repository.UpdateGift(ctx, gift); err != nil {
+				return nil, fmt.Errorf("failed to update gift: %v", err)
+			}
+		}
+	case PaymentItemTypeTierChange:
+		// the income of the upgrade was saved for the new tier
+		change, err := s.repository.TierChangeById(ctx, req.ItemId)
+		if err != nil || change == nil {
+			return nil, err
+		}
+		blogId = change.BlogId
+		incomeItemId, incomeItemType = change.ToSubscriptionId, PaymentItemTypeSubscription
+		if req.Full {
+			if err := s.revokeItemAccess(ctx, change.ToSubscriptionId, PaymentItemTypeSubscription, blogId, req.UserId); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown item type: %s", req.ItemType)
+	}
+
+	blog, err := s.repository.BlogById(ctx, blogId)
+	if err != nil || blog == nil {
+		return nil, err
+	}
+
+	blogIncome := BlogIncome{
+		ID:               uuid.New(),
+		BlogId:           blog.ID,
+		UserId:           req.UserId,
+		Value:            -req.Value,
+		Currency:         req.Currency,
+		ItemId:           incomeItemId,
+		ItemType:         incomeItemType,
+		SentToUserWallet: req.Currency == CurrencyTon,
+		Created:          timeNow,
+	}
+	created, err := s.repository.CreateRefundReversal(ctx, req.RefundId, &blogIncome)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blog income reversal: %v", err)
+	}
+	if !created {
+		return blog, nil
+	}
+
+	go s.notifService.RefundAuthor(
+		blog.AuthorId.String(), blog.ID.String(), req.ItemId.String(), req.ItemType,
+		req.UserId.String(), req.Value, req.Currency)
+
+	go s.notifService.RefundUser(
+		req.UserId.String(), blog.ID.String(), req.ItemId.String(), req.ItemType,
+		req.Value, req.Currency, req.Full)
+
+	return blog, nil
+}
+
+// revokeItemAccess expires the user subscription or deletes the post paid access of the user
+func (s *Service) revokeItemAccess(ctx context.Context, itemId uuid.UUID, itemType string, blogId, userId uuid.UUID) error {
+	timeNow := time.Now().UTC()
+	switch itemType {
+	case PaymentItemTypeSubscription:
+		userSubscription, err := s.repository.UserSubscriptionByParams(ctx, userId, blogId, itemId)
+		if err != nil {
+			return fmt.Errorf("failed to get user subscription: %v", err)
+		}
+		if userSubscription != nil {
+			userSubscription.IsActive = false
+			userSubscription.Status = UserSubscriptionStatusExpired
+			userSubscription.ExpiresAt = &timeNow
+			userSubscription.Updated = timeNow
+			if err := s.repository.UpdateUserSubscription(ctx, userSubscription); err != nil {
+				return fmt.Errorf("failed to revoke user subscription: %v", err)
+			}
+		}
+	case PaymentItemTypePost:
+		paidAccess, err := s.repository.PostPaidAccessByPostIdAndUserId(ctx, itemId, userId)
+		if err != nil {
+			return fmt.Errorf("failed to get post paid access: %v", err)
+		}
+		if paidAccess != nil {
+			if err := s.repository.DeletePostPaidAccess(ctx, paidAccess); err != nil {
+				return fmt.Errorf("failed to revoke post paid access: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// CreateRefundReversal saves the negative blog income of the refund unless the refund is already reversed.
+// Returns false if the refund is already reversed.
+func (r *Repository) CreateRefundReversal(ctx context.Context, refundId string, blogIncome *BlogIncome) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `insert into refund_reversals (refund_id, blog_income_id, created) values ($1, $2, $3)
+	on conflict (refund_id) do nothing`
+	tag, err := tx.Exec(ctx, query, refundId, blogIncome.ID, blogIncome.Created)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	query = `insert into blog_incomes
+	(id, blog_id, user_id, value, currency, item_id, item_type, sent_to_user_wallet, created)
+	values
+	($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err = tx.Exec(ctx, query,
+		blogIncome.ID,
+		blogIncome.BlogId,
+		blogIncome.UserId,
+		blogIncome.Value,
+		blogIncome.Currency,
+		blogIncome.ItemId,
+		blogIncome.ItemType,
+		blogIncome.SentToUserWallet,
+		blogIncome.Created,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
@@ -431,7 +431,7 @@ func (r *Repository) SetUserCategoriesPreferences(ctx context.Context, userId uu
 func (r *Repository) AllPosts(ctx context.Context) ([]Post, error) {
 
 	query := `select id, blog_id, title, url, short_description, tags_string, status, cover, 
-       access_mode, price, subscription_id, likes_count, comments_count, created, updated
+       access_mode, price, subscription_id, early_access_days, public_at, likes_count, comments_count, created, updated
 	from posts
 	order by created desc`
 
@@ -456,6 +456,8 @@ func (r *Repository) AllPosts(ctx context.Context) ([]Post, error) {
 			&post.AccessMode,
 			&post.Price,
 			&post.SubscriptionId,
+			&post.EarlyAccessDays,
+			&post.PublicAt,
 			&post.LikesCount,
 			&post.CommentsCount,
 			&post.Created,
@@ -472,7 +474,7 @@ func (r *Repository) AllPosts(ctx context.Context) ([]Post, error) {
 
 func (r *Repository) PostsByCategories(ctx context.Context, categoryCodes []string) ([]Post, error) {
 	query := `SELECT p.id, p.blog_id, p.title, p.url, p.short_description, p.tags_string, p.status, p.cover,
-              p.access_mode, p.price, p.subscription_id, p.likes_count, p.comments_count, p.created, p.updated
+              p.access_mode, p.price, p.subscription_id, p.early_access_days, p.public_at, p.likes_count, p.comments_count, p.created, p.updated
               FROM posts p
               JOIN blogs b ON p.blog_id = b.id
               JOIN blog_categories bc ON b.id = bc.blog_id
@@ -500,6 +502,8 @@ func (r *Repository) PostsByCategories(ctx context.Context, categoryCodes []stri
 			&post.AccessMode,
 			&post.Price,
 			&post.SubscriptionId,
+			&post.EarlyAccessDays,
+			&post.PublicAt,
 			&post.LikesCount,
 			&post.CommentsCount,
 			&post.Created,
@@ -515,7 +519,7 @@ func (r *Repository) PostsByCategories(ctx context.Context, categoryCodes []stri
 
 func (r *Repository) PostById(ctx context.Context, id uuid.UUID) (*Post, error) {
 	query := `select id, blog_id, title, url, short_description, tags_string, status, cover, 
-       access_mode, price, subscription_id, likes_count, comments_count, created, updated
+       access_mode, price, subscription_id, early_access_days, public_at, likes_count, comments_count, created, updated
 	from posts
 	where id = $1`
 	var post Post
@@ -531,6 +535,8 @@ func (r *Repository) PostById(ctx context.Context, id uuid.UUID) (*Post, error)
 		&post.AccessMode,
 		&post.Price,
 		&post.SubscriptionId,
+		&post.EarlyAccessDays,
+		&post.PublicAt,
 		&post.LikesCount,
 		&post.CommentsCount,
 		&post.Created,
@@ -548,7 +554,7 @@ func (r *Repository) PostById(ctx context.Context, id uuid.UUID) (*Post, error)
 func (r *Repository) PostsByBlogId(ctx context.Context, blogId uuid.UUID) ([]Post, error) {
 
 	query := `select id, blog_id, title, url, short_description, tags_string, status, cover, 
-       access_mode, price, subscription_id, likes_count, comments_count, created, updated
+       access_mode, price, subscription_id, early_access_days, public_at, likes_count, comments_count, created, updated
 	from posts
 	where blog_id = $1
 	order by created desc`
@@ -574,6 +580,8 @@ func (r *Repository) PostsByBlogId(ctx context.Context, blogId uuid.UUID) ([]Pos
 			&post.AccessMode,
 			&post.Price,
 			&post.SubscriptionId,
+			&post.EarlyAccessDays,
+			&post.PublicAt,
 			&post.LikesCount,
 			&post.CommentsCount,
 			&post.Created,
@@ -591,7 +599,7 @@ func (r *Repository) PostsByBlogIdList(ctx context.Context, ids []string) ([]Pos
 		return make([]Post, 0), nil
 	}
 	query := `select id, blog_id, title, url, short_description, tags_string, status, cover, 
-       access_mode, price, subscription_id, likes_count, comments_count, created, updated
+       access_mode, price, subscription_id, early_access_days, public_at, likes_count, comments_count, created, updated
 	from posts`
 	placeholders := make([]string, len(ids))
 	idInterfaceSlice := make([]interface{}, len(ids))
@@ -623,6 +631,8 @@ func (r *Repository) PostsByBlogIdList(ctx context.Context, ids []string) ([]Pos
 			&post.AccessMode,
 			&post.Price,
 			&post.SubscriptionId,
+			&post.EarlyAccessDays,
+			&post.PublicAt,
 			&post.LikesCount,
 			&post.CommentsCount,
 			&post.Created,
@@ -639,7 +649,7 @@ func (r *Repository) PostsByBlogIdList(ctx context.Context, ids []string) ([]Pos
 func (r *Repository) PostsByBlogIdAndUrl(ctx context.Context, blogId uuid.UUID, url string) (*Post, error) {
 
 	query := `select id, blog_id, title, url, short_description, tags_string, status, cover, 
-       access_mode, price, subscription_id, likes_count, comments_count, created, updated
+       access_mode, price, subscription_id, early_access_days, public_at, likes_count, comments_count, created, updated
 	from posts
 	where blog_id = $1 and url = $2
 	order by created desc`
@@ -657,6 +667,8 @@ func (r *Repository) PostsByBlogIdAndUrl(ctx context.Context, blogId uuid.UUID,
 		&post.AccessMode,
 		&post.Price,
 		&post.SubscriptionId,
+		&post.EarlyAccessDays,
+		&post.PublicAt,
 		&post.LikesCount,
 		&post.CommentsCount,
 		&post.Created,
@@ -674,9 +686,9 @@ func (r *Repository) PostsByBlogIdAndUrl(ctx context.Context, blogId uuid.UUID,
 func (r *Repository) CreatePost(ctx context.Context, post *Post) error {
 	query := `insert into posts
 	(id, blog_id, title, url, short_description, tags_string, status, cover, 
-	 access_mode, price, subscription_id, created, updated)
+	 access_mode, price, subscription_id, early_access_days, public_at, created, updated)
 	values
-	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
 	_, err := r.db.Exec(ctx, query,
 		post.ID,
 		post.BlogId,
@@ -689,6 +701,8 @@ func (r *Repository) CreatePost(ctx context.Context, post *Post) error {
 		post.AccessMode,
 		post.Price,
 		post.SubscriptionId,
+		post.EarlyAccessDays,
+		post.PublicAt,
 		post.Created,
 		post.Updated,
 	)
@@ -707,8 +721,10 @@ func (r *Repository) UpdatePost(ctx context.Context, post *Post) error {
 	access_mode = $9,
 	price = $10,
 	subscription_id = $11,
-	created = $12,
-	updated = $13
+	early_access_days = $12,
+	public_at = $13,
+	created = $14,
+	updated = $15
 	where id = $1`
 	_, err := r.db.Exec(ctx, query,
 		post.ID,
@@ -722,6 +738,8 @@ func (r *Repository) UpdatePost(ctx context.Context, post *Post) error {
 		post.AccessMode,
 		post.Price,
 		post.SubscriptionId,
+		post.EarlyAccessDays,
+		post.PublicAt,
 		post.Created,
 		post.Updated,
 	)
@@ -890,7 +908,7 @@ func (r *Repository) SubscriptionsByIdList(ctx context.Context, ids []string) ([
 	if len(ids) == 0 {
 		return make([]Subscription, 0), nil
 	}
-	query := `select id, blog_id, title, short_description, cover, is_free, price_rub, is_active, created, updated 
+	query := `select id, blog_id, title, short_description, cover, is_free, price_rub, trial_days, perks, max_subscribers, is_active, created, updated 
 			from subscriptions
 			where id = any($1)
 			order by price_rub, created, is_active desc
@@ -911,6 +929,9 @@ func (r *Repository) SubscriptionsByIdList(ctx context.Context, ids []string) ([
 			&subscription.Cover,
 			&subscription.IsFree,
 			&subscription.PriceRub,
+			&subscription.TrialDays,
+			&subscription.Perks,
+			&subscription.MaxSubscribers,
 			&subscription.IsActive,
 			&subscription.Created,
 			&subscription.Updated,
@@ -925,7 +946,7 @@ func (r *Repository) SubscriptionsByIdList(ctx context.Context, ids []string) ([
 }
 
 func (r *Repository) SubscriptionsByBlogId(ctx context.Context, blogId uuid.UUID) ([]Subscription, error) {
-	query := `select id, blog_id, title, short_description, cover, is_free, price_rub, is_active, created, updated 
+	query := `select id, blog_id, title, short_description, cover, is_free, price_rub, trial_days, perks, max_subscribers, is_active, created, updated 
 			from subscriptions where blog_id = $1
 			order by price_rub
 			`
@@ -945,6 +966,9 @@ func (r *Repository) SubscriptionsByBlogId(ctx context.Context, blogId uuid.UUID
 			&subscription.Cover,
 			&subscription.IsFree,
 			&subscription.PriceRub,
+			&subscription.TrialDays,
+			&subscription.Perks,
+			&subscription.MaxSubscribers,
 			&subscription.IsActive,
 			&subscription.Created,
 			&subscription.Updated,
@@ -960,7 +984,7 @@ func (r *Repository) SubscriptionsByBlogId(ctx context.Context, blogId uuid.UUID
 
 func (r *Repository) SubscriptionById(ctx context.Context, id uuid.UUID) (*Subscription, error) {
 
-	query := `select id, blog_id, title, short_description, cover, is_free, price_rub, is_active, created, updated 
+	query := `select id, blog_id, title, short_description, cover, is_free, price_rub, trial_days, perks, max_subscribers, is_active, created, updated 
 			from subscriptions where id = $1`
 
 	var subscription Subscription
@@ -972,6 +996,9 @@ func (r *Repository) SubscriptionById(ctx context.Context, id uuid.UUID) (*Subsc
 		&subscription.Cover,
 		&subscription.IsFree,
 		&subscription.PriceRub,
+		&subscription.TrialDays,
+		&subscription.Perks,
+		&subscription.MaxSubscribers,
 		&subscription.IsActive,
 		&subscription.Created,
 		&subscription.Updated,
@@ -988,9 +1015,9 @@ func (r *Repository) SubscriptionById(ctx context.Context, id uuid.UUID) (*Subsc
 
 func (r *Repository) CreateSubscription(ctx context.Context, subscription *Subscription) error {
 	query := `insert into subscriptions
-	(id, blog_id, title, short_description, cover, is_free, price_rub, is_active, created, updated)
+	(id, blog_id, title, short_description, cover, is_free, price_rub, trial_days, perks, max_subscribers, is_active, created, updated)
 	values
-	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
 	_, err := r.db.Exec(ctx, query,
 		subscription.ID,
 		subscription.BlogId,
@@ -999,6 +1026,9 @@ func (r *Repository) CreateSubscription(ctx context.Context, subscription *Subsc
 		subscription.Cover,
 		subscription.IsFree,
 		subscription.PriceRub,
+		subscription.TrialDays,
+		subscription.Perks,
+		subscription.MaxSubscribers,
 		subscription.IsActive,
 		subscription.Created,
 		subscription.Updated,
@@ -1015,9 +1045,12 @@ func (r *Repository) UpdateSubscription(ctx context.Context, subscription *Subsc
 		cover = $5,
 		is_free = $6,
 		price_rub = $7,
-		is_active = $8,
-		created = $9,
-		updated = $10
+		trial_days = $8,
+		perks = $9,
+		max_subscribers = $10,
+		is_active = $11,
+		created = $12,
+		updated = $13
 		where id = $1
 		`
 	_, err := r.db.Exec(ctx, query,
@@ -1028,6 +1061,9 @@ func (r *Repository) UpdateSubscription(ctx context.Context, subscription *Subsc
 		subscription.Cover,
 		subscription.IsFree,
 		subscription.PriceRub,
+		subscription.TrialDays,
+		subscription.Perks,
+		subscription.MaxSubscribers,
 		subscription.IsActive,
 		subscription.Created,
 		subscription.Updated,
@@ -1176,6 +1212,33 @@ func (r *Repository) UserSubscriptionsByUserIdAndBlogId(ctx context.Context, use
 	return resultArray, nil
 }
 
+func (r *Repository) UserSubscriptionById(ctx context.Context, id uuid.UUID) (*UserSubscription, error) {
+	query := `select id, user_id, subscription_id, blog_id, status, is_active, expires_at, created, updated 
+			from user_subscriptions 
+			where id = $1`
+
+	var subscription UserSubscription
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&subscription.ID,
+		&subscription.UserId,
+		&subscription.SubscriptionId,
+		&subscription.BlogId,
+		&subscription.Status,
+		&subscription.IsActive,
+		&subscription.ExpiresAt,
+		&subscription.Created,
+		&subscription.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
 func (r *Repository) UserSubscriptionByParams(ctx context.Context, userId uuid.UUID, blogId uuid.UUID, subscriptionId uuid.UUID) (*UserSubscription, error) {
 	query := `select id, user_id, subscription_id, blog_id, status, is_active, expires_at, created, updated 
 			from user_subscriptions 
@@ -1255,10 +1318,23 @@ func (r *Repository) CountBlogPaidUserSubscriptions(ctx context.Context, blogId
 
 	query := `select count(us.id) from user_subscriptions us
 				 join subscriptions s on us.subscription_id = s.id
-                 where s.blog_id = $1 and us.is_active = true and s.is_free = false`
+                 where s.blog_id = $1 and us.is_active = true and s.is_free = false and us.status != $2`
 
 	var count int
-	err := r.db.QueryRow(ctx, query, blogId).Scan(&count)
+	err := r.db.QueryRow(ctx, query, blogId, UserSubscriptionStatusTrial).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *Repository) CountBlogTrialUserSubscriptions(ctx context.Context, blogId uuid.UUID) (int, error) {
+
+	query := `select count(id) from user_subscriptions
+                 where blog_id = $1 and is_active = true and status = $2`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, blogId, UserSubscriptionStatusTrial).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -1456,6 +1532,12 @@ func (r *Repository) CreatePostPaidAccess(ctx context.Context, postPaidAccess *P
 	return err
 }
 
+func (r *Repository) DeletePostPaidAccess(ctx context.Context, postPaidAccess *PostPaidAccess) error {
+	query := `delete from post_paid_access where id = $1`
+	_, err := r.db.Exec(ctx, query, postPaidAccess.ID)
+	return err
+}
+
 func (r *Repository) AllGoals(ctx context.Context) ([]Goal, error) {
 
 	query := `select id, blog_id, type, description, target, current, created, updated from goals`
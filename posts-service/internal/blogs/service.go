@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gosimple/slug"
+	"math"
 	"net/url"
 	"posts-service/internal/billing"
 	"posts-service/internal/comments"
 	"posts-service/internal/files"
 	"posts-service/internal/notifications"
+	"posts-service/internal/toncoin"
 	"posts-service/internal/users"
 	configService "posts-service/pkg/config-client"
 	"strings"
@@ -24,6 +26,7 @@ type Service struct {
 	commentsService *comments.Service
 	usersService    *users.Service
 	notifService    *notifications.Service
+	tonWatcher      toncoin.Watcher
 
 	mu *sync.RWMutex
 
@@ -32,6 +35,10 @@ type Service struct {
 	commentsTicker         *time.Ticker
 	userSubscriptionTicker *time.Ticker
 	incomeTicker           *time.Ticker
+	toncoinTicker          *time.Ticker
+	giftsTicker            *time.Ticker
+	waitlistTicker         *time.Ticker
+	earlyAccessTicker      *time.Ticker
 
 	mainPageLikesRequirement    int
 	mainPageCommentsRequirement int
@@ -40,10 +47,14 @@ type Service struct {
 
 	donationsRobokassaMinValue float64
 	donationsToncoinMinValue   float64
+
+	// toncoinRubRate is the latest stored toncoin rate in rubles, updated by the toncoin donations worker
+	toncoinRubRate float64
 }
 
 func NewService(repository *Repository,
 	filesService *files.Service, billingService *billing.Service, commentsService *comments.Service, usersService *users.Service, notifService *notifications.Service,
+	tonWatcher toncoin.Watcher,
 	mpLikesReq, mpCommentsReq, mpViewsReq, mpDislikesReq int, donatRobokassaMinValue, donatToncoinMinValue float64,
 	cfgService *configService.ConfigServiceManager) *Service {
 
@@ -54,6 +65,7 @@ func NewService(repository *Repository,
 		commentsService: commentsService,
 		usersService:    usersService,
 		notifService:    notifService,
+		tonWatcher:      tonWatcher,
 
 		mu: &sync.RWMutex{},
 
@@ -81,6 +93,18 @@ func NewService(repository *Repository,
 	service.incomeTicker = time.NewTicker(1 * time.Minute)
 	go service.StartBlogIncomeWorker(context.Background(), service.incomeTicker)
 
+	service.toncoinTicker = time.NewTicker(1 * time.Minute)
+	go service.StartToncoinDonationsWorker(context.Background(), service.toncoinTicker)
+
+	service.giftsTicker = time.NewTicker(1 * time.Minute)
+	go service.StartGiftsWorker(context.Background(), service.giftsTicker)
+
+	service.waitlistTicker = time.NewTicker(1 * time.Minute)
+	go service.StartSubscriptionWaitlistWorker(context.Background(), service.waitlistTicker)
+
+	service.earlyAccessTicker = time.NewTicker(1 * time.Minute)
+	go service.StartEarlyAccessWorker(context.Background(), service.earlyAccessTicker)
+
 	service.SetConfigUpdateHandlers(cfgService)
 
 	return service
@@ -92,6 +116,10 @@ func (s *Service) StopWorkers() {
 	s.commentsTicker.Stop()
 	s.userSubscriptionTicker.Stop()
 	s.incomeTicker.Stop()
+	s.toncoinTicker.Stop()
+	s.giftsTicker.Stop()
+	s.waitlistTicker.Stop()
+	s.earlyAccessTicker.Stop()
 }
 
 func (s *Service) BlogById(ctx context.Context, id uuid.UUID) (*Blog, error) {
@@ -293,14 +321,52 @@ func (s *Service) SetSubscriptionCover(ctx context.Context, subscription *Subscr
 	return nil
 }
 
-func (s *Service) GetSubscriptionRobokassaPaymentLink(ctx context.Context, subscription *Subscription, userId uuid.UUID) (string, error) {
+// GetSubscriptionRobokassaPaymentLink creates the payment link, the price is discounted if the promo code is given.
+// Returns empty link if the promo code was used up meanwhile.
+func (s *Service) GetSubscriptionRobokassaPaymentLink(ctx context.Context, subscription *Subscription, userId uuid.UUID, promoCode *PromoCode) (string, error) {
 	description := fmt.Sprintf("Оплата подписки \"%s\" (ID: %s)", subscription.Title, subscription.ID)
-	return s.billingService.RobokassaPaymentLink(subscription.ID, userId, subscription.PriceRub, PaymentItemTypeSubscription, description)
+	price := subscription.PriceRub
+	if promoCode == nil {
+		return s.billingService.RobokassaPaymentLink(subscription.ID, userId, price, PaymentItemTypeSubscription, description)
+	}
+
+	use, err := s.reservePromoCode(ctx, promoCode, userId, subscription.ID, PaymentItemTypeSubscription, price)
+	if err != nil {
+		return "", fmt.Errorf("failed to reserve promo code: %v", err)
+	}
+	if use == nil {
+		return "", nil
+	}
+	description += fmt.Sprintf(" по промокоду %s", promoCode.Code)
+	payment, err := s.billingService.RobokassaPaymentInvoice(subscription.ID, userId, use.PriceRub-use.DiscountRub, PaymentItemTypeSubscription, description)
+	if err != nil {
+		return "", err
+	}
+	return payment.Url, s.repository.SetPromoCodeUseInvoice(ctx, use, payment.InvoiceId)
 }
 
-func (s *Service) GetPostRobokassaPaymentLink(ctx context.Context, post *Post, userId uuid.UUID) (string, error) {
+// GetPostRobokassaPaymentLink creates the payment link, the price is discounted if the promo code is given.
+// Returns empty link if the promo code was used up meanwhile.
+func (s *Service) GetPostRobokassaPaymentLink(ctx context.Context, post *Post, userId uuid.UUID, promoCode *PromoCode) (string, error) {
 	description := fmt.Sprintf("Оплата публикации \"%s\" (ID: %s)", post.Title, post.ID)
-	return s.billingService.RobokassaPaymentLink(post.ID, userId, *post.Price, PaymentItemTypePost, description)
+	price := *post.Price
+	if promoCode == nil {
+		return s.billingService.RobokassaPaymentLink(post.ID, userId, price, PaymentItemTypePost, description)
+	}
+
+	use, err := s.reservePromoCode(ctx, promoCode, userId, post.ID, PaymentItemTypePost, price)
+	if err != nil {
+		return "", fmt.Errorf("failed to reserve promo code: %v", err)
+	}
+	if use == nil {
+		return "", nil
+	}
+	description += fmt.Sprintf(" по промокоду %s", promoCode.Code)
+	payment, err := s.billingService.RobokassaPaymentInvoice(post.ID, userId, use.PriceRub-use.DiscountRub, PaymentItemTypePost, description)
+	if err != nil {
+		return "", err
+	}
+	return payment.Url, s.repository.SetPromoCodeUseInvoice(ctx, use, payment.InvoiceId)
 }
 
 func (s *Service) GetDonationRobokassaPaymentLink(blog *Blog, donation *Donation, userId uuid.UUID) (string, error) {
@@ -309,6 +375,259 @@ func (s *Service) GetDonationRobokassaPaymentLink(blog *Blog, donation *Donation
 	return s.billingService.RobokassaPaymentLink(donation.ID, userId, donation.Value, PaymentItemTypeDonation, description)
 }
 
+// ConfirmDonation registers blog income of the paid donation and notifies the author and the donator
+func (s *Service) ConfirmDonation(ctx context.Context, blog *Blog, donation *Donation, userId uuid.UUID, value float64, currency string) error {
+	timeNow := time.Now().UTC()
+	blogIncome := BlogIncome{
+		ID:               uuid.New(),
+		BlogId:           donation.BlogId,
+		UserId:           userId,
+		Value:            value,
+		Currency:         currency,
+		ItemId:           donation.ID,
+		ItemType:         PaymentItemTypeDonation,
+		SentToUserWallet: currency == CurrencyTon,
+		Created:          timeNow,
+	}
+	err := s.repository.CreateBlogIncome(ctx, &blogIncome)
+	if err != nil {
+		return fmt.Errorf("failed to create blog income: %v", err)
+	}
+
+	donation.Status = DonationStatusConfirmed
+	donation.PaymentConfirmed = true
+	donation.Updated = timeNow
+
+	err = s.repository.UpdateDonation(ctx, donation)
+	if err != nil {
+		return fmt.Errorf("failed to update donation: %v", err)
+	}
+
+	go s.notifService.DonationAuthor(
+		blog.AuthorId.String(), blog.ID.String(), userId.String(),
+		donation.UserComment, value, currency)
+
+	go s.notifService.DonationUser(
+		userId.String(), blog.ID.String(),
+		value, currency)
+
+	return nil
+}
+
+// ConfirmToncoinDonation confirms the toncoin donation with the transfer value and saves the transfer
+// linked to the donation atomically. Returns false if the donation was confirmed meanwhile.
+func (s *Service) ConfirmToncoinDonation(ctx context.Context, blog *Blog, donation *Donation, transfer *ToncoinTransfer) (bool, error) {
+	timeNow := time.Now().UTC()
+	blogIncome := BlogIncome{
+		ID:               uuid.New(),
+		BlogId:           donation.BlogId,
+		UserId:           donation.UserId,
+		Value:            transfer.Value,
+		Currency:         CurrencyTon,
+		ItemId:           donation.ID,
+		ItemType:         PaymentItemTypeDonation,
+		SentToUserWallet: true,
+		Created:          timeNow,
+	}
+
+	donation.Status = DonationStatusConfirmed
+	donation.PaymentConfirmed = true
+	donation.Updated = timeNow
+
+	transfer.BlogId = &donation.BlogId
+	transfer.DonationId = &donation.ID
+	transfer.Updated = timeNow
+
+	confirmed, err := s.repository.ConfirmToncoinDonation(ctx, donation, &blogIncome, transfer)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm toncoin donation: %v", err)
+	}
+	if !confirmed {
+		return false, nil
+	}
+
+	go s.notifService.DonationAuthor(
+		blog.AuthorId.String(), blog.ID.String(), donation.UserId.String(),
+		donation.UserComment, transfer.Value, CurrencyTon)
+
+	go s.notifService.DonationUser(
+		donation.UserId.String(), blog.ID.String(),
+		transfer.Value, CurrencyTon)
+
+	return true, nil
+}
+
+// IncomesValueRub sums incomes in rubles, converting other currencies at the rate in effect at the income time
+func (s *Service) IncomesValueRub(incomes []BlogIncome) (float64, error) {
+	var total float64 = 0
+	items := make([]billing.ConvertItem, 0)
+	for _, income := range incomes {
+		if income.Currency == CurrencyRub {
+			total += income.Value
+			continue
+		}
+		items = append(items, billing.ConvertItem{
+			Value:    income.Value,
+			Currency: income.Currency,
+			At:       income.Created,
+		})
+	}
+	if len(items) == 0 {
+		return total, nil
+	}
+	values, err := s.billingService.Convert(items, CurrencyRub)
+	if err != nil {
+		return 0, err
+	}
+	for _, value := range values {
+		total += value
+	}
+	return total, nil
+}
+
+// IncomesSummary returns incomes totals by currency and the total in rubles
+func (s *Service) IncomesSummary(incomes []BlogIncome) (*BlogIncomeSummaryResponse, error) {
+	summary := BlogIncomeSummaryResponse{
+		ByCurrency: make(map[string]float64),
+	}
+	for _, income := range incomes {
+		summary.ByCurrency[income.Currency] += income.Value
+	}
+	totalRub, err := s.IncomesValueRub(incomes)
+	if err != nil {
+		return nil, err
+	}
+	summary.TotalRub = totalRub
+	return &summary, nil
+}
+
+// ToncoinDonationMinValue returns the configured toncoin minimum, raised to the rubles minimum
+// converted at the latest stored rate
+func (s *Service) ToncoinDonationMinValue() float64 {
+	minValue := s.DonationsToncoinMinValue()
+	rate := s.ToncoinRubRate()
+	if rate <= 0 {
+		return minValue
+	}
+	return math.Max(minValue, s.DonationsRobokassaMinValue()/rate)
+}
+
+// UpdateToncoinRubRate stores the latest toncoin rate in rubles known by the billing service
+func (s *Service) UpdateToncoinRubRate() error {
+	values, err := s.billingService.Convert([]billing.ConvertItem{{
+		Value:    1,
+		Currency: CurrencyTon,
+		At:       time.Now().UTC(),
+	}}, CurrencyRub)
+	if err != nil {
+		return err
+	}
+	if values[0] <= 0 {
+		return fmt.Errorf("toncoin rate is not positive: %v", values[0])
+	}
+	s.mu.Lock()
+	s.toncoinRubRate = values[0]
+	s.mu.Unlock()
+	return nil
+}
+
+// PaymentItemsInfo returns titles of paid items and their blogs. Items that don't exist anymore
+// are returned with empty titles.
+func (s *Service) PaymentItemsInfo(ctx context.Context, items []PaymentItemRef) ([]PaymentItemInfo, error) {
+	blogsTitles := make(map[uuid.UUID]string)
+	blogTitle := func(blogId uuid.UUID) (string, error) {
+		if title, ok := blogsTitles[blogId]; ok {
+			return title, nil
+		}
+		blog, err := s.repository.BlogById(ctx, blogId)
+		if err != nil {
+			return "", err
+		}
+		if blog != nil {
+			blogsTitles[blogId] = blog.Title
+		}
+		return blogsTitles[blogId], nil
+	}
+
+	result := make([]PaymentItemInfo, len(items))
+	for i, item := range items {
+		info := PaymentItemInfo{ItemId: item.ItemId, ItemType: item.ItemType}
+		switch item.ItemType {
+		case PaymentItemTypeSubscription:
+			subscription, err := s.repository.SubscriptionById(ctx, item.ItemId)
+			if err != nil {
+				return nil, err
+			}
+			if subscription != nil {
+				info.Title = subscription.Title
+				info.BlogId = &subscription.BlogId
+			}
+		case PaymentItemTypePost:
+			post, err := s.repository.PostById(ctx, item.ItemId)
+			if err != nil {
+				return nil, err
+			}
+			if post != nil {
+				info.Title = post.Title
+				info.BlogId = &post.BlogId
+			}
+		case PaymentItemTypeGift:
+			gift, err := s.repository.GiftById(ctx, item.ItemId)
+			if err != nil {
+				return nil, err
+			}
+			if gift != nil {
+				giftItems, err := s.PaymentItemsInfo(ctx, []PaymentItemRef{{ItemId: gift.ItemId, ItemType: gift.ItemType}})
+				if err != nil {
+					return nil, err
+				}
+				info.Title = giftItems[0].Title
+				info.BlogId = &gift.BlogId
+			}
+		case PaymentItemTypeTierChange:
+			change, err := s.repository.TierChangeById(ctx, item.ItemId)
+			if err != nil {
+				return nil, err
+			}
+			if change != nil {
+				subscription, err := s.repository.SubscriptionById(ctx, change.ToSubscriptionId)
+				if err != nil {
+					return nil, err
+				}
+				if subscription != nil {
+					info.Title = subscription.Title
+				}
+				info.BlogId = &change.BlogId
+			}
+		case PaymentItemTypeDonation:
+			donation, err := s.repository.DonationById(ctx, item.ItemId)
+			if err != nil {
+				return nil, err
+			}
+			if donation != nil {
+				info.BlogId = &donation.BlogId
+			}
+		}
+		if info.BlogId != nil {
+			title, err := blogTitle(*info.BlogId)
+			if err != nil {
+				return nil, err
+			}
+			info.BlogTitle = title
+			if item.ItemType == PaymentItemTypeDonation {
+				info.Title = title
+			}
+		}
+		result[i] = info
+	}
+	return result, nil
+}
+
+// Memo is the comment the donator puts into the toncoin transfer to match it with the donation
+func (d *Donation) Memo() string {
+	return d.ID.String()
+}
+
 func (s *Service) PostLikesInfo(ctx context.Context, postId uuid.UUID, userId uuid.UUID) (*PostLikesInfoResponse, error) {
 	likes, dislikes, err := s.repository.CountPostLikes(ctx, postId)
 	if err != nil {
@@ -435,6 +754,10 @@ func (s *Service) CheckUserContentAccess(ctx context.Context, post *Post, userId
 	if blog.AuthorId == userId {
 		return true, nil
 	}
+	// the worker could not switch the post to free yet
+	if post.IsOpenedUp(time.Now().UTC()) {
+		return true, nil
+	}
 
 	blogSubscriptions, err := s.repository.SubscriptionsByBlogId(ctx, post.BlogId)
 	if err != nil {
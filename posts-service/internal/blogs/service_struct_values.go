@@ -93,3 +93,9 @@ func (s *Service) DonationsToncoinMinValue() float64 {
 	defer s.mu.RUnlock()
 	return s.donationsToncoinMinValue
 }
+
+func (s *Service) ToncoinRubRate() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.toncoinRubRate
+}
@@ -0,0 +1,307 @@
+package blogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"log"
+	"time"
+)
+
+// subscriptionSlotReservationTime is how long the slot of the capped subscription is held for the user,
+// it matches the invoice lifetime so the slot isn't taken while the user is paying
+const subscriptionSlotReservationTime = 1 * time.Hour
+
+// waitlistSlotHoldTime is how long the freed slot is held for the notified waitlist user,
+// the next waiting user is notified only after the hold expires
+const waitlistSlotHoldTime = 24 * time.Hour
+
+// ReserveSubscriptionSlot holds the slot of the capped subscription for the user and removes the user
+// from the waitlist. Returns false and adds the user to the waitlist if the subscription is full.
+// Subscriptions without cap always have a slot.
+func (s *Service) ReserveSubscriptionSlot(ctx context.Context, subscription *Subscription, userId uuid.UUID) (bool, error) {
+	if subscription.MaxSubscribers == nil {
+		return true, nil
+	}
+	timeNow := time.Now().UTC()
+	reserved, err := s.repository.ReserveSubscriptionSlot(ctx, subscription.ID, userId, timeNow.Add(subscriptionSlotReservationTime), timeNow)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve subscription slot: %v", err)
+	}
+	if !reserved {
+		err = s.JoinSubscriptionWaitlist(ctx, subscription.ID, userId)
+		return false, err
+	}
+	if err = s.repository.DeleteWaitlistEntry(ctx, subscription.ID, userId); err != nil {
+		return false, fmt.Errorf("failed to delete waitlist entry: %v", err)
+	}
+	return true, nil
+}
+
+// JoinSubscriptionWaitlist adds the user to the waitlist or puts the notified user back to waiting
+func (s *Service) JoinSubscriptionWaitlist(ctx context.Context, subscriptionId, userId uuid.UUID) error {
+	timeNow := time.Now().UTC()
+	entry := SubscriptionWaitlistEntry{
+		ID:             uuid.New(),
+		SubscriptionId: subscriptionId,
+		UserId:         userId,
+		Status:         WaitlistStatusWaiting,
+		Created:        timeNow,
+		Updated:        timeNow,
+	}
+	if err := s.repository.UpsertWaitlistEntry(ctx, &entry); err != nil {
+		return fmt.Errorf("failed to save waitlist entry: %v", err)
+	}
+	return nil
+}
+
+// StartSubscriptionWaitlistWorker removes expired slot reservations and notifies waiting users
+// in the order they joined when slots of the capped subscription free up, the slot is held for the notified user
+func (s *Service) StartSubscriptionWaitlistWorker(ctx context.Context, ticker *time.Ticker) {
+	for range ticker.C {
+		timeNow := time.Now().UTC()
+		err := s.repository.DeleteExpiredSubscriptionReservations(ctx, timeNow)
+		if err != nil {
+			log.Println("error worker deleting expired subscription reservations:", err)
+			continue
+		}
+
+		subscriptionIds, err := s.repository.WaitlistedSubscriptionIds(ctx)
+		if err != nil {
+			log.Println("error worker getting waitlisted subscriptions:", err)
+			continue
+		}
+		for _, subscriptionId := range subscriptionIds {
+			err = s.notifyWaitlist(ctx, subscriptionId, timeNow)
+			if err != nil {
+				log.Println("error worker notifying subscription waitlist:", err)
+			}
+		}
+	}
+}
+
+func (s *Service) notifyWaitlist(ctx context.Context, subscriptionId uuid.UUID, timeNow time.Time) error {
+	subscription, err := s.repository.SubscriptionById(ctx, subscriptionId)
+	if err != nil || subscription == nil || !subscription.IsActive {
+		return err
+	}
+
+	// the cap could be removed by the author, then every waiting user can subscribe
+	limit := -1
+	if subscription.MaxSubscribers != nil {
+		occupied, err := s.repository.CountOccupiedSubscriptionSlots(ctx, subscription.ID, timeNow)
+		if err != nil {
+			return err
+		}
+		limit = *subscription.MaxSubscribers - occupied
+		if limit <= 0 {
+			return nil
+		}
+	}
+
+	entries, err := s.repository.WaitingEntriesBySubscriptionId(ctx, subscription.ID, limit)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if subscription.MaxSubscribers != nil {
+			reserved, err := s.repository.ReserveSubscriptionSlot(ctx, subscription.ID, entries[i].UserId,
+				timeNow.Add(waitlistSlotHoldTime), timeNow)
+			if err != nil {
+				return err
+			}
+			// the slot was taken by another user meanwhile
+			if !reserved {
+				return nil
+			}
+		}
+		entries[i].Status = WaitlistStatusNotified
+		entries[i].NotifiedAt = &timeNow
+		entries[i].Updated = timeNow
+		err = s.repository.UpdateWaitlistEntry(ctx, &entries[i])
+		if err != nil {
+			return err
+		}
+		go s.notifService.SubscriptionSlotAvailable(
+			entries[i].UserId.String(), subscription.BlogId.String(), subscription.ID.String())
+	}
+	return nil
+}
+
+// ReserveSubscriptionSlot locks the subscription and counts users that are subscribed or hold a reservation.
+// The user that already occupies a slot keeps it, the reservation is refreshed in both cases.
+func (r *Repository) ReserveSubscriptionSlot(ctx context.Context, subscriptionId, userId uuid.UUID, expiresAt, timeNow time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var maxSubscribers *int
+	err = tx.QueryRow(ctx, `select max_subscribers from subscriptions where id = $1 for update`, subscriptionId).Scan(&maxSubscribers)
+	if err != nil {
+		return false, err
+	}
+
+	if maxSubscribers != nil {
+		var occupied int
+		var holdsSlot bool
+		err = tx.QueryRow(ctx, `select count(distinct user_id) filter (where user_id <> $2), coalesce(bool_or(user_id = $2), false)
+				from (select user_id from user_subscriptions where subscription_id = $1 and is_active = true
+				      union all
+				      select user_id from subscription_reservations where subscription_id = $1 and expires_at > $3) o`,
+			subscriptionId, userId, timeNow).Scan(&occupied, &holdsSlot)
+		if err != nil {
+			return false, err
+		}
+		if !holdsSlot && occupied >= *maxSubscribers {
+			return false, nil
+		}
+	}
+
+	_, err = tx.Exec(ctx, `delete from subscription_reservations where subscription_id = $1 and user_id = $2`,
+		subscriptionId, userId)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(ctx, `insert into subscription_reservations (id, subscription_id, user_id, expires_at, created)
+			values ($1, $2, $3, $4, $5)`,
+		uuid.New(), subscriptionId, userId, expiresAt, timeNow)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *Repository) CountOccupiedSubscriptionSlots(ctx context.Context, subscriptionId uuid.UUID, timeNow time.Time) (int, error) {
+	query := `select count(distinct user_id)
+			from (select user_id from user_subscriptions where subscription_id = $1 and is_active = true
+			      union all
+			      select user_id from subscription_reservations where subscription_id = $1 and expires_at > $2) o`
+	var count int
+	err := r.db.QueryRow(ctx, query, subscriptionId, timeNow).Scan(&count)
+	return count, err
+}
+
+func (r *Repository) DeleteExpiredSubscriptionReservations(ctx context.Context, timeNow time.Time) error {
+	_, err := r.db.Exec(ctx, `delete from subscription_reservations where expires_at < $1`, timeNow)
+	return err
+}
+
+func (r *Repository) WaitlistEntryByParams(ctx context.Context, subscriptionId, userId uuid.UUID) (*SubscriptionWaitlistEntry, error) {
+	query := `select id, subscription_id, user_id, status, notified_at, created, updated
+			from subscription_waitlist
+			where subscription_id = $1 and user_id = $2`
+
+	var item SubscriptionWaitlistEntry
+	err := r.db.QueryRow(ctx, query, subscriptionId, userId).Scan(
+		&item.ID,
+		&item.SubscriptionId,
+		&item.UserId,
+		&item.Status,
+		&item.NotifiedAt,
+		&item.Created,
+		&item.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// WaitingEntriesBySubscriptionId returns the oldest waiting entries, negative limit returns all of them
+func (r *Repository) WaitingEntriesBySubscriptionId(ctx context.Context, subscriptionId uuid.UUID, limit int) ([]SubscriptionWaitlistEntry, error) {
+	query := `select id, subscription_id, user_id, status, notified_at, created, updated
+			from subscription_waitlist
+			where subscription_id = $1 and status = $2
+			order by created
+			limit nullif($3, -1)`
+
+	rows, err := r.db.Query(ctx, query, subscriptionId, WaitlistStatusWaiting, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]SubscriptionWaitlistEntry, 0)
+	var item SubscriptionWaitlistEntry
+	for rows.Next() {
+		err = rows.Scan(
+			&item.ID,
+			&item.SubscriptionId,
+			&item.UserId,
+			&item.Status,
+			&item.NotifiedAt,
+			&item.Created,
+			&item.Updated,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, item)
+	}
+	return resultArray, nil
+}
+
+func (r *Repository) WaitlistedSubscriptionIds(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `select distinct subscription_id from subscription_waitlist where status = $1`,
+		WaitlistStatusWaiting)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]uuid.UUID, 0)
+	var id uuid.UUID
+	for rows.Next() {
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, id)
+	}
+	return resultArray, nil
+}
+
+// CountWaitingBefore returns the position of the waiting entry in the waitlist, starting from zero
+func (r *Repository) CountWaitingBefore(ctx context.Context, entry *SubscriptionWaitlistEntry) (int, error) {
+	query := `select count(*) from subscription_waitlist where subscription_id = $1 and status = $2 and created < $3`
+	var count int
+	err := r.db.QueryRow(ctx, query, entry.SubscriptionId, WaitlistStatusWaiting, entry.Created).Scan(&count)
+	return count, err
+}
+
+// UpsertWaitlistEntry creates the entry or sets the existing one of the user back to waiting keeping its place
+func (r *Repository) UpsertWaitlistEntry(ctx context.Context, entry *SubscriptionWaitlistEntry) error {
+	query := `insert into subscription_waitlist
+	(id, subscription_id, user_id, status, notified_at, created, updated)
+	values
+	($1, $2, $3, $4, $5, $6, $7)
+	on conflict (subscription_id, user_id) do update set status = excluded.status, updated = excluded.updated`
+	_, err := r.db.Exec(ctx, query,
+		entry.ID,
+		entry.SubscriptionId,
+		entry.UserId,
+		entry.Status,
+		entry.NotifiedAt,
+		entry.Created,
+		entry.Updated,
+	)
+	return err
+}
+
+func (r *Repository) UpdateWaitlistEntry(ctx context.Context, entry *SubscriptionWaitlistEntry) error {
+	query := `update subscription_waitlist set status = $2, notified_at = $3, updated = $4 where id = $1`
+	_, err := r.db.Exec(ctx, query, entry.ID, entry.Status, entry.NotifiedAt, entry.Updated)
+	return err
+}
+
+func (r *Repository) DeleteWaitlistEntry(ctx context.Context, subscriptionId, userId uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `delete from subscription_waitlist where subscription_id = $1 and user_id = $2`,
+		subscriptionId, userId)
+	return err
+}
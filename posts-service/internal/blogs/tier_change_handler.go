@@ -0,0 +1,189 @@
+package blogs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	requestuser "posts-service/pkg/hidepost-requestuser"
+	serverlogging "posts-service/pkg/serverlogging/gin"
+	"time"
+)
+
+// changeSubscriptionTier moves the paid user subscription to the subscription by param of the same blog.
+// Upgrade returns the payment link of the prorated price difference, downgrade is scheduled
+// to the end of the paid period.
+func (h *blogHandler) changeSubscriptionTier(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	var req ChangeSubscriptionTierRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	if req.FromSubscriptionId == id {
+		loggingMap.SetMessage("bad request, subscription is the same")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	to, err := h.service.repository.SubscriptionById(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get subscription by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if to == nil {
+		loggingMap.SetMessage("subscription by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if to.IsFree || !to.IsActive {
+		loggingMap.SetMessage("subscription is free or not active")
+		ctx.JSON(http.StatusForbidden, nil)
+		return
+	}
+	from, err := h.service.repository.SubscriptionById(ctx, req.FromSubscriptionId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get subscription by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if from == nil || from.BlogId != to.BlogId {
+		loggingMap.SetMessage("current subscription of the blog doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	timeNow := time.Now().UTC()
+	userSubscription, err := h.service.repository.UserSubscriptionByParams(ctx, *userId, from.BlogId, from.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get user subscription")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if userSubscription == nil || !userSubscription.IsPaidPeriod(timeNow) {
+		loggingMap.SetMessage("user has no paid period of the current subscription")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+	toUserSubscription, err := h.service.repository.UserSubscriptionByParams(ctx, *userId, to.BlogId, to.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get user subscription")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if toUserSubscription != nil && toUserSubscription.IsPaidPeriod(timeNow) {
+		loggingMap.SetMessage("user is already subscribed")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// the downgrade takes the slot at the end of the paid period
+	if to.PriceRub > from.PriceRub && !reserveSubscriptionSlot(ctx, h.service, to, *userId) {
+		return
+	}
+
+	change, link, err := h.service.ChangeSubscriptionTier(ctx, userSubscription, from, to)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to change subscription tier")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap["tier_change_id"] = change.ID
+	loggingMap.SetMessage("subscription tier change created")
+	ctx.JSON(http.StatusOK, ChangeSubscriptionTierResponse{
+		TierChange:  change,
+		PaymentLink: link,
+	})
+}
+
+func (h *blogHandler) getMyTierChanges(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	changes, err := h.service.repository.TierChangesByUserIdAndBlogId(ctx, *userId, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get tier changes")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, changes)
+}
+
+// cancelTierChange cancels the scheduled downgrade or the upgrade that isn't paid yet
+func (h *blogHandler) cancelTierChange(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	change, err := h.service.repository.TierChangeById(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get tier change by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if change == nil || change.UserId != *userId {
+		loggingMap.SetMessage("tier change by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if change.Status != TierChangeStatusNew && change.Status != TierChangeStatusScheduled {
+		loggingMap.SetMessage("tier change is already applied or cancelled")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	change.Status = TierChangeStatusCancelled
+	change.Updated = time.Now().UTC()
+	err = h.service.repository.UpdateTierChange(ctx, change)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to update tier change")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, change)
+}
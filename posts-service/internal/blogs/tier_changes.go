@@ -0,0 +1,360 @@
+package blogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"log"
+	"math"
+	"time"
+)
+
+// subscriptionPeriod is the period a payment for the paid subscription is granted for
+const subscriptionPeriod = 720 * time.Hour
+
+// tierChangeMinPriceRub is the lowest upgrade price, as the payment can't be free
+const tierChangeMinPriceRub = 1.0
+
+// IsPaidPeriod reports whether the user subscription is a paid one that is active now
+func (us *UserSubscription) IsPaidPeriod(timeNow time.Time) bool {
+	return us.IsActive &&
+		(us.Status == UserSubscriptionStatusCancelled || us.Status == UserSubscriptionStatusRecurrent) &&
+		us.ExpiresAt != nil && us.ExpiresAt.After(timeNow)
+}
+
+// ProratedTierPrice returns the price difference of the tiers for the rest of the paid period
+func ProratedTierPrice(from, to *Subscription, expiresAt, timeNow time.Time) float64 {
+	remaining := expiresAt.Sub(timeNow)
+	if remaining <= 0 || to.PriceRub <= from.PriceRub {
+		return 0
+	}
+	price := (to.PriceRub - from.PriceRub) * float64(remaining) / float64(subscriptionPeriod)
+	price = math.Round(price*100) / 100
+	return math.Max(price, tierChangeMinPriceRub)
+}
+
+// ChangeSubscriptionTier cancels pending tier changes of the user subscription and creates the new one.
+// Upgrade is returned with the payment link of the prorated price difference, downgrade is scheduled
+// to the end of the paid period.
+func (s *Service) ChangeSubscriptionTier(ctx context.Context, userSubscription *UserSubscription, from, to *Subscription) (*SubscriptionTierChange, string, error) {
+	timeNow := time.Now().UTC()
+	err := s.repository.CancelPendingTierChanges(ctx, userSubscription.ID, timeNow)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to cancel pending tier changes: %v", err)
+	}
+
+	change := SubscriptionTierChange{
+		ID:                 uuid.New(),
+		UserId:             userSubscription.UserId,
+		BlogId:             userSubscription.BlogId,
+		UserSubscriptionId: userSubscription.ID,
+		FromSubscriptionId: from.ID,
+		ToSubscriptionId:   to.ID,
+		Created:            timeNow,
+		Updated:            timeNow,
+	}
+	if to.PriceRub > from.PriceRub {
+		change.Kind = TierChangeKindUpgrade
+		change.Status = TierChangeStatusNew
+		change.PriceRub = ProratedTierPrice(from, to, *userSubscription.ExpiresAt, timeNow)
+		change.EffectiveAt = timeNow
+	} else {
+		change.Kind = TierChangeKindDowngrade
+		change.Status = TierChangeStatusScheduled
+		change.EffectiveAt = *userSubscription.ExpiresAt
+	}
+	err = s.repository.CreateTierChange(ctx, &change)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create tier change: %v", err)
+	}
+	if change.Kind == TierChangeKindDowngrade {
+		return &change, "", nil
+	}
+
+	description := fmt.Sprintf("Оплата перехода с подписки \"%s\" на подписку \"%s\" (ID: %s)", from.Title, to.Title, change.ID)
+	link, err := s.billingService.RobokassaPaymentLink(change.ID, change.UserId, change.PriceRub, PaymentItemTypeTierChange, description)
+	if err != nil {
+		return nil, "", err
+	}
+	return &change, link, nil
+}
+
+// ConfirmTierChange switches the paid upgrade to the new tier for the rest of the paid period,
+// or for the full period if the paid one ended meanwhile, and creates the blog income of the new tier.
+// Returns nil change if the change doesn't exist.
+func (s *Service) ConfirmTierChange(ctx context.Context, changeId uuid.UUID, value float64, currency string) (*SubscriptionTierChange, error) {
+	change, err := s.repository.TierChangeById(ctx, changeId)
+	if err != nil || change == nil {
+		return nil, err
+	}
+	if change.Status != TierChangeStatusNew {
+		return change, nil
+	}
+	blog, err := s.repository.BlogById(ctx, change.BlogId)
+	if err != nil || blog == nil {
+		return nil, err
+	}
+	userSubscription, err := s.repository.UserSubscriptionById(ctx, change.UserSubscriptionId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user subscription: %v", err)
+	}
+	if userSubscription == nil {
+		return nil, fmt.Errorf("user subscription of tier change %s doesn't exist", change.ID)
+	}
+
+	timeNow := time.Now().UTC()
+	// the paid period could end while the user was paying, the new tier is then granted for the full period
+	expiresAt := timeNow.Add(subscriptionPeriod)
+	if userSubscription.ExpiresAt != nil && userSubscription.ExpiresAt.After(timeNow) {
+		expiresAt = *userSubscription.ExpiresAt
+	}
+	applied, err := s.repository.ApplyTierChange(ctx, change, expiresAt, timeNow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply tier change: %v", err)
+	}
+	if !applied {
+		return change, nil
+	}
+	change.Status = TierChangeStatusApplied
+	change.Updated = timeNow
+
+	blogIncome := BlogIncome{
+		ID:               uuid.New(),
+		BlogId:           change.BlogId,
+		UserId:           change.UserId,
+		Value:            value,
+		Currency:         currency,
+		ItemId:           change.ToSubscriptionId,
+		ItemType:         PaymentItemTypeSubscription,
+		SentToUserWallet: currency == CurrencyTon,
+		Created:          timeNow,
+	}
+	err = s.repository.CreateBlogIncome(ctx, &blogIncome)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blog income: %v", err)
+	}
+
+	go s.notifService.SubscriptionAuthor(
+		blog.AuthorId.String(), blog.ID.String(), change.ToSubscriptionId.String(),
+		change.UserId.String(), value, currency)
+
+	go s.notifService.SubscriptionTierChanged(
+		change.UserId.String(), blog.ID.String(), change.FromSubscriptionId.String(), change.ToSubscriptionId.String(),
+		change.Kind, value, "")
+	return change, nil
+}
+
+// applyDueDowngrades sends users the payment link of the lower tier when the paid period of the higher one ends.
+// Downgrades of subscriptions that were prolonged meanwhile are moved to the new end of the period.
+func (s *Service) applyDueDowngrades(ctx context.Context) {
+	changes, err := s.repository.DueScheduledTierChanges(ctx, time.Now().UTC())
+	if err != nil {
+		log.Println("error worker getting scheduled tier changes:", err)
+		return
+	}
+	for i := range changes {
+		err = s.applyDowngrade(ctx, &changes[i])
+		if err != nil {
+			log.Println("error worker applying scheduled tier change:", err)
+		}
+	}
+}
+
+func (s *Service) applyDowngrade(ctx context.Context, change *SubscriptionTierChange) error {
+	timeNow := time.Now().UTC()
+	userSubscription, err := s.repository.UserSubscriptionById(ctx, change.UserSubscriptionId)
+	if err != nil {
+		return err
+	}
+	if userSubscription != nil && userSubscription.IsPaidPeriod(timeNow) {
+		change.EffectiveAt = *userSubscription.ExpiresAt
+		change.Updated = timeNow
+		return s.repository.UpdateTierChange(ctx, change)
+	}
+
+	change.Updated = timeNow
+	to, err := s.repository.SubscriptionById(ctx, change.ToSubscriptionId)
+	if err != nil {
+		return err
+	}
+	if to == nil || !to.IsActive || to.IsFree {
+		change.Status = TierChangeStatusCancelled
+		return s.repository.UpdateTierChange(ctx, change)
+	}
+	// the full tier puts the user to its waitlist, the user is notified when a slot frees up
+	reserved, err := s.ReserveSubscriptionSlot(ctx, to, change.UserId)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		change.Status = TierChangeStatusCancelled
+		return s.repository.UpdateTierChange(ctx, change)
+	}
+	change.Status = TierChangeStatusApplied
+	err = s.repository.UpdateTierChange(ctx, change)
+	if err != nil {
+		return err
+	}
+
+	link, err := s.GetSubscriptionRobokassaPaymentLink(ctx, to, change.UserId, nil)
+	if err != nil {
+		return err
+	}
+	go s.notifService.SubscriptionTierChanged(
+		change.UserId.String(), change.BlogId.String(), change.FromSubscriptionId.String(), change.ToSubscriptionId.String(),
+		change.Kind, to.PriceRub, link)
+	return nil
+}
+
+const tierChangeColumns = `id, user_id, blog_id, user_subscription_id, from_subscription_id, to_subscription_id, kind,
+			price_rub, status, effective_at, created, updated`
+
+func scanTierChange(row pgx.Row, item *SubscriptionTierChange) error {
+	return row.Scan(
+		&item.ID,
+		&item.UserId,
+		&item.BlogId,
+		&item.UserSubscriptionId,
+		&item.FromSubscriptionId,
+		&item.ToSubscriptionId,
+		&item.Kind,
+		&item.PriceRub,
+		&item.Status,
+		&item.EffectiveAt,
+		&item.Created,
+		&item.Updated,
+	)
+}
+
+func (r *Repository) queryTierChanges(ctx context.Context, query string, args ...any) ([]SubscriptionTierChange, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultArray := make([]SubscriptionTierChange, 0)
+	var item SubscriptionTierChange
+	for rows.Next() {
+		if err = scanTierChange(rows, &item); err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, item)
+	}
+	return resultArray, nil
+}
+
+func (r *Repository) TierChangeById(ctx context.Context, id uuid.UUID) (*SubscriptionTierChange, error) {
+	query := `select ` + tierChangeColumns + ` from subscription_tier_changes where id = $1`
+	var item SubscriptionTierChange
+	err := scanTierChange(r.db.QueryRow(ctx, query, id), &item)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) TierChangesByUserIdAndBlogId(ctx context.Context, userId, blogId uuid.UUID) ([]SubscriptionTierChange, error) {
+	query := `select ` + tierChangeColumns + ` from subscription_tier_changes
+			where user_id = $1 and blog_id = $2
+			order by created desc`
+	return r.queryTierChanges(ctx, query, userId, blogId)
+}
+
+func (r *Repository) DueScheduledTierChanges(ctx context.Context, timeNow time.Time) ([]SubscriptionTierChange, error) {
+	query := `select ` + tierChangeColumns + ` from subscription_tier_changes
+			where status = $1 and effective_at < $2`
+	return r.queryTierChanges(ctx, query, TierChangeStatusScheduled, timeNow)
+}
+
+func (r *Repository) CreateTierChange(ctx context.Context, change *SubscriptionTierChange) error {
+	query := `insert into subscription_tier_changes
+	(` + tierChangeColumns + `)
+	values
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err := r.db.Exec(ctx, query,
+		change.ID,
+		change.UserId,
+		change.BlogId,
+		change.UserSubscriptionId,
+		change.FromSubscriptionId,
+		change.ToSubscriptionId,
+		change.Kind,
+		change.PriceRub,
+		change.Status,
+		change.EffectiveAt,
+		change.Created,
+		change.Updated,
+	)
+	return err
+}
+
+func (r *Repository) UpdateTierChange(ctx context.Context, change *SubscriptionTierChange) error {
+	query := `update subscription_tier_changes set
+		status = $2,
+		effective_at = $3,
+		updated = $4
+		where id = $1`
+	_, err := r.db.Exec(ctx, query, change.ID, change.Status, change.EffectiveAt, change.Updated)
+	return err
+}
+
+func (r *Repository) CancelPendingTierChanges(ctx context.Context, userSubscriptionId uuid.UUID, timeNow time.Time) error {
+	query := `update subscription_tier_changes set status = $1, updated = $2
+			where user_subscription_id = $3 and status in ($4, $5)`
+	_, err := r.db.Exec(ctx, query, TierChangeStatusCancelled, timeNow, userSubscriptionId,
+		TierChangeStatusNew, TierChangeStatusScheduled)
+	return err
+}
+
+// ApplyTierChange marks the new tier change applied, expires the user subscription of the old tier
+// and activates the user subscription of the new tier until the time in one transaction.
+// Returns false if the change isn't new anymore.
+func (r *Repository) ApplyTierChange(ctx context.Context, change *SubscriptionTierChange, expiresAt, timeNow time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `update subscription_tier_changes set status = $1, updated = $2 where id = $3 and status = $4`,
+		TierChangeStatusApplied, timeNow, change.ID, TierChangeStatusNew)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, `update user_subscriptions set is_active = false, status = $1, expires_at = $2, updated = $2 where id = $3`,
+		UserSubscriptionStatusExpired, timeNow, change.UserSubscriptionId)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err = tx.Exec(ctx, `update user_subscriptions set is_active = true, status = $1, expires_at = $2, updated = $3
+			where id = (select id from user_subscriptions
+			            where user_id = $4 and blog_id = $5 and subscription_id = $6
+			            order by created desc limit 1)`,
+		UserSubscriptionStatusCancelled, expiresAt, timeNow, change.UserId, change.BlogId, change.ToSubscriptionId)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		_, err = tx.Exec(ctx, `insert into user_subscriptions
+			(id, user_id, subscription_id, blog_id, status, is_active, expires_at, created, updated)
+			values
+			($1, $2, $3, $4, $5, true, $6, $7, $7)`,
+			uuid.New(), change.UserId, change.ToSubscriptionId, change.BlogId, UserSubscriptionStatusCancelled,
+			expiresAt, timeNow)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,358 @@
+package blogs
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"posts-service/internal/toncoin"
+	"strings"
+	"time"
+)
+
+// toncoinValueEpsilon is the tolerance used when the transfer value is compared with the donation value
+const toncoinValueEpsilon = 0.000000001
+
+// StartToncoinDonationsWorker polls wallets of authors that have pending toncoin donations
+// and confirms donations matched with incoming transfers. The wallet belongs to the author,
+// so transfers are matched with pending donations of all author blogs.
+// A transfer is matched only by the donation memo in the transfer comment,
+// transfers that can't be matched are saved with unmatched status for manual review.
+// The worker also refreshes the toncoin rate used for the donation minimum.
+func (s *Service) StartToncoinDonationsWorker(ctx context.Context, ticker *time.Ticker) {
+	_ = s.UpdateToncoinRubRate()
+	for range ticker.C {
+		_ = s.UpdateToncoinRubRate()
+
+		donations, err := s.repository.DonationsByStatusAndCurrency(ctx, DonationStatusNew, CurrencyTon)
+		if err != nil {
+			continue
+		}
+		blogs := make(map[uuid.UUID]*Blog)
+		authorsDonations := make(map[uuid.UUID][]Donation)
+		for _, donation := range donations {
+			blog, found := blogs[donation.BlogId]
+			if !found {
+				blog, err = s.repository.BlogById(ctx, donation.BlogId)
+				if err != nil {
+					break
+				}
+				blogs[donation.BlogId] = blog
+			}
+			if blog == nil {
+				continue
+			}
+			authorsDonations[blog.AuthorId] = append(authorsDonations[blog.AuthorId], donation)
+		}
+		if err != nil {
+			continue
+		}
+		for authorId, authorDonations := range authorsDonations {
+			_ = s.processAuthorToncoinTransfers(ctx, authorId, authorDonations, blogs)
+		}
+	}
+}
+
+func (s *Service) processAuthorToncoinTransfers(ctx context.Context, authorId uuid.UUID, donations []Donation,
+	blogs map[uuid.UUID]*Blog) error {
+
+	address, err := s.usersService.WalletAddressOfUser(authorId)
+	if err != nil {
+		return err
+	}
+
+	since := donations[0].Created
+	for _, donation := range donations {
+		if donation.Created.Before(since) {
+			since = donation.Created
+		}
+	}
+	transfers, err := s.tonWatcher.IncomingTransfers(ctx, address, since)
+	if err != nil {
+		return err
+	}
+
+	for _, transfer := range transfers {
+		exists, err := s.repository.ToncoinTransferExists(ctx, transfer.Hash)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		timeNow := time.Now().UTC()
+		tonTransfer := ToncoinTransfer{
+			Hash:        transfer.Hash,
+			AuthorId:    authorId,
+			Address:     address,
+			FromAddress: transfer.FromAddress,
+			Value:       transfer.Value,
+			Comment:     transfer.Comment,
+			Status:      ToncoinTransferStatusUnmatched,
+			TransferAt:  transfer.At,
+			Created:     timeNow,
+			Updated:     timeNow,
+		}
+
+		index := matchToncoinTransfer(transfer, donations)
+		if index >= 0 {
+			donation := donations[index]
+			donations = append(donations[:index], donations[index+1:]...)
+			tonTransfer.Status = ToncoinTransferStatusMatched
+			confirmed, err := s.ConfirmToncoinDonation(ctx, blogs[donation.BlogId], &donation, &tonTransfer)
+			if err != nil {
+				return err
+			}
+			if confirmed {
+				continue
+			}
+			// the donation is confirmed meanwhile, the transfer is left for manual review
+			tonTransfer.Status = ToncoinTransferStatusUnmatched
+			tonTransfer.BlogId = nil
+			tonTransfer.DonationId = nil
+		}
+
+		err = s.repository.CreateToncoinTransfer(ctx, &tonTransfer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchToncoinTransfer returns index of the donation the memo of which is the transfer comment or -1.
+// Transfers without the memo aren't matched by the value, since the value doesn't tell the sender.
+func matchToncoinTransfer(transfer toncoin.Transfer, donations []Donation) int {
+	comment := strings.TrimSpace(transfer.Comment)
+	if comment == "" {
+		return -1
+	}
+	for i, donation := range donations {
+		if strings.EqualFold(comment, donation.Memo()) && transfer.Value+toncoinValueEpsilon >= donation.Value {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Repository) DonationsByStatusAndCurrency(ctx context.Context, status, currency string) ([]Donation, error) {
+	query := `select id, user_id, blog_id, value, currency, user_comment, status, payment_confirmed, created, updated
+			from donations
+         	where status = $1 and currency = $2
+         	order by created`
+
+	rows, err := r.db.Query(ctx, query, status, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var donations = make([]Donation, 0)
+	var item Donation
+	for rows.Next() {
+		err := rows.Scan(
+			&item.ID,
+			&item.UserId,
+			&item.BlogId,
+			&item.Value,
+			&item.Currency,
+			&item.UserComment,
+			&item.Status,
+			&item.PaymentConfirmed,
+			&item.Created,
+			&item.Updated,
+		)
+		if err != nil {
+			return nil, err
+		}
+		donations = append(donations, item)
+	}
+	return donations, nil
+}
+
+func (r *Repository) ToncoinTransferExists(ctx context.Context, hash string) (bool, error) {
+	query := `select exists(select 1 from toncoin_transfers where hash = $1)`
+	var exists bool
+	err := r.db.QueryRow(ctx, query, hash).Scan(&exists)
+	return exists, err
+}
+
+func (r *Repository) ToncoinTransferByHash(ctx context.Context, hash string) (*ToncoinTransfer, error) {
+	query := `select hash, blog_id, author_id, address, from_address, value, comment, donation_id, status, transfer_at, created, updated
+			from toncoin_transfers
+			where hash = $1`
+
+	var item ToncoinTransfer
+	err := r.db.QueryRow(ctx, query, hash).Scan(
+		&item.Hash,
+		&item.BlogId,
+		&item.AuthorId,
+		&item.Address,
+		&item.FromAddress,
+		&item.Value,
+		&item.Comment,
+		&item.DonationId,
+		&item.Status,
+		&item.TransferAt,
+		&item.Created,
+		&item.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) ToncoinTransfersByStatus(ctx context.Context, status string) ([]ToncoinTransfer, error) {
+	query := `select hash, blog_id, author_id, address, from_address, value, comment, donation_id, status, transfer_at, created, updated
+			from toncoin_transfers
+			where status = $1
+			order by transfer_at desc`
+
+	rows, err := r.db.Query(ctx, query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers = make([]ToncoinTransfer, 0)
+	var item ToncoinTransfer
+	for rows.Next() {
+		err := rows.Scan(
+			&item.Hash,
+			&item.BlogId,
+			&item.AuthorId,
+			&item.Address,
+			&item.FromAddress,
+			&item.Value,
+			&item.Comment,
+			&item.DonationId,
+			&item.Status,
+			&item.TransferAt,
+			&item.Created,
+			&item.Updated,
+		)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, item)
+	}
+	return transfers, nil
+}
+
+func (r *Repository) CreateToncoinTransfer(ctx context.Context, transfer *ToncoinTransfer) error {
+	query := `insert into toncoin_transfers
+	(hash, blog_id, author_id, address, from_address, value, comment, donation_id, status, transfer_at, created, updated)
+	values
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	on conflict (hash) do nothing`
+	_, err := r.db.Exec(ctx, query,
+		transfer.Hash,
+		transfer.BlogId,
+		transfer.AuthorId,
+		transfer.Address,
+		transfer.FromAddress,
+		transfer.Value,
+		transfer.Comment,
+		transfer.DonationId,
+		transfer.Status,
+		transfer.TransferAt,
+		transfer.Created,
+		transfer.Updated,
+	)
+	return err
+}
+
+// ConfirmToncoinDonation confirms the new donation, creates its blog income and saves the transfer
+// matched with it in one transaction. Returns false if the donation isn't new anymore.
+func (r *Repository) ConfirmToncoinDonation(ctx context.Context, donation *Donation, blogIncome *BlogIncome,
+	transfer *ToncoinTransfer) (bool, error) {
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `update donations set status = $2, payment_confirmed = $3, updated = $4 where id = $1 and status = $5`
+	tag, err := tx.Exec(ctx, query,
+		donation.ID,
+		donation.Status,
+		donation.PaymentConfirmed,
+		donation.Updated,
+		DonationStatusNew,
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	query = `insert into blog_incomes
+	(id, blog_id, user_id, value, currency, item_id, item_type, sent_to_user_wallet, created)
+	values
+	($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err = tx.Exec(ctx, query,
+		blogIncome.ID,
+		blogIncome.BlogId,
+		blogIncome.UserId,
+		blogIncome.Value,
+		blogIncome.Currency,
+		blogIncome.ItemId,
+		blogIncome.ItemType,
+		blogIncome.SentToUserWallet,
+		blogIncome.Created,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	query = `insert into toncoin_transfers
+	(hash, blog_id, author_id, address, from_address, value, comment, donation_id, status, transfer_at, created, updated)
+	values
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	on conflict (hash) do update set
+		blog_id = excluded.blog_id,
+		donation_id = excluded.donation_id,
+		status = excluded.status,
+		updated = excluded.updated`
+	_, err = tx.Exec(ctx, query,
+		transfer.Hash,
+		transfer.BlogId,
+		transfer.AuthorId,
+		transfer.Address,
+		transfer.FromAddress,
+		transfer.Value,
+		transfer.Comment,
+		transfer.DonationId,
+		transfer.Status,
+		transfer.TransferAt,
+		transfer.Created,
+		transfer.Updated,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *Repository) UpdateToncoinTransfer(ctx context.Context, transfer *ToncoinTransfer) error {
+	query := `update toncoin_transfers set
+		donation_id = $2,
+		status = $3,
+		updated = $4
+		where hash = $1`
+	_, err := r.db.Exec(ctx, query,
+		transfer.Hash,
+		transfer.DonationId,
+		transfer.Status,
+		transfer.Updated,
+	)
+	return err
+}
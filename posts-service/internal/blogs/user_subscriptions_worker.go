@@ -17,8 +17,13 @@ func (s *Service) StartUserSubscriptionsWorker(ctx context.Context, ticker *time
 
 		subscriptionsToUpdate := make([]UserSubscription, len(allUserSubscriptions))
 		subscriptionsToUpdateCount := 0
+		endedTrials := make([]UserSubscription, 0)
 		for i := range allUserSubscriptions {
-			if allUserSubscriptions[i].Status == UserSubscriptionStatusCancelled {
+			if allUserSubscriptions[i].Status == UserSubscriptionStatusCancelled ||
+				allUserSubscriptions[i].Status == UserSubscriptionStatusTrial {
+				if allUserSubscriptions[i].Status == UserSubscriptionStatusTrial {
+					endedTrials = append(endedTrials, allUserSubscriptions[i])
+				}
 				allUserSubscriptions[i].Status = UserSubscriptionStatusExpired
 				allUserSubscriptions[i].IsActive = false
 				subscriptionsToUpdate[subscriptionsToUpdateCount] = allUserSubscriptions[i]
@@ -31,9 +36,39 @@ func (s *Service) StartUserSubscriptionsWorker(ctx context.Context, ticker *time
 		err = s.repository.BulkUpdateUserSubscriptionsStatus(ctx, subscriptionsToUpdate)
 		if err != nil {
 			log.Println("error worker updating subscriptions status:", err)
+			continue
+		}
+
+		for _, trial := range endedTrials {
+			err = s.convertEndedTrial(ctx, &trial)
+			if err != nil {
+				log.Println("error worker converting ended trial:", err)
+			}
 		}
 
+		s.applyDueDowngrades(ctx)
+
+	}
+}
+
+// convertEndedTrial sends the user the payment link of the subscription which trial is ended
+func (s *Service) convertEndedTrial(ctx context.Context, trial *UserSubscription) error {
+	subscription, err := s.repository.SubscriptionById(ctx, trial.SubscriptionId)
+	if err != nil || subscription == nil || !subscription.IsActive {
+		return err
+	}
+	reserved, err := s.ReserveSubscriptionSlot(ctx, subscription, trial.UserId)
+	if err != nil || !reserved {
+		return err
+	}
+	link, err := s.GetSubscriptionRobokassaPaymentLink(ctx, subscription, trial.UserId, nil)
+	if err != nil {
+		return err
 	}
+	go s.notifService.SubscriptionTrialEnded(
+		trial.UserId.String(), subscription.BlogId.String(), subscription.ID.String(),
+		subscription.PriceRub, link)
+	return nil
 }
 
 func (r *Repository) ActiveUserSubscriptionsWithExpiration(ctx context.Context) ([]UserSubscription, error) {
@@ -0,0 +1,136 @@
+package blogs
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	requestuser "posts-service/pkg/hidepost-requestuser"
+	serverlogging "posts-service/pkg/serverlogging/gin"
+)
+
+// reserveSubscriptionSlot holds the slot of the capped subscription for the user,
+// the user is added to the waitlist and 409 is written if the subscription is full
+func reserveSubscriptionSlot(ctx *gin.Context, service *Service, subscription *Subscription, userId uuid.UUID) bool {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	reserved, err := service.ReserveSubscriptionSlot(ctx, subscription, userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reserve subscription slot")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return false
+	}
+	if !reserved {
+		loggingMap.SetMessage("subscription is full, user is added to the waitlist")
+		ctx.JSON(http.StatusConflict, gin.H{"waitlisted": true})
+		return false
+	}
+	return true
+}
+
+// cappedSubscriptionByParam returns the subscription from id param if it has the subscribers cap
+func (h *blogHandler) cappedSubscriptionByParam(ctx *gin.Context) *Subscription {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	idParam := ctx.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return nil
+	}
+
+	subscription, err := h.service.repository.SubscriptionById(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get subscription by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return nil
+	}
+	if subscription == nil {
+		loggingMap.SetMessage("subscription by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return nil
+	}
+	if subscription.MaxSubscribers == nil {
+		loggingMap.SetMessage("subscription has no subscribers cap")
+		ctx.JSON(http.StatusConflict, nil)
+		return nil
+	}
+	return subscription
+}
+
+func (h *blogHandler) getMyWaitlistEntry(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	subscription := h.cappedSubscriptionByParam(ctx)
+	if subscription == nil {
+		return
+	}
+
+	entry, err := h.service.repository.WaitlistEntryByParams(ctx, subscription.ID, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get waitlist entry")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if entry == nil {
+		loggingMap.SetMessage("user is not in the waitlist")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	response := WaitlistEntryResponse{SubscriptionWaitlistEntry: *entry}
+	if entry.Status == WaitlistStatusWaiting {
+		response.Position, err = h.service.repository.CountWaitingBefore(ctx, entry)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to count waitlist position")
+			ctx.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		response.Position++
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h *blogHandler) joinWaitlist(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	subscription := h.cappedSubscriptionByParam(ctx)
+	if subscription == nil {
+		return
+	}
+
+	err := h.service.JoinSubscriptionWaitlist(ctx, subscription.ID, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to join waitlist")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, nil)
+}
+
+func (h *blogHandler) leaveWaitlist(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	subscription := h.cappedSubscriptionByParam(ctx)
+	if subscription == nil {
+		return
+	}
+
+	err := h.service.repository.DeleteWaitlistEntry(ctx, subscription.ID, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to leave waitlist")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, nil)
+}
@@ -12,10 +12,11 @@ import (
 
 type Service struct {
 	ServiceUrl string
+	signer     *requestuser.Signer
 }
 
-func NewService(serviceUrl string, cfgService *configService.ConfigServiceManager) *Service {
-	service := Service{ServiceUrl: serviceUrl}
+func NewService(serviceUrl string, signer *requestuser.Signer, cfgService *configService.ConfigServiceManager) *Service {
+	service := Service{ServiceUrl: serviceUrl, signer: signer}
 	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
 		service.ServiceUrl = ss.Value
 	}, "COMMENTS_SERVICE_URL")
@@ -35,7 +36,7 @@ func (s *Service) CountPostComments(postId uuid.UUID) (int, error) {
 		return 0, fmt.Errorf("fail to create request cause %v", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set(requestuser.UserRoleHeaderKey, requestuser.UserRoleService)
+	s.signer.SignServiceRequest(req)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
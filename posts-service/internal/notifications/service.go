@@ -16,6 +16,16 @@ const (
 	EventCodePostPaidAccessUser   = "POST_PAID_ACCESS_USER"
 	EventCodeDonationAuthor       = "DONATION_AUTHOR"
 	EventCodeDonationUser         = "DONATION_USER"
+	EventCodeRefundAuthor         = "REFUND_AUTHOR"
+	EventCodeRefundUser           = "REFUND_USER"
+	EventCodeTrialEnded           = "SUBSCRIPTION_TRIAL_ENDED"
+	EventCodeGiftPurchased        = "GIFT_PURCHASED"
+	EventCodeGiftReceived         = "GIFT_RECEIVED"
+	EventCodeGiftRedeemed         = "GIFT_REDEEMED"
+	EventCodeGiftExpired          = "GIFT_EXPIRED"
+	EventCodeTierChanged          = "SUBSCRIPTION_TIER_CHANGED"
+	EventCodeSlotAvailable        = "SUBSCRIPTION_SLOT_AVAILABLE"
+	EventCodePostOpened           = "POST_OPENED"
 )
 
 type Service struct {
@@ -192,3 +202,264 @@ func (s *Service) DonationUser(userId, blogId string, paymentValue float64, paym
 		_ = s.queueLogger.Error(nil, loggingMap)
 	}
 }
+
+func (s *Service) RefundAuthor(userId, blogId, itemId, itemType, fromUserId string, refundValue float64, refundCurrency string) {
+	loggingMap := map[string]any{}
+	obj := RefundAuthorEventData{
+		At:             time.Now().UTC(),
+		BlogId:         blogId,
+		ItemId:         itemId,
+		ItemType:       itemType,
+		FromUserId:     fromUserId,
+		RefundValue:    refundValue,
+		RefundCurrency: refundCurrency,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal REFUND_AUTHOR event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeRefundAuthor, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send REFUND_AUTHOR event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) RefundUser(userId, blogId, itemId, itemType string, refundValue float64, refundCurrency string, accessRevoked bool) {
+	loggingMap := map[string]any{}
+	obj := RefundUserEventData{
+		At:             time.Now().UTC(),
+		BlogId:         blogId,
+		ItemId:         itemId,
+		ItemType:       itemType,
+		RefundValue:    refundValue,
+		RefundCurrency: refundCurrency,
+		AccessRevoked:  accessRevoked,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal REFUND_USER event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeRefundUser, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send REFUND_USER event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) SubscriptionTrialEnded(userId, blogId, subscriptionId string, priceRub float64, paymentLink string) {
+	loggingMap := map[string]any{}
+	obj := SubscriptionTrialEndedEventData{
+		At:             time.Now().UTC(),
+		BlogId:         blogId,
+		SubscriptionId: subscriptionId,
+		PriceRub:       priceRub,
+		PaymentLink:    paymentLink,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal SUBSCRIPTION_TRIAL_ENDED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeTrialEnded, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send SUBSCRIPTION_TRIAL_ENDED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) GiftPurchased(userId, blogId, giftId, itemId, itemType, code string, paymentValue float64, paymentCurrency string, expiresAt time.Time) {
+	loggingMap := map[string]any{}
+	obj := GiftPurchasedEventData{
+		At:              time.Now().UTC(),
+		BlogId:          blogId,
+		GiftId:          giftId,
+		ItemId:          itemId,
+		ItemType:        itemType,
+		Code:            code,
+		PaymentValue:    paymentValue,
+		PaymentCurrency: paymentCurrency,
+		ExpiresAt:       expiresAt,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal GIFT_PURCHASED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeGiftPurchased, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send GIFT_PURCHASED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) GiftReceived(userId, blogId, giftId, itemId, itemType, fromUserId, code string, expiresAt time.Time) {
+	loggingMap := map[string]any{}
+	obj := GiftReceivedEventData{
+		At:         time.Now().UTC(),
+		BlogId:     blogId,
+		GiftId:     giftId,
+		ItemId:     itemId,
+		ItemType:   itemType,
+		FromUserId: fromUserId,
+		Code:       code,
+		ExpiresAt:  expiresAt,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal GIFT_RECEIVED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeGiftReceived, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send GIFT_RECEIVED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) GiftRedeemed(userId, blogId, giftId, itemId, itemType, redeemedBy string) {
+	loggingMap := map[string]any{}
+	obj := GiftRedeemedEventData{
+		At:         time.Now().UTC(),
+		BlogId:     blogId,
+		GiftId:     giftId,
+		ItemId:     itemId,
+		ItemType:   itemType,
+		RedeemedBy: redeemedBy,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal GIFT_REDEEMED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeGiftRedeemed, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send GIFT_REDEEMED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) GiftExpired(userId, blogId, giftId, itemId, itemType string) {
+	loggingMap := map[string]any{}
+	obj := GiftExpiredEventData{
+		At:       time.Now().UTC(),
+		BlogId:   blogId,
+		GiftId:   giftId,
+		ItemId:   itemId,
+		ItemType: itemType,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal GIFT_EXPIRED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeGiftExpired, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send GIFT_EXPIRED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) SubscriptionTierChanged(userId, blogId, fromSubscriptionId, toSubscriptionId, kind string, priceRub float64, paymentLink string) {
+	loggingMap := map[string]any{}
+	obj := SubscriptionTierChangedEventData{
+		At:                 time.Now().UTC(),
+		BlogId:             blogId,
+		FromSubscriptionId: fromSubscriptionId,
+		ToSubscriptionId:   toSubscriptionId,
+		Kind:               kind,
+		PriceRub:           priceRub,
+		PaymentLink:        paymentLink,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal SUBSCRIPTION_TIER_CHANGED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeTierChanged, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send SUBSCRIPTION_TIER_CHANGED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) SubscriptionSlotAvailable(userId, blogId, subscriptionId string) {
+	loggingMap := map[string]any{}
+	obj := SubscriptionSlotAvailableEventData{
+		At:             time.Now().UTC(),
+		BlogId:         blogId,
+		SubscriptionId: subscriptionId,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal SUBSCRIPTION_SLOT_AVAILABLE event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodeSlotAvailable, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send SUBSCRIPTION_SLOT_AVAILABLE event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) PostOpened(userId, blogId, postId, postTitle string) {
+	loggingMap := map[string]any{}
+	obj := PostOpenedEventData{
+		At:        time.Now().UTC(),
+		BlogId:    blogId,
+		PostId:    postId,
+		PostTitle: postTitle,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal POST_OPENED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodePostOpened, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send POST_OPENED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
@@ -12,6 +12,8 @@ type Config struct {
 	ConfigServicePort    string `env:"CONFIG_SERVICE_PORT"`
 	ConfigUpdateInterval int    `env:"CONFIG_UPDATE_INTERVAL" env-default:"60"`
 
+	IdentityAssertionSecret string `config-service:"IDENTITY_ASSERTION_SECRET"`
+
 	FileQueue          string `config-service:"FILE_QUEUE"`
 	FileGetEndpointUrl string `config-service:"FILE_GET_URL"`
 
@@ -33,6 +35,9 @@ type Config struct {
 	CommentsServiceUrl string `config-service:"COMMENTS_SERVICE_URL"`
 	UsersServiceUrl    string `config-service:"USERS_SERVICE_URL"`
 
+	TonCenterApiUrl string `config-service:"TONCENTER_API_URL"`
+	TonCenterApiKey string `config-service:"TONCENTER_API_KEY"`
+
 	MainPageLikesRequirement    int `config-service:"MAIN_PAGE_LIKES_REQUIREMENT"`
 	MainPageCommentsRequirement int `config-service:"MAIN_PAGE_COMMENTS_REQUIREMENT"`
 	MainPageViewsRequirement    int `config-service:"MAIN_PAGE_VIEWS_REQUIREMENT"`
@@ -15,9 +15,11 @@ import (
 	"posts-service/internal/comments"
 	"posts-service/internal/files"
 	"posts-service/internal/notifications"
+	"posts-service/internal/toncoin"
 	"posts-service/internal/users"
 	configService "posts-service/pkg/config-client"
 	"posts-service/pkg/filelogger"
+	requestuser "posts-service/pkg/hidepost-requestuser"
 	"posts-service/pkg/pgutils"
 	queuelogger "posts-service/pkg/queuelogger"
 	serverlogging "posts-service/pkg/serverlogging/gin"
@@ -85,13 +87,30 @@ func Run() {
 	}
 	defer notificationsSender.Close()
 
+	// identity assertions are verified for every request and signed for requests to other services
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := identitySigner.SetSecret(ss.Value); err != nil {
+			fileLogger.Error("failed to update identity assertion secret from config",
+				map[string]any{"error": err.Error(), "key": ss.Key})
+		}
+	}, requestuser.IdentityAssertionSecretConfigKey)
+
 	// init services
 	notificationsService := notifications.NewService(notificationsSender, cfgService, fileLogger, mqLogger)
-	usersService := users.NewService(cfg.UsersServiceUrl, cfgService)
-	commentsService := comments.NewService(cfg.CommentsServiceUrl, cfgService)
-	billingService := billing.NewService(cfg.BillingServiceUrl, cfgService)
+	usersService := users.NewService(cfg.UsersServiceUrl, identitySigner, cfgService)
+	commentsService := comments.NewService(cfg.CommentsServiceUrl, identitySigner, cfgService)
+	billingService := billing.NewService(cfg.BillingServiceUrl, identitySigner, cfgService)
 	filesService := files.NewService(filesSender, cfg.FileGetEndpointUrl, cfgService, fileLogger, mqLogger)
+	var tonWatcher toncoin.Watcher = toncoin.NewStubWatcher()
+	if cfg.TonCenterApiUrl != "" {
+		tonWatcher = toncoin.NewTonCenterWatcher(cfg.TonCenterApiUrl, cfg.TonCenterApiKey, cfgService)
+	}
 	blogsService := blogs.NewService(blogsRepository, filesService, billingService, commentsService, usersService, notificationsService,
+		tonWatcher,
 		cfg.MainPageLikesRequirement,
 		cfg.MainPageCommentsRequirement,
 		cfg.MainPageViewsRequirement,
@@ -106,6 +125,7 @@ func Run() {
 	app := gin.New()
 	app.Use(gin.CustomRecovery(serverlogging.NewPanicLogger(fileLogger, mqLogger)))
 	app.Use(serverlogging.NewRequestLogger(fileLogger, mqLogger))
+	app.Use(identitySigner.Middleware())
 
 	// init handlers
 	apiV1 := app.Group("/api/v1")
@@ -113,6 +133,7 @@ func Run() {
 	// blogs handler
 	blogs.RegisterBlogHandler(apiV1.Group("/blogs"), blogsService)
 	blogs.RegisterBlogServiceHandler(apiV1.Group("/blogs/service"), blogsService)
+	blogs.RegisterBlogAdminHandler(apiV1.Group("/blogs/admin"), blogsService)
 
 	// posts handler
 	blogs.RegisterPostHandler(apiV1.Group("/posts"), blogsService)
@@ -0,0 +1,153 @@
+package toncoin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	configService "posts-service/pkg/config-client"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	ApiUrlConfigKey = "TONCENTER_API_URL"
+	ApiKeyConfigKey = "TONCENTER_API_KEY"
+
+	transactionsPageLimit = 50
+)
+
+type transactionsResponse struct {
+	Ok     bool          `json:"ok"`
+	Error  string        `json:"error"`
+	Result []transaction `json:"result"`
+}
+
+type transaction struct {
+	Utime         int64 `json:"utime"`
+	TransactionId struct {
+		Lt   string `json:"lt"`
+		Hash string `json:"hash"`
+	} `json:"transaction_id"`
+	InMsg struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+		Value       string `json:"value"`
+		Message     string `json:"message"`
+	} `json:"in_msg"`
+}
+
+// TonCenterWatcher reads wallet transactions from the toncenter v2 http api.
+type TonCenterWatcher struct {
+	mu     *sync.RWMutex
+	apiUrl string
+	apiKey string
+}
+
+func NewTonCenterWatcher(apiUrl, apiKey string, cfgService *configService.ConfigServiceManager) *TonCenterWatcher {
+	watcher := &TonCenterWatcher{
+		mu:     &sync.RWMutex{},
+		apiUrl: apiUrl,
+		apiKey: apiKey,
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		watcher.mu.Lock()
+		watcher.apiUrl = ss.Value
+		watcher.mu.Unlock()
+	}, ApiUrlConfigKey)
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		watcher.mu.Lock()
+		watcher.apiKey = ss.Value
+		watcher.mu.Unlock()
+	}, ApiKeyConfigKey)
+	return watcher
+}
+
+func (w *TonCenterWatcher) IncomingTransfers(ctx context.Context, address string, since time.Time) ([]Transfer, error) {
+	result := make([]Transfer, 0)
+	lt, hash := "", ""
+	for {
+		page, err := w.transactions(ctx, address, lt, hash)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range page {
+			// pagination starts from the last transaction of the previous page
+			if tx.TransactionId.Lt == lt && tx.TransactionId.Hash == hash {
+				continue
+			}
+			at := time.Unix(tx.Utime, 0).UTC()
+			if at.Before(since) {
+				return result, nil
+			}
+			// outgoing transactions have empty in message source
+			if tx.InMsg.Source == "" || tx.InMsg.Value == "" {
+				continue
+			}
+			nanoValue, err := strconv.ParseInt(tx.InMsg.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse transaction %s value cause %v", tx.TransactionId.Hash, err)
+			}
+			if nanoValue == 0 {
+				continue
+			}
+			result = append(result, Transfer{
+				Hash:        tx.TransactionId.Hash,
+				FromAddress: tx.InMsg.Source,
+				ToAddress:   address,
+				Value:       float64(nanoValue) / NanoTonInTon,
+				Comment:     tx.InMsg.Message,
+				At:          at,
+			})
+		}
+		if len(page) < transactionsPageLimit {
+			return result, nil
+		}
+		last := page[len(page)-1]
+		lt, hash = last.TransactionId.Lt, last.TransactionId.Hash
+	}
+}
+
+func (w *TonCenterWatcher) transactions(ctx context.Context, address, lt, hash string) ([]transaction, error) {
+	w.mu.RLock()
+	apiUrl, apiKey := w.apiUrl, w.apiKey
+	w.mu.RUnlock()
+
+	reqUrl, err := url.JoinPath(apiUrl, "getTransactions")
+	if err != nil {
+		return nil, fmt.Errorf("fail to build request url cause %v", err)
+	}
+	query := url.Values{}
+	query.Set("address", address)
+	query.Set("limit", strconv.Itoa(transactionsPageLimit))
+	query.Set("archival", "true")
+	if lt != "" {
+		query.Set("lt", lt)
+		query.Set("hash", hash)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqUrl+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request cause %v", err)
+	}
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	var respBody transactionsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, fmt.Errorf("fail to decode response body cause %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !respBody.Ok {
+		return nil, fmt.Errorf("unexpected response status code: %d, error: %s", resp.StatusCode, respBody.Error)
+	}
+	return respBody.Result, nil
+}
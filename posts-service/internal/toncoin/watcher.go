@@ -0,0 +1,55 @@
+package toncoin
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const NanoTonInTon = 1_000_000_000
+
+type Transfer struct {
+	Hash        string
+	FromAddress string
+	ToAddress   string
+	Value       float64
+	Comment     string
+	At          time.Time
+}
+
+// Watcher returns incoming transfers of the wallet address made after since.
+type Watcher interface {
+	IncomingTransfers(ctx context.Context, address string, since time.Time) ([]Transfer, error)
+}
+
+// StubWatcher keeps transfers in memory, used for local runs without access to the chain.
+type StubWatcher struct {
+	mu        sync.Mutex
+	transfers map[string][]Transfer
+}
+
+func NewStubWatcher() *StubWatcher {
+	return &StubWatcher{transfers: make(map[string][]Transfer)}
+}
+
+func (w *StubWatcher) AddTransfer(transfer Transfer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.transfers[transfer.ToAddress] = append(w.transfers[transfer.ToAddress], transfer)
+}
+
+func (w *StubWatcher) IncomingTransfers(ctx context.Context, address string, since time.Time) ([]Transfer, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	result := make([]Transfer, 0)
+	for _, transfer := range w.transfers[address] {
+		if !transfer.At.Before(since) {
+			result = append(result, transfer)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].At.Before(result[j].At)
+	})
+	return result, nil
+}
@@ -13,10 +13,11 @@ import (
 
 type Service struct {
 	ServiceUrl string
+	signer     *requestuser.Signer
 }
 
-func NewService(serviceUrl string, cfgService *configService.ConfigServiceManager) *Service {
-	service := Service{ServiceUrl: serviceUrl}
+func NewService(serviceUrl string, signer *requestuser.Signer, cfgService *configService.ConfigServiceManager) *Service {
+	service := Service{ServiceUrl: serviceUrl, signer: signer}
 	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
 		service.ServiceUrl = ss.Value
 	}, "USERS_SERVICE_URL")
@@ -44,7 +45,7 @@ func (s *Service) AddRubToBalanceOfUser(userId uuid.UUID, value float64) error {
 		return fmt.Errorf("fail to create request cause %v", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set(requestuser.UserRoleHeaderKey, requestuser.UserRoleService)
+	s.signer.SignServiceRequest(req)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -58,3 +59,71 @@ func (s *Service) AddRubToBalanceOfUser(userId uuid.UUID, value float64) error {
 
 	return nil
 }
+
+type WalletAddressResponse struct {
+	Address string `json:"address"`
+}
+
+func (s *Service) WalletAddressOfUser(userId uuid.UUID) (string, error) {
+
+	// wallet address is served by the info group, next to the service group
+	reqUrl, _ := url.JoinPath(s.ServiceUrl, "../info/id/", userId.String(), "/wallet-address")
+
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		return "", fmt.Errorf("fail to create request cause %v", err)
+	}
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	var respBody WalletAddressResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return "", fmt.Errorf("fail to decode response body cause %v", err)
+	}
+	return respBody.Address, nil
+}
+
+type UserInfoResponse struct {
+	ID    uuid.UUID `json:"id"`
+	Login string    `json:"login"`
+}
+
+// UserIdByLogin returns nil if the user with the login doesn't exist
+func (s *Service) UserIdByLogin(login string) (*uuid.UUID, error) {
+
+	reqUrl, _ := url.JoinPath(s.ServiceUrl, "../info/login/", url.PathEscape(login))
+
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request cause %v", err)
+	}
+	s.signer.SignServiceRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fail to send request cause %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	}
+
+	var respBody UserInfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, fmt.Errorf("fail to decode response body cause %v", err)
+	}
+	return &respBody.ID, nil
+}
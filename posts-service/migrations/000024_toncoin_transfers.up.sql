@@ -0,0 +1,16 @@
+create table toncoin_transfers
+(
+    hash         text primary key,
+    blog_id      uuid             not null,
+    address      text             not null,
+    from_address text             not null,
+    value        double precision not null,
+    comment      text             not null,
+    donation_id  uuid,
+    status       text             not null,
+    transfer_at  timestamp        not null,
+    created      timestamp        not null default current_timestamp,
+    updated      timestamp        not null default current_timestamp
+);
+
+create index toncoin_transfers_status_idx on toncoin_transfers (status);
@@ -0,0 +1,36 @@
+alter table subscriptions
+    add column trial_days int not null default 0;
+
+create table promo_codes
+(
+    id             uuid primary key,
+    blog_id        uuid           not null,
+    code           text           not null,
+    discount_type  text           not null,
+    discount_value numeric(10, 2) not null,
+    max_uses       int                     default null,
+    item_ids       uuid[]         not null default '{}',
+    expires_at     timestamp               default null,
+    is_active      boolean        not null,
+    created        timestamp      not null,
+    updated        timestamp      not null
+);
+
+create unique index promo_codes_blog_id_code_idx on promo_codes (blog_id, upper(code));
+
+create table promo_code_uses
+(
+    id            uuid primary key,
+    promo_code_id uuid           not null references promo_codes (id),
+    user_id       uuid           not null,
+    item_id       uuid           not null,
+    item_type     text           not null,
+    price_rub     numeric(10, 2) not null,
+    discount_rub  numeric(10, 2) not null,
+    status        text           not null,
+    created       timestamp      not null,
+    updated       timestamp      not null
+);
+
+create index promo_code_uses_promo_code_id_idx on promo_code_uses (promo_code_id);
+create index promo_code_uses_user_id_item_id_idx on promo_code_uses (user_id, item_id);
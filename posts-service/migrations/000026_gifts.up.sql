@@ -0,0 +1,20 @@
+create table gifts
+(
+    id           uuid primary key,
+    code         text           not null unique,
+    buyer_id     uuid           not null,
+    blog_id      uuid           not null,
+    item_id      uuid           not null,
+    item_type    text           not null,
+    recipient_id uuid                    default null,
+    price_rub    numeric(10, 2) not null,
+    status       text           not null,
+    expires_at   timestamp               default null,
+    redeemed_by  uuid                    default null,
+    redeemed_at  timestamp               default null,
+    created      timestamp      not null,
+    updated      timestamp      not null
+);
+
+create index gifts_buyer_id_idx on gifts (buyer_id);
+create index gifts_recipient_id_idx on gifts (recipient_id);
@@ -0,0 +1,17 @@
+create table subscription_tier_changes
+(
+    id                   uuid primary key,
+    user_id              uuid           not null,
+    blog_id              uuid           not null,
+    user_subscription_id uuid           not null,
+    from_subscription_id uuid           not null,
+    to_subscription_id   uuid           not null,
+    kind                 text           not null,
+    price_rub            numeric(10, 2) not null default 0,
+    status               text           not null,
+    effective_at         timestamp      not null,
+    created              timestamp      not null,
+    updated              timestamp      not null
+);
+
+create index subscription_tier_changes_user_subscription_id_idx on subscription_tier_changes (user_subscription_id);
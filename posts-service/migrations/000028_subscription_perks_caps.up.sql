@@ -0,0 +1,26 @@
+alter table subscriptions
+    add column perks           jsonb not null default '[]',
+    add column max_subscribers int            default null;
+
+create table subscription_waitlist
+(
+    id              uuid primary key,
+    subscription_id uuid      not null,
+    user_id         uuid      not null,
+    status          text      not null,
+    notified_at     timestamp default null,
+    created         timestamp not null,
+    updated         timestamp not null,
+    unique (subscription_id, user_id)
+);
+
+create table subscription_reservations
+(
+    id              uuid primary key,
+    subscription_id uuid      not null,
+    user_id         uuid      not null,
+    expires_at      timestamp not null,
+    created         timestamp not null
+);
+
+create index subscription_reservations_subscription_id_idx on subscription_reservations (subscription_id);
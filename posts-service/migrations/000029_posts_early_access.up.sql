@@ -0,0 +1,5 @@
+alter table posts
+    add column early_access_days int       default null,
+    add column public_at         timestamp default null;
+
+create index posts_public_at_idx on posts (public_at) where public_at is not null;
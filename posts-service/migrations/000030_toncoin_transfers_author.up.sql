@@ -0,0 +1,18 @@
+alter table toncoin_transfers
+    add column author_id uuid,
+    alter column blog_id drop not null;
+
+update toncoin_transfers t
+set author_id = b.author_id
+from blogs b
+where b.id = t.blog_id;
+
+-- unmatched transfers belong to the author wallet, the blog is known when the donation is matched
+update toncoin_transfers
+set blog_id = null
+where donation_id is null;
+
+alter table toncoin_transfers
+    alter column author_id set not null;
+
+create index toncoin_transfers_author_id_idx on toncoin_transfers (author_id);
@@ -0,0 +1,6 @@
+create table refund_reversals
+(
+    refund_id      text primary key,
+    blog_income_id uuid      not null,
+    created        timestamp not null
+);
@@ -0,0 +1,4 @@
+alter table promo_code_uses
+    add column invoice_id integer;
+
+create index promo_code_uses_invoice_id_idx on promo_code_uses (invoice_id);
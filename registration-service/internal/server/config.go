@@ -10,10 +10,24 @@ type Config struct {
 	ConfigServicePort    string `env:"CONFIG_SERVICE_PORT"`
 	ConfigUpdateInterval int    `env:"CONFIG_UPDATE_INTERVAL" env-default:"60"`
 
-	EmailQueue          string `config-service:"EMAIL_QUEUE"`
-	UserAutoEnable      bool   `config-service:"USER_AUTO_ENABLE"`
-	SignupLifetime      int    `config-service:"SIGNUP_LIFETIME"`
-	UserCleanupInterval int    `config-service:"USER_CLEANUP_INTERVAL"`
+	IdentityAssertionSecret string `config-service:"IDENTITY_ASSERTION_SECRET"`
+
+	EmailQueue           string `config-service:"EMAIL_QUEUE"`
+	UserAutoEnable       bool   `config-service:"USER_AUTO_ENABLE"`
+	InviteOnly           bool   `config-service:"INVITE_ONLY"`
+	SignupLifetime       int    `config-service:"SIGNUP_LIFETIME"`
+	SignupResendCooldown int    `config-service:"SIGNUP_RESEND_COOLDOWN"`
+	UserCleanupInterval  int    `config-service:"USER_CLEANUP_INTERVAL"`
+
+	SignupBlockDisposableEmails bool   `config-service:"SIGNUP_BLOCK_DISPOSABLE_EMAILS"`
+	SignupDisposableDomains     string `config-service:"SIGNUP_DISPOSABLE_DOMAINS"`
+	SignupReservedLogins        string `config-service:"SIGNUP_RESERVED_LOGINS"`
+	SignupOffensiveLoginWords   string `config-service:"SIGNUP_OFFENSIVE_LOGIN_WORDS"`
+	SignupConfusableLoginCheck  bool   `config-service:"SIGNUP_CONFUSABLE_LOGIN_CHECK"`
+	SignupIpLimit               int    `config-service:"SIGNUP_IP_LIMIT"`
+	SignupIpWindow              int    `config-service:"SIGNUP_IP_WINDOW"`
+	SignupPowDifficulty         int    `config-service:"SIGNUP_POW_DIFFICULTY"`
+	SignupPowLifetime           int    `config-service:"SIGNUP_POW_LIFETIME"`
 
 	NotificationQueue string `config-service:"NOTIFICATION_QUEUE"`
 
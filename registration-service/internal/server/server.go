@@ -13,8 +13,10 @@ import (
 	"os/signal"
 	"registration-service/internal/email"
 	"registration-service/internal/notifications"
+	signuppolicy "registration-service/internal/signup-policy"
 	"registration-service/internal/users"
 	"registration-service/pkg/filelogger"
+	requestuser "registration-service/pkg/hidepost-requestuser"
 	"registration-service/pkg/pgutils"
 	"registration-service/pkg/queuelogger"
 	serverlogging "registration-service/pkg/serverlogging/gin"
@@ -62,6 +64,7 @@ func Run() {
 
 	// init repositories
 	usersRepository := users.NewRepository(dbConn)
+	signupPolicyRepository := signuppolicy.NewRepository(dbConn)
 
 	// init senders
 	emailSender, err := email.NewSender(ctx, cfg.MqUrl(), cfg.EmailQueue)
@@ -80,13 +83,38 @@ func Run() {
 	notificationsService := notifications.NewService(notificationsSender, cfgService, fileLogger, mqLogger)
 	emailService := email.NewService(emailSender, cfgService, fileLogger, mqLogger)
 	usersService := users.NewService(ctx, usersRepository, emailService, notificationsService,
-		cfg.UserAutoEnable, cfg.SignupLifetime, cfg.UserCleanupInterval, cfgService, fileLogger, mqLogger)
+		cfg.UserAutoEnable, cfg.InviteOnly, cfg.SignupLifetime, cfg.SignupResendCooldown, cfg.UserCleanupInterval,
+		cfgService, fileLogger, mqLogger)
+	signupPolicyService := signuppolicy.NewService(signupPolicyRepository, signuppolicy.Settings{
+		BlockDisposableEmails: cfg.SignupBlockDisposableEmails,
+		DisposableDomains:     cfg.SignupDisposableDomains,
+		ReservedLogins:        cfg.SignupReservedLogins,
+		OffensiveLoginWords:   cfg.SignupOffensiveLoginWords,
+		ConfusableLoginCheck:  cfg.SignupConfusableLoginCheck,
+		IpLimit:               cfg.SignupIpLimit,
+		IpWindow:              cfg.SignupIpWindow,
+		PowDifficulty:         cfg.SignupPowDifficulty,
+		PowLifetime:           cfg.SignupPowLifetime,
+	}, cfgService, fileLogger, mqLogger)
+
+	// identity assertions are verified for every request and signed for requests to other services
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := identitySigner.SetSecret(ss.Value); err != nil {
+			fileLogger.Error("failed to update identity assertion secret from config",
+				map[string]any{"error": err.Error(), "key": ss.Key})
+		}
+	}, requestuser.IdentityAssertionSecretConfigKey)
 
 	// setting up gin app
 	gin.SetMode(gin.ReleaseMode)
 	app := gin.New()
 	app.Use(gin.CustomRecovery(serverlogging.NewPanicLogger(fileLogger, mqLogger)))
 	app.Use(serverlogging.NewRequestLogger(fileLogger, mqLogger))
+	app.Use(identitySigner.Middleware())
 
 	// init handlers
 	apiV1 := app.Group("/api/v1/registration")
@@ -98,11 +126,18 @@ func Run() {
 	users.RegisterUserExistsHandler(apiV1.Group("/user-exists"), usersService)
 
 	// signup handler
-	users.RegisterSignupHandler(apiV1.Group("/signup"), usersService)
+	users.RegisterSignupHandler(apiV1.Group("/signup"), usersService, signupPolicyService)
+
+	// signup policy handlers
+	signuppolicy.RegisterChallengeHandler(apiV1.Group("/signup/challenge"), signupPolicyService)
+	signuppolicy.RegisterAdminHandler(apiV1.Group("/admin/signup-policy"), signupPolicyService)
 
 	// confirm handler
 	users.RegisterConfirmHandler(apiV1.Group("/confirm"), usersService)
 
+	// invite handler
+	users.RegisterInviteHandler(apiV1.Group("/invite"), usersService)
+
 	// start config updater
 	go cfgService.Updater()
 
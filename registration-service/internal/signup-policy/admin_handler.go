@@ -0,0 +1,102 @@
+package signuppolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	requestuser "registration-service/pkg/hidepost-requestuser"
+	serverlogging "registration-service/pkg/serverlogging/gin"
+	"strings"
+)
+
+type adminHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterAdminHandler(api *gin.RouterGroup, service *Service) {
+	h := &adminHandler{service: service, validate: validator.New(validator.WithRequiredStructEnabled())}
+	adminM := AdminMiddleware()
+
+	api.GET("/email-domains", adminM, h.domainRules)
+	api.PUT("/email-domains/:domain", adminM, h.setDomainRule)
+	api.DELETE("/email-domains/:domain", adminM, h.deleteDomainRule)
+}
+
+func (h *adminHandler) domainRules(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	rules, err := h.service.DomainRules(ctx)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get email domain rules")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, rules)
+}
+
+func (h *adminHandler) setDomainRule(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	domain := NormalizeDomain(ctx.Param("domain"))
+	loggingMap["domain"] = domain
+	if !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@/ ") || len(domain) > 253 {
+		loggingMap.SetMessage("bad email domain")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	var req DomainRuleRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad email domain rule request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad email domain rule request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	rule, err := h.service.SetDomainRule(ctx, domain, req.Policy, requestuser.GetUserID(ctx))
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to set email domain rule")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	loggingMap.SetMessage("email domain rule set")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, rule)
+}
+
+func (h *adminHandler) deleteDomainRule(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	domain := ctx.Param("domain")
+	loggingMap["domain"] = domain
+
+	deleted, err := h.service.DeleteDomainRule(ctx, domain)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to delete email domain rule")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !deleted {
+		loggingMap.SetMessage("email domain rule not found")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	loggingMap.SetMessage("email domain rule deleted")
+	loggingMap.Info()
+	ctx.Status(http.StatusNoContent)
+}
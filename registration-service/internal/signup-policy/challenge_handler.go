@@ -0,0 +1,43 @@
+package signuppolicy
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	serverlogging "registration-service/pkg/serverlogging/gin"
+)
+
+type challengeHandler struct {
+	service *Service
+}
+
+func RegisterChallengeHandler(api *gin.RouterGroup, service *Service) {
+	h := &challengeHandler{service: service}
+
+	api.POST("", h.issue)
+}
+
+// issue creates the proof-of-work challenge the client solves before the signup,
+// it's not required if the difficulty is 0 in config
+func (h *challengeHandler) issue(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	challenge, err := h.service.IssueChallenge(ctx)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to issue signup challenge")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if challenge == nil {
+		ctx.JSON(http.StatusOK, ChallengeResponse{Required: false})
+		return
+	}
+	ctx.JSON(http.StatusCreated, ChallengeResponse{
+		Required:   true,
+		ID:         &challenge.ID,
+		Nonce:      challenge.Nonce,
+		Difficulty: challenge.Difficulty,
+		Algorithm:  powAlgorithm,
+		ExpiresAt:  &challenge.ExpiresAt,
+	})
+}
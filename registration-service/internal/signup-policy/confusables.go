@@ -0,0 +1,48 @@
+package signuppolicy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// confusables maps lowercase letters of other scripts to the latin ones they look like,
+// login_skeleton of users migrations translates the same letters and their uppercase forms
+var confusables = map[rune]rune{
+	// cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c',
+	'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i', 'ї': 'i', 'ј': 'j', 'ԁ': 'd', 'һ': 'h', 'ԛ': 'q',
+	'ԝ': 'w', 'ɡ': 'g', 'ь': 'b', 'п': 'n',
+	// greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't',
+	'υ': 'u', 'χ': 'x', 'ω': 'w', 'ζ': 'z',
+	// latin look-alikes
+	'ı': 'i', 'ł': 'l', 'ℓ': 'l', 'ø': 'o', 'ß': 'b', 'đ': 'd', 'ħ': 'h',
+}
+
+// skeletonReplacer folds ascii look-alikes the same way as login_skeleton of users migrations
+var skeletonReplacer = strings.NewReplacer("0", "o", "1", "l", "i", "l", "_", "")
+
+// Skeleton returns the form of the login in which look-alike spellings are equal, e.g. "Adm1n",
+// "admin" with the cyrillic "а" and "аdmin" have the same skeleton. Fullwidth characters and
+// letters of other scripts are mapped to latin ones first, then ascii look-alikes are folded.
+func Skeleton(login string) string {
+	var b strings.Builder
+	for _, r := range login {
+		// fullwidth forms of ascii
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		r = unicode.ToLower(r)
+		if latin, found := confusables[r]; found {
+			r = latin
+		}
+		// combining diacritical marks, e.g. of "a" + U+0301, are invisible additions
+		if r >= 0x0300 && r <= 0x036F {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	skeleton := strings.ReplaceAll(b.String(), "rn", "m")
+	skeleton = strings.ReplaceAll(skeleton, "vv", "w")
+	return skeletonReplacer.Replace(skeleton)
+}
@@ -0,0 +1,24 @@
+package signuppolicy
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ViolationResponse has the reason the signup is rejected by the policy
+type ViolationResponse struct {
+	Reason string `json:"reason"`
+}
+
+type ChallengeResponse struct {
+	Required   bool       `json:"required"`
+	ID         *uuid.UUID `json:"id,omitempty"`
+	Nonce      string     `json:"nonce,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	Algorithm  string     `json:"algorithm,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+type DomainRuleRequest struct {
+	Policy string `json:"policy" validate:"required,oneof=allow deny"`
+}
@@ -0,0 +1,72 @@
+package signuppolicy
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed disposable_domains.txt
+var disposableDomainsFile string
+
+var defaultReservedLogins = []string{
+	"admin", "administrator", "root", "system", "sysadmin", "superuser", "support", "help", "helpdesk",
+	"moderator", "mod", "staff", "team", "official", "owner", "security", "abuse", "postmaster", "webmaster",
+	"hostmaster", "noreply", "no_reply", "info", "mail", "email", "www", "api", "billing", "payments",
+	"service", "services", "bot", "robot", "hidepost", "hidepost_team", "hidepost_support", "null", "undefined",
+}
+
+// parseList splits the comma or newline separated list, values are trimmed and lowercased
+func parseList(value string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" && !strings.HasPrefix(item, "#") {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func domainSet(lists ...[]string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, list := range lists {
+		for _, domain := range list {
+			set[strings.TrimPrefix(domain, "@")] = struct{}{}
+		}
+	}
+	return set
+}
+
+// skeletonSet returns skeletons of logins, so look-alike spellings of reserved logins match too
+func skeletonSet(lists ...[]string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, list := range lists {
+		for _, login := range list {
+			set[Skeleton(login)] = struct{}{}
+		}
+	}
+	return set
+}
+
+func skeletonList(list []string) []string {
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if skeleton := Skeleton(item); skeleton != "" {
+			result = append(result, skeleton)
+		}
+	}
+	return result
+}
+
+// domainCandidates returns the domain and its parent domains, e.g. a.mailinator.com and mailinator.com
+func domainCandidates(domain string) []string {
+	candidates := make([]string, 0)
+	for {
+		candidates = append(candidates, domain)
+		_, parent, found := strings.Cut(domain, ".")
+		if !found || !strings.Contains(parent, ".") {
+			return candidates
+		}
+		domain = parent
+	}
+}
@@ -0,0 +1,31 @@
+package signuppolicy
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	requestuser "registration-service/pkg/hidepost-requestuser"
+	serverlogging "registration-service/pkg/serverlogging/gin"
+)
+
+func AdminMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggingMap := serverlogging.GetLoggingMap(ctx)
+		userId := requestuser.GetUserID(ctx)
+		if userId == nil {
+			loggingMap.SetMessage("request user is not authenticated")
+			loggingMap["user_id_header"] = ctx.GetHeader(requestuser.UserIdHeaderKey)
+			loggingMap["user_role_header"] = ctx.GetHeader(requestuser.UserRoleHeaderKey)
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		loggingMap.SetUserId(userId)
+		if !requestuser.IsAdmin(ctx) {
+			loggingMap.SetMessage("request user is not admin")
+			loggingMap["user_id_header"] = ctx.GetHeader(requestuser.UserIdHeaderKey)
+			loggingMap["user_role_header"] = ctx.GetHeader(requestuser.UserRoleHeaderKey)
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package signuppolicy
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	BlockDisposableEmailsConfigKey = "SIGNUP_BLOCK_DISPOSABLE_EMAILS"
+	DisposableDomainsConfigKey     = "SIGNUP_DISPOSABLE_DOMAINS"
+	ReservedLoginsConfigKey        = "SIGNUP_RESERVED_LOGINS"
+	OffensiveLoginWordsConfigKey   = "SIGNUP_OFFENSIVE_LOGIN_WORDS"
+	ConfusableLoginCheckConfigKey  = "SIGNUP_CONFUSABLE_LOGIN_CHECK"
+	IpLimitConfigKey               = "SIGNUP_IP_LIMIT"
+	IpWindowConfigKey              = "SIGNUP_IP_WINDOW"
+	PowDifficultyConfigKey         = "SIGNUP_POW_DIFFICULTY"
+	PowLifetimeConfigKey           = "SIGNUP_POW_LIFETIME"
+)
+
+// Settings of the signup policy. Lists are comma separated and extend the built-in ones,
+// the ip window is in minutes, the proof-of-work difficulty is in leading zero bits
+// (0 disables the challenge) and its lifetime is in seconds.
+type Settings struct {
+	BlockDisposableEmails bool
+	DisposableDomains     string
+	ReservedLogins        string
+	OffensiveLoginWords   string
+	ConfusableLoginCheck  bool
+	IpLimit               int
+	IpWindow              int
+	PowDifficulty         int
+	PowLifetime           int
+}
+
+// Reasons the signup is rejected with
+const (
+	ReasonInvalidEmail          = "invalid_email"
+	ReasonEmailDomainDenied     = "email_domain_denied"
+	ReasonEmailDomainDisposable = "email_domain_disposable"
+	ReasonLoginReserved         = "login_reserved"
+	ReasonLoginOffensive        = "login_offensive"
+	ReasonLoginConfusable       = "login_confusable"
+	ReasonChallengeRequired     = "challenge_required"
+	ReasonChallengeInvalid      = "challenge_invalid"
+	ReasonIpThrottled           = "ip_throttled"
+)
+
+const (
+	DomainPolicyAllow = "allow"
+	DomainPolicyDeny  = "deny"
+)
+
+// DomainRule is the admin decision on the email domain, it applies to subdomains too.
+// Allowed domains skip the disposable blocklist.
+type DomainRule struct {
+	Domain    string     `json:"domain"`
+	Policy    string     `json:"policy"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	Created   time.Time  `json:"created"`
+}
+
+// Challenge is the proof-of-work the client solves before the signup: it finds the solution
+// the sha256 hash of nonce + solution of which starts with difficulty zero bits
+type Challenge struct {
+	ID         uuid.UUID
+	Nonce      string
+	Difficulty int
+	ExpiresAt  time.Time
+	SolvedAt   *time.Time
+}
@@ -0,0 +1,31 @@
+package signuppolicy
+
+import (
+	"crypto/sha256"
+	"math/bits"
+)
+
+const (
+	powAlgorithm = "sha256"
+	// maxPowDifficulty keeps the challenge solvable by browsers in seconds, every bit doubles the work
+	// and 20 bits take about a million hashes, which is a second or two of javascript on slow phones
+	maxPowDifficulty  = 20
+	maxSolutionLength = 128
+)
+
+// solves reports whether the sha256 hash of nonce + solution starts with difficulty zero bits
+func solves(nonce, solution string, difficulty int) bool {
+	if len(solution) == 0 || len(solution) > maxSolutionLength {
+		return false
+	}
+	hash := sha256.Sum256([]byte(nonce + solution))
+	zeros := 0
+	for _, b := range hash {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
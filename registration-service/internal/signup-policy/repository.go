@@ -0,0 +1,183 @@
+package signuppolicy
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) DomainRules(ctx context.Context) ([]DomainRule, error) {
+	query := `select domain, policy, created_by, created from signup_email_domains order by domain`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resultArray := make([]DomainRule, 0)
+	var item DomainRule
+	for rows.Next() {
+		err := rows.Scan(
+			&item.Domain,
+			&item.Policy,
+			&item.CreatedBy,
+			&item.Created,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, item)
+	}
+	return resultArray, nil
+}
+
+// DomainRuleByDomains returns the rule of the most specific of domains, nil if there is no rule
+func (r *Repository) DomainRuleByDomains(ctx context.Context, domains []string) (*DomainRule, error) {
+	query := `select domain, policy, created_by, created from signup_email_domains
+	where domain = any($1)
+	order by length(domain) desc
+	limit 1`
+	var item DomainRule
+	err := r.db.QueryRow(ctx, query, domains).Scan(
+		&item.Domain,
+		&item.Policy,
+		&item.CreatedBy,
+		&item.Created,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) SetDomainRule(ctx context.Context, rule *DomainRule) error {
+	query := `insert into signup_email_domains (domain, policy, created_by, created) values ($1, $2, $3, $4)
+	on conflict (domain) do update set policy = excluded.policy, created_by = excluded.created_by, created = excluded.created`
+	_, err := r.db.Exec(ctx, query, rule.Domain, rule.Policy, rule.CreatedBy, rule.Created)
+	return err
+}
+
+func (r *Repository) DeleteDomainRule(ctx context.Context, domain string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `delete from signup_email_domains where domain = $1`, domain)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ExistsByLoginSkeleton checks whether the login of any user looks like the new one
+func (r *Repository) ExistsByLoginSkeleton(ctx context.Context, skeleton string) (bool, error) {
+	query := `select count(id) from users where login_skeleton(login) = $1`
+	count := 0
+	err := r.db.QueryRow(ctx, query, skeleton).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SignupRequestsByIp returns the count of signups from the ip since the time and the time of the earliest one
+func (r *Repository) SignupRequestsByIp(ctx context.Context, ip string, since time.Time) (int, *time.Time, error) {
+	query := `select count(id), min(created) from signup_requests where ip = $1 and created >= $2`
+	count := 0
+	var earliest *time.Time
+	err := r.db.QueryRow(ctx, query, ip, since).Scan(&count, &earliest)
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, earliest, nil
+}
+
+// ReserveSignupRequest counts the signup from the ip if there are less than limit signups since the time.
+// Signups of the ip are serialized by the advisory lock, so concurrent ones can't exceed the limit.
+// False and the time of the earliest signup are returned if the limit is reached.
+func (r *Repository) ReserveSignupRequest(ctx context.Context, ip string, since time.Time, limit int,
+	timeNow time.Time) (bool, *time.Time, error) {
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext('signup_requests:' || $1))`, ip)
+	if err != nil {
+		return false, nil, err
+	}
+
+	query := `select count(id), min(created) from signup_requests where ip = $1 and created >= $2`
+	count := 0
+	var earliest *time.Time
+	err = tx.QueryRow(ctx, query, ip, since).Scan(&count, &earliest)
+	if err != nil {
+		return false, nil, err
+	}
+	if count >= limit {
+		return false, earliest, nil
+	}
+
+	query = `insert into signup_requests (id, ip, created) values ($1, $2, $3)`
+	_, err = tx.Exec(ctx, query, uuid.New(), ip, timeNow)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, nil, tx.Commit(ctx)
+}
+
+func (r *Repository) DeleteSignupRequestsBefore(ctx context.Context, before time.Time) error {
+	_, err := r.db.Exec(ctx, `delete from signup_requests where created < $1`, before)
+	return err
+}
+
+func (r *Repository) CreateChallenge(ctx context.Context, challenge *Challenge) error {
+	query := `insert into signup_challenges (id, nonce, difficulty, expires_at) values ($1, $2, $3, $4)`
+	_, err := r.db.Exec(ctx, query, challenge.ID, challenge.Nonce, challenge.Difficulty, challenge.ExpiresAt)
+	return err
+}
+
+func (r *Repository) ChallengeById(ctx context.Context, id uuid.UUID) (*Challenge, error) {
+	query := `select id, nonce, difficulty, expires_at, solved_at from signup_challenges where id = $1`
+	var item Challenge
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&item.ID,
+		&item.Nonce,
+		&item.Difficulty,
+		&item.ExpiresAt,
+		&item.SolvedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// SolveChallenge marks the active challenge solved, false is returned if it's already solved or expired
+func (r *Repository) SolveChallenge(ctx context.Context, id uuid.UUID, timeNow time.Time) (bool, error) {
+	query := `update signup_challenges set solved_at = $2 where id = $1 and solved_at is null and expires_at > $2`
+	tag, err := r.db.Exec(ctx, query, id, timeNow)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repository) DeleteChallengesBefore(ctx context.Context, before time.Time) error {
+	_, err := r.db.Exec(ctx, `delete from signup_challenges where expires_at < $1`, before)
+	return err
+}
@@ -0,0 +1,344 @@
+package signuppolicy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"registration-service/pkg/filelogger"
+	"registration-service/pkg/queuelogger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Service decides whether the signup is allowed: it checks the email domain against admin rules and
+// the disposable blocklist, the login against reserved, offensive and look-alike existing logins,
+// throttles signups from one ip and issues proof-of-work challenges
+type Service struct {
+	repository  *Repository
+	fileLogger  *filelogger.FileLogger
+	queueLogger *queuelogger.RemoteLogger
+
+	mu                  *sync.RWMutex
+	settings            Settings
+	disposableDomains   map[string]struct{}
+	reservedLogins      map[string]struct{}
+	offensiveLoginWords []string
+}
+
+func NewService(repository *Repository, settings Settings,
+	cfgService *configService.ConfigServiceManager,
+	fileLogger *filelogger.FileLogger,
+	queueLogger *queuelogger.RemoteLogger) *Service {
+
+	s := &Service{
+		repository:  repository,
+		fileLogger:  fileLogger,
+		queueLogger: queueLogger,
+		mu:          &sync.RWMutex{},
+	}
+	s.apply(settings)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.ParseBool(ss.Value)
+		if err != nil {
+			s.logConfigError(ss, err)
+			return
+		}
+		s.update(func(settings *Settings) {
+			switch ss.Key {
+			case BlockDisposableEmailsConfigKey:
+				settings.BlockDisposableEmails = value
+			case ConfusableLoginCheckConfigKey:
+				settings.ConfusableLoginCheck = value
+			}
+		})
+	}, BlockDisposableEmailsConfigKey, ConfusableLoginCheckConfigKey)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		s.update(func(settings *Settings) {
+			switch ss.Key {
+			case DisposableDomainsConfigKey:
+				settings.DisposableDomains = ss.Value
+			case ReservedLoginsConfigKey:
+				settings.ReservedLogins = ss.Value
+			case OffensiveLoginWordsConfigKey:
+				settings.OffensiveLoginWords = ss.Value
+			}
+		})
+	}, DisposableDomainsConfigKey, ReservedLoginsConfigKey, OffensiveLoginWordsConfigKey)
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil && value < 0 {
+			err = fmt.Errorf("negative value")
+		}
+		if err == nil && ss.Key == PowDifficultyConfigKey && value > maxPowDifficulty {
+			err = fmt.Errorf("difficulty is greater than %d", maxPowDifficulty)
+		}
+		if err != nil {
+			s.logConfigError(ss, err)
+			return
+		}
+		s.update(func(settings *Settings) {
+			switch ss.Key {
+			case IpLimitConfigKey:
+				settings.IpLimit = value
+			case IpWindowConfigKey:
+				settings.IpWindow = value
+			case PowDifficultyConfigKey:
+				settings.PowDifficulty = value
+			case PowLifetimeConfigKey:
+				settings.PowLifetime = value
+			}
+		})
+	}, IpLimitConfigKey, IpWindowConfigKey, PowDifficultyConfigKey, PowLifetimeConfigKey)
+
+	return s
+}
+
+func (s *Service) logConfigError(ss configService.ServiceSetting, err error) {
+	message := fmt.Sprintf("failed to parse value for %s from config", ss.Key)
+	data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+	s.fileLogger.Error(message, data)
+	data["message"] = message
+	err1 := s.queueLogger.Error(nil, data)
+	if err1 != nil {
+		s.fileLogger.Error("failed to send log about signup policy config parsing error to queue",
+			map[string]any{"error": err1.Error()})
+	}
+}
+
+func (s *Service) update(change func(settings *Settings)) {
+	s.mu.RLock()
+	settings := s.settings
+	s.mu.RUnlock()
+	change(&settings)
+	s.apply(settings)
+}
+
+// apply sets the settings and rebuilds lists extended by them
+func (s *Service) apply(settings Settings) {
+	if settings.PowDifficulty > maxPowDifficulty {
+		settings.PowDifficulty = maxPowDifficulty
+	}
+	disposableDomains := domainSet(parseList(disposableDomainsFile), parseList(settings.DisposableDomains))
+	reservedLogins := skeletonSet(defaultReservedLogins, parseList(settings.ReservedLogins))
+	offensiveLoginWords := skeletonList(parseList(settings.OffensiveLoginWords))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings = settings
+	s.disposableDomains = disposableDomains
+	s.reservedLogins = reservedLogins
+	s.offensiveLoginWords = offensiveLoginWords
+}
+
+func (s *Service) Settings() Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings
+}
+
+// CheckEmail returns the reason the email can't be used for the signup, empty if it can
+func (s *Service) CheckEmail(ctx context.Context, email string) (string, error) {
+	at := strings.LastIndex(email, "@")
+	domain := NormalizeDomain(email[at+1:])
+	if at <= 0 || !strings.Contains(domain, ".") {
+		return ReasonInvalidEmail, nil
+	}
+	candidates := domainCandidates(domain)
+
+	rule, err := s.repository.DomainRuleByDomains(ctx, candidates)
+	if err != nil {
+		return "", fmt.Errorf("failed to get email domain rule: %v", err)
+	}
+	if rule != nil {
+		if rule.Policy == DomainPolicyDeny {
+			return ReasonEmailDomainDenied, nil
+		}
+		return "", nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.settings.BlockDisposableEmails {
+		for _, candidate := range candidates {
+			if _, found := s.disposableDomains[candidate]; found {
+				return ReasonEmailDomainDisposable, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// CheckLogin returns the reason the login can't be used for the signup, empty if it can.
+// Logins are compared by skeletons, so look-alike spellings are rejected as well.
+func (s *Service) CheckLogin(ctx context.Context, login string) (string, error) {
+	skeleton := Skeleton(login)
+
+	s.mu.RLock()
+	_, reserved := s.reservedLogins[skeleton]
+	offensive := false
+	for _, word := range s.offensiveLoginWords {
+		if strings.Contains(skeleton, word) {
+			offensive = true
+			break
+		}
+	}
+	confusableCheck := s.settings.ConfusableLoginCheck
+	s.mu.RUnlock()
+
+	if reserved {
+		return ReasonLoginReserved, nil
+	}
+	if offensive {
+		return ReasonLoginOffensive, nil
+	}
+	if confusableCheck {
+		exists, err := s.repository.ExistsByLoginSkeleton(ctx, skeleton)
+		if err != nil {
+			return "", fmt.Errorf("failed to check look-alike logins: %v", err)
+		}
+		if exists {
+			return ReasonLoginConfusable, nil
+		}
+	}
+	return "", nil
+}
+
+// IpBlockedUntil returns the time the next signup from the ip is allowed at, nil if it is allowed now
+func (s *Service) IpBlockedUntil(ctx context.Context, ip string) (*time.Time, error) {
+	settings := s.Settings()
+	if settings.IpLimit == 0 {
+		return nil, nil
+	}
+	window := time.Minute * time.Duration(settings.IpWindow)
+	timeNow := time.Now().UTC()
+	since := timeNow.Add(-window)
+	if err := s.repository.DeleteSignupRequestsBefore(ctx, since); err != nil {
+		return nil, fmt.Errorf("failed to delete old signup requests: %v", err)
+	}
+	count, earliest, err := s.repository.SignupRequestsByIp(ctx, ip, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count signup requests: %v", err)
+	}
+	if count < settings.IpLimit || earliest == nil {
+		return nil, nil
+	}
+	blockedUntil := earliest.Add(window)
+	return &blockedUntil, nil
+}
+
+// ReserveSignup counts the signup from the ip if the ip limit isn't reached, so concurrent signups can't
+// pass the IpBlockedUntil check together. The time the next signup is allowed at is returned if it is reached.
+func (s *Service) ReserveSignup(ctx context.Context, ip string) (*time.Time, error) {
+	settings := s.Settings()
+	if settings.IpLimit == 0 {
+		return nil, nil
+	}
+	window := time.Minute * time.Duration(settings.IpWindow)
+	timeNow := time.Now().UTC()
+	reserved, earliest, err := s.repository.ReserveSignupRequest(ctx, ip, timeNow.Add(-window), settings.IpLimit, timeNow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve signup request: %v", err)
+	}
+	if reserved {
+		return nil, nil
+	}
+	blockedUntil := timeNow
+	if earliest != nil {
+		blockedUntil = earliest.Add(window)
+	}
+	return &blockedUntil, nil
+}
+
+func (s *Service) ChallengeRequired() bool {
+	return s.Settings().PowDifficulty > 0
+}
+
+// IssueChallenge creates the proof-of-work challenge, nil is returned if challenges are disabled
+func (s *Service) IssueChallenge(ctx context.Context) (*Challenge, error) {
+	settings := s.Settings()
+	if settings.PowDifficulty == 0 {
+		return nil, nil
+	}
+	timeNow := time.Now().UTC()
+	if err := s.repository.DeleteChallengesBefore(ctx, timeNow); err != nil {
+		return nil, fmt.Errorf("failed to delete expired signup challenges: %v", err)
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate signup challenge nonce: %v", err)
+	}
+	challenge := Challenge{
+		ID:         uuid.New(),
+		Nonce:      hex.EncodeToString(nonce),
+		Difficulty: settings.PowDifficulty,
+		ExpiresAt:  timeNow.Add(time.Second * time.Duration(settings.PowLifetime)),
+	}
+	if err := s.repository.CreateChallenge(ctx, &challenge); err != nil {
+		return nil, fmt.Errorf("failed to save signup challenge: %v", err)
+	}
+	return &challenge, nil
+}
+
+// VerifyChallenge returns the reason the challenge solution isn't accepted, empty if it is or challenges
+// are disabled. The challenge is solved once, so the solution can't be reused for other signups.
+func (s *Service) VerifyChallenge(ctx context.Context, challengeId, solution string) (string, error) {
+	if !s.ChallengeRequired() {
+		return "", nil
+	}
+	if challengeId == "" || solution == "" {
+		return ReasonChallengeRequired, nil
+	}
+	id, err := uuid.Parse(challengeId)
+	if err != nil {
+		return ReasonChallengeInvalid, nil
+	}
+
+	challenge, err := s.repository.ChallengeById(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get signup challenge: %v", err)
+	}
+	if challenge == nil || !solves(challenge.Nonce, solution, challenge.Difficulty) {
+		return ReasonChallengeInvalid, nil
+	}
+	solved, err := s.repository.SolveChallenge(ctx, challenge.ID, time.Now().UTC())
+	if err != nil {
+		return "", fmt.Errorf("failed to solve signup challenge: %v", err)
+	}
+	if !solved {
+		return ReasonChallengeInvalid, nil
+	}
+	return "", nil
+}
+
+func (s *Service) DomainRules(ctx context.Context) ([]DomainRule, error) {
+	return s.repository.DomainRules(ctx)
+}
+
+func (s *Service) SetDomainRule(ctx context.Context, domain, policy string, adminId *uuid.UUID) (*DomainRule, error) {
+	rule := DomainRule{
+		Domain:    NormalizeDomain(domain),
+		Policy:    policy,
+		CreatedBy: adminId,
+		Created:   time.Now().UTC(),
+	}
+	return &rule, s.repository.SetDomainRule(ctx, &rule)
+}
+
+func (s *Service) DeleteDomainRule(ctx context.Context, domain string) (bool, error) {
+	return s.repository.DeleteDomainRule(ctx, NormalizeDomain(domain))
+}
+
+// NormalizeDomain lowercases the domain and trims the leading "@" and the trailing dot
+func NormalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	return strings.TrimSuffix(strings.TrimPrefix(domain, "@"), ".")
+}
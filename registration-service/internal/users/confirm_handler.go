@@ -4,8 +4,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	serverlogging "registration-service/pkg/serverlogging/gin"
+	"time"
 )
 
+// confirmFailureUnknown is the reason for codes which don't exist, other reasons are code statuses
+const confirmFailureUnknown = "unknown"
+
 type confirmHandler struct {
 	service *Service
 }
@@ -24,42 +28,60 @@ func (h *confirmHandler) confirm(ctx *gin.Context) {
 	confirmCode := ctx.Param("code")
 	loggingMap["confirm_code"] = confirmCode
 
-	userId, err := h.service.UserIdByConfirmCode(ctx, confirmCode)
+	code, err := h.service.ConfirmCode(ctx, confirmCode)
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("failed to check the signup confirmation code")
-		ctx.Status(http.StatusInternalServerError)
+		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
-	if userId == nil {
+	if code == nil {
 		loggingMap.SetMessage("signup confirmation code not found")
-		ctx.Status(http.StatusNotFound)
+		ctx.JSON(http.StatusNotFound, ConfirmFailureResponse{Reason: confirmFailureUnknown})
 		return
 	}
 
-	user, err := h.service.repository.ByID(ctx, *userId)
+	status := code.Status(time.Now().UTC())
+	loggingMap["confirm_code_status"] = status
+	switch status {
+	case ConfirmCodeStatusUsed:
+		loggingMap.SetMessage("signup confirmation code is already used")
+		ctx.JSON(http.StatusConflict, ConfirmFailureResponse{Reason: status})
+		return
+	case ConfirmCodeStatusExpired, ConfirmCodeStatusInvalidated:
+		loggingMap.SetMessage("signup confirmation code is not active")
+		ctx.JSON(http.StatusGone, ConfirmFailureResponse{Reason: status})
+		return
+	}
+
+	user, err := h.service.repository.ByID(ctx, code.UserId)
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("failed to check if user exists by id")
-		ctx.Status(http.StatusInternalServerError)
+		ctx.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 	if user == nil {
 		_ = h.service.EraseConfirmCode(ctx, confirmCode)
 		loggingMap.SetMessage("user by confirmation code already deleted")
-		ctx.Status(http.StatusNotFound)
+		ctx.JSON(http.StatusNotFound, ConfirmFailureResponse{Reason: confirmFailureUnknown})
 		return
 	}
 
-	err = h.service.Enable(ctx, *userId, confirmCode)
+	confirmed, err := h.service.Enable(ctx, code.UserId, confirmCode)
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("failed to enable user")
-		ctx.Status(http.StatusInternalServerError)
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !confirmed {
+		loggingMap.SetMessage("signup confirmation code is used by the concurrent request")
+		ctx.JSON(http.StatusConflict, ConfirmFailureResponse{Reason: ConfirmCodeStatusUsed})
 		return
 	}
 
-	go h.service.notifService.Registration(userId.String(), user.Email, user.Login)
+	go h.service.notifService.Registration(code.UserId.String(), user.Email, user.Login)
 
 	loggingMap.SetMessage("user signup confirmed")
 	ctx.Status(http.StatusOK)
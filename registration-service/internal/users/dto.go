@@ -1,9 +1,39 @@
 package users
 
+import "time"
+
 type SignupRequest struct {
-	Login    string `json:"login" validate:"required,min=2,max=30,login"`
-	Email    string `json:"email" validate:"required"`
-	Password string `json:"password" validate:"required,password"`
+	Login      string `json:"login" validate:"required,min=2,max=30,login"`
+	Email      string `json:"email" validate:"required"`
+	Password   string `json:"password" validate:"required,password"`
+	InviteCode string `json:"invite_code" validate:"max=32"`
+
+	// the solved proof-of-work challenge, required if the signup policy enables it
+	ChallengeId       string `json:"challenge_id" validate:"max=36"`
+	ChallengeSolution string `json:"challenge_solution" validate:"max=128"`
+}
+
+type ResendRequest struct {
+	Email string `json:"email" validate:"required"`
+}
+
+// PendingConfirmationResponse tells the frontend when the unconfirmed account is erased
+// and when the code may be resent
+type PendingConfirmationResponse struct {
+	Pending           bool       `json:"pending"`
+	EraseAt           *time.Time `json:"erase_at"`
+	CodeExpiresAt     *time.Time `json:"code_expires_at"`
+	ResendAvailableAt *time.Time `json:"resend_available_at"`
+}
+
+// ConfirmFailureResponse has the status of the code which can't confirm the signup,
+// one of expired, used, invalidated or unknown
+type ConfirmFailureResponse struct {
+	Reason string `json:"reason"`
+}
+
+type InviteModeResponse struct {
+	InviteOnly bool `json:"invite_only"`
 }
 
 type HealthCheckResponse struct {
@@ -0,0 +1,45 @@
+package users
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	serverlogging "registration-service/pkg/serverlogging/gin"
+)
+
+type inviteHandler struct {
+	service *Service
+}
+
+func RegisterInviteHandler(api *gin.RouterGroup, service *Service) {
+	h := &inviteHandler{
+		service: service,
+	}
+
+	api.GET("/mode", h.mode)
+	api.GET("/code/:code", h.codeExists)
+}
+
+func (h *inviteHandler) mode(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, InviteModeResponse{InviteOnly: h.service.InviteOnly()})
+}
+
+func (h *inviteHandler) codeExists(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	code := ctx.Param("code")
+	loggingMap["invite_code"] = code
+
+	invite, err := h.service.InviteByCode(ctx, code)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get invite by code")
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if invite != nil {
+		ctx.Status(http.StatusOK)
+	} else {
+		ctx.Status(http.StatusNotFound)
+	}
+}
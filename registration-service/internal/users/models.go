@@ -21,9 +21,45 @@ type User struct {
 	BannedReason     *string
 }
 
+// Invite is the personal invite code of the referrer, the signup with the code is recorded as the referral
+type Invite struct {
+	ReferrerId uuid.UUID
+	Code       string
+}
+
 type Profile struct {
 	ID         uuid.UUID
 	FirstName  string
 	LastName   string
 	MiddleName string
 }
+
+const (
+	ConfirmCodeStatusActive      = "active"
+	ConfirmCodeStatusExpired     = "expired"
+	ConfirmCodeStatusUsed        = "used"
+	ConfirmCodeStatusInvalidated = "invalidated"
+)
+
+type ConfirmCode struct {
+	Code          string
+	UserId        uuid.UUID
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+	UsedAt        *time.Time
+	InvalidatedAt *time.Time
+}
+
+// Status tells whether the code may confirm the signup, codes are invalidated when the new one is sent
+func (c *ConfirmCode) Status(timeNow time.Time) string {
+	switch {
+	case c.UsedAt != nil:
+		return ConfirmCodeStatusUsed
+	case c.InvalidatedAt != nil:
+		return ConfirmCodeStatusInvalidated
+	case !timeNow.Before(c.ExpiresAt):
+		return ConfirmCodeStatusExpired
+	default:
+		return ConfirmCodeStatusActive
+	}
+}
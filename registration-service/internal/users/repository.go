@@ -74,7 +74,8 @@ func (r *Repository) ExistsByEmail(ctx context.Context, email string) (bool, err
 	return count > 0, nil
 }
 
-func (r *Repository) CreateUser(ctx context.Context, user *User, profile *Profile) error {
+// CreateUser creates the user with the profile, the referral is recorded if the user is invited
+func (r *Repository) CreateUser(ctx context.Context, user *User, profile *Profile, invite *Invite) error {
 	if user == nil {
 		return fmt.Errorf("tried to create a nil user")
 	}
@@ -124,42 +125,134 @@ func (r *Repository) CreateUser(ctx context.Context, user *User, profile *Profil
 		return err
 	}
 
+	if invite != nil {
+		query = `insert into referrals (referee_id, referrer_id, code, created) values ($1, $2, $3, $4)`
+		_, err = tx.Exec(ctx, query, user.ID, invite.ReferrerId, invite.Code, user.Created)
+		if err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit(ctx)
 }
 
-func (r *Repository) UserIdByConfirmCode(ctx context.Context, code string) (*uuid.UUID, error) {
-	query := `select user_id from signup_confirm_codes where code = $1`
-	var userId uuid.UUID
-	err := r.db.QueryRow(ctx, query, code).Scan(&userId)
+// InviteByCode returns the invite of the active user by the code, codes are case insensitive
+func (r *Repository) InviteByCode(ctx context.Context, code string) (*Invite, error) {
+	query := `select i.user_id, i.code
+	from invite_codes i
+	join users u on u.id = i.user_id
+	where i.code = upper($1) and u.deleted is false and u.enabled is true`
+	var invite Invite
+	err := r.db.QueryRow(ctx, query, code).Scan(
+		&invite.ReferrerId,
+		&invite.Code,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *Repository) ByEmail(ctx context.Context, email string) (*User, error) {
+	query := `select 
+			id, login, email, role, deleted, enabled, email_confirmed_at, erase_at, created
+	from users
+	where email = $1 and deleted is false`
+	var user User
+	err := r.db.QueryRow(ctx, query, email).Scan(
+		&user.ID,
+		&user.Login,
+		&user.Email,
+		&user.Role,
+		&user.Deleted,
+		&user.Enabled,
+		&user.EmailConfirmedAt,
+		&user.EraseAt,
+		&user.Created,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *Repository) ConfirmCode(ctx context.Context, code string) (*ConfirmCode, error) {
+	query := `select code, user_id, expires_at, created_at, used_at, invalidated_at
+	from signup_confirm_codes where code = $1`
+	var confirmCode ConfirmCode
+	err := r.db.QueryRow(ctx, query, code).Scan(
+		&confirmCode.Code,
+		&confirmCode.UserId,
+		&confirmCode.ExpiresAt,
+		&confirmCode.CreatedAt,
+		&confirmCode.UsedAt,
+		&confirmCode.InvalidatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &confirmCode, nil
+}
+
+func (r *Repository) LatestConfirmCode(ctx context.Context, userId uuid.UUID) (*ConfirmCode, error) {
+	query := `select code, user_id, expires_at, created_at, used_at, invalidated_at
+	from signup_confirm_codes where user_id = $1
+	order by created_at desc
+	limit 1`
+	var confirmCode ConfirmCode
+	err := r.db.QueryRow(ctx, query, userId).Scan(
+		&confirmCode.Code,
+		&confirmCode.UserId,
+		&confirmCode.ExpiresAt,
+		&confirmCode.CreatedAt,
+		&confirmCode.UsedAt,
+		&confirmCode.InvalidatedAt,
+	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &userId, nil
+	return &confirmCode, nil
 }
 
-func (r *Repository) Enable(ctx context.Context, userId uuid.UUID, confirmCode string) error {
+// Enable enables the user and marks the code as used, false is returned if the code isn't active anymore,
+// e.g. it was used or invalidated by the concurrent request
+func (r *Repository) Enable(ctx context.Context, userId uuid.UUID, confirmCode string) (bool, error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer tx.Rollback(ctx)
 
-	query := `update users set enabled = true, erase_at = null, email_confirmed_at = $2 where id = $1`
-	_, err = tx.Exec(ctx, query, userId, time.Now().UTC())
+	timeNow := time.Now().UTC()
+	query := `update signup_confirm_codes set used_at = $2
+	where code = $1 and used_at is null and invalidated_at is null and expires_at > $2`
+	tag, err := tx.Exec(ctx, query, confirmCode, timeNow)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
 	}
 
-	query = `delete from signup_confirm_codes where code = $1`
-	_, err = tx.Exec(ctx, query, confirmCode)
+	query = `update users set enabled = true, erase_at = null, email_confirmed_at = $2 where id = $1`
+	_, err = tx.Exec(ctx, query, userId, timeNow)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return tx.Commit(ctx)
+	return true, tx.Commit(ctx)
 }
 
 func (r *Repository) EraseAllDue(ctx context.Context) error {
@@ -168,8 +261,9 @@ func (r *Repository) EraseAllDue(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	query = `delete from signup_confirm_codes where expires_at < current_timestamp`
-	_, err = r.db.Exec(ctx, query)
+	// expired codes are kept for a while, so confirmation tells them from unknown ones
+	query = `delete from signup_confirm_codes where expires_at < $1`
+	_, err = r.db.Exec(ctx, query, time.Now().UTC().Add(-confirmCodeRetention))
 	return err
 }
 
@@ -179,6 +273,51 @@ func (r *Repository) CreateConfirmationCode(ctx context.Context, code string, us
 	return err
 }
 
+// ReplaceConfirmationCode invalidates active codes of the user and creates the new one. The code isn't created
+// and false is returned if the user has got the code after notBefore, the user row is locked so concurrent
+// requests can't both pass the check.
+func (r *Repository) ReplaceConfirmationCode(ctx context.Context, code string, userId uuid.UUID,
+	expiresAt time.Time, notBefore time.Time) (bool, error) {
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `select id from users where id = $1 for update`
+	_, err = tx.Exec(ctx, query, userId)
+	if err != nil {
+		return false, err
+	}
+
+	query = `select count(code) from signup_confirm_codes where user_id = $1 and created_at > $2`
+	count := 0
+	err = tx.QueryRow(ctx, query, userId, notBefore).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	timeNow := time.Now().UTC()
+	query = `update signup_confirm_codes set invalidated_at = $2
+	where user_id = $1 and used_at is null and invalidated_at is null`
+	_, err = tx.Exec(ctx, query, userId, timeNow)
+	if err != nil {
+		return false, err
+	}
+
+	query = `insert into signup_confirm_codes(code, user_id, expires_at, created_at) values ($1, $2, $3, $4)`
+	_, err = tx.Exec(ctx, query, code, userId, expiresAt, timeNow)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
 func (r *Repository) EraseConfirmCode(ctx context.Context, code string) error {
 	query := `delete from signup_confirm_codes where code = $1`
 	_, err := r.db.Exec(ctx, query, code)
@@ -18,6 +18,11 @@ const (
 	UserAutoEnableConfigKey      = "USER_AUTO_ENABLE"
 	UserCleanupIntervalConfigKey = "USER_CLEANUP_INTERVAL"
 	SignupLifetimeConfigKey      = "SIGNUP_LIFETIME"
+	ResendCooldownConfigKey      = "SIGNUP_RESEND_COOLDOWN"
+	InviteOnlyConfigKey          = "INVITE_ONLY"
+
+	// confirmCodeRetention is how long expired codes are kept after the expiry
+	confirmCodeRetention = 7 * 24 * time.Hour
 )
 
 type Service struct {
@@ -25,13 +30,15 @@ type Service struct {
 	emailService        *email.Service
 	notifService        *notifications.Service
 	autoEnable          bool
+	inviteOnly          bool
 	signupLifetime      int
+	resendCooldown      int
 	userCleanupInterval int
 }
 
 func NewService(ctx context.Context, repository *Repository,
 	emailService *email.Service, notifService *notifications.Service,
-	autoEnable bool, signupLifetime int, userCleanupInterval int,
+	autoEnable bool, inviteOnly bool, signupLifetime int, resendCooldown int, userCleanupInterval int,
 	cfgService *configService.ConfigServiceManager,
 	fileLogger *filelogger.FileLogger,
 	queueLogger *queuelogger.RemoteLogger) *Service {
@@ -41,7 +48,9 @@ func NewService(ctx context.Context, repository *Repository,
 		emailService:        emailService,
 		notifService:        notifService,
 		autoEnable:          autoEnable,
+		inviteOnly:          inviteOnly,
 		signupLifetime:      signupLifetime,
+		resendCooldown:      resendCooldown,
 		userCleanupInterval: userCleanupInterval,
 	}
 
@@ -52,6 +61,13 @@ func NewService(ctx context.Context, repository *Repository,
 		}
 	}, UserAutoEnableConfigKey)
 
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.ParseBool(ss.Value)
+		if err == nil {
+			service.inviteOnly = value
+		}
+	}, InviteOnlyConfigKey)
+
 	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
 		value, err := strconv.Atoi(ss.Value)
 		if err != nil {
@@ -67,6 +83,22 @@ func NewService(ctx context.Context, repository *Repository,
 		service.signupLifetime = value
 	}, SignupLifetimeConfigKey)
 
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err != nil {
+			data := map[string]any{"error": err.Error(), "key": ss.Key, "value": ss.Value}
+			fileLogger.Error("failed to parse value for signup resend cooldown from config", data)
+			data["message"] = "failed to parse value for signup resend cooldown from config"
+			err1 := queueLogger.Error(nil, data)
+			if err1 != nil {
+				fileLogger.Error("failed to send log about signup resend cooldown parsing error to queue",
+					map[string]any{"error": err1.Error()})
+			}
+			return
+		}
+		service.resendCooldown = value
+	}, ResendCooldownConfigKey)
+
 	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
 		interval, err := strconv.Atoi(ss.Value)
 		if err != nil {
@@ -114,7 +146,16 @@ func (s *Service) ExistsByEmail(ctx context.Context, email string) (bool, error)
 	return s.repository.ExistsByEmail(ctx, email)
 }
 
-func (s *Service) HandleSignup(ctx context.Context, login, email, password string) error {
+// InviteOnly tells whether the signup requires the invite code
+func (s *Service) InviteOnly() bool {
+	return s.inviteOnly
+}
+
+func (s *Service) InviteByCode(ctx context.Context, code string) (*Invite, error) {
+	return s.repository.InviteByCode(ctx, code)
+}
+
+func (s *Service) HandleSignup(ctx context.Context, login, email, password string, invite *Invite) error {
 	timeNow := time.Now().UTC()
 	id := uuid.New()
 	passwordHashed, err := cryptservice.CryptValue(password)
@@ -148,7 +189,7 @@ func (s *Service) HandleSignup(ctx context.Context, login, email, password strin
 		MiddleName: "",
 	}
 
-	err = s.repository.CreateUser(ctx, &user, &profile)
+	err = s.repository.CreateUser(ctx, &user, &profile, invite)
 	if err != nil {
 		return err
 	}
@@ -164,11 +205,47 @@ func (s *Service) HandleSignup(ctx context.Context, login, email, password strin
 	return nil
 }
 
-func (s *Service) UserIdByConfirmCode(ctx context.Context, code string) (*uuid.UUID, error) {
-	return s.repository.UserIdByConfirmCode(ctx, code)
+func (s *Service) ByEmail(ctx context.Context, email string) (*User, error) {
+	return s.repository.ByEmail(ctx, email)
+}
+
+func (s *Service) ConfirmCode(ctx context.Context, code string) (*ConfirmCode, error) {
+	return s.repository.ConfirmCode(ctx, code)
+}
+
+func (s *Service) LatestConfirmCode(ctx context.Context, userId uuid.UUID) (*ConfirmCode, error) {
+	return s.repository.LatestConfirmCode(ctx, userId)
+}
+
+// ResendAvailableAt returns the time the new code may be sent after the latest one
+func (s *Service) ResendAvailableAt(latest *ConfirmCode) time.Time {
+	if latest == nil {
+		return time.Now().UTC()
+	}
+	return latest.CreatedAt.Add(time.Duration(s.resendCooldown) * time.Second)
+}
+
+// ResendConfirmationCode emails the new code to the pending user, previous codes are invalidated.
+// The code expires when the user is erased, so resending doesn't prolong unconfirmed accounts.
+// False is returned if the cooldown since the latest code hasn't passed.
+func (s *Service) ResendConfirmationCode(ctx context.Context, user *User) (bool, error) {
+	timeNow := time.Now().UTC()
+	expiresAt := timeNow.Add(time.Duration(s.signupLifetime) * time.Hour)
+	if user.EraseAt != nil {
+		expiresAt = *user.EraseAt
+	}
+
+	code := uuid.New().String()
+	notBefore := timeNow.Add(-time.Duration(s.resendCooldown) * time.Second)
+	sent, err := s.repository.ReplaceConfirmationCode(ctx, code, user.ID, expiresAt, notBefore)
+	if err != nil || !sent {
+		return false, err
+	}
+	go s.emailService.SendSignupConfirmEmail(user.Login, user.Email, code)
+	return true, nil
 }
 
-func (s *Service) Enable(ctx context.Context, userId uuid.UUID, confirmCode string) error {
+func (s *Service) Enable(ctx context.Context, userId uuid.UUID, confirmCode string) (bool, error) {
 	return s.repository.Enable(ctx, userId, confirmCode)
 }
 
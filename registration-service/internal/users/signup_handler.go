@@ -5,21 +5,28 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"net/http"
+	signuppolicy "registration-service/internal/signup-policy"
 	serverlogging "registration-service/pkg/serverlogging/gin"
+	"strconv"
+	"time"
 )
 
 type signupHandler struct {
 	service  *Service
+	policy   *signuppolicy.Service
 	validate *validator.Validate
 }
 
-func RegisterSignupHandler(api *gin.RouterGroup, service *Service) {
+func RegisterSignupHandler(api *gin.RouterGroup, service *Service, policy *signuppolicy.Service) {
 	h := &signupHandler{
 		service:  service,
+		policy:   policy,
 		validate: NewUsersValidator(),
 	}
 
 	api.POST("", h.signup)
+	api.POST("/resend", h.resend)
+	api.GET("/pending/:email", h.pending)
 }
 
 func (h *signupHandler) signup(ctx *gin.Context) {
@@ -40,6 +47,61 @@ func (h *signupHandler) signup(ctx *gin.Context) {
 		return
 	}
 
+	// the proof-of-work goes first, so unsolved requests can't probe existing logins and emails
+	reason, err := h.policy.VerifyChallenge(ctx, req.ChallengeId, req.ChallengeSolution)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to verify signup challenge")
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if reason != "" {
+		loggingMap["reason"] = reason
+		loggingMap.SetMessage("signup is rejected by signup policy")
+		loggingMap.Info()
+		ctx.JSON(http.StatusBadRequest, signuppolicy.ViolationResponse{Reason: reason})
+		return
+	}
+
+	ip := ctx.ClientIP()
+	loggingMap["ip"] = ip
+	blockedUntil, err := h.policy.IpBlockedUntil(ctx, ip)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to check signup throttling of ip")
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if blockedUntil != nil {
+		retryAfter := int(time.Until(*blockedUntil).Seconds()) + 1
+		loggingMap.SetMessage("too many signups from ip")
+		loggingMap.Info()
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		ctx.JSON(http.StatusTooManyRequests, signuppolicy.ViolationResponse{Reason: signuppolicy.ReasonIpThrottled})
+		return
+	}
+
+	var invite *Invite
+	if req.InviteCode != "" {
+		loggingMap["invite_code"] = req.InviteCode
+		invite, err = h.service.InviteByCode(ctx, req.InviteCode)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to get invite by code")
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		if invite == nil {
+			loggingMap.SetMessage("invite code doesn't exists")
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+	} else if h.service.InviteOnly() {
+		loggingMap.SetMessage("signup without invite code in invite only mode")
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
 	exists, err := h.service.ExistsByLogin(ctx, req.Login)
 	if err != nil {
 		loggingMap.SetError(err.Error())
@@ -65,7 +127,47 @@ func (h *signupHandler) signup(ctx *gin.Context) {
 		return
 	}
 
-	err = h.service.HandleSignup(ctx, req.Login, req.Email, req.Password)
+	reason, err = h.policy.CheckLogin(ctx, req.Login)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to check login by signup policy")
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if reason == "" {
+		reason, err = h.policy.CheckEmail(ctx, req.Email)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to check email by signup policy")
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+	}
+	if reason != "" {
+		loggingMap["reason"] = reason
+		loggingMap.SetMessage("signup is rejected by signup policy")
+		loggingMap.Info()
+		ctx.JSON(http.StatusBadRequest, signuppolicy.ViolationResponse{Reason: reason})
+		return
+	}
+
+	blockedUntil, err = h.policy.ReserveSignup(ctx, ip)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reserve signup of ip")
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if blockedUntil != nil {
+		retryAfter := int(time.Until(*blockedUntil).Seconds()) + 1
+		loggingMap.SetMessage("too many signups from ip")
+		loggingMap.Info()
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		ctx.JSON(http.StatusTooManyRequests, signuppolicy.ViolationResponse{Reason: signuppolicy.ReasonIpThrottled})
+		return
+	}
+
+	err = h.service.HandleSignup(ctx, req.Login, req.Email, req.Password, invite)
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("failed to handle signup")
@@ -76,3 +178,109 @@ func (h *signupHandler) signup(ctx *gin.Context) {
 	loggingMap.SetMessage("user successfully signed up")
 	ctx.Status(http.StatusCreated)
 }
+
+func (h *signupHandler) resend(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var req ResendRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to decode resend request")
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad resend request, failed to validate data")
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+	loggingMap["email"] = req.Email
+
+	user, err := h.service.ByEmail(ctx, req.Email)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get user by email")
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		loggingMap.SetMessage("user by email doesn't exists")
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	if user.Enabled || user.EmailConfirmedAt != nil {
+		loggingMap.SetMessage("user signup is already confirmed")
+		ctx.Status(http.StatusConflict)
+		return
+	}
+
+	sent, err := h.service.ResendConfirmationCode(ctx, user)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to resend signup confirmation code")
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if !sent {
+		latest, err := h.service.LatestConfirmCode(ctx, user.ID)
+		if err != nil {
+			loggingMap.SetError(err.Error())
+			loggingMap.SetMessage("failed to get latest signup confirmation code")
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		retryAfter := int(time.Until(h.service.ResendAvailableAt(latest)).Seconds()) + 1
+		loggingMap.SetMessage("signup confirmation code is resent too often")
+		loggingMap.Info()
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		ctx.Status(http.StatusTooManyRequests)
+		return
+	}
+
+	loggingMap.SetMessage("signup confirmation code resent")
+	ctx.Status(http.StatusAccepted)
+}
+
+func (h *signupHandler) pending(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	email := ctx.Param("email")
+	loggingMap["email"] = email
+
+	user, err := h.service.ByEmail(ctx, email)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get user by email")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if user == nil {
+		loggingMap.SetMessage("user by email doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	if user.Enabled || user.EmailConfirmedAt != nil {
+		ctx.JSON(http.StatusOK, PendingConfirmationResponse{Pending: false})
+		return
+	}
+
+	latest, err := h.service.LatestConfirmCode(ctx, user.ID)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get latest signup confirmation code")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	resendAvailableAt := h.service.ResendAvailableAt(latest)
+	response := PendingConfirmationResponse{
+		Pending:           true,
+		EraseAt:           user.EraseAt,
+		ResendAvailableAt: &resendAvailableAt,
+	}
+	if latest != nil && latest.Status(time.Now().UTC()) == ConfirmCodeStatusActive {
+		response.CodeExpiresAt = &latest.ExpiresAt
+	}
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,32 @@
+package notifications
+
+import "time"
+
+type PayoutRequestedEventData struct {
+	At       time.Time `json:"at" validate:"required"`
+	PayoutId string    `json:"payout_id" validate:"required"`
+	Value    float64   `json:"value" validate:"required"`
+	Currency string    `json:"currency" validate:"required"`
+}
+
+type PayoutCompletedEventData struct {
+	At       time.Time `json:"at" validate:"required"`
+	PayoutId string    `json:"payout_id" validate:"required"`
+	Value    float64   `json:"value" validate:"required"`
+	Currency string    `json:"currency" validate:"required"`
+}
+
+type PayoutRejectedEventData struct {
+	At       time.Time `json:"at" validate:"required"`
+	PayoutId string    `json:"payout_id" validate:"required"`
+	Value    float64   `json:"value" validate:"required"`
+	Currency string    `json:"currency" validate:"required"`
+	Reason   string    `json:"reason"`
+}
+
+type PayoutFailedEventData struct {
+	At       time.Time `json:"at" validate:"required"`
+	PayoutId string    `json:"payout_id" validate:"required"`
+	Value    float64   `json:"value" validate:"required"`
+	Currency string    `json:"currency" validate:"required"`
+}
@@ -0,0 +1,141 @@
+package notifications
+
+import (
+	"encoding/json"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"time"
+	"users-service/pkg/filelogger"
+	"users-service/pkg/queuelogger"
+)
+
+const (
+	QueueConfigKey           = "NOTIFICATIONS_QUEUE"
+	EventCodePayoutRequested = "PAYOUT_REQUESTED"
+	EventCodePayoutCompleted = "PAYOUT_COMPLETED"
+	EventCodePayoutRejected  = "PAYOUT_REJECTED"
+	EventCodePayoutFailed    = "PAYOUT_FAILED"
+)
+
+const (
+	CurrencyRub = "rub"
+)
+
+type Service struct {
+	sender      *Sender
+	fileLogger  *filelogger.FileLogger
+	queueLogger *queuelogger.RemoteLogger
+}
+
+func NewService(sender *Sender, cfgService *configService.ConfigServiceManager,
+	fileLogger *filelogger.FileLogger,
+	queueLogger *queuelogger.RemoteLogger) *Service {
+
+	service := &Service{
+		sender:      sender,
+		fileLogger:  fileLogger,
+		queueLogger: queueLogger,
+	}
+
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		sender.queue = ss.Value
+	}, QueueConfigKey)
+
+	return service
+}
+
+func (s *Service) PayoutRequested(userId, payoutId string, value float64) {
+	loggingMap := map[string]any{}
+	obj := PayoutRequestedEventData{
+		At:       time.Now().UTC(),
+		PayoutId: payoutId,
+		Value:    value,
+		Currency: CurrencyRub,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal PAYOUT_REQUESTED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodePayoutRequested, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send PAYOUT_REQUESTED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) PayoutCompleted(userId, payoutId string, value float64) {
+	loggingMap := map[string]any{}
+	obj := PayoutCompletedEventData{
+		At:       time.Now().UTC(),
+		PayoutId: payoutId,
+		Value:    value,
+		Currency: CurrencyRub,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal PAYOUT_COMPLETED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodePayoutCompleted, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send PAYOUT_COMPLETED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) PayoutRejected(userId, payoutId string, value float64, reason string) {
+	loggingMap := map[string]any{}
+	obj := PayoutRejectedEventData{
+		At:       time.Now().UTC(),
+		PayoutId: payoutId,
+		Value:    value,
+		Currency: CurrencyRub,
+		Reason:   reason,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal PAYOUT_REJECTED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodePayoutRejected, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send PAYOUT_REJECTED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
+
+func (s *Service) PayoutFailed(userId, payoutId string, value float64) {
+	loggingMap := map[string]any{}
+	obj := PayoutFailedEventData{
+		At:       time.Now().UTC(),
+		PayoutId: payoutId,
+		Value:    value,
+		Currency: CurrencyRub,
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		loggingMap["message"] = "failed to marshal PAYOUT_FAILED event data"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+	err = s.sender.publishMessage(userId, EventCodePayoutFailed, body)
+	if err != nil {
+		loggingMap["message"] = "failed to send PAYOUT_FAILED event message to notification queue"
+		loggingMap["error"] = err.Error()
+		s.fileLogger.Error("error occurred", loggingMap)
+		_ = s.queueLogger.Error(nil, loggingMap)
+	}
+}
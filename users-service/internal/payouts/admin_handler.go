@@ -0,0 +1,200 @@
+package payouts
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	requestuser "users-service/pkg/hidepost-requestuser"
+	serverlogging "users-service/pkg/serverlogging/gin"
+)
+
+type adminHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterAdminHandler(api *gin.RouterGroup, service *Service) {
+	h := &adminHandler{service: service, validate: validator.New(validator.WithRequiredStructEnabled())}
+
+	api.Use(AdminMiddleware())
+
+	api.GET("", h.all)
+	api.GET("/id/:id", h.byId)
+	api.PUT("/id/:id/approve", h.approve)
+	api.PUT("/id/:id/reject", h.reject)
+	api.PUT("/id/:id/reconcile", h.reconcile)
+}
+
+func (h *adminHandler) all(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	var statuses []string
+	if statusQuery := ctx.Query("status"); statusQuery != "" {
+		statuses = strings.Split(statusQuery, ",")
+	}
+
+	payouts, err := h.service.PayoutsByStatuses(ctx, statuses)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get payouts")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, payouts)
+}
+
+func (h *adminHandler) byId(ctx *gin.Context) {
+	payout := h.payoutFromParam(ctx)
+	if payout == nil {
+		return
+	}
+	ctx.JSON(http.StatusOK, payout)
+}
+
+func (h *adminHandler) approve(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	adminId := requestuser.GetUserID(ctx)
+
+	payout := h.payoutFromParam(ctx)
+	if payout == nil {
+		return
+	}
+
+	ok, err := h.service.Approve(ctx, payout, *adminId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to approve payout")
+		ctx.JSON(http.StatusInternalServerError, payout)
+		return
+	}
+	if !ok {
+		loggingMap.SetMessage("payout is already processed")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	loggingMap.Info()
+	loggingMap.SetMessage("payout approved")
+	ctx.JSON(http.StatusOK, payout)
+}
+
+func (h *adminHandler) reject(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	adminId := requestuser.GetUserID(ctx)
+
+	var req RejectPayoutRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad reject payout request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad reject payout request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	payout := h.payoutFromParam(ctx)
+	if payout == nil {
+		return
+	}
+
+	ok, err := h.service.Reject(ctx, payout, *adminId, req.Reason)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reject payout")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !ok {
+		loggingMap.SetMessage("payout is already processed")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	loggingMap.Info()
+	loggingMap.SetMessage("payout rejected")
+	ctx.JSON(http.StatusOK, payout)
+}
+
+// reconcile resolves the payout stuck in processing, e.g. if it wasn't completed after the provider sent it
+func (h *adminHandler) reconcile(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	adminId := requestuser.GetUserID(ctx)
+
+	var req ReconcilePayoutRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad reconcile payout request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad reconcile payout request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	payout := h.payoutFromParam(ctx)
+	if payout == nil {
+		return
+	}
+
+	var providerRef *string
+	if req.Sent {
+		providerRef = &req.ProviderRef
+	}
+	ok, err := h.service.Reconcile(ctx, payout, *adminId, providerRef)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reconcile payout")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if !ok {
+		loggingMap.SetMessage("payout is not processing or provider ref doesn't match")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	loggingMap.Info()
+	loggingMap.SetMessage("payout reconciled")
+	ctx.JSON(http.StatusOK, payout)
+}
+
+func (h *adminHandler) payoutFromParam(ctx *gin.Context) *Payout {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	idParam := ctx.Param("id")
+	loggingMap["id_param"] = idParam
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return nil
+	}
+
+	payout, err := h.service.PayoutById(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get payout by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return nil
+	}
+	if payout == nil {
+		loggingMap.SetMessage("payout by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return nil
+	}
+	return payout
+}
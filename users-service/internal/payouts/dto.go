@@ -0,0 +1,20 @@
+package payouts
+
+type CreatePayoutRequest struct {
+	ValueRub    float64 `json:"value_rub" validate:"required,gt=0"`
+	Destination string  `json:"destination" validate:"required,max=200"`
+}
+
+type RejectPayoutRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// ReconcilePayoutRequest tells whether the provider sent the stuck payout, the ref is required if it did
+type ReconcilePayoutRequest struct {
+	Sent        bool   `json:"sent"`
+	ProviderRef string `json:"provider_ref" validate:"required_if=Sent true,max=200"`
+}
+
+type MinValueResponse struct {
+	Rub int `json:"rub"`
+}
@@ -0,0 +1,31 @@
+package payouts
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type Payout struct {
+	ID           uuid.UUID  `json:"id"`
+	UserId       uuid.UUID  `json:"user_id"`
+	ValueRub     float64    `json:"value_rub"`
+	Destination  string     `json:"destination"`
+	Status       string     `json:"status"`
+	ProviderRef  *string    `json:"provider_ref"`
+	RejectReason *string    `json:"reject_reason"`
+	ProcessedBy  *uuid.UUID `json:"processed_by"`
+	Created      time.Time  `json:"created"`
+	Updated      time.Time  `json:"updated"`
+}
+
+const (
+	PayoutStatusNew        = "new"
+	PayoutStatusProcessing = "processing"
+	PayoutStatusCompleted  = "completed"
+	PayoutStatusRejected   = "rejected"
+	PayoutStatusFailed     = "failed"
+)
+
+const (
+	MinValueRubConfigKey = "PAYOUT_MIN_VALUE_RUB"
+)
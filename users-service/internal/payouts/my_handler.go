@@ -0,0 +1,125 @@
+package payouts
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"net/http"
+	requestuser "users-service/pkg/hidepost-requestuser"
+	serverlogging "users-service/pkg/serverlogging/gin"
+)
+
+type myHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterMyHandler(api *gin.RouterGroup, service *Service) {
+	h := &myHandler{service: service, validate: validator.New(validator.WithRequiredStructEnabled())}
+	userM := UserMiddleware()
+
+	api.GET("/min-value", h.minValue)
+	api.GET("/my", userM, h.myPayouts)
+	api.POST("/my", userM, h.createMyPayout)
+	api.GET("/my/id/:id", userM, h.myPayoutById)
+}
+
+func (h *myHandler) minValue(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, MinValueResponse{Rub: h.service.MinValueRub()})
+}
+
+func (h *myHandler) myPayouts(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	payouts, err := h.service.PayoutsByUserId(ctx, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get payouts by user id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, payouts)
+}
+
+func (h *myHandler) createMyPayout(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	if requestuser.IsBanned(ctx) {
+		loggingMap.SetMessage("banned user cannot request payouts")
+		ctx.JSON(http.StatusForbidden, nil)
+		return
+	}
+
+	var req CreatePayoutRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad create payout request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad create payout request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	minValue := h.service.MinValueRub()
+	if req.ValueRub < float64(minValue) {
+		loggingMap.SetMessage("value is below minimum payout value")
+		loggingMap["min_value"] = minValue
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	payout, err := h.service.Request(ctx, *userId, req.ValueRub, req.Destination)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to request payout")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if payout == nil {
+		loggingMap.SetMessage("wallet balance is not enough for payout")
+		ctx.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	loggingMap.Info()
+	loggingMap.SetMessage("payout requested")
+	ctx.JSON(http.StatusCreated, payout)
+}
+
+func (h *myHandler) myPayoutById(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	idParam := ctx.Param("id")
+	loggingMap["id_param"] = idParam
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("incorrect param id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	payout, err := h.service.PayoutById(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get payout by id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if payout == nil || payout.UserId != *userId {
+		loggingMap.SetMessage("payout by id doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, payout)
+}
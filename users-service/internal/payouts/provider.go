@@ -0,0 +1,38 @@
+package payouts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Provider transfers an approved payout to the author's destination and
+// returns the provider side reference of the transfer.
+type Provider interface {
+	Send(ctx context.Context, payout *Payout) (string, error)
+}
+
+// ManualProvider is used when admins transfer the money outside the platform
+// before approving the payout, so there is nothing to send.
+type ManualProvider struct{}
+
+func (ManualProvider) Send(ctx context.Context, payout *Payout) (string, error) {
+	return fmt.Sprintf("manual-%s", payout.ID), nil
+}
+
+// StubProvider settles every payout locally. Set Fail to simulate a provider error.
+type StubProvider struct {
+	mu   sync.Mutex
+	Fail bool
+	Sent []Payout
+}
+
+func (p *StubProvider) Send(ctx context.Context, payout *Payout) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Fail {
+		return "", fmt.Errorf("stub provider failed to send payout %s", payout.ID)
+	}
+	p.Sent = append(p.Sent, *payout)
+	return fmt.Sprintf("stub-%d", len(p.Sent)), nil
+}
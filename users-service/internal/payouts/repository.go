@@ -0,0 +1,251 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) scanPayouts(rows pgx.Rows) ([]Payout, error) {
+	defer rows.Close()
+	resultArray := make([]Payout, 0)
+	var item Payout
+	for rows.Next() {
+		err := rows.Scan(
+			&item.ID,
+			&item.UserId,
+			&item.ValueRub,
+			&item.Destination,
+			&item.Status,
+			&item.ProviderRef,
+			&item.RejectReason,
+			&item.ProcessedBy,
+			&item.Created,
+			&item.Updated,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, item)
+	}
+	return resultArray, nil
+}
+
+func (r *Repository) PayoutById(ctx context.Context, id uuid.UUID) (*Payout, error) {
+	query := `select id, user_id, value_rub, destination, status, provider_ref, reject_reason, processed_by, created, updated
+			from payouts
+			where id = $1`
+	var item Payout
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&item.ID,
+		&item.UserId,
+		&item.ValueRub,
+		&item.Destination,
+		&item.Status,
+		&item.ProviderRef,
+		&item.RejectReason,
+		&item.ProcessedBy,
+		&item.Created,
+		&item.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) PayoutsByUserId(ctx context.Context, userId uuid.UUID) ([]Payout, error) {
+	query := `select id, user_id, value_rub, destination, status, provider_ref, reject_reason, processed_by, created, updated
+			from payouts
+			where user_id = $1
+			order by created desc`
+	rows, err := r.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanPayouts(rows)
+}
+
+func (r *Repository) PayoutsByStatuses(ctx context.Context, statuses []string) ([]Payout, error) {
+	query := `select id, user_id, value_rub, destination, status, provider_ref, reject_reason, processed_by, created, updated
+			from payouts
+			where cardinality($1::text[]) = 0 or status = any($1)
+			order by created desc`
+	if statuses == nil {
+		statuses = []string{}
+	}
+	rows, err := r.db.Query(ctx, query, statuses)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanPayouts(rows)
+}
+
+// CreatePayoutWithHold moves the payout value from the wallet balance to the held amount
+// and creates the payout in one transaction. Returns false if the balance is not enough.
+func (r *Repository) CreatePayoutWithHold(ctx context.Context, payout *Payout) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `update wallets set
+		balance_rub = balance_rub - $2,
+		held_rub = held_rub + $2
+	where id = $1 and balance_rub >= $2`
+	tag, err := tx.Exec(ctx, query, payout.UserId, payout.ValueRub)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	query = `insert into payouts
+	(id, user_id, value_rub, destination, status, provider_ref, reject_reason, processed_by, created, updated)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = tx.Exec(ctx, query,
+		payout.ID,
+		payout.UserId,
+		payout.ValueRub,
+		payout.Destination,
+		payout.Status,
+		payout.ProviderRef,
+		payout.RejectReason,
+		payout.ProcessedBy,
+		payout.Created,
+		payout.Updated,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// SetPayoutProcessing moves a new payout to processing. Returns false if the payout
+// was already taken by another admin.
+func (r *Repository) SetPayoutProcessing(ctx context.Context, payout *Payout) (bool, error) {
+	query := `update payouts set
+		status = $2,
+		processed_by = $3,
+		updated = $4
+	where id = $1 and status = $5`
+	tag, err := r.db.Exec(ctx, query,
+		payout.ID,
+		PayoutStatusProcessing,
+		payout.ProcessedBy,
+		payout.Updated,
+		PayoutStatusNew,
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	payout.Status = PayoutStatusProcessing
+	return true, nil
+}
+
+// CompletePayout writes off the held amount of a processing payout.
+func (r *Repository) CompletePayout(ctx context.Context, payout *Payout) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `update payouts set
+		status = $2,
+		provider_ref = $3,
+		updated = $4
+	where id = $1 and status = $5 and (provider_ref is null or provider_ref = $3)`
+	tag, err := tx.Exec(ctx, query,
+		payout.ID,
+		PayoutStatusCompleted,
+		payout.ProviderRef,
+		payout.Updated,
+		PayoutStatusProcessing,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	query = `update wallets set held_rub = held_rub - $2 where id = $1`
+	_, err = tx.Exec(ctx, query, payout.UserId, payout.ValueRub)
+	if err != nil {
+		return err
+	}
+
+	payout.Status = PayoutStatusCompleted
+	return tx.Commit(ctx)
+}
+
+// SetPayoutProviderRef keeps the provider ref of the processing payout which couldn't be completed,
+// so the admin reconciles it by the ref
+func (r *Repository) SetPayoutProviderRef(ctx context.Context, payout *Payout) error {
+	query := `update payouts set provider_ref = $2, updated = $3 where id = $1 and status = $4`
+	_, err := r.db.Exec(ctx, query, payout.ID, payout.ProviderRef, payout.Updated, PayoutStatusProcessing)
+	return err
+}
+
+// ReleasePayoutHold returns the held amount back to the wallet balance and sets the payout
+// to the given final status. Returns false if the payout is not new or processing anymore.
+func (r *Repository) ReleasePayoutHold(ctx context.Context, payout *Payout, status string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `update payouts set
+		status = $2,
+		reject_reason = $3,
+		processed_by = $4,
+		updated = $5
+	where id = $1 and status = any($6) and provider_ref is null`
+	tag, err := tx.Exec(ctx, query,
+		payout.ID,
+		status,
+		payout.RejectReason,
+		payout.ProcessedBy,
+		payout.Updated,
+		[]string{PayoutStatusNew, PayoutStatusProcessing},
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	query = `update wallets set
+		balance_rub = balance_rub + $2,
+		held_rub = held_rub - $2
+	where id = $1`
+	_, err = tx.Exec(ctx, query, payout.UserId, payout.ValueRub)
+	if err != nil {
+		return false, err
+	}
+
+	payout.Status = status
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,207 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"strconv"
+	"sync"
+	"time"
+	"users-service/pkg/queuelogger"
+)
+
+type Notifier interface {
+	PayoutRequested(userId, payoutId string, value float64)
+	PayoutCompleted(userId, payoutId string, value float64)
+	PayoutRejected(userId, payoutId string, value float64, reason string)
+	PayoutFailed(userId, payoutId string, value float64)
+}
+
+type Service struct {
+	repository  *Repository
+	provider    Provider
+	notifier    Notifier
+	queueLogger queuelogger.QueueLogger
+
+	mu          *sync.RWMutex
+	minValueRub int
+}
+
+func NewService(repository *Repository, provider Provider, notifier Notifier,
+	queueLogger queuelogger.QueueLogger, minValueRub int) *Service {
+	return &Service{
+		repository:  repository,
+		provider:    provider,
+		notifier:    notifier,
+		queueLogger: queueLogger,
+		mu:          &sync.RWMutex{},
+		minValueRub: minValueRub,
+	}
+}
+
+func (s *Service) SetConfigUpdateHandlers(cfgService *configService.ConfigServiceManager) {
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil {
+			s.mu.Lock()
+			s.minValueRub = value
+			s.mu.Unlock()
+		}
+	}, MinValueRubConfigKey)
+}
+
+func (s *Service) MinValueRub() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.minValueRub
+}
+
+func (s *Service) PayoutById(ctx context.Context, id uuid.UUID) (*Payout, error) {
+	return s.repository.PayoutById(ctx, id)
+}
+
+func (s *Service) PayoutsByUserId(ctx context.Context, userId uuid.UUID) ([]Payout, error) {
+	return s.repository.PayoutsByUserId(ctx, userId)
+}
+
+func (s *Service) PayoutsByStatuses(ctx context.Context, statuses []string) ([]Payout, error) {
+	return s.repository.PayoutsByStatuses(ctx, statuses)
+}
+
+// Request holds the value on the user wallet and creates a new payout.
+// Returns nil payout if the wallet balance is not enough.
+func (s *Service) Request(ctx context.Context, userId uuid.UUID, valueRub float64, destination string) (*Payout, error) {
+	timeNow := time.Now().UTC()
+	payout := Payout{
+		ID:          uuid.New(),
+		UserId:      userId,
+		ValueRub:    valueRub,
+		Destination: destination,
+		Status:      PayoutStatusNew,
+		Created:     timeNow,
+		Updated:     timeNow,
+	}
+	ok, err := s.repository.CreatePayoutWithHold(ctx, &payout)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	s.historyLog(&payout, "payout requested")
+	go s.notifier.PayoutRequested(userId.String(), payout.ID.String(), payout.ValueRub)
+	return &payout, nil
+}
+
+// Approve sends the payout through the provider. If the provider fails, the held value
+// is returned to the wallet and the payout is marked as failed.
+// Returns false if the payout is not new anymore.
+func (s *Service) Approve(ctx context.Context, payout *Payout, adminId uuid.UUID) (bool, error) {
+	payout.ProcessedBy = &adminId
+	payout.Updated = time.Now().UTC()
+	ok, err := s.repository.SetPayoutProcessing(ctx, payout)
+	if err != nil || !ok {
+		return ok, err
+	}
+	s.historyLog(payout, "payout approved")
+
+	ref, sendErr := s.provider.Send(ctx, payout)
+	payout.Updated = time.Now().UTC()
+	if sendErr != nil {
+		_, err = s.repository.ReleasePayoutHold(ctx, payout, PayoutStatusFailed)
+		if err != nil {
+			return true, fmt.Errorf("provider error: %v, failed to release hold: %v", sendErr, err)
+		}
+		s.historyLog(payout, "payout failed")
+		go s.notifier.PayoutFailed(payout.UserId.String(), payout.ID.String(), payout.ValueRub)
+		return true, fmt.Errorf("provider error: %v", sendErr)
+	}
+
+	payout.ProviderRef = &ref
+	err = s.repository.CompletePayout(ctx, payout)
+	if err != nil {
+		// the money is sent, so the payout stays processing with the provider ref until the admin reconciles it
+		s.historyLog(payout, "payout sent but not completed")
+		if refErr := s.repository.SetPayoutProviderRef(ctx, payout); refErr != nil {
+			return true, fmt.Errorf("payout sent with provider ref %s, failed to complete: %v, failed to save ref: %v",
+				ref, err, refErr)
+		}
+		return true, fmt.Errorf("payout sent with provider ref %s, failed to complete: %v", ref, err)
+	}
+	s.historyLog(payout, "payout completed")
+	go s.notifier.PayoutCompleted(payout.UserId.String(), payout.ID.String(), payout.ValueRub)
+	return true, nil
+}
+
+// Reconcile resolves the payout stuck in processing after the provider call: if the provider sent it,
+// the payout is completed with the provider ref, otherwise the held value is returned to the wallet.
+// The payout with the stored provider ref was sent by the provider, so it is only completed with the same ref.
+// Returns false if the payout is not processing or the provider ref doesn't match.
+func (s *Service) Reconcile(ctx context.Context, payout *Payout, adminId uuid.UUID, providerRef *string) (bool, error) {
+	if payout.Status != PayoutStatusProcessing {
+		return false, nil
+	}
+	if payout.ProviderRef != nil && (providerRef == nil || *providerRef != *payout.ProviderRef) {
+		return false, nil
+	}
+	payout.ProcessedBy = &adminId
+	payout.Updated = time.Now().UTC()
+	if providerRef == nil {
+		ok, err := s.repository.ReleasePayoutHold(ctx, payout, PayoutStatusFailed)
+		if err != nil || !ok {
+			return ok, err
+		}
+		s.historyLog(payout, "payout reconciled as failed")
+		go s.notifier.PayoutFailed(payout.UserId.String(), payout.ID.String(), payout.ValueRub)
+		return true, nil
+	}
+
+	payout.ProviderRef = providerRef
+	err := s.repository.CompletePayout(ctx, payout)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	s.historyLog(payout, "payout reconciled as completed")
+	go s.notifier.PayoutCompleted(payout.UserId.String(), payout.ID.String(), payout.ValueRub)
+	return true, nil
+}
+
+// Reject returns the held value to the wallet. Returns false if the payout is not new anymore.
+func (s *Service) Reject(ctx context.Context, payout *Payout, adminId uuid.UUID, reason string) (bool, error) {
+	if payout.Status != PayoutStatusNew {
+		return false, nil
+	}
+	payout.ProcessedBy = &adminId
+	payout.RejectReason = &reason
+	payout.Updated = time.Now().UTC()
+	ok, err := s.repository.ReleasePayoutHold(ctx, payout, PayoutStatusRejected)
+	if err != nil || !ok {
+		return ok, err
+	}
+	s.historyLog(payout, "payout rejected")
+	go s.notifier.PayoutRejected(payout.UserId.String(), payout.ID.String(), payout.ValueRub, reason)
+	return true, nil
+}
+
+func (s *Service) historyLog(payout *Payout, message string) {
+	data := map[string]any{
+		"message":   message,
+		"payout_id": payout.ID.String(),
+		"value_rub": payout.ValueRub,
+		"status":    payout.Status,
+	}
+	if payout.ProviderRef != nil {
+		data["provider_ref"] = *payout.ProviderRef
+	}
+	if payout.ProcessedBy != nil {
+		data["processed_by"] = payout.ProcessedBy.String()
+	}
+	_ = s.queueLogger.Info(&payout.UserId, data)
+}
@@ -0,0 +1,19 @@
+package referrals
+
+type InviteCodeResponse struct {
+	Code string `json:"code"`
+}
+
+type FirstPaymentServiceRequest struct {
+	InvoiceId int `json:"invoice_id" validate:"required"`
+}
+
+type RewardResponse struct {
+	Rewarded  bool    `json:"rewarded"`
+	RewardRub float64 `json:"reward_rub"`
+}
+
+type RevokeResponse struct {
+	Revoked   bool    `json:"revoked"`
+	RewardRub float64 `json:"reward_rub"`
+}
@@ -0,0 +1,35 @@
+package referrals
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type InviteCode struct {
+	UserId  uuid.UUID `json:"user_id"`
+	Code    string    `json:"code"`
+	Created time.Time `json:"created"`
+}
+
+type Referral struct {
+	RefereeId      uuid.UUID  `json:"referee_id"`
+	RefereeLogin   string     `json:"referee_login"`
+	ReferrerId     uuid.UUID  `json:"referrer_id"`
+	Code           string     `json:"code"`
+	Created        time.Time  `json:"created"`
+	FirstPaymentAt *time.Time `json:"first_payment_at"`
+	InvoiceId      *int       `json:"-"`
+	RewardRub      *float64   `json:"reward_rub"`
+	RewardedAt     *time.Time `json:"rewarded_at"`
+}
+
+type Stats struct {
+	Invited   int     `json:"invited"`
+	Paid      int     `json:"paid"`
+	Rewarded  int     `json:"rewarded"`
+	RewardRub float64 `json:"reward_rub"`
+}
+
+const (
+	RewardRubConfigKey = "REFERRAL_REWARD_RUB"
+)
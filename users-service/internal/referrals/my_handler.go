@@ -0,0 +1,63 @@
+package referrals
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	requestuser "users-service/pkg/hidepost-requestuser"
+	serverlogging "users-service/pkg/serverlogging/gin"
+)
+
+type myHandler struct {
+	service *Service
+}
+
+func RegisterMyHandler(api *gin.RouterGroup, service *Service) {
+	h := &myHandler{service: service}
+	userM := UserMiddleware()
+
+	api.GET("/my", userM, h.myReferrals)
+	api.GET("/my/invite-code", userM, h.myInviteCode)
+	api.GET("/my/stats", userM, h.myStats)
+}
+
+func (h *myHandler) myReferrals(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	referrals, err := h.service.ReferralsByReferrerId(ctx, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get referrals by referrer id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, referrals)
+}
+
+func (h *myHandler) myInviteCode(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	item, err := h.service.InviteCodeByUserId(ctx, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get invite code by user id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, InviteCodeResponse{Code: item.Code})
+}
+
+func (h *myHandler) myStats(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+	userId := requestuser.GetUserID(ctx)
+
+	stats, err := h.service.StatsByReferrerId(ctx, *userId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get referral stats by referrer id")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
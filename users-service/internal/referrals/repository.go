@@ -0,0 +1,225 @@
+package referrals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) InviteCodeByUserId(ctx context.Context, userId uuid.UUID) (*InviteCode, error) {
+	query := `select user_id, code, created from invite_codes where user_id = $1`
+	var item InviteCode
+	err := r.db.QueryRow(ctx, query, userId).Scan(
+		&item.UserId,
+		&item.Code,
+		&item.Created,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CreateInviteCode does nothing if the user already has the code or the code is taken
+func (r *Repository) CreateInviteCode(ctx context.Context, item *InviteCode) error {
+	query := `insert into invite_codes (user_id, code, created) values ($1, $2, $3) on conflict do nothing`
+	_, err := r.db.Exec(ctx, query, item.UserId, item.Code, item.Created)
+	return err
+}
+
+func (r *Repository) ReferralByRefereeId(ctx context.Context, refereeId uuid.UUID) (*Referral, error) {
+	query := `select r.referee_id, u.login, r.referrer_id, r.code, r.created, r.first_payment_at, r.reward_rub, r.rewarded_at
+			from referrals r
+			join users u on u.id = r.referee_id
+			where r.referee_id = $1`
+	var item Referral
+	err := r.db.QueryRow(ctx, query, refereeId).Scan(
+		&item.RefereeId,
+		&item.RefereeLogin,
+		&item.ReferrerId,
+		&item.Code,
+		&item.Created,
+		&item.FirstPaymentAt,
+		&item.RewardRub,
+		&item.RewardedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) ReferralsByReferrerId(ctx context.Context, referrerId uuid.UUID) ([]Referral, error) {
+	query := `select r.referee_id, u.login, r.referrer_id, r.code, r.created, r.first_payment_at, r.reward_rub, r.rewarded_at
+			from referrals r
+			join users u on u.id = r.referee_id
+			where r.referrer_id = $1 and u.enabled is true
+			order by r.created desc`
+	rows, err := r.db.Query(ctx, query, referrerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resultArray := make([]Referral, 0)
+	var item Referral
+	for rows.Next() {
+		err := rows.Scan(
+			&item.RefereeId,
+			&item.RefereeLogin,
+			&item.ReferrerId,
+			&item.Code,
+			&item.Created,
+			&item.FirstPaymentAt,
+			&item.RewardRub,
+			&item.RewardedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resultArray = append(resultArray, item)
+	}
+	return resultArray, nil
+}
+
+// StatsByReferrerId counts confirmed referees of the referrer, unconfirmed signups are erased with their referrals
+func (r *Repository) StatsByReferrerId(ctx context.Context, referrerId uuid.UUID) (*Stats, error) {
+	query := `select count(r.referee_id),
+				count(r.first_payment_at),
+				count(r.rewarded_at),
+				coalesce(sum(r.reward_rub), 0)
+			from referrals r
+			join users u on u.id = r.referee_id
+			where r.referrer_id = $1 and u.enabled is true`
+	var stats Stats
+	err := r.db.QueryRow(ctx, query, referrerId).Scan(
+		&stats.Invited,
+		&stats.Paid,
+		&stats.Rewarded,
+		&stats.RewardRub,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// RewardFirstPayment records the first payment of the referee and adds the reward to the wallet of the referrer
+// in one transaction. Nil is returned if the first payment is already recorded, so the reward is granted once.
+func (r *Repository) RewardFirstPayment(ctx context.Context, refereeId uuid.UUID, invoiceId int, rewardRub float64,
+	timeNow time.Time) (*Referral, error) {
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var reward *float64
+	var rewardedAt *time.Time
+	if rewardRub > 0 {
+		reward = &rewardRub
+		rewardedAt = &timeNow
+	}
+
+	query := `update referrals set first_payment_at = $2, invoice_id = $3, reward_rub = $4, rewarded_at = $5
+			where referee_id = $1 and first_payment_at is null
+			returning referee_id, referrer_id, code, created, first_payment_at, invoice_id, reward_rub, rewarded_at`
+	var item Referral
+	err = tx.QueryRow(ctx, query, refereeId, timeNow, invoiceId, reward, rewardedAt).Scan(
+		&item.RefereeId,
+		&item.ReferrerId,
+		&item.Code,
+		&item.Created,
+		&item.FirstPaymentAt,
+		&item.InvoiceId,
+		&item.RewardRub,
+		&item.RewardedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if reward != nil {
+		query = `update wallets set balance_rub = balance_rub + $2 where id = $1`
+		tag, err := tx.Exec(ctx, query, item.ReferrerId, rewardRub)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil, fmt.Errorf("wallet of referrer %s not found", item.ReferrerId)
+		}
+	}
+
+	return &item, tx.Commit(ctx)
+}
+
+// RevokeReward resets the first payment of the referee paid by the refunded invoice and takes the reward back
+// from the wallet of the referrer in one transaction, the balance may become negative if the reward is spent.
+// The next payment of the referee is rewarded again. Nil is returned if the invoice isn't the first payment.
+func (r *Repository) RevokeReward(ctx context.Context, invoiceId int) (*Referral, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `select referee_id, referrer_id, code, created, first_payment_at, invoice_id, reward_rub, rewarded_at
+			from referrals
+			where invoice_id = $1
+			for update`
+	var item Referral
+	err = tx.QueryRow(ctx, query, invoiceId).Scan(
+		&item.RefereeId,
+		&item.ReferrerId,
+		&item.Code,
+		&item.Created,
+		&item.FirstPaymentAt,
+		&item.InvoiceId,
+		&item.RewardRub,
+		&item.RewardedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	query = `update referrals set first_payment_at = null, invoice_id = null, reward_rub = null, rewarded_at = null
+			where referee_id = $1`
+	_, err = tx.Exec(ctx, query, item.RefereeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.RewardRub != nil {
+		query = `update wallets set balance_rub = balance_rub - $2 where id = $1`
+		_, err = tx.Exec(ctx, query, item.ReferrerId, *item.RewardRub)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &item, tx.Commit(ctx)
+}
@@ -0,0 +1,133 @@
+package referrals
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"github.com/google/uuid"
+	configService "github.com/llc-ldbit/go-cloud-config-client"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+	"users-service/internal/users"
+)
+
+const (
+	inviteCodeLength   = 8
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	inviteCodeAttempts = 5
+)
+
+type Service struct {
+	repository   *Repository
+	usersService *users.Service
+
+	mu        *sync.RWMutex
+	rewardRub int
+}
+
+func NewService(repository *Repository, usersService *users.Service, rewardRub int) *Service {
+	return &Service{
+		repository:   repository,
+		usersService: usersService,
+		mu:           &sync.RWMutex{},
+		rewardRub:    rewardRub,
+	}
+}
+
+func (s *Service) SetConfigUpdateHandlers(cfgService *configService.ConfigServiceManager) {
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		value, err := strconv.Atoi(ss.Value)
+		if err == nil {
+			s.mu.Lock()
+			s.rewardRub = value
+			s.mu.Unlock()
+		}
+	}, RewardRubConfigKey)
+}
+
+func (s *Service) RewardRub() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rewardRub
+}
+
+// InviteCodeByUserId returns the personal invite code of the user, the code is created on the first request
+func (s *Service) InviteCodeByUserId(ctx context.Context, userId uuid.UUID) (*InviteCode, error) {
+	item, err := s.repository.InviteCodeByUserId(ctx, userId)
+	if err != nil || item != nil {
+		return item, err
+	}
+	for i := 0; i < inviteCodeAttempts; i++ {
+		code, err := generateInviteCode()
+		if err != nil {
+			return nil, err
+		}
+		err = s.repository.CreateInviteCode(ctx, &InviteCode{
+			UserId:  userId,
+			Code:    code,
+			Created: time.Now().UTC(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		// the code is either created, created by the concurrent request or taken by another user
+		item, err = s.repository.InviteCodeByUserId(ctx, userId)
+		if err != nil || item != nil {
+			return item, err
+		}
+	}
+	return nil, errors.New("failed to generate unique invite code")
+}
+
+func (s *Service) ReferralsByReferrerId(ctx context.Context, referrerId uuid.UUID) ([]Referral, error) {
+	return s.repository.ReferralsByReferrerId(ctx, referrerId)
+}
+
+func (s *Service) StatsByReferrerId(ctx context.Context, referrerId uuid.UUID) (*Stats, error) {
+	return s.repository.StatsByReferrerId(ctx, referrerId)
+}
+
+// RewardFirstPayment grants the configured reward to the referrer of the user after the first payment.
+// Nil is returned if the user wasn't invited or the first payment is already recorded.
+func (s *Service) RewardFirstPayment(ctx context.Context, refereeId uuid.UUID, invoiceId int) (*Referral, error) {
+	referral, err := s.repository.ReferralByRefereeId(ctx, refereeId)
+	if err != nil || referral == nil || referral.FirstPaymentAt != nil {
+		return nil, err
+	}
+
+	rewardRub := s.RewardRub()
+	if rewardRub > 0 {
+		wallet, err := s.usersService.WalletByUserId(ctx, referral.ReferrerId)
+		if err != nil {
+			return nil, err
+		}
+		if wallet == nil {
+			if _, err := s.usersService.CreateWalletToUser(ctx, referral.ReferrerId); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s.repository.RewardFirstPayment(ctx, refereeId, invoiceId, float64(rewardRub), time.Now().UTC())
+}
+
+// RevokeReward takes back the reward granted for the refunded invoice.
+// Nil is returned if the invoice isn't the recorded first payment of a referee.
+func (s *Service) RevokeReward(ctx context.Context, invoiceId int) (*Referral, error) {
+	return s.repository.RevokeReward(ctx, invoiceId)
+}
+
+func generateInviteCode() (string, error) {
+	code := make([]byte, inviteCodeLength)
+	max := big.NewInt(int64(len(inviteCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,111 @@
+package referrals
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	serverlogging "users-service/pkg/serverlogging/gin"
+)
+
+type serviceHandler struct {
+	service  *Service
+	validate *validator.Validate
+}
+
+func RegisterServiceHandler(api *gin.RouterGroup, service *Service) {
+	h := &serviceHandler{service: service, validate: validator.New(validator.WithRequiredStructEnabled())}
+	serviceM := ServiceMiddleware()
+
+	api.PUT("/referee/id/:id/first-payment", serviceM, h.firstPayment)
+	api.PUT("/invoice/id/:id/refund", serviceM, h.revokeReward)
+}
+
+// firstPayment is called by billing service after every confirmed payment of the user,
+// the reward is granted once for the first one
+func (h *serviceHandler) firstPayment(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	idParam := ctx.Param("id")
+	loggingMap["id_param"] = idParam
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to parse referee id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	var req FirstPaymentServiceRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to unmarshal to struct")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+	loggingMap["req_body"] = fmt.Sprintf("%+v", req)
+	if err := h.validate.Struct(req); err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("bad request, failed to validate data")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	referral, err := h.service.RewardFirstPayment(ctx, id, req.InvoiceId)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to reward first payment of referee")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if referral == nil {
+		ctx.JSON(http.StatusOK, RewardResponse{Rewarded: false})
+		return
+	}
+	loggingMap["referrer_id"] = referral.ReferrerId
+	response := RewardResponse{Rewarded: referral.RewardRub != nil}
+	if referral.RewardRub != nil {
+		response.RewardRub = *referral.RewardRub
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// revokeReward is called by billing service after every refund of the invoice,
+// the reward is revoked if the invoice is the rewarded first payment of the referee
+func (h *serviceHandler) revokeReward(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	idParam := ctx.Param("id")
+	loggingMap["id_param"] = idParam
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to parse invoice id")
+		ctx.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	referral, err := h.service.RevokeReward(ctx, id)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to revoke referral reward of refunded invoice")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if referral == nil {
+		ctx.JSON(http.StatusOK, RevokeResponse{Revoked: false})
+		return
+	}
+	loggingMap["referrer_id"] = referral.ReferrerId
+	loggingMap["referee_id"] = referral.RefereeId
+	response := RevokeResponse{Revoked: true}
+	if referral.RewardRub != nil {
+		response.RewardRub = *referral.RewardRub
+	}
+	loggingMap.SetMessage("referral reward revoked")
+	loggingMap.Info()
+	ctx.JSON(http.StatusOK, response)
+}
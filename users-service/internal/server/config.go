@@ -12,6 +12,8 @@ type Config struct {
 	ConfigServicePort    string `env:"CONFIG_SERVICE_PORT"`
 	ConfigUpdateInterval int    `env:"CONFIG_UPDATE_INTERVAL" env-default:"60"`
 
+	IdentityAssertionSecret string `config-service:"IDENTITY_ASSERTION_SECRET"`
+
 	WalletServiceUrl string `config-service:"WALLET_SERVICE_URL"`
 
 	FileQueue          string `config-service:"FILE_QUEUE"`
@@ -28,6 +30,12 @@ type Config struct {
 	MQUser     string `config-service:"MQ_USER"`
 	MQPassword string `config-service:"MQ_PASSWORD"`
 	LogQueue   string `config-service:"LOG_QUEUE"`
+
+	NotificationQueue string `config-service:"NOTIFICATION_QUEUE"`
+
+	PayoutMinValueRub int `config-service:"PAYOUT_MIN_VALUE_RUB"`
+
+	ReferralRewardRub int `config-service:"REFERRAL_REWARD_RUB"`
 }
 
 func (cfg *Config) DbUrl() string {
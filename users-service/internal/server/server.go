@@ -14,9 +14,13 @@ import (
 	"syscall"
 	"time"
 	"users-service/internal/files"
+	"users-service/internal/notifications"
+	"users-service/internal/payouts"
+	"users-service/internal/referrals"
 	"users-service/internal/users"
 	"users-service/internal/wallet"
 	"users-service/pkg/filelogger"
+	requestuser "users-service/pkg/hidepost-requestuser"
 	"users-service/pkg/pgutils"
 	"users-service/pkg/queuelogger"
 	serverlogging "users-service/pkg/serverlogging/gin"
@@ -73,8 +77,16 @@ func Run() {
 	}
 	defer sender.Close()
 
+	notificationsSender, err := notifications.NewSender(ctx, cfg.MqUrl(), cfg.NotificationQueue)
+	if err != nil {
+		log.Fatalln("failed to init notifications sender:", err)
+	}
+	defer notificationsSender.Close()
+
 	// init repositories
 	usersRepository := users.NewRepository(dbConn)
+	payoutsRepository := payouts.NewRepository(dbConn)
+	referralsRepository := referrals.NewRepository(dbConn)
 
 	// init services
 	filesService := files.NewService(sender, cfg.FileGetEndpointUrl, cfgService, fileLogger, mqLogger)
@@ -83,12 +95,31 @@ func Run() {
 		log.Fatalln("failed to init wallet service:", err)
 	}
 	usersService := users.NewService(ctx, usersRepository, walletService, filesService)
+	notificationsService := notifications.NewService(notificationsSender, cfgService, fileLogger, mqLogger)
+	payoutsService := payouts.NewService(payoutsRepository, payouts.ManualProvider{}, notificationsService,
+		mqLogger, cfg.PayoutMinValueRub)
+	payoutsService.SetConfigUpdateHandlers(cfgService)
+	referralsService := referrals.NewService(referralsRepository, usersService, cfg.ReferralRewardRub)
+	referralsService.SetConfigUpdateHandlers(cfgService)
+
+	// identity assertions are verified for every request and signed for requests to other services
+	identitySigner, err := requestuser.NewSigner(cfg.IdentityAssertionSecret, cfg.ServiceName)
+	if err != nil {
+		log.Fatalln("failed to init identity signer:", err)
+	}
+	cfgService.SetUpdateHandler(func(ss configService.ServiceSetting) {
+		if err := identitySigner.SetSecret(ss.Value); err != nil {
+			fileLogger.Error("failed to update identity assertion secret from config",
+				map[string]any{"error": err.Error(), "key": ss.Key})
+		}
+	}, requestuser.IdentityAssertionSecretConfigKey)
 
 	// setting up gin app
 	gin.SetMode(gin.ReleaseMode)
 	app := gin.New()
 	app.Use(gin.CustomRecovery(serverlogging.NewPanicLogger(fileLogger, mqLogger)))
 	app.Use(serverlogging.NewRequestLogger(fileLogger, mqLogger))
+	app.Use(identitySigner.Middleware())
 
 	// init handlers
 	apiV1 := app.Group("/api/v1/users")
@@ -105,6 +136,14 @@ func Run() {
 	// profile handler
 	users.RegisterProfileHandler(apiV1.Group("/profile"), usersService)
 
+	// payouts handlers
+	payouts.RegisterMyHandler(apiV1.Group("/payouts"), payoutsService)
+	payouts.RegisterAdminHandler(apiV1.Group("/admin/payouts"), payoutsService)
+
+	// referrals handlers
+	referrals.RegisterMyHandler(apiV1.Group("/referrals"), referralsService)
+	referrals.RegisterServiceHandler(apiV1.Group("/service/referrals"), referralsService)
+
 	//start config updater
 	go cfgService.Updater()
 
@@ -13,9 +13,19 @@ type UserInfoResponse struct {
 	BannedReason *string   `json:"banned_reason"`
 }
 
+// PublicUserInfoResponse is the user info without private fields, it is returned by public lookups
+type PublicUserInfoResponse struct {
+	ID           uuid.UUID `json:"id"`
+	Role         string    `json:"role"`
+	Login        string    `json:"login"`
+	IsBanned     bool      `json:"is_banned"`
+	BannedReason *string   `json:"banned_reason"`
+}
+
 type MyProfileWalletResponse struct {
 	BalanceTon float64 `json:"balance_ton"`
 	BalanceRub float64 `json:"balance_rub"`
+	HeldRub    float64 `json:"held_rub"`
 	Address    string  `json:"address"`
 }
 
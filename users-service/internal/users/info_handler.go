@@ -18,6 +18,7 @@ func RegisterInfoHandler(api *gin.RouterGroup, service *Service) {
 	api.GET("/id", h.byIdList)
 	api.GET("/id/:id", h.byId)
 	api.GET("/id/:id/wallet-address", h.userWalletAddress)
+	api.GET("/login/:login", h.byLogin)
 }
 
 func (h *infoHandler) byIdList(ctx *gin.Context) {
@@ -93,6 +94,39 @@ func (h *infoHandler) byId(ctx *gin.Context) {
 	})
 }
 
+func (h *infoHandler) byLogin(ctx *gin.Context) {
+	loggingMap := serverlogging.GetLoggingMap(ctx)
+
+	login := ctx.Param("login")
+	loggingMap["req_login_param"] = login
+
+	user, err := h.service.ByLogin(ctx, login)
+	if err != nil {
+		loggingMap.SetError(err.Error())
+		loggingMap.SetMessage("failed to get user by login")
+		ctx.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	if user == nil {
+		loggingMap.SetMessage("user by login doesn't exists")
+		ctx.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	var isBanned = false
+	if user.BannedUntil != nil && time.Now().UTC().Before(*user.BannedUntil) {
+		isBanned = true
+	}
+
+	ctx.JSON(http.StatusOK, PublicUserInfoResponse{
+		ID:           user.ID,
+		Login:        user.Login,
+		Role:         user.Role,
+		BannedReason: user.BannedReason,
+		IsBanned:     isBanned,
+	})
+}
+
 func (h *infoHandler) userWalletAddress(ctx *gin.Context) {
 	loggingMap := serverlogging.GetLoggingMap(ctx)
 
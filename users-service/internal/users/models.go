@@ -36,5 +36,6 @@ type Wallet struct {
 	Mnemonic   []string  `json:"mnemonic"`
 	Address    string    `json:"address"`
 	BalanceRub float64   `json:"balanceRub"`
+	HeldRub    float64   `json:"heldRub"`
 	Created    time.Time `json:"created"`
 }
@@ -127,6 +127,7 @@ func (h *profileHandler) myWallet(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, MyProfileWalletResponse{
 		BalanceTon: balance,
 		BalanceRub: walletObj.BalanceRub,
+		HeldRub:    walletObj.HeldRub,
 		Address:    walletObj.Address,
 	})
 }
@@ -294,7 +294,7 @@ func (r *Repository) EraseById(ctx context.Context, id uuid.UUID) error {
 }
 
 func (r *Repository) WalletByUserId(ctx context.Context, userId uuid.UUID) (*Wallet, error) {
-	query := `select id, address, publicKey, secretKey, mnemonic, balance_rub, created from wallets where id = $1`
+	query := `select id, address, publicKey, secretKey, mnemonic, balance_rub, held_rub, created from wallets where id = $1`
 	var wallet Wallet
 	err := r.db.QueryRow(ctx, query, userId).Scan(
 		&wallet.ID,
@@ -303,6 +303,7 @@ func (r *Repository) WalletByUserId(ctx context.Context, userId uuid.UUID) (*Wal
 		&wallet.SecretKey,
 		&wallet.Mnemonic,
 		&wallet.BalanceRub,
+		&wallet.HeldRub,
 		&wallet.Created,
 	)
 	if err != nil {
@@ -340,6 +341,14 @@ func (r *Repository) UpdateWalletRubBalance(ctx context.Context, wallet *Wallet)
 	return err
 }
 
+func (r *Repository) AddToWalletRubBalance(ctx context.Context, walletId uuid.UUID, value float64) error {
+	query := `update wallets set
+		balance_rub = balance_rub + $2
+	where id = $1`
+	_, err := r.db.Exec(ctx, query, walletId, value)
+	return err
+}
+
 func (r *Repository) ProfileById(ctx context.Context, userId uuid.UUID) (*Profile, error) {
 	query := `select id, first_name, last_name, middle_name, avatar from profiles where id = $1`
 	var profile Profile
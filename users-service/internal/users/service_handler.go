@@ -68,9 +68,7 @@ func (h *serviceHandler) addRubToWallet(ctx *gin.Context) {
 		return
 	}
 
-	walletObj.BalanceRub += req.Value
-
-	err = h.service.repository.UpdateWalletRubBalance(ctx, walletObj)
+	err = h.service.repository.AddToWalletRubBalance(ctx, walletObj.ID, req.Value)
 	if err != nil {
 		loggingMap.SetError(err.Error())
 		loggingMap.SetMessage("failed to update wallet")
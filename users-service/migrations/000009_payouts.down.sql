@@ -0,0 +1,4 @@
+drop table if exists payouts;
+
+alter table wallets
+    drop column if exists held_rub;
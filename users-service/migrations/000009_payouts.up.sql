@@ -0,0 +1,18 @@
+alter table wallets
+    add column if not exists held_rub numeric(10, 2) not null default 0.00;
+
+create table payouts
+(
+    id            uuid primary key,
+    user_id       uuid           not null,
+    value_rub     numeric(10, 2) not null,
+    destination   text           not null,
+    status        text           not null,
+    provider_ref  text                    default null,
+    reject_reason text                    default null,
+    processed_by  uuid                    default null,
+    created       timestamp      not null default current_timestamp,
+    updated       timestamp      not null default current_timestamp
+);
+
+create index payouts_user_id_idx on payouts (user_id);
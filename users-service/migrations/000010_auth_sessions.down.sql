@@ -0,0 +1 @@
+drop table if exists auth_sessions;
\ No newline at end of file
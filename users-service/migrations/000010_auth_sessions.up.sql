@@ -0,0 +1,15 @@
+create table auth_sessions
+(
+    id                 uuid primary key,
+    user_id            uuid      not null references users (id) on delete cascade,
+    refresh_token_hash text      not null,
+    remember_me        boolean   not null default false,
+    user_agent         text      not null default '',
+    ip                 text      not null default '',
+    last_seen          timestamp not null,
+    expires_at         timestamp not null,
+    revoked_at         timestamp          default null,
+    created            timestamp not null default current_timestamp
+);
+
+create index auth_sessions_user_id_idx on auth_sessions (user_id);
@@ -0,0 +1,2 @@
+drop table if exists password_reset_requests;
+drop table if exists password_reset_tokens;
\ No newline at end of file
@@ -0,0 +1,21 @@
+create table password_reset_tokens
+(
+    token_hash text primary key,
+    user_id    uuid      not null references users (id) on delete cascade,
+    expires_at timestamp not null,
+    used_at    timestamp          default null,
+    created    timestamp not null default current_timestamp
+);
+
+create index password_reset_tokens_user_id_idx on password_reset_tokens (user_id);
+
+create table password_reset_requests
+(
+    id      uuid primary key,
+    email   text      not null,
+    ip      text      not null,
+    created timestamp not null default current_timestamp
+);
+
+create index password_reset_requests_email_idx on password_reset_requests (lower(email), created);
+create index password_reset_requests_ip_idx on password_reset_requests (ip, created);
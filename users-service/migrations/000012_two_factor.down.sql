@@ -0,0 +1,2 @@
+drop table if exists two_factor_recovery_codes;
+drop table if exists user_two_factor;
\ No newline at end of file
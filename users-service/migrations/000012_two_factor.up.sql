@@ -0,0 +1,20 @@
+create table user_two_factor
+(
+    user_id        uuid primary key references users (id) on delete cascade,
+    secret         text      not null,
+    enabled_at     timestamp          default null,
+    last_used_step bigint    not null default 0,
+    created        timestamp not null default current_timestamp,
+    updated        timestamp not null default current_timestamp
+);
+
+create table two_factor_recovery_codes
+(
+    id        uuid primary key,
+    user_id   uuid      not null references users (id) on delete cascade,
+    code_hash text      not null,
+    used_at   timestamp          default null,
+    created   timestamp not null default current_timestamp
+);
+
+create index two_factor_recovery_codes_user_id_idx on two_factor_recovery_codes (user_id);
@@ -0,0 +1,9 @@
+create table login_failures
+(
+    key           text primary key,
+    failures      int       not null default 0,
+    blocked_until timestamp          default null,
+    last_failure  timestamp not null
+);
+
+create index login_failures_last_failure_idx on login_failures (last_failure);
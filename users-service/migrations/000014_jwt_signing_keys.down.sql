@@ -0,0 +1 @@
+drop table if exists jwt_signing_keys;
\ No newline at end of file
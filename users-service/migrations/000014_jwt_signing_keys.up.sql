@@ -0,0 +1,8 @@
+create table jwt_signing_keys
+(
+    kid         text primary key,
+    private_key bytea     not null,
+    public_key  bytea     not null,
+    rotated_at  timestamp          default null,
+    created     timestamp not null default current_timestamp
+);
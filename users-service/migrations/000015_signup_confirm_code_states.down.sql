@@ -0,0 +1,5 @@
+drop index if exists signup_confirm_codes_user_id_idx;
+
+alter table signup_confirm_codes
+    drop column if exists used_at,
+    drop column if exists invalidated_at;
@@ -0,0 +1,5 @@
+alter table signup_confirm_codes
+    add column used_at        timestamp default null,
+    add column invalidated_at timestamp default null;
+
+create index signup_confirm_codes_user_id_idx on signup_confirm_codes (user_id, created_at);
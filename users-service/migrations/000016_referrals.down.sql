@@ -0,0 +1,2 @@
+drop table if exists referrals;
+drop table if exists invite_codes;
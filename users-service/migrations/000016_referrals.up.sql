@@ -0,0 +1,19 @@
+create table invite_codes
+(
+    user_id uuid primary key references users (id) on delete cascade,
+    code    text      not null unique,
+    created timestamp not null default current_timestamp
+);
+
+create table referrals
+(
+    referee_id       uuid primary key references users (id) on delete cascade,
+    referrer_id      uuid           not null references users (id) on delete cascade,
+    code             text           not null,
+    created          timestamp      not null default current_timestamp,
+    first_payment_at timestamp               default null,
+    reward_rub       numeric(10, 2)          default null,
+    rewarded_at      timestamp               default null
+);
+
+create index referrals_referrer_id_idx on referrals (referrer_id);
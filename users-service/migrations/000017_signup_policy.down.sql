@@ -0,0 +1,5 @@
+drop index if exists users_login_skeleton_idx;
+drop function if exists login_skeleton(text);
+drop table if exists signup_challenges;
+drop table if exists signup_requests;
+drop table if exists signup_email_domains;
@@ -0,0 +1,41 @@
+create table signup_email_domains
+(
+    domain     text primary key,
+    policy     text      not null,
+    created_by uuid               default null references users (id) on delete set null,
+    created    timestamp not null default current_timestamp
+);
+
+create table signup_requests
+(
+    id      uuid primary key,
+    ip      text      not null,
+    created timestamp not null default current_timestamp
+);
+
+create index signup_requests_ip_idx on signup_requests (ip, created);
+
+create table signup_challenges
+(
+    id         uuid primary key,
+    nonce      text      not null,
+    difficulty int       not null,
+    expires_at timestamp not null,
+    solved_at  timestamp default null
+);
+
+create index signup_challenges_expires_at_idx on signup_challenges (expires_at);
+
+-- login_skeleton folds look-alike characters of ascii logins, registration service
+-- maps unicode confusables to ascii before comparing the skeleton of the new login
+create function login_skeleton(login text) returns text
+    language sql
+    immutable
+    strict
+    parallel safe
+as
+$$
+select translate(replace(replace(lower(login), 'rn', 'm'), 'vv', 'w'), '01i_', 'oll')
+$$;
+
+create index users_login_skeleton_idx on users (login_skeleton(login));
@@ -0,0 +1,4 @@
+drop index if exists referrals_invoice_id_idx;
+
+alter table referrals
+    drop column if exists invoice_id;
@@ -0,0 +1,4 @@
+alter table referrals
+    add column if not exists invoice_id integer default null;
+
+create index referrals_invoice_id_idx on referrals (invoice_id);
@@ -0,0 +1,11 @@
+create or replace function login_skeleton(login text) returns text
+    language sql
+    immutable
+    strict
+    parallel safe
+as
+$$
+select translate(replace(replace(lower(login), 'rn', 'm'), 'vv', 'w'), '01i_', 'oll')
+$$;
+
+reindex index users_login_skeleton_idx;
@@ -0,0 +1,27 @@
+-- login_skeleton matches Skeleton of registration service: fullwidth forms are mapped to ascii, letters of other
+-- scripts to latin look-alikes (uppercase ones are listed as well, so the mapping doesn't depend on the locale
+-- of lower), combining diacritical marks are dropped and then ascii look-alikes are folded
+create or replace function login_skeleton(login text) returns text
+    language sql
+    immutable
+    strict
+    parallel safe
+as
+$$
+select translate(
+               replace(
+                       replace(
+                               regexp_replace(
+                                       translate(
+                                               lower(translate(login,
+                                                               '！＂＃＄％＆＇（）＊＋，－．／０１２３４５６７８９：；＜＝＞？＠ＡＢＣＤＥＦＧＨＩＪＫＬＭＮＯＰＱＲＳＴＵＶＷＸＹＺ［＼］＾＿｀ａｂｃｄｅｆｇｈｉｊｋｌｍｎｏｐｑｒｓｔｕｖｗｘｙｚ｛｜｝～',
+                                                               '!"#$%&''()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\]^_`abcdefghijklmnopqrstuvwxyz{|}~')),
+                                               'авеёкмнорстухѕіїјԁһԛԝɡьпαβεηικνορτυχωζıłℓøßđħАВЕЁКМНОРСТУХЅІЇЈԀҺԚԜꞬЬПΑΒΕΗΙΚΝΟΡΤΥΧΩΖŁØĐĦ',
+                                               'abeekmhopctyxsiijdhqwgbnabenikvoptuxwzillobdhabeekmhopctyxsiijdhqwgbnabenikvoptuxwzlodh'),
+                                       '[\u0300-\u036f]', '', 'g'),
+                               'rn', 'm'),
+                       'vv', 'w'),
+               '01i_', 'oll')
+$$;
+
+reindex index users_login_skeleton_idx;
@@ -0,0 +1 @@
+drop table if exists auth_session_rotated_tokens;
\ No newline at end of file
@@ -0,0 +1,8 @@
+create table auth_session_rotated_tokens
+(
+    token_hash text primary key,
+    session_id uuid      not null references auth_sessions (id) on delete cascade,
+    rotated_at timestamp not null
+);
+
+create index auth_session_rotated_tokens_session_id_idx on auth_session_rotated_tokens (session_id);
@@ -0,0 +1,175 @@
+package requestuser
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	IdentityAssertionHeaderKey       = "IDENTITY-ASSERTION"
+	IdentityAssertionSecretConfigKey = "IDENTITY_ASSERTION_SECRET"
+
+	// GatewayIssuer is the only issuer trusted to assert the identity of a user,
+	// services assert only their own service identity
+	GatewayIssuer = "api_gateway"
+
+	// MinSecretLength is the shortest secret accepted, shorter ones could be brute forced
+	MinSecretLength = 32
+
+	// assertionLifetime is short since the assertion is made for every request
+	assertionLifetime = 1 * time.Minute
+	// secretGraceWindow is how long the previous secret is accepted after the secret is changed,
+	// so requests signed by services that haven't got the new secret yet aren't rejected
+	secretGraceWindow = 10 * time.Minute
+)
+
+// Assertion is the identity of the request user signed by the gateway or the calling service
+type Assertion struct {
+	UserId    string `json:"user_id"`
+	Role      string `json:"role"`
+	Banned    bool   `json:"banned"`
+	Issuer    string `json:"iss"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Signer signs and verifies identity assertions with the secret shared by the gateway and services
+type Signer struct {
+	secret         []byte
+	previousSecret []byte
+	secretChanged  time.Time
+	issuer         string
+	mu             *sync.RWMutex
+}
+
+func NewSigner(secret string, issuer string) (*Signer, error) {
+	if err := validateSecret(secret); err != nil {
+		return nil, err
+	}
+	return &Signer{
+		secret: []byte(secret),
+		issuer: issuer,
+		mu:     &sync.RWMutex{},
+	}, nil
+}
+
+func validateSecret(secret string) error {
+	if len(secret) < MinSecretLength {
+		return fmt.Errorf("identity assertion secret must be at least %d characters long", MinSecretLength)
+	}
+	return nil
+}
+
+// SetSecret changes the signing secret, the previous one is still accepted for the grace window.
+// The short secret is rejected and the current one is kept.
+func (s *Signer) SetSecret(secret string) error {
+	if err := validateSecret(secret); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if string(s.secret) == secret {
+		return nil
+	}
+	s.previousSecret = s.secret
+	s.secretChanged = time.Now()
+	s.secret = []byte(secret)
+	return nil
+}
+
+func mac(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Signer) mac(payload string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return mac(s.secret, payload)
+}
+
+// validSignature checks the signature with the current secret or with the previous one within the grace window
+func (s *Signer) validSignature(payload, signature string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if hmac.Equal([]byte(signature), []byte(mac(s.secret, payload))) {
+		return true
+	}
+	return s.previousSecret != nil && time.Since(s.secretChanged) < secretGraceWindow &&
+		hmac.Equal([]byte(signature), []byte(mac(s.previousSecret, payload)))
+}
+
+// Sign returns the assertion value of the header, the issuer and the expiry are set by the signer
+func (s *Signer) Sign(assertion Assertion) string {
+	assertion.Issuer = s.issuer
+	assertion.ExpiresAt = time.Now().Add(assertionLifetime).Unix()
+	// marshaling of the plain struct can't fail
+	body, _ := json.Marshal(assertion)
+	payload := base64.RawURLEncoding.EncodeToString(body)
+	return payload + "." + s.mac(payload)
+}
+
+// Verify returns the assertion of the validly signed value. The user identity is accepted
+// only from the gateway, other issuers can assert only the service identity.
+func (s *Signer) Verify(value string) (*Assertion, error) {
+	payload, signature, found := strings.Cut(value, ".")
+	if !found {
+		return nil, fmt.Errorf("malformed identity assertion")
+	}
+	if !s.validSignature(payload, signature) {
+		return nil, fmt.Errorf("invalid identity assertion signature")
+	}
+	body, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("malformed identity assertion: %v", err)
+	}
+	var assertion Assertion
+	if err = json.Unmarshal(body, &assertion); err != nil {
+		return nil, fmt.Errorf("malformed identity assertion: %v", err)
+	}
+	if time.Now().Unix() > assertion.ExpiresAt {
+		return nil, fmt.Errorf("identity assertion expired")
+	}
+	if (assertion.UserId != "" || assertion.Role != UserRoleService) && assertion.Issuer != GatewayIssuer {
+		return nil, fmt.Errorf("issuer %s can't assert the user identity", assertion.Issuer)
+	}
+	return &assertion, nil
+}
+
+// SignServiceRequest sets the service credentials to the request made to another service
+func (s *Signer) SignServiceRequest(req *http.Request) {
+	req.Header.Set(IdentityAssertionHeaderKey, s.Sign(Assertion{Role: UserRoleService}))
+}
+
+// Middleware replaces the identity headers of the request with the ones of the verified assertion,
+// the request without valid assertion is handled as the one of unknown user
+func (s *Signer) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		header := ctx.Request.Header
+		value := header.Get(IdentityAssertionHeaderKey)
+		header.Del(UserIdHeaderKey)
+		header.Del(UserRoleHeaderKey)
+		header.Del(UserIsBannedHeaderKey)
+		header.Del(IdentityAssertionHeaderKey)
+
+		if value != "" {
+			assertion, err := s.Verify(value)
+			if err == nil {
+				header.Set(UserIdHeaderKey, assertion.UserId)
+				header.Set(UserRoleHeaderKey, assertion.Role)
+				if assertion.Banned {
+					header.Set(UserIsBannedHeaderKey, "true")
+				}
+			}
+		}
+		ctx.Next()
+	}
+}